@@ -0,0 +1,76 @@
+package main
+
+// agent.go 提供隐藏的 cloudcode agent 子命令：在 ECS 实例上由 systemd（cloudcode-agent.service）运行，
+// 按自动停机策略检测闲置并停机；抢占式实例同时监测回收通知并创建快照。
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/hwuu/cloudcode/internal/agent"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/spf13/cobra"
+)
+
+func newAgentCmd() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:         "agent",
+		Short:       "自动停机 / 抢占式实例回收监测 agent（在 ECS 实例上运行）",
+		Hidden:      true,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{cloudSideAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := agent.LoadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			ecsCli, err := alicloud.NewECSClientWithRAMRole(cfg.Region, cfg.RoleName)
+			if err != nil {
+				return fmt.Errorf("初始化 ECS 客户端失败: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			var wg sync.WaitGroup
+			if cfg.Policy.Enabled() {
+				probe := &agent.ProcProbe{}
+				a := &agent.Agent{
+					Config: *cfg,
+					ECS:    ecsCli,
+					Probe:  probe.Count,
+					Output: os.Stdout,
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = a.Run(ctx)
+				}()
+			}
+			if cfg.Spot {
+				w := &agent.SpotWatcher{
+					Config:  *cfg,
+					ECS:     ecsCli,
+					Output:  os.Stdout,
+					Version: version,
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = w.Run(ctx)
+				}()
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", agent.DefaultConfigPath, "agent 配置文件")
+
+	return cmd
+}
@@ -0,0 +1,139 @@
+package main
+
+// autosuspend.go 提供 cloudcode autosuspend 子命令：set / off / status 管理自动停机策略。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newAutoSuspendCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "autosuspend",
+		Short: "管理自动停机策略",
+		Long: `管理自动停机策略：实例上的 cloudcode-agent 每分钟统计活动会话
+（OpenCode / ttyd 连接和 SSH 会话），闲置超时或不在活跃时段时以 StopCharging 模式停机。
+
+agent 使用绑定到实例的 RAM 角色（cloudcode-agent-<部署 ID>）调用 StopInstance，
+该角色只能停机本实例，实例上不保存 AccessKey。
+停机后运行 cloudcode resume 恢复；本地状态在下次运行 cloudcode 时自动同步。`,
+	}
+
+	cmd.AddCommand(newAutoSuspendSetCmd())
+	cmd.AddCommand(newAutoSuspendOffCmd())
+	cmd.AddCommand(newAutoSuspendStatusCmd())
+
+	return cmd
+}
+
+// newAutoSuspendManager 创建带阿里云客户端的 AutoSuspendManager
+func newAutoSuspendManager() (*deploy.AutoSuspendManager, error) {
+	cfg, err := alicloud.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("阿里云配置错误: %w", err)
+	}
+	clients, err := alicloud.NewClients(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+	}
+	return &deploy.AutoSuspendManager{
+		ECS:    clients.ECS,
+		STS:    clients.STS,
+		RAM:    clients.RAM,
+		Output: os.Stdout,
+		Region: cfg.RegionID,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return sshPool.DialFunc(host, port, user, privateKey)
+		},
+		SFTPFactory: sshPool.NewSFTPClient,
+		Version:     version,
+	}, nil
+}
+
+func newAutoSuspendSetCmd() *cobra.Command {
+	var policy config.AutoSuspendPolicy
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "开启或更新自动停机",
+		Example: `  cloudcode autosuspend set --idle 30m
+  cloudcode autosuspend set --active-hours "Mon-Fri 09:00-19:00" --timezone Asia/Shanghai
+  cloudcode autosuspend set --idle 2h --active-hours "09:00-23:00"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newAutoSuspendManager()
+			if err != nil {
+				return err
+			}
+			return m.Set(cmd.Context(), &policy)
+		},
+	}
+
+	cmd.Flags().StringVar(&policy.Idle, "idle", "", fmt.Sprintf("闲置多久后停机（如 30m、2h，最少 %s）", config.MinAutoSuspendIdle))
+	cmd.Flags().StringVar(&policy.ActiveHours, "active-hours", "", `活跃时段（如 "Mon-Fri 09:00-19:00"），时段外闲置 10 分钟即停机`)
+	cmd.Flags().StringVar(&policy.Timezone, "timezone", "", "活跃时段所在时区（默认 "+config.DefaultAutoSuspendTimezone+"）")
+
+	return cmd
+}
+
+func newAutoSuspendOffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "off",
+		Short: "关闭自动停机（停止 agent 并删除 RAM 角色）",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newAutoSuspendManager()
+			if err != nil {
+				return err
+			}
+			return m.Disable(cmd.Context())
+		},
+	}
+}
+
+func newAutoSuspendStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "查看自动停机策略和 agent 状态",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m := &deploy.AutoSuspendManager{
+				Output: os.Stdout,
+				SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+					return sshPool.DialFunc(host, port, user, privateKey)
+				},
+			}
+			return m.Status(cmd.Context())
+		},
+	}
+}
+
+// reconcileInstanceStatus 开启自动停机、唤醒页或使用抢占式实例时将本地 state 与云上实例状态对齐
+// （agent 停机、唤醒页启动、抢占式实例回收都不会更新本地 state）。
+// 尽力而为：凭证不可用或查询失败时只提示，不影响当前命令。
+func reconcileInstanceStatus() {
+	state, err := config.LoadState()
+	if err != nil || (!state.CloudCode.AutoSuspend.Enabled() && state.Resources.Wake.FunctionName == "" && !state.Resources.ECS.Spot) {
+		return
+	}
+	cfg, err := alicloud.LoadConfig()
+	if err != nil {
+		return
+	}
+	clients, err := alicloud.NewClients(cfg)
+	if err != nil {
+		return
+	}
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return
+	}
+	if _, err := deploy.ReconcileStatus(clients.ECS, cfg.RegionID, stateDir, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ 同步实例状态失败: %v\n", err)
+	}
+}
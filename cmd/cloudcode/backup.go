@@ -0,0 +1,286 @@
+package main
+
+// backup.go 提供 cloudcode backup 子命令：create / list / restore / prune 管理系统盘快照备份。
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/objstore"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "管理系统盘快照备份",
+		Long: `管理系统盘快照备份。备份记录在 ~/.cloudcode/envs/<env>/backups.json，
+快照按容量计费（cloudcode cost 可查看估算）。
+
+定时备份可用 cron 调用，例如每天 3 点备份并按策略清理：
+  0 3 * * * cloudcode backup create --label nightly --keep-daily 7 --keep-weekly 4
+
+--volumes 仅备份数据（devbox 工作区、OpenCode 配置 / 会话和 Authelia 账号），
+通过 SSH 打包传输到本地文件或 S3 兼容对象存储，可恢复到其他区域的全新部署。`,
+	}
+
+	cmd.AddCommand(newBackupCreateCmd())
+	cmd.AddCommand(newBackupListCmd())
+	cmd.AddCommand(newBackupRestoreCmd())
+	cmd.AddCommand(newBackupPruneCmd())
+
+	return cmd
+}
+
+// newBackupManager 创建带阿里云客户端的 BackupManager
+func newBackupManager() (*deploy.BackupManager, error) {
+	cfg, err := alicloud.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("阿里云配置错误: %w", err)
+	}
+	clients, err := alicloud.NewClients(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+	}
+	return &deploy.BackupManager{
+		ECS:      clients.ECS,
+		Prompter: config.NewPrompter(os.Stdin, os.Stdout),
+		Output:   os.Stdout,
+		Region:   cfg.RegionID,
+		Env:      config.ActiveEnv(),
+		Version:  version,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return sshPool.DialFunc(host, port, user, privateKey)
+		},
+	}, nil
+}
+
+// newVolumeStore 按位置创建数据卷备份存储；OSS 地址未配置 AWS_* 密钥时使用阿里云 AccessKey
+func newVolumeStore(location, defaultName string) (objstore.Store, error) {
+	if !objstore.IsS3URL(location) {
+		return objstore.New(location, defaultName, nil)
+	}
+	cfg := objstore.S3ConfigFromEnv()
+	if cfg.AccessKeyID == "" && objstore.IsOSSEndpoint(cfg.Endpoint) {
+		if aliCfg, err := alicloud.LoadConfig(); err == nil {
+			cfg.AccessKeyID = aliCfg.AccessKeyID
+			cfg.SecretAccessKey = aliCfg.AccessKeySecret
+		}
+	}
+	client, err := objstore.NewS3Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return objstore.New(location, defaultName, client)
+}
+
+// newAppDeployer 创建用于重新部署应用层（deploy --app）的 Deployer
+func newAppDeployer() (*deploy.Deployer, error) {
+	cfg, err := alicloud.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("阿里云配置错误: %w", err)
+	}
+	clients, err := alicloud.NewClients(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+	}
+	return &deploy.Deployer{
+		ECS:      clients.ECS,
+		VPC:      clients.VPC,
+		STS:      clients.STS,
+		DNS:      clients.DNS,
+		RAM:      clients.RAM,
+		Prompter: config.NewPrompter(os.Stdin, os.Stdout),
+		Output:   os.Stdout,
+		Region:   cfg.RegionID,
+		Env:      config.ActiveEnv(),
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return sshPool.DialFunc(host, port, user, privateKey)
+		},
+		SFTPFactory: sshPool.NewSFTPClient,
+		GetPublicIP: remote.GetPublicIP,
+		Version:     version,
+	}, nil
+}
+
+// addRetentionFlags 注册保留策略参数
+func addRetentionFlags(cmd *cobra.Command, policy *config.RetentionPolicy) {
+	cmd.Flags().IntVar(&policy.KeepLast, "keep-last", 0, "保留最近 N 份")
+	cmd.Flags().IntVar(&policy.KeepDaily, "keep-daily", 0, "保留最近 N 天每天最新的一份")
+	cmd.Flags().IntVar(&policy.KeepWeekly, "keep-weekly", 0, "保留最近 N 周每周最新的一份")
+}
+
+func newBackupCreateCmd() *cobra.Command {
+	var label, to string
+	var quiesce, volumes bool
+	var policy config.RetentionPolicy
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "为系统盘创建快照",
+		Long: `为系统盘创建快照，实例运行中也可创建。
+
+--quiesce 快照前停止容器（docker compose stop）、快照时间点确定后立即启动，
+保证 Authelia 数据库等文件一致，期间服务中断约数十秒。
+指定 --keep-* 时创建后按策略清理旧备份（不再确认），适合 cron 定时执行。
+
+--volumes 改为备份数据卷，--to 指定位置（默认 ~/.cloudcode/envs/<env>/volumes/）：
+  cloudcode backup create --volumes --to ./backups/
+  cloudcode backup create --volumes --to s3://my-bucket/cloudcode/
+对象存储通过 AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY / AWS_REGION 和
+CLOUDCODE_S3_ENDPOINT（如 http://localhost:9000、https://oss-cn-hangzhou.aliyuncs.com）配置，
+OSS 未设置 AWS_* 密钥时使用阿里云 AccessKey。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !volumes && to != "" {
+				return fmt.Errorf("--to 仅用于 --volumes")
+			}
+			if volumes && (label != "" || !policy.IsZero()) {
+				return fmt.Errorf("--volumes 不支持 --label 和 --keep-*")
+			}
+			m, err := newBackupManager()
+			if err != nil {
+				return err
+			}
+			if volumes {
+				if to == "" {
+					dir, err := config.GetActiveEnvDir()
+					if err != nil {
+						return err
+					}
+					to = filepath.Join(dir, "volumes") + string(filepath.Separator)
+				}
+				store, err := newVolumeStore(to, deploy.VolumeBackupName(config.ActiveEnv(), time.Now()))
+				if err != nil {
+					return err
+				}
+				return m.BackupVolumes(cmd.Context(), store, quiesce)
+			}
+			if _, err := m.Create(cmd.Context(), label, quiesce); err != nil {
+				return err
+			}
+			if policy.IsZero() {
+				return nil
+			}
+			return m.Prune(policy, false, true)
+		},
+	}
+
+	cmd.Flags().StringVar(&label, "label", "", "备份标签（restore 时可用标签代替快照 ID）")
+	cmd.Flags().BoolVar(&quiesce, "quiesce", false, "快照前停止容器，保证数据一致")
+	cmd.Flags().BoolVar(&volumes, "volumes", false, "仅备份数据卷（打包传输到本地或对象存储）")
+	cmd.Flags().StringVar(&to, "to", "", "数据卷备份位置：本地文件 / 目录或 s3://bucket/key")
+	addRetentionFlags(cmd, &policy)
+
+	return cmd
+}
+
+func newBackupListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出备份",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newBackupManager()
+			if err != nil {
+				return err
+			}
+			return m.List()
+		},
+	}
+}
+
+func newBackupRestoreCmd() *cobra.Command {
+	var force, noBackup, volumes bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <快照 ID 或标签 | 数据卷备份位置>",
+		Short: "从备份恢复",
+		Long: `从备份恢复。
+
+实例存在时：停机，为当前系统盘创建 pre-restore 备份（--no-backup 跳过），
+从快照创建镜像并替换系统盘，然后启动实例。EIP、域名和 RAM 角色不变，
+磁盘上的数据（账号、API Key、工作区等）回到备份时的状态。
+
+实例已销毁时：将该备份设为恢复点，运行 cloudcode deploy 从快照重新部署。
+
+--volumes 从数据卷备份（本地文件或 s3://bucket/key）恢复到运行中的部署（可以是新部署），
+覆盖工作区、OpenCode 配置 / 会话和 Authelia 账号，然后重新部署应用层：
+  cloudcode deploy && cloudcode backup restore --volumes s3://my-bucket/cloudcode/cloudcode-volumes-default-20260101-030000.tar.gz`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if volumes && noBackup {
+				return fmt.Errorf("--no-backup 不适用于 --volumes")
+			}
+			m, err := newBackupManager()
+			if err != nil {
+				return err
+			}
+			if volumes {
+				store, err := newVolumeStore(args[0], "")
+				if err != nil {
+					return err
+				}
+				restored, err := m.RestoreVolumes(cmd.Context(), store, force)
+				if err != nil || !restored {
+					return err
+				}
+				d, err := newAppDeployer()
+				if err != nil {
+					return err
+				}
+				fmt.Println()
+				return d.Run(cmd.Context(), true)
+			}
+			needsDeploy, err := m.Restore(cmd.Context(), args[0], force, noBackup)
+			if err != nil {
+				return err
+			}
+			if needsDeploy {
+				fmt.Println("  运行 cloudcode deploy 从该快照恢复部署")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "跳过确认")
+	cmd.Flags().BoolVar(&noBackup, "no-backup", false, "恢复前不备份当前系统盘")
+	cmd.Flags().BoolVar(&volumes, "volumes", false, "从数据卷备份恢复")
+
+	return cmd
+}
+
+func newBackupPruneCmd() *cobra.Command {
+	var dryRun, force bool
+	var policy config.RetentionPolicy
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "按保留策略删除旧备份",
+		Long: `按保留策略删除旧备份：保留满足任一条件的备份，其余删除。
+天 / 周按本地时区划分，只统计有备份的天 / 周；最新的一份和 deploy 恢复点始终保留。
+
+示例：保留最近 7 天每天一份、最近 4 周每周一份
+  cloudcode backup prune --keep-daily 7 --keep-weekly 4`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newBackupManager()
+			if err != nil {
+				return err
+			}
+			return m.Prune(policy, dryRun, force)
+		},
+	}
+
+	addRetentionFlags(cmd, &policy)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "仅展示将删除的备份")
+	cmd.Flags().BoolVar(&force, "force", false, "跳过确认")
+
+	return cmd
+}
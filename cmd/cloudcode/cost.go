@@ -0,0 +1,81 @@
+package main
+
+// cost.go 提供 cloudcode cost：估算部署的每小时 / 每月费用和自部署以来的累计费用。
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/spf13/cobra"
+)
+
+func newCostCmd() *cobra.Command {
+	var pricesFile string
+	var spec config.CloudSpec
+
+	cmd := &cobra.Command{
+		Use:   "cost",
+		Short: "估算部署费用",
+		Long: `按区域价格表估算实例、系统盘、EIP 和快照的每小时 / 每月费用，
+并根据状态记录统计自部署以来的运行 / 停机时长和累计费用（不含公网流量）。
+
+价格为内置的按量付费参考价，可在 ~/.cloudcode/prices.yaml 中覆盖或补充
+（--prices 指定其他文件），格式同内置价格表：
+  currency: USD
+  regions:
+    ap-southeast-1:
+      instance_types:
+        ecs.g7.xlarge: 0.196      # 每小时
+      disks:
+        cloud_essd: 0.02          # 每 GB 每月
+      eip_hourly: 0.004
+      eip_traffic_gb: 0.117
+      snapshot_gb_month: 0.02
+
+--instance-type / --disk-size / --disk-category 估算变更规格后的费用（未部署时基于默认规格）。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := spec.Validate(); err != nil {
+				return err
+			}
+			prices, err := loadPrices(pricesFile)
+			if err != nil {
+				return err
+			}
+			region := alicloud.DefaultRegion
+			if cfg, err := alicloud.LoadConfig(); err == nil {
+				region = cfg.RegionID
+			}
+			c := &deploy.CostRunner{
+				Output: os.Stdout,
+				Region: region,
+				Prices: prices,
+				Spec:   spec,
+			}
+			return c.Run()
+		},
+	}
+
+	cmd.Flags().StringVar(&pricesFile, "prices", "", "价格文件（默认 ~/.cloudcode/"+cost.PricesFileName+"，不存在时只用内置价格）")
+	cmd.Flags().StringVar(&spec.InstanceType, "instance-type", "", "按指定实例规格估算")
+	cmd.Flags().IntVar(&spec.DiskSize, "disk-size", 0, "按指定系统盘大小（GB）估算")
+	cmd.Flags().StringVar(&spec.DiskCategory, "disk-category", "", "按指定系统盘类型估算")
+
+	return cmd
+}
+
+// loadPrices 加载内置价格表，path 为空时用 ~/.cloudcode/prices.yaml（存在时）覆盖
+func loadPrices(path string) (*cost.PriceTable, error) {
+	if path != "" {
+		return cost.LoadPrices(path, true)
+	}
+	stateDir, err := config.GetStateDir()
+	if err != nil {
+		return cost.BuiltinPrices(), nil
+	}
+	return cost.LoadPrices(filepath.Join(stateDir, cost.PricesFileName), false)
+}
@@ -0,0 +1,256 @@
+package main
+
+// env.go 提供 cloudcode env 子命令：list（列出环境）、use（切换默认环境）、rm（删除本地环境记录）、
+// ssh-route（连接实例 SSH 的跳板机 / 代理）。
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newEnvCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "管理多个部署环境",
+		Long: `管理多个部署环境。每个环境拥有独立的 state.json、backup.json 和 SSH 私钥，
+存放在 ~/.cloudcode/envs/<name>/ 下。
+
+其他命令通过 --env <name> 或 CLOUDCODE_ENV 环境变量选择环境，
+未指定时使用 cloudcode env use 设置的环境（默认 default）。
+连接实例 SSH 的跳板机 / 代理也按环境配置（cloudcode env ssh-route）。`,
+	}
+
+	cmd.AddCommand(newEnvListCmd())
+	cmd.AddCommand(newEnvUseCmd())
+	cmd.AddCommand(newEnvRmCmd())
+	cmd.AddCommand(newEnvSSHRouteCmd())
+
+	return cmd
+}
+
+func newEnvListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出所有环境",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envs, err := config.ListEnvs()
+			if err != nil {
+				return err
+			}
+			if len(envs) == 0 {
+				fmt.Printf("暂无环境。当前环境: %s\n", config.ActiveEnv())
+				return nil
+			}
+			for _, e := range envs {
+				marker := " "
+				if e.Current {
+					marker = "*"
+				}
+				status := e.Status
+				if status == "" {
+					status = "-"
+				}
+				line := fmt.Sprintf("%s %-16s %-10s", marker, e.Name, status)
+				if e.Region != "" {
+					line += " " + e.Region
+				}
+				if e.Domain != "" {
+					line += " " + e.Domain
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+}
+
+func newEnvUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "切换默认环境",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.UseEnv(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("当前环境: %s\n", args[0])
+			if os.Getenv(config.EnvVarName) != "" {
+				fmt.Printf("  ⚠ 环境变量 %s 已设置，会覆盖此选择\n", config.EnvVarName)
+			}
+			return nil
+		},
+	}
+}
+
+func newEnvRmCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "rm <name>",
+		Short: "删除环境的本地记录（不删除云资源）",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := config.ValidateEnvName(name); err != nil {
+				return err
+			}
+			dir, err := config.GetEnvDir(name)
+			if err != nil {
+				return err
+			}
+
+			// 仍有云资源时拒绝删除，避免资源成为孤儿
+			state, err := config.LoadStateFrom(dir)
+			if err == nil && state.Status != "destroyed" && hasAnyResource(state) && !force {
+				return fmt.Errorf("环境 %s 仍有云资源，请先运行 cloudcode --env %s destroy，或使用 --force 强制删除本地记录", name, name)
+			}
+
+			if err := config.RemoveEnv(name); err != nil {
+				return err
+			}
+			fmt.Printf("已删除环境 %s\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "即使仍有云资源也删除本地记录")
+
+	return cmd
+}
+
+// hasAnyResource 判断 state 中是否记录了任一云资源
+func hasAnyResource(state *config.State) bool {
+	return state.HasVPC() || state.HasVSwitch() || state.HasSecurityGroup() ||
+		state.HasECS() || state.HasEIP() || state.HasSSHKeyPair()
+}
+
+func newEnvSSHRouteCmd() *cobra.Command {
+	var jump, jumpKey, proxy string
+	var direct bool
+
+	cmd := &cobra.Command{
+		Use:   "ssh-route",
+		Short: "设置当前环境连接实例 SSH 的路径（跳板机 / 代理）",
+		Long: `设置当前环境连接实例 SSH 的路径，不带参数时显示当前设置。所有 SSH 连接（部署、ssh、logs、exec 等）都走该路径。
+
+  --jump [user@]host[:port]   经跳板机连接（ProxyJump），用户默认 root，端口默认 22
+  --jump-key <path>           跳板机私钥（默认使用实例的 SSH 私钥）
+  --proxy <url>               经代理连接：socks5://[user:pass@]host:port 或 http://[user:pass@]host:port
+  --direct                    清除跳板机和代理，恢复直连
+
+同时设置代理和跳板机时，经代理连接跳板机。传入空值（如 --proxy ""）清除单项设置。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := config.GetActiveEnvDir()
+			if err != nil {
+				return err
+			}
+			route, err := config.LoadSSHRouteFrom(dir)
+			if err != nil {
+				return err
+			}
+
+			flags := cmd.Flags()
+			changed := flags.Changed("jump") || flags.Changed("jump-key") || flags.Changed("proxy") || direct
+			if changed {
+				if direct {
+					route = &config.SSHRoute{}
+				}
+				if flags.Changed("proxy") {
+					route.Proxy = proxy
+				}
+				if flags.Changed("jump") && jump != route.Jump {
+					route.Jump = jump
+					route.JumpHostKey = ""
+				}
+				if flags.Changed("jump-key") {
+					route.JumpKey = ""
+					if jumpKey != "" {
+						if route.JumpKey, err = filepath.Abs(jumpKey); err != nil {
+							return err
+						}
+						if _, err := os.ReadFile(route.JumpKey); err != nil {
+							return fmt.Errorf("读取跳板机私钥失败: %w", err)
+						}
+					}
+				}
+				if route.Jump == "" {
+					route.JumpKey = ""
+					route.JumpHostKey = ""
+				}
+				if err := route.Validate(); err != nil {
+					return err
+				}
+				if err := config.SaveSSHRouteTo(dir, route); err != nil {
+					return err
+				}
+			}
+
+			printSSHRoute(route)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&jump, "jump", "", "跳板机 [user@]host[:port]")
+	cmd.Flags().StringVar(&jumpKey, "jump-key", "", "跳板机私钥路径")
+	cmd.Flags().StringVar(&proxy, "proxy", "", "代理 URL（socks5:// 或 http://）")
+	cmd.Flags().BoolVar(&direct, "direct", false, "清除跳板机和代理，恢复直连")
+
+	return cmd
+}
+
+// printSSHRoute 显示 SSH 连接路径（隐藏代理密码）
+func printSSHRoute(route *config.SSHRoute) {
+	fmt.Printf("环境 %s 的 SSH 连接路径:\n", config.ActiveEnv())
+	if route.IsDirect() {
+		fmt.Println("  直连")
+		return
+	}
+	if route.Proxy != "" {
+		shown := route.Proxy
+		if u, err := url.Parse(route.Proxy); err == nil {
+			shown = u.Redacted()
+		}
+		fmt.Printf("  代理:   %s\n", shown)
+	}
+	if route.Jump != "" {
+		key := "实例的 SSH 私钥"
+		if route.JumpKey != "" {
+			key = route.JumpKey
+		}
+		fmt.Printf("  跳板机: %s（私钥: %s）\n", route.Jump, key)
+	}
+}
+
+// sshRoute 读取环境目录中的 SSH 连接路径并转换为 remote.Route（直连时返回 nil），每次建立 SSH 连接时调用
+func sshRoute(dir string) (*remote.Route, error) {
+	r, err := config.LoadSSHRouteFrom(dir)
+	if err != nil {
+		return nil, err
+	}
+	if r.IsDirect() {
+		return nil, nil
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", config.SSHRouteFileName, err)
+	}
+	route := &remote.Route{Proxy: r.Proxy}
+	if r.Jump != "" {
+		user, host, port, _ := config.ParseJumpHost(r.Jump)
+		jump := &remote.JumpHost{Host: host, Port: port, User: user, HostKeys: config.JumpHostKeyStore{Dir: dir}}
+		if r.JumpKey != "" {
+			if jump.PrivateKey, err = os.ReadFile(r.JumpKey); err != nil {
+				return nil, fmt.Errorf("读取跳板机私钥失败: %w", err)
+			}
+		}
+		route.Jump = jump
+	}
+	return route, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+// forward.go 提供 cloudcode forward：将本地端口经 SSH 转发到 ECS 上 cloudcode-net 网络中的容器端口，
+// 用于访问 devbox 内未对外暴露的服务（开发服务器、数据库等），按 Ctrl-C 停止。
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newForwardCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "forward <local>:<container>:<port>...",
+		Short: "将本地端口转发到容器端口",
+		Long: `将本地端口经 SSH 转发到 ECS 上 cloudcode-net 网络中的容器端口，容器端口无需对外暴露。
+可同时指定多条转发规则，按 Ctrl-C 停止。
+
+  cloudcode forward 3000:devbox:3000                 # 本地 127.0.0.1:3000 → devbox 容器的 3000 端口
+  cloudcode forward 3000:devbox:3000 5432:devbox:5432
+  cloudcode forward devbox:8080                      # 省略本地端口时使用相同端口
+
+本地端口只监听 127.0.0.1；本地端口为 0 时随机分配。`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs := make([]remote.ForwardSpec, 0, len(args))
+			for _, arg := range args {
+				spec, err := remote.ParseForwardSpec(arg)
+				if err != nil {
+					return err
+				}
+				specs = append(specs, spec)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runForward(ctx, specs)
+		},
+	}
+}
+
+// runForward 为每条规则打开本地监听端口并转发，直到 ctx 取消
+func runForward(ctx context.Context, specs []remote.ForwardSpec) error {
+	client, err := dialActiveInstance(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	listeners := make([]net.Listener, 0, len(specs))
+	defer func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}()
+	dialers := make([]*remote.ContainerDialer, 0, len(specs))
+	for _, spec := range specs {
+		// 先解析容器 IP：容器名写错或容器未运行时立即报错
+		dialer := &remote.ContainerDialer{Client: client, Container: spec.Container, Port: spec.Port}
+		if _, err := dialer.Resolve(ctx); err != nil {
+			return err
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(spec.LocalPort)))
+		if err != nil {
+			return fmt.Errorf("监听本地端口 %d 失败: %w", spec.LocalPort, err)
+		}
+		listeners = append(listeners, ln)
+		dialers = append(dialers, dialer)
+		fmt.Printf("转发 %s → %s:%d\n", ln.Addr(), spec.Container, spec.Port)
+	}
+	fmt.Println("按 Ctrl-C 停止转发")
+
+	// 任一监听端口异常时停止全部转发
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make(chan error, len(specs))
+	for i, spec := range specs {
+		ln, dialer := listeners[i], dialers[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			onError := func(err error) {
+				fmt.Fprintf(os.Stderr, "转发 %s:%d 失败: %v\n", spec.Container, spec.Port, err)
+			}
+			if err := remote.ServeForward(ctx, ln, dialer.Dial, onError); err != nil {
+				errs <- fmt.Errorf("转发 %s 中断: %w", spec, err)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+	fmt.Println("\n已停止转发")
+	return nil
+}
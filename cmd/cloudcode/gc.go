@@ -0,0 +1,63 @@
+package main
+
+// gc.go 提供 cloudcode gc 子命令：列出并可选删除带 CloudCode 标签、但不被任何本地环境引用的云资源。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/spf13/cobra"
+)
+
+func newGCCmd() *cobra.Command {
+	var del, force bool
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "清理未被任何环境引用的云资源",
+		Long: `按 cloudcode-deployment-id 标签查找当前区域的 VPC、交换机、安全组、密钥对、
+ECS、EIP 和快照，列出不被任何本地环境 state（及 backup.json 快照）引用的资源，
+通常是中断的部署或手动删除 state 后留下的。
+
+默认只列出，--delete 删除。同一阿里云账号下其他人部署的资源同样不在本地 state 中，
+删除前请确认列表。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := alicloud.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("阿里云配置错误: %w", err)
+			}
+			clients, err := alicloud.NewClients(cfg)
+			if err != nil {
+				return fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+			}
+			known, err := config.KnownResourceIDs()
+			if err != nil {
+				return err
+			}
+			deployments, err := config.KnownDeploymentIDs()
+			if err != nil {
+				return err
+			}
+
+			g := &deploy.GarbageCollector{
+				ECS:              clients.ECS,
+				VPC:              clients.VPC,
+				Prompter:         config.NewPrompter(os.Stdin, os.Stdout),
+				Output:           os.Stdout,
+				Region:           cfg.RegionID,
+				KnownIDs:         known,
+				KnownDeployments: deployments,
+			}
+			return g.Run(cmd.Context(), del, force)
+		},
+	}
+
+	cmd.Flags().BoolVar(&del, "delete", false, "删除列出的资源")
+	cmd.Flags().BoolVar(&force, "force", false, "删除时跳过确认")
+
+	return cmd
+}
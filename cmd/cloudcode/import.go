@@ -0,0 +1,66 @@
+package main
+
+// import.go 提供 cloudcode import 子命令：将云上已有的 CloudCode 资源重新纳入当前环境的 state。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd() *cobra.Command {
+	var instanceID, keyFile, domain, username string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "导入云上已有的部署（本地记录丢失时使用）",
+		Long: `按名称查找云上的 cloudcode-ecs 实例，反查其 VPC、交换机、安全组和 EIP，
+重建当前环境的 state.json。
+
+SSH 私钥无法从云上取回：
+  --ssh-key <path>  使用已有私钥
+  不指定            生成新密钥对并绑定到实例（运行中的实例会被重启）`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := alicloud.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("阿里云配置错误: %w", err)
+			}
+			clients, err := alicloud.NewClients(cfg)
+			if err != nil {
+				return fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+			}
+			known, err := config.KnownResourceIDs()
+			if err != nil {
+				return err
+			}
+
+			im := &deploy.Importer{
+				ECS:        clients.ECS,
+				VPC:        clients.VPC,
+				Prompter:   config.NewPrompter(os.Stdin, os.Stdout),
+				Output:     os.Stdout,
+				Region:     cfg.RegionID,
+				Env:        config.ActiveEnv(),
+				InstanceID: instanceID,
+				KeyFile:    keyFile,
+				Domain:     domain,
+				Username:   username,
+				KnownIDs:   known,
+				Version:    version,
+			}
+			return im.Run(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVar(&instanceID, "instance", "", "要导入的 ECS 实例 ID（默认按名称 cloudcode-ecs 查找）")
+	cmd.Flags().StringVar(&keyFile, "ssh-key", "", "已有 SSH 私钥路径（不指定则生成新密钥并绑定到实例）")
+	cmd.Flags().StringVar(&domain, "domain", "", "部署时使用的域名（默认 EIP.nip.io）")
+	cmd.Flags().StringVar(&username, "username", "", "Authelia 管理员用户名（默认 admin）")
+
+	return cmd
+}
@@ -1,17 +1,22 @@
 // Package main 是 CloudCode CLI 的入口。
 // 提供子命令：deploy（部署）、status（状态）、destroy（销毁）、
-// otc（读取验证码）、logs（容器日志）、ssh（登录 ECS）、exec（容器内执行命令）、version（版本）。
+// otc（读取验证码）、logs（容器日志）、ssh（登录 ECS）、exec（容器内执行命令）、
+// import（导入已有资源）、gc（清理孤儿资源）、secrets（provider API Key）、user（Authelia 账号）、
+// autosuspend（自动停机）、wake（唤醒页）、cost（费用估算）、backup（快照 / 数据卷备份）、migrate（跨区域迁移）、resize（变更规格）、env（多环境管理）、version（版本），
+// 以及在云上运行的隐藏命令 agent（ECS 实例）和 wake serve（函数计算）。
+// 全局参数 --env（或 CLOUDCODE_ENV）选择操作的环境。
 // 版本信息通过 ldflags 在构建时注入。
 package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
 
 	"github.com/hwuu/cloudcode/internal/alicloud"
 	"github.com/hwuu/cloudcode/internal/config"
@@ -27,13 +32,53 @@ var (
 	date    = "unknown"
 )
 
+// sshPool 一次命令运行中各阶段共享的 SSH 连接（部署、健康检查、恢复、状态检查等复用同一连接）
+var sshPool = remote.NewPool()
+
 func newRootCmd() *cobra.Command {
+	var envName string
+
 	rootCmd := &cobra.Command{
 		Use:   "cloudcode",
 		Short: "一键部署 OpenCode 到阿里云 ECS",
 		Long:  "CloudCode — 一键部署 OpenCode 到阿里云 ECS，带 HTTPS + Authelia 两步认证。",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// 在云上运行的命令（agent、wake serve）不读写本地 state
+			if cmd.Annotations[cloudSideAnnotation] != "" {
+				return nil
+			}
+
+			config.SetActiveEnv(envName)
+			if err := config.ValidateEnvName(config.ActiveEnv()); err != nil {
+				return err
+			}
+
+			// 旧版单环境布局自动迁移到 default 环境
+			migrated, err := config.MigrateLegacyState()
+			if err != nil {
+				return fmt.Errorf("迁移旧版状态失败: %w", err)
+			}
+			if migrated {
+				fmt.Fprintf(os.Stderr, "已将旧版部署记录迁移到 %s 环境\n", config.DefaultEnvName)
+			}
+
+			// SSH 主机公钥记录在当前环境的 state 中，首次连接时记录，之后每次连接校验
+			envDir, err := config.GetActiveEnvDir()
+			if err != nil {
+				return err
+			}
+			remote.SetHostKeyStore(config.HostKeyStore{Dir: envDir})
+			// 跳板机 / 代理按环境配置（cloudcode env ssh-route），每次建立 SSH 连接时读取
+			remote.SetRouteFunc(func() (*remote.Route, error) { return sshRoute(envDir) })
+
+			// 自动停机 agent 和唤醒页直接停机 / 启动实例，本地 state 在此同步
+			reconcileInstanceStatus()
+			return nil
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&envName, "env", "", "操作的环境名（默认读取 "+config.EnvVarName+" 或 cloudcode env use 的设置）")
+
 	rootCmd.AddCommand(newInitCmd())
 	rootCmd.AddCommand(newDeployCmd())
 	rootCmd.AddCommand(newStatusCmd())
@@ -44,6 +89,19 @@ func newRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newLogsCmd())
 	rootCmd.AddCommand(newSSHCmd())
 	rootCmd.AddCommand(newExecCmd())
+	rootCmd.AddCommand(newForwardCmd())
+	rootCmd.AddCommand(newImportCmd())
+	rootCmd.AddCommand(newGCCmd())
+	rootCmd.AddCommand(newSecretsCmd())
+	rootCmd.AddCommand(newUserCmd())
+	rootCmd.AddCommand(newAutoSuspendCmd())
+	rootCmd.AddCommand(newWakeCmd())
+	rootCmd.AddCommand(newCostCmd())
+	rootCmd.AddCommand(newBackupCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newResizeCmd())
+	rootCmd.AddCommand(newAgentCmd())
+	rootCmd.AddCommand(newEnvCmd())
 	rootCmd.AddCommand(newVersionCmd())
 
 	return rootCmd
@@ -141,12 +199,75 @@ func newInitCmd() *cobra.Command {
 }
 
 func newDeployCmd() *cobra.Command {
-	var appOnly bool
+	var appOnly, nonInteractive bool
+	var configFile, devboxMode string
+	var flagSpec config.CloudSpec
 
 	cmd := &cobra.Command{
 		Use:   "deploy",
 		Short: "部署 OpenCode 到阿里云 ECS",
+		Long: `部署 OpenCode 到阿里云 ECS。
+
+--config 从 YAML 文件读取部署配置，文件提供管理员密码时不再交互提示
+（--non-interactive 强制不提示，缺少必填项直接报错，适用于 CI）：
+  domain: code.example.com          # 留空使用 EIP.nip.io
+  ssh_ip: 203.0.113.7               # SSH 源地址限制，留空不限制
+  admin:
+    username: admin
+    password: ${CLOUDCODE_ADMIN_PASSWORD}
+  api_keys:
+    openai: ${OPENAI_API_KEY}
+    anthropic: ${ANTHROPIC_API_KEY:-}
+    extra:
+      GEMINI_API_KEY: ${GEMINI_API_KEY:-}
+  devbox_mode: per-user             # 每个账号一个独立 devbox（默认 shared）
+  auto_suspend:                     # 自动停机（见 cloudcode autosuspend）
+    idle: 30m
+    active_hours: Mon-Fri 09:00-19:00
+    timezone: Asia/Shanghai
+  authelia:
+    session_expiration: 12h
+    session_inactivity: 30m
+    policy: two_factor
+  cloud:
+    instance_type: ecs.g7.xlarge
+    image: ""                       # 留空自动查找区域内最新的 Ubuntu 24.04
+    disk_size: 100
+    disk_category: cloud_essd
+    zones: [cn-hangzhou-j, cn-hangzhou-k]
+    spot: true                      # 抢占式实例（见下）
+    spot_price_limit: 0.05          # 每小时最高出价，留空跟随市场价
+
+--spot 使用抢占式实例：费用通常只有按量付费的一到三成，但可能被回收。实例上的 agent
+在回收通知（提前约 5 分钟）时停止容器并为系统盘创建快照；之后运行 cloudcode deploy
+会检测到实例已回收，从最新快照重建实例并沿用原 EIP 和域名。
+
+字符串值支持 ${VAR} / ${VAR:-default} 环境变量插值。实例规格参数优先于文件。`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// 实例规格：配置文件 + 命令行参数
+			var spec config.CloudSpec
+			var preset *deploy.DeployConfig
+			var autoSuspend *config.AutoSuspendPolicy
+			if configFile != "" {
+				file, err := config.LoadDeployFile(configFile)
+				if err != nil {
+					return err
+				}
+				spec = file.Cloud
+				preset = deploy.NewDeployConfigFromFile(file)
+				if devboxMode == "" {
+					devboxMode = file.DevboxMode
+				}
+				autoSuspend = file.AutoSuspend
+			}
+			spec = spec.Merge(flagSpec)
+			if err := spec.Validate(); err != nil {
+				return err
+			}
+			if err := config.ValidateDevboxMode(devboxMode); err != nil {
+				return err
+			}
+
 			// 加载阿里云配置
 			cfg, err := alicloud.LoadConfig()
 			if err != nil {
@@ -166,15 +287,22 @@ func newDeployCmd() *cobra.Command {
 				VPC:      clients.VPC,
 				STS:      clients.STS,
 				DNS:      clients.DNS,
+				RAM:      clients.RAM,
 				Prompter: prompter,
 				Output:   os.Stdout,
 				Region:   cfg.RegionID,
+				Env:      config.ActiveEnv(),
 				SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
-					return remote.NewSSHDialFunc(host, port, user, privateKey)
+					return sshPool.DialFunc(host, port, user, privateKey)
 				},
-				SFTPFactory: remote.NewSFTPClient,
-				GetPublicIP: remote.GetPublicIP,
-				Version:     version,
+				SFTPFactory:    sshPool.NewSFTPClient,
+				GetPublicIP:    remote.GetPublicIP,
+				Version:        version,
+				Spec:           spec,
+				Config:         preset,
+				NonInteractive: nonInteractive,
+				DevboxMode:     devboxMode,
+				AutoSuspend:    autoSuspend,
 			}
 
 			return d.Run(cmd.Context(), appOnly)
@@ -182,24 +310,76 @@ func newDeployCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&appOnly, "app", false, "仅重新部署应用层（跳过云资源创建）")
+	cmd.Flags().StringVar(&configFile, "config", "", "部署配置文件（YAML）")
+	cmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "不交互提示，缺少必填配置时直接失败")
+	cmd.Flags().StringVar(&devboxMode, "devbox-mode", "", "devbox 模式: shared（所有账号共用）/ per-user（每个账号独立，默认沿用当前模式）")
+	cmd.Flags().StringVar(&flagSpec.InstanceType, "instance-type", "", "实例规格（默认 "+alicloud.DefaultInstanceType+"）")
+	cmd.Flags().StringVar(&flagSpec.Image, "image", "", "镜像 ID（默认自动查找区域内最新的 Ubuntu 24.04）")
+	cmd.Flags().IntVar(&flagSpec.DiskSize, "disk-size", 0, fmt.Sprintf("系统盘大小 GB（默认 %d）", alicloud.DefaultSystemDiskSize))
+	cmd.Flags().StringVar(&flagSpec.DiskCategory, "disk-category", "", "系统盘类型（默认 "+alicloud.DefaultSystemDiskCategory+"）")
+	cmd.Flags().StringSliceVar(&flagSpec.Zones, "zones", nil, "可用区优先级，逗号分隔（默认自动选择）")
+	cmd.Flags().BoolVar(&flagSpec.Spot, "spot", false, "使用抢占式实例（可能被回收，回收后 deploy 从快照重建）")
+	cmd.Flags().Float64Var(&flagSpec.SpotPriceLimit, "spot-price-limit", 0, "抢占式实例每小时最高出价（默认跟随市场价）")
 
 	return cmd
 }
 
 func newStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	var output string
+	var checkCloud bool
+
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "查看部署状态",
+		Long: `查看部署状态：云资源、ECS 实例实时状态和容器状态。
+
+--output json|yaml 输出带 schema_version 的机器可读文档，
+部署存在异常（实例未运行、容器异常等）时以非零退出码退出。
+
+--check-cloud 逐个查询云上资源，报告与 state.json 不一致之处：
+已不存在（missing）、属性不符（mismatch，如规格变更、EIP 未绑定、实例状态与 state 不符）、
+以及按 cloudcode 命名但不属于任何环境的资源（extra）。`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			s := &deploy.StatusRunner{
 				Output: os.Stdout,
+				Format: output,
+				Env:    config.ActiveEnv(),
 				SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
-					return remote.NewSSHDialFunc(host, port, user, privateKey)
+					return sshPool.DialFunc(host, port, user, privateKey)
 				},
 			}
-			return s.Run(cmd.Context())
+
+			// 凭证可用时查询实例实时状态，不可用时仅展示本地记录（--check-cloud 时凭证必需）
+			cfg, err := alicloud.LoadConfig()
+			if err == nil {
+				var clients *alicloud.Clients
+				if clients, err = alicloud.NewClients(cfg); err == nil {
+					s.ECS = clients.ECS
+					s.VPC = clients.VPC
+				}
+			}
+			if checkCloud {
+				if err != nil {
+					return fmt.Errorf("阿里云配置错误: %w", err)
+				}
+				s.CheckCloud = true
+				if s.KnownIDs, err = config.KnownResourceIDs(); err != nil {
+					return err
+				}
+			}
+
+			err = s.Run(cmd.Context())
+			if errors.Is(err, deploy.ErrUnhealthy) {
+				cmd.SilenceUsage = true
+			}
+			return err
 		},
 	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", deploy.OutputText, "输出格式：text / json / yaml")
+	cmd.Flags().BoolVar(&checkCloud, "check-cloud", false, "核对云上资源与 state 是否一致（drift 检测）")
+
+	return cmd
 }
 
 func newDestroyCmd() *cobra.Command {
@@ -223,11 +403,16 @@ func newDestroyCmd() *cobra.Command {
 			d := &deploy.Destroyer{
 				ECS:      clients.ECS,
 				VPC:      clients.VPC,
+				RAM:      clients.RAM,
 				Prompter: prompter,
 				Output:   os.Stdout,
 				Region:   cfg.RegionID,
+				Env:      config.ActiveEnv(),
 				Version:  version,
 			}
+			if fc, err := newFCClient(cfg, clients); err == nil {
+				d.FC = fc
+			}
 
 			return d.Run(cmd.Context(), force, dryRun)
 		},
@@ -252,12 +437,17 @@ func newSuspendCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
 			}
+			prices, err := loadPrices("")
+			if err != nil {
+				return err
+			}
 			prompter := config.NewPrompter(os.Stdin, os.Stdout)
 			s := &deploy.Suspender{
 				ECS:      clients.ECS,
 				Prompter: prompter,
 				Output:   os.Stdout,
 				Region:   cfg.RegionID,
+				Prices:   prices,
 			}
 			return s.Run(cmd.Context())
 		},
@@ -284,7 +474,7 @@ func newResumeCmd() *cobra.Command {
 				Output:   os.Stdout,
 				Region:   cfg.RegionID,
 				SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
-					return remote.NewSSHDialFunc(host, port, user, privateKey)
+					return sshPool.DialFunc(host, port, user, privateKey)
 				},
 			}
 			return r.Run(cmd.Context())
@@ -307,24 +497,44 @@ func newVersionCmd() *cobra.Command {
 
 // sshRunCommand 从 state 读取连接信息，SSH 到 ECS 执行命令并返回输出
 func sshRunCommand(ctx context.Context, cmd string) (string, error) {
-	state, privateKey, err := loadStateAndKey("")
+	client, err := dialActiveInstance(ctx)
 	if err != nil {
 		return "", err
 	}
-	dialFunc := remote.NewSSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
-	client, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	defer client.Close()
+	return client.RunCommand(ctx, cmd)
+}
+
+// sshStreamCommand 从 state 读取连接信息，SSH 到 ECS 执行命令，stdout / stderr 实时输出；
+// 远程以非零状态退出时返回 *remote.ExitError
+func sshStreamCommand(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	client, err := dialActiveInstance(ctx)
 	if err != nil {
-		return "", fmt.Errorf("SSH 连接失败: %w", err)
+		return err
 	}
 	defer client.Close()
-	return client.RunCommand(ctx, cmd)
+	return remote.RunCommandStreaming(ctx, client, cmd, stdout, stderr)
+}
+
+// dialActiveInstance 从 state 读取连接信息，SSH 连接当前环境的 ECS 实例
+func dialActiveInstance(ctx context.Context) (remote.SSHClient, error) {
+	state, privateKey, err := loadStateAndKey("")
+	if err != nil {
+		return nil, err
+	}
+	dialFunc := sshPool.DialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+	client, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return nil, fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	return client, nil
 }
 
 // loadStateAndKey 加载 state 和 SSH 私钥
 func loadStateAndKey(stateDir string) (*config.State, []byte, error) {
 	state, err := config.LoadState()
 	if err != nil {
-		return nil, nil, fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+		return nil, nil, fmt.Errorf("环境 %s 未找到部署记录，请先运行 cloudcode deploy", config.ActiveEnv())
 	}
 	if state.Status == "suspended" {
 		return nil, nil, fmt.Errorf("实例已停机，请先运行 cloudcode resume")
@@ -337,12 +547,12 @@ func loadStateAndKey(stateDir string) (*config.State, []byte, error) {
 	}
 	dir := stateDir
 	if dir == "" {
-		dir, err = config.GetStateDir()
+		dir, err = config.GetActiveEnvDir()
 		if err != nil {
 			return nil, nil, err
 		}
 	}
-	keyPath := dir + "/ssh_key"
+	keyPath := filepath.Join(dir, config.SSHKeyFileName)
 	privateKey, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("读取 SSH 私钥失败: %w", err)
@@ -387,25 +597,11 @@ func newLogsCmd() *cobra.Command {
 				composeCmd += fmt.Sprintf(" --tail=%d", tail)
 			}
 			if follow {
-				// follow 模式需要交互式 SSH，用 exec 替代
-				state, _, err := loadStateAndKey("")
-				if err != nil {
-					return err
-				}
-				dir, _ := config.GetStateDir()
-				keyPath := dir + "/ssh_key"
-				sshArgs := []string{
-					"-i", keyPath,
-					"-o", "StrictHostKeyChecking=no",
-					"-o", "UserKnownHostsFile=/dev/null",
-					"-o", "LogLevel=ERROR",
-					"root@" + state.Resources.EIP.IP,
-					composeCmd + " -f",
-				}
+				followCmd := composeCmd + " -f"
 				if len(args) > 0 {
-					sshArgs[len(sshArgs)-1] += " " + args[0]
+					followCmd += " " + args[0]
 				}
-				return syscall.Exec(sshBinary(), append([]string{"ssh"}, sshArgs...), os.Environ())
+				return silenceExitError(cmd, runRemoteSession(cmd.Context(), followCmd, false, false))
 			}
 			if len(args) > 0 {
 				composeCmd += " " + args[0]
@@ -427,6 +623,8 @@ func newLogsCmd() *cobra.Command {
 
 // newSSHCmd 快捷 SSH 登录 ECS 或进入容器
 func newSSHCmd() *cobra.Command {
+	var resetHostKey bool
+
 	cmd := &cobra.Command{
 		Use:   "ssh [target]",
 		Short: "SSH 登录到 ECS 实例或容器",
@@ -436,75 +634,96 @@ target 可选值：
   host       登录 ECS 宿主机（默认）
   devbox     进入 devbox 容器
   authelia   进入 authelia 容器
-  caddy      进入 caddy 容器`,
+  caddy      进入 caddy 容器
+
+首次连接时记录实例的 SSH 主机公钥，之后每次连接都会校验。实例重建或替换系统盘后
+主机公钥会变化，确认后用 --reset-hostkey 清除记录并重新记录。`,
 		ValidArgs: []string{"host", "devbox", "authelia", "caddy"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			state, _, err := loadStateAndKey("")
-			if err != nil {
-				return err
+			if resetHostKey {
+				dir, err := config.GetActiveEnvDir()
+				if err != nil {
+					return err
+				}
+				if err := config.ClearHostKey(dir); err != nil {
+					return fmt.Errorf("清除主机公钥记录失败: %w", err)
+				}
+				fmt.Fprintln(os.Stderr, "已清除记录的 SSH 主机公钥，本次连接时重新记录。")
 			}
-			dir, _ := config.GetStateDir()
-			keyPath := dir + "/ssh_key"
 
 			target := "host"
 			if len(args) > 0 {
 				target = args[0]
 			}
 
-			sshArgs := []string{
-				"ssh",
-				"-i", keyPath,
-				"-o", "StrictHostKeyChecking=no",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "LogLevel=ERROR",
-				"-t",
-				"root@" + state.Resources.EIP.IP,
-			}
-
+			remoteCmd := ""
 			if target != "host" {
 				// 进入容器的交互式 shell
-				sshArgs = append(sshArgs, fmt.Sprintf("cd ~/cloudcode && docker compose exec %s sh -c 'if command -v bash >/dev/null; then bash; else sh; fi'", target))
+				remoteCmd = fmt.Sprintf("cd ~/cloudcode && docker compose exec %s sh -c 'if command -v bash >/dev/null; then bash; else sh; fi'", target)
 			}
 
-			return syscall.Exec(sshBinary(), sshArgs, os.Environ())
+			return silenceExitError(cmd, runRemoteSession(cmd.Context(), remoteCmd, true, true))
 		},
 	}
+
+	cmd.Flags().BoolVar(&resetHostKey, "reset-hostkey", false, "清除记录的 SSH 主机公钥并重新记录（实例重建后使用）")
+
 	return cmd
 }
 
 // newExecCmd 在容器内执行命令
 func newExecCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "exec <container> <command> [args...]",
+	var interactive, tty bool
+
+	cmd := &cobra.Command{
+		Use:   "exec [-i] [-t] <container> <command> [args...]",
 		Short: "在容器内执行命令",
-		Long:  "在指定容器内执行命令。例如: cloudcode exec devbox opencode --version",
-		Args:  cobra.MinimumNArgs(2),
+		Long: `在指定容器内执行命令。例如: cloudcode exec devbox opencode --version
+
+-i 转发标准输入，-t 分配终端（交互式程序如 bash、vim 使用 -it）。参数需写在容器名之前。`,
+		Args:      cobra.MinimumNArgs(2),
 		ValidArgs: []string{"authelia", "caddy", "devbox"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			container := args[0]
 			containerCmd := strings.Join(args[1:], " ")
-			remoteCmd := fmt.Sprintf("cd ~/cloudcode && docker compose exec %s %s", container, containerCmd)
-			output, err := sshRunCommand(cmd.Context(), remoteCmd)
+			if interactive || tty {
+				execFlags := ""
+				if !tty {
+					execFlags = " -T"
+				}
+				remoteCmd := fmt.Sprintf("cd ~/cloudcode && docker compose exec%s %s %s", execFlags, container, containerCmd)
+				return silenceExitError(cmd, runRemoteSession(cmd.Context(), remoteCmd, tty, interactive))
+			}
+			remoteCmd := fmt.Sprintf("cd ~/cloudcode && docker compose exec -T %s %s", container, containerCmd)
+			err := sshStreamCommand(cmd.Context(), remoteCmd, os.Stdout, os.Stderr)
+			var exitErr *remote.ExitError
+			if errors.As(err, &exitErr) {
+				return silenceExitError(cmd, err)
+			}
 			if err != nil {
 				return fmt.Errorf("执行失败: %w", err)
 			}
-			fmt.Print(output)
 			return nil
 		},
 	}
-}
 
-// sshBinary 查找 ssh 可执行文件路径
-func sshBinary() string {
-	path, err := exec.LookPath("ssh")
-	if err != nil {
-		return "/usr/bin/ssh"
-	}
-	return path
+	// 容器名之后的参数都属于容器内的命令
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "转发标准输入")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "分配终端（PTY）")
+
+	return cmd
 }
 
 func main() {
-	if err := newRootCmd().Execute(); err != nil {
+	err := newRootCmd().Execute()
+	sshPool.Close()
+	if err != nil {
+		// ssh / exec / logs -f 返回远程命令的退出状态时（可能经过包装），按远程退出码退出
+		var exitErr *remote.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Status)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,98 @@
+package main
+
+// migrate.go 提供 cloudcode migrate --to-region：通过快照将部署迁移到其他区域。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	var toRegion, sshIP string
+	var force bool
+	var spec config.CloudSpec
+
+	cmd := &cobra.Command{
+		Use:   "migrate --to-region <区域>",
+		Short: "将部署迁移到其他区域",
+		Long: `将部署迁移到其他区域：停机并创建系统盘快照，复制到目标区域后在那里创建
+VPC / 交换机 / 安全组 / ECS / EIP，更新 DNS 并确认服务正常后，再删除原区域的资源。
+
+数据、账号和 Passkey 随系统盘一起迁移；EIP 会变更，nip.io 域名随之改变，
+自有域名在阿里云 DNS 中时自动更新 A 记录。迁移中断后重新运行同一命令继续。
+迁移完成后本环境的命令自动使用新区域（以 state 中记录的区域为准）。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if toRegion == "" {
+				return fmt.Errorf("请用 --to-region 指定目标区域")
+			}
+			if err := spec.Validate(); err != nil {
+				return err
+			}
+
+			cfg, err := alicloud.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("阿里云配置错误: %w", err)
+			}
+			clients, err := alicloud.NewClients(cfg)
+			if err != nil {
+				return fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+			}
+			targetCfg := *cfg
+			targetCfg.RegionID = toRegion
+			targetClients, err := alicloud.NewClients(&targetCfg)
+			if err != nil {
+				return fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+			}
+
+			prompter := config.NewPrompter(os.Stdin, os.Stdout)
+			m := &deploy.Migrator{
+				ECS:      clients.ECS,
+				VPC:      clients.VPC,
+				RAM:      clients.RAM,
+				Prompter: prompter,
+				Output:   os.Stdout,
+				Region:   cfg.RegionID,
+				Version:  version,
+				SSHIP:    sshIP,
+				Target: &deploy.Deployer{
+					ECS:      targetClients.ECS,
+					VPC:      targetClients.VPC,
+					STS:      targetClients.STS,
+					DNS:      targetClients.DNS,
+					RAM:      targetClients.RAM,
+					Prompter: prompter,
+					Output:   os.Stdout,
+					Region:   toRegion,
+					Env:      config.ActiveEnv(),
+					SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+						return sshPool.DialFunc(host, port, user, privateKey)
+					},
+					SFTPFactory: sshPool.NewSFTPClient,
+					GetPublicIP: remote.GetPublicIP,
+					Version:     version,
+					Spec:        spec,
+				},
+			}
+			if fc, err := newFCClient(cfg, clients); err == nil {
+				m.FC = fc
+			}
+
+			return m.Run(cmd.Context(), force)
+		},
+	}
+
+	cmd.Flags().StringVar(&toRegion, "to-region", "", "目标区域（如 cn-hongkong）")
+	cmd.Flags().BoolVar(&force, "force", false, "跳过确认")
+	cmd.Flags().StringVar(&sshIP, "ssh-ip", "", "目标安全组的 SSH 源地址限制（留空不限制）")
+	cmd.Flags().StringVar(&spec.InstanceType, "instance-type", "", "目标区域的实例规格（默认沿用当前规格）")
+	cmd.Flags().StringSliceVar(&spec.Zones, "zones", nil, "目标区域的可用区优先级，逗号分隔（默认自动选择）")
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+package main
+
+// resize.go 提供 cloudcode resize：原地变更实例规格 / 扩容系统盘。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newResizeCmd() *cobra.Command {
+	var dryRun, force bool
+	var spec config.CloudSpec
+
+	cmd := &cobra.Command{
+		Use:   "resize",
+		Short: "变更实例规格 / 扩容系统盘",
+		Long: `原地变更实例规格或扩容系统盘：停机后调用 ModifyInstanceSpec / ResizeDisk，
+重新启动并通过 SSH 扩展根分区和文件系统，数据和 EIP 保持不变。
+
+系统盘只能扩容。变更期间实例停机几分钟；--dry-run 只显示变更内容和每月费用差额
+（价格表同 cloudcode cost）。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := spec.Validate(); err != nil {
+				return err
+			}
+			cfg, err := alicloud.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("阿里云配置错误: %w", err)
+			}
+			clients, err := alicloud.NewClients(cfg)
+			if err != nil {
+				return fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+			}
+			prices, err := loadPrices("")
+			if err != nil {
+				return err
+			}
+			r := &deploy.Resizer{
+				ECS:      clients.ECS,
+				Prompter: config.NewPrompter(os.Stdin, os.Stdout),
+				Output:   os.Stdout,
+				Region:   cfg.RegionID,
+				SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+					return sshPool.DialFunc(host, port, user, privateKey)
+				},
+				Prices: prices,
+				Spec:   spec,
+			}
+			return r.Run(cmd.Context(), dryRun, force)
+		},
+	}
+
+	cmd.Flags().StringVar(&spec.InstanceType, "instance-type", "", "新的实例规格（如 ecs.g7.xlarge）")
+	cmd.Flags().IntVar(&spec.DiskSize, "disk-size", 0, "新的系统盘大小（GB，只能增大）")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "仅显示变更内容和费用差额，不实际变更")
+	cmd.Flags().BoolVar(&force, "force", false, "跳过确认")
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+package main
+
+// secrets.go 提供 cloudcode secrets 子命令：set / list / unset 管理 devbox 的 provider API Key。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newSecretsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "管理 LLM provider API Key",
+		Long: `管理写入 devbox .env 的 LLM provider API Key 等环境变量。
+
+变量保存在 ~/.cloudcode/envs/<name>/secrets.env（权限 600），修改后推送到实例并仅重启 devbox 容器。
+变量名可以是任意合法环境变量名，常用的有：
+  OPENAI_API_KEY  OPENAI_BASE_URL  ANTHROPIC_API_KEY  GEMINI_API_KEY
+  DEEPSEEK_API_KEY  OPENROUTER_API_KEY  AZURE_API_KEY  AZURE_RESOURCE_NAME`,
+	}
+
+	cmd.AddCommand(newSecretsSetCmd())
+	cmd.AddCommand(newSecretsListCmd())
+	cmd.AddCommand(newSecretsUnsetCmd())
+
+	return cmd
+}
+
+func newSecretsManager(localOnly bool) *deploy.SecretsManager {
+	return &deploy.SecretsManager{
+		Output: os.Stdout,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return sshPool.DialFunc(host, port, user, privateKey)
+		},
+		SFTPFactory: sshPool.NewSFTPClient,
+		LocalOnly:   localOnly,
+	}
+}
+
+func newSecretsSetCmd() *cobra.Command {
+	var localOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "set <NAME> [VALUE]",
+		Short: "设置变量（不指定 VALUE 时交互输入，避免留在 shell 历史中）",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := config.ValidateSecretName(name); err != nil {
+				return err
+			}
+			var value string
+			if len(args) == 2 {
+				value = args[1]
+			} else {
+				prompter := config.NewPrompter(os.Stdin, os.Stdout)
+				var err error
+				if config.IsSecretValue(name) {
+					value, err = prompter.PromptPassword(fmt.Sprintf("%s: ", name))
+				} else {
+					value, err = prompter.Prompt(fmt.Sprintf("%s: ", name))
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return newSecretsManager(localOnly).Set(cmd.Context(), name, value)
+		},
+	}
+
+	cmd.Flags().BoolVar(&localOnly, "local", false, "仅保存到本地，不推送到实例")
+
+	return cmd
+}
+
+func newSecretsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出已配置的变量（脱敏显示）",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return newSecretsManager(false).List()
+		},
+	}
+}
+
+func newSecretsUnsetCmd() *cobra.Command {
+	var localOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "unset <NAME>",
+		Short: "删除变量",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return newSecretsManager(localOnly).Unset(cmd.Context(), args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&localOnly, "local", false, "仅从本地删除，不推送到实例")
+
+	return cmd
+}
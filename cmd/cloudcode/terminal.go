@@ -0,0 +1,115 @@
+package main
+
+// terminal.go 在 ECS 上运行交互式会话（cloudcode ssh / logs -f / exec -it），直接使用 Go SSH 客户端，
+// 不依赖本机的 ssh 命令：本地终端切到 raw 模式，窗口尺寸变化同步到远程 PTY，信号转发给远程进程。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// runRemoteSession 在 ECS 上运行 remoteCmd（为空时启动登录 shell）。
+// tty 时分配 PTY（标准输入不是终端时退化为非 PTY）；interactive 时转发标准输入。
+// 非 PTY 会话按 Ctrl-C 结束会话；PTY 会话中 Ctrl-C 由远程终端处理。
+func runRemoteSession(ctx context.Context, remoteCmd string, tty, interactive bool) error {
+	client, err := dialActiveInstance(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	opts := remote.SessionOptions{Stdout: os.Stdout, Stderr: os.Stderr}
+	if interactive {
+		opts.Stdin = os.Stdin
+	}
+
+	fd := int(os.Stdin.Fd())
+	if tty && !term.IsTerminal(fd) {
+		fmt.Fprintln(os.Stderr, "标准输入不是终端，不分配 PTY")
+		tty = false
+	}
+
+	forwarded := []os.Signal{syscall.SIGTERM, syscall.SIGHUP}
+	if tty {
+		opts.TTY = true
+		opts.Term = os.Getenv("TERM")
+		if w, h, err := term.GetSize(fd); err == nil {
+			opts.Size = remote.WindowSize{Width: w, Height: h}
+		}
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("设置终端 raw 模式失败: %w", err)
+		}
+		defer term.Restore(fd, oldState)
+
+		resize, stopResize := watchWindowSize(fd)
+		defer stopResize()
+		opts.Resize = resize
+		forwarded = append(forwarded, os.Interrupt)
+	} else {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, forwarded...)
+	defer signal.Stop(sigCh)
+	opts.Signals = sigCh
+
+	err = remote.RunInteractive(ctx, client, remoteCmd, opts)
+	if err != nil && ctx.Err() != nil {
+		// 用户按 Ctrl-C 结束非 PTY 会话
+		return nil
+	}
+	return err
+}
+
+// watchWindowSize 监听 SIGWINCH，本地终端尺寸变化时发送新尺寸；返回的函数停止监听
+func watchWindowSize(fd int) (<-chan remote.WindowSize, func()) {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	sizes := make(chan remote.WindowSize, 1)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-winch:
+				w, h, err := term.GetSize(fd)
+				if err != nil {
+					continue
+				}
+				// 只保留最新尺寸
+				select {
+				case <-sizes:
+				default:
+				}
+				sizes <- remote.WindowSize{Width: w, Height: h}
+			}
+		}
+	}()
+	return sizes, func() {
+		signal.Stop(winch)
+		close(done)
+	}
+}
+
+// silenceExitError 远程命令以非零状态退出时不打印错误和用法，由 main 按远程退出码退出
+func silenceExitError(cmd *cobra.Command, err error) error {
+	var exitErr *remote.ExitError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+// user.go 提供 cloudcode user 子命令：add / rm / passwd / list 管理 Authelia 登录账号。
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+func newUserCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "管理 Authelia 登录账号",
+		Long: `管理 Authelia 登录账号（多人共用一个 devbox 时为每人创建独立账号）。
+
+直接修改实例上的 authelia/users_database.yml，其他账号保持不变。
+新账号首次登录同样需要注册 Passkey（cloudcode otc 获取验证链接）。`,
+	}
+
+	cmd.AddCommand(newUserAddCmd())
+	cmd.AddCommand(newUserRmCmd())
+	cmd.AddCommand(newUserPasswdCmd())
+	cmd.AddCommand(newUserListCmd())
+
+	return cmd
+}
+
+func newUserManager() *deploy.UserManager {
+	return &deploy.UserManager{
+		Output: os.Stdout,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return sshPool.DialFunc(host, port, user, privateKey)
+		},
+		SFTPFactory: sshPool.NewSFTPClient,
+	}
+}
+
+// promptNewPassword 交互输入两次新密码
+func promptNewPassword(username string) (string, error) {
+	prompter := config.NewPrompter(os.Stdin, os.Stdout)
+	password, err := prompter.PromptPassword(fmt.Sprintf("请输入 %s 的密码: ", username))
+	if err != nil {
+		return "", err
+	}
+	confirmPassword, err := prompter.PromptPassword("请确认密码: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirmPassword {
+		return "", fmt.Errorf("两次输入的密码不一致")
+	}
+	return password, config.ValidatePassword(password)
+}
+
+func newUserAddCmd() *cobra.Command {
+	var spec deploy.UserSpec
+
+	cmd := &cobra.Command{
+		Use:   "add <username>",
+		Short: "添加账号",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec.Username = args[0]
+			if err := config.ValidateUsername(spec.Username); err != nil {
+				return err
+			}
+			if err := config.ValidateGroups(spec.Groups); err != nil {
+				return err
+			}
+			password, err := promptNewPassword(spec.Username)
+			if err != nil {
+				return err
+			}
+			spec.Password = password
+			return newUserManager().Add(cmd.Context(), spec)
+		},
+	}
+
+	cmd.Flags().StringVar(&spec.Email, "email", "", "邮箱（默认 <username>@localhost）")
+	cmd.Flags().StringVar(&spec.DisplayName, "display-name", "", "显示名称（默认与用户名相同）")
+	cmd.Flags().StringSliceVar(&spec.Groups, "groups", nil, "所属组，逗号分隔（如 "+config.AdminGroup+"）")
+
+	return cmd
+}
+
+func newUserRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <username>",
+		Short: "删除账号",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return newUserManager().Remove(cmd.Context(), args[0])
+		},
+	}
+}
+
+func newUserPasswdCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "passwd <username>",
+		Short: "修改账号密码",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := promptNewPassword(args[0])
+			if err != nil {
+				return err
+			}
+			return newUserManager().Passwd(cmd.Context(), args[0], password)
+		},
+	}
+}
+
+func newUserListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出所有账号",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return newUserManager().List(cmd.Context())
+		},
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+// wake.go 提供 cloudcode wake 子命令：enable / off / status 管理停机实例的唤醒页，
+// 以及在函数计算中运行的隐藏命令 wake serve。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/wake"
+	"github.com/spf13/cobra"
+)
+
+// cloudSideAnnotation 标记在云上（ECS 实例 / 函数计算）运行的命令，跳过本地 state 相关的前置处理
+const cloudSideAnnotation = "cloudcode/cloud-side"
+
+func newWakeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wake",
+		Short: "管理停机实例的唤醒页",
+		Long: `管理停机实例的唤醒页：在函数计算中部署一个常驻的小页面（按调用计费，闲置不收费），
+实例停机后打开 https://wake.<域名>，输入唤醒密钥登录并点击启动，实例就绪后自动跳转回 https://<域名>。
+
+唤醒页需要自有域名：wake.<域名> CNAME 到函数计算（阿里云 DNS 自动配置），
+HTTPS 证书由 Let's Encrypt 签发，有效期 90 天，重新运行 cloudcode wake enable 续期。
+函数使用的 RAM 角色（cloudcode-wake-<部署 ID>）只能启动和查询本实例。`,
+	}
+
+	cmd.AddCommand(newWakeEnableCmd())
+	cmd.AddCommand(newWakeOffCmd())
+	cmd.AddCommand(newWakeStatusCmd())
+	cmd.AddCommand(newWakeServeCmd())
+
+	return cmd
+}
+
+// newFCClient 创建函数计算客户端（服务地址包含账号 ID，需先查询调用者身份）
+func newFCClient(cfg *alicloud.Config, clients *alicloud.Clients) (alicloud.FCAPI, error) {
+	identity, err := alicloud.GetCallerIdentity(clients.STS)
+	if err != nil {
+		return nil, err
+	}
+	return alicloud.NewFCClient(cfg, identity.AccountID)
+}
+
+// newWakeManager 创建带阿里云客户端的 WakeManager
+func newWakeManager() (*deploy.WakeManager, error) {
+	cfg, err := alicloud.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("阿里云配置错误: %w", err)
+	}
+	clients, err := alicloud.NewClients(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化阿里云 SDK 失败: %w", err)
+	}
+	fc, err := newFCClient(cfg, clients)
+	if err != nil {
+		return nil, fmt.Errorf("初始化函数计算客户端失败: %w", err)
+	}
+	return &deploy.WakeManager{
+		ECS:     clients.ECS,
+		STS:     clients.STS,
+		RAM:     clients.RAM,
+		DNS:     clients.DNS,
+		FC:      fc,
+		Output:  os.Stdout,
+		Region:  cfg.RegionID,
+		Version: version,
+	}, nil
+}
+
+func newWakeEnableCmd() *cobra.Command {
+	var domain string
+	var rotateKey bool
+
+	cmd := &cobra.Command{
+		Use:   "enable",
+		Short: "开启或更新唤醒页（续期证书）",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newWakeManager()
+			if err != nil {
+				return err
+			}
+			return m.Enable(cmd.Context(), domain, rotateKey)
+		},
+	}
+
+	cmd.Flags().StringVar(&domain, "domain", "", "唤醒页域名（默认 wake.<部署域名>）")
+	cmd.Flags().BoolVar(&rotateKey, "rotate-key", false, "重新生成唤醒密钥（已登录的浏览器需重新登录）")
+
+	return cmd
+}
+
+func newWakeOffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "off",
+		Short: "删除唤醒页",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newWakeManager()
+			if err != nil {
+				return err
+			}
+			return m.Disable(cmd.Context())
+		},
+	}
+}
+
+func newWakeStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "查看唤醒页配置",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m := &deploy.WakeManager{Output: os.Stdout}
+			return m.Status()
+		},
+	}
+}
+
+func newWakeServeCmd() *cobra.Command {
+	var port int
+
+	cmd := &cobra.Command{
+		Use:         "serve",
+		Short:       "唤醒页 HTTP 服务（在函数计算中运行）",
+		Hidden:      true,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{cloudSideAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := wake.ConfigFromEnv()
+			if err != nil {
+				return err
+			}
+			s := &wake.Server{Config: *cfg}
+			srv := &http.Server{
+				Addr:              fmt.Sprintf(":%d", port),
+				Handler:           s.Handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+			}()
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&port, "port", wake.DefaultPort, "监听端口")
+
+	return cmd
+}
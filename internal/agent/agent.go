@@ -0,0 +1,164 @@
+// Package agent 实现运行在 ECS 实例上的 agent（cloudcode agent，由 systemd 启动）：自动停机，
+// 以及抢占式实例的回收通知监测（见 spot.go）。
+// 自动停机每分钟统计一次活动会话：devbox 上的 OpenCode (4096) / ttyd (7681) 连接（只有通过 Authelia 认证的请求
+// 才会被 Caddy 转发到 devbox）以及宿主机上的 SSH 会话。按策略判断需要停机时，使用实例 RAM 角色的
+// 临时凭证调用 StopInstance（StopCharging 模式），本地 state 由下一次 CLI 调用与云上状态对齐。
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+const (
+	DefaultConfigPath = "/etc/cloudcode/agent.json"
+	DefaultInterval   = time.Minute
+)
+
+// Config agent 配置（deploy / cloudcode autosuspend set 写入实例）
+type Config struct {
+	InstanceID   string                   `json:"instance_id"`
+	Region       string                   `json:"region"`
+	RoleName     string                   `json:"role_name"`
+	Policy       config.AutoSuspendPolicy `json:"policy"`
+	Spot         bool                     `json:"spot,omitempty"`          // 抢占式实例：监测回收通知并创建快照
+	DeploymentID string                   `json:"deployment_id,omitempty"` // 写入回收快照的标签，CLI 据此找到快照
+	Env          string                   `json:"env,omitempty"`
+}
+
+// LoadConfig 读取并校验 agent 配置
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 agent 配置失败: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析 agent 配置失败: %w", err)
+	}
+	if cfg.InstanceID == "" || cfg.Region == "" || cfg.RoleName == "" {
+		return nil, fmt.Errorf("agent 配置缺少 instance_id / region / role_name")
+	}
+	if !cfg.Policy.Enabled() && !cfg.Spot {
+		return nil, fmt.Errorf("agent 配置未设置停机策略")
+	}
+	if cfg.Policy.Enabled() {
+		if err := cfg.Policy.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// Probe 返回当前活动会话数
+type Probe func(ctx context.Context) (int, error)
+
+// Agent 自动停机 agent
+type Agent struct {
+	Config   Config
+	ECS      alicloud.ECSAPI
+	Probe    Probe
+	Output   io.Writer
+	Now      func() time.Time // 测试用，默认 time.Now
+	Interval time.Duration    // 检查间隔，默认 1 分钟
+
+	lastActive time.Time
+	stopped    bool
+}
+
+func (a *Agent) logf(format string, args ...interface{}) {
+	fmt.Fprintf(a.Output, format+"\n", args...)
+}
+
+func (a *Agent) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// Run 按间隔循环检查，直到 ctx 取消
+func (a *Agent) Run(ctx context.Context) error {
+	interval := a.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	a.logf("cloudcode agent 已启动: %s", a.Config.Policy.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := a.Check(ctx); err != nil {
+			a.logf("⚠ %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check 执行一次检查，需要停机时调用 StopInstance，返回是否已发起停机。
+// agent 启动时间视为最近一次活动，实例恢复后总有完整的闲置时长可用。
+func (a *Agent) Check(ctx context.Context) (bool, error) {
+	if a.stopped {
+		return true, nil
+	}
+	now := a.now()
+	if a.lastActive.IsZero() {
+		a.lastActive = now
+	}
+
+	sessions, err := a.Probe(ctx)
+	if err != nil {
+		// 无法判断活动时按有活动处理，宁可多开机也不中断用户
+		a.lastActive = now
+		return false, fmt.Errorf("活动检测失败: %w", err)
+	}
+	if sessions > 0 {
+		a.lastActive = now
+		return false, nil
+	}
+
+	reason, err := ShouldSuspend(&a.Config.Policy, now, a.lastActive)
+	if err != nil || reason == "" {
+		return false, err
+	}
+	a.logf("%s，停机（StopCharging）", reason)
+	if err := alicloud.StopECSInstance(a.ECS, a.Config.InstanceID, true); err != nil {
+		return false, fmt.Errorf("停机失败: %w", err)
+	}
+	a.stopped = true
+	return true, nil
+}
+
+// ShouldSuspend 根据策略判断当前是否应停机，返回原因（空表示不停机）。
+// 活跃时段之外闲置超过 OutsideHoursGrace 即停机；闲置超过 idle 时任何时段都停机。
+func ShouldSuspend(policy *config.AutoSuspendPolicy, now, lastActive time.Time) (string, error) {
+	idle := now.Sub(lastActive)
+	if policy.ActiveHours != "" {
+		hours, err := config.ParseActiveHours(policy.ActiveHours)
+		if err != nil {
+			return "", err
+		}
+		loc, err := policy.Location()
+		if err != nil {
+			return "", err
+		}
+		if !hours.Contains(now.In(loc)) && idle >= config.OutsideHoursGrace {
+			return fmt.Sprintf("当前不在活跃时段 %s 内且已闲置 %s", policy.ActiveHours, idle.Round(time.Minute)), nil
+		}
+	}
+	if d := policy.IdleDuration(); d > 0 && idle >= d {
+		return fmt.Sprintf("已闲置 %s", idle.Round(time.Minute)), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,128 @@
+package agent
+
+// probe.go 通过 /proc/<pid>/net/tcp 统计活动连接：宿主机网络命名空间中的 SSH 会话，
+// 以及各 devbox 容器网络命名空间中 OpenCode / ttyd 端口上的连接。
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 统计活动的端口
+const (
+	SSHPort      = 22
+	OpenCodePort = 4096
+	TTYDPort     = 7681
+)
+
+// tcpEstablished /proc/net/tcp 中 ESTABLISHED 状态的编码
+const tcpEstablished = "01"
+
+// ProcProbe 基于 /proc 的活动检测
+type ProcProbe struct {
+	ProcRoot   string                                   // 默认 /proc（测试用）
+	DevboxPIDs func(ctx context.Context) ([]int, error) // 默认通过 docker inspect 获取 devbox 容器的主进程 PID
+}
+
+// Count 返回活动会话数，实现 Probe
+func (p *ProcProbe) Count(ctx context.Context) (int, error) {
+	root := p.ProcRoot
+	if root == "" {
+		root = "/proc"
+	}
+	listPIDs := p.DevboxPIDs
+	if listPIDs == nil {
+		listPIDs = dockerDevboxPIDs
+	}
+
+	count, err := countNetns(filepath.Join(root, "self"), SSHPort)
+	if err != nil {
+		return 0, err
+	}
+	pids, err := listPIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, pid := range pids {
+		n, err := countNetns(filepath.Join(root, strconv.Itoa(pid)), OpenCodePort, TTYDPort)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// countNetns 统计进程所在网络命名空间中本地端口为 ports 的 ESTABLISHED 连接（IPv4 + IPv6）
+func countNetns(procDir string, ports ...int) (int, error) {
+	count := 0
+	for _, name := range []string{"tcp", "tcp6"} {
+		data, err := os.ReadFile(filepath.Join(procDir, "net", name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		count += CountEstablished(data, ports...)
+	}
+	return count, nil
+}
+
+// CountEstablished 统计 /proc/net/tcp(6) 内容中本地端口为 ports 的 ESTABLISHED 连接
+func CountEstablished(data []byte, ports ...int) int {
+	want := make(map[int64]bool, len(ports))
+	for _, p := range ports {
+		want[int64(p)] = true
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// 格式: sl local_address rem_address st ...，地址为 十六进制IP:十六进制端口
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		i := strings.LastIndexByte(fields[1], ':')
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseInt(fields[1][i+1:], 16, 32)
+		if err == nil && want[port] {
+			count++
+		}
+	}
+	return count
+}
+
+// dockerDevboxPIDs 返回运行中的 devbox 容器（devbox 或 devbox-<用户>）主进程 PID
+func dockerDevboxPIDs(ctx context.Context) ([]int, error) {
+	out, err := exec.CommandContext(ctx, "docker", "ps", "-q", "--filter", "name=^devbox").Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker ps 失败: %w", err)
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"inspect", "-f", "{{.State.Pid}}"}, ids...)
+	out, err = exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker inspect 失败: %w", err)
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(out)) {
+		if pid, err := strconv.Atoi(field); err == nil && pid > 0 {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
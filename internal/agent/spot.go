@@ -0,0 +1,146 @@
+package agent
+
+// spot.go 实现抢占式实例的回收通知监测：实例被回收前约 5 分钟，元数据服务返回回收时间。
+// 监测到通知后先停止容器并落盘（docker compose stop + sync），再为系统盘创建带部署标签的快照，
+// CLI 检测到实例被回收后据此快照重建（见 deploy/spot.go）。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+)
+
+const (
+	// SpotNoticeURL 抢占式实例回收通知的元数据地址，未进入回收流程时返回 404
+	SpotNoticeURL = "http://100.100.100.200/latest/meta-data/instance/spot/termination-time"
+	// DefaultSpotInterval 回收通知轮询间隔（通知提前约 5 分钟下发）
+	DefaultSpotInterval = 5 * time.Second
+
+	spotFlushScript = "cd /root/cloudcode && docker compose stop; sync"
+)
+
+// SpotWatcher 抢占式实例回收通知监测
+type SpotWatcher struct {
+	Config   Config
+	ECS      alicloud.ECSAPI
+	Notice   func(ctx context.Context) (time.Time, bool, error) // 测试用，默认查询元数据服务
+	Flush    func(ctx context.Context) error                    // 测试用，默认停止容器并 sync
+	Output   io.Writer
+	Interval time.Duration // 轮询间隔，默认 5 秒
+	Version  string        // 写入快照标签
+
+	snapshotID string
+}
+
+func (w *SpotWatcher) logf(format string, args ...interface{}) {
+	fmt.Fprintf(w.Output, format+"\n", args...)
+}
+
+// Run 按间隔轮询回收通知，直到 ctx 取消
+func (w *SpotWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultSpotInterval
+	}
+	w.logf("抢占式实例回收监测已启动")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Check(ctx); err != nil {
+			w.logf("⚠ %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check 执行一次检查，收到回收通知时落盘并创建快照，返回是否已创建快照。快照只创建一次。
+func (w *SpotWatcher) Check(ctx context.Context) (bool, error) {
+	if w.snapshotID != "" {
+		return true, nil
+	}
+	notice := w.Notice
+	if notice == nil {
+		notice = MetadataSpotNotice
+	}
+	at, ok, err := notice(ctx)
+	if err != nil {
+		return false, fmt.Errorf("查询回收通知失败: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+	w.logf("收到回收通知，实例将于 %s 回收，创建快照", at.Local().Format("2006-01-02 15:04:05"))
+
+	flush := w.Flush
+	if flush == nil {
+		flush = flushContainers
+	}
+	// 落盘失败仍创建快照：崩溃一致的快照好过没有快照
+	if err := flush(ctx); err != nil {
+		w.logf("⚠ 停止容器失败: %v", err)
+	}
+
+	diskID, err := alicloud.GetSystemDiskID(w.ECS, w.Config.InstanceID, w.Config.Region)
+	if err != nil {
+		return false, err
+	}
+	name := "cloudcode-spot-" + time.Now().Format("20060102-150405")
+	snapshotID, err := alicloud.CreateDiskSnapshot(w.ECS, diskID, name,
+		alicloud.ResourceTags(w.Config.DeploymentID, w.Config.Env, w.Version)...)
+	if err != nil {
+		return false, err
+	}
+	w.snapshotID = snapshotID
+	w.logf("✓ 已创建快照 %s", snapshotID)
+	return true, nil
+}
+
+// MetadataSpotNotice 查询元数据服务的回收通知，返回回收时间和是否已收到通知
+func MetadataSpotNotice(ctx context.Context) (time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, SpotNoticeURL, nil)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return time.Time{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, fmt.Errorf("元数据服务返回 %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	at, err := time.Parse(time.RFC3339, strings.TrimSpace(string(body)))
+	if err != nil {
+		// 时间格式无法解析也视为已收到通知
+		return time.Now(), true, nil
+	}
+	return at, true, nil
+}
+
+func flushContainers(ctx context.Context) error {
+	out, err := exec.CommandContext(ctx, "sh", "-c", spotFlushScript).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
 	stsclient "github.com/alibabacloud-go/sts-20150401/v2/client"
 	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/aliyun/credentials-go/credentials"
 	"github.com/hwuu/cloudcode/internal/config"
 )
 
@@ -28,6 +29,7 @@ type Config struct {
 
 // LoadConfig 加载阿里云配置。
 // 优先级：环境变量 → ~/.cloudcode/credentials → 报错提示 cloudcode init。
+// 区域：ALICLOUD_REGION → 当前环境 state 中记录的区域 → credentials 中的默认区域。
 func LoadConfig() (*Config, error) {
 	// 优先从环境变量加载
 	accessKeyID := os.Getenv(EnvAccessKeyID)
@@ -35,6 +37,9 @@ func LoadConfig() (*Config, error) {
 
 	if accessKeyID != "" && accessKeySecret != "" {
 		regionID := os.Getenv("ALICLOUD_REGION")
+		if regionID == "" {
+			regionID = deployedRegion()
+		}
 		if regionID == "" {
 			regionID = DefaultRegion
 		}
@@ -58,7 +63,10 @@ func LoadConfig() (*Config, error) {
 		return nil, ErrMissingConfig
 	}
 
-	regionID := cred.Region
+	regionID := deployedRegion()
+	if regionID == "" {
+		regionID = cred.Region
+	}
 	if regionID == "" {
 		regionID = DefaultRegion
 	}
@@ -70,6 +78,16 @@ func LoadConfig() (*Config, error) {
 	}, nil
 }
 
+// deployedRegion 返回当前环境 state 中记录的区域（未部署时为空）。
+// 资源所在区域以 state 为准，cloudcode migrate 之后可能与 credentials 中的默认区域不同。
+func deployedRegion() string {
+	state, err := config.LoadState()
+	if err != nil {
+		return ""
+	}
+	return state.Region
+}
+
 // LoadConfigFromEnv 从环境变量加载阿里云配置（向后兼容）。
 func LoadConfigFromEnv() (*Config, error) {
 	accessKeyID := os.Getenv(EnvAccessKeyID)
@@ -100,9 +118,10 @@ type Clients struct {
 	VPC *vpcclient.Client
 	STS *stsclient.Client
 	DNS *dnsclient.Client
+	RAM RAMAPI
 }
 
-// NewClients 使用统一配置初始化 ECS/VPC/STS/DNS 四个 SDK 客户端
+// NewClients 使用统一配置初始化 ECS/VPC/STS/DNS/RAM 五个客户端
 func NewClients(cfg *Config) (*Clients, error) {
 	openAPIConfig := &openapi.Config{
 		AccessKeyId:     &cfg.AccessKeyID,
@@ -130,14 +149,47 @@ func NewClients(cfg *Config) (*Clients, error) {
 		return nil, err
 	}
 
+	ramCli, err := NewRAMClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Clients{
 		ECS: ecsCli,
 		VPC: vpcCli,
 		STS: stsCli,
 		DNS: dnsCli,
+		RAM: ramCli,
 	}, nil
 }
 
+// NewECSClientWithRAMRole 使用实例 RAM 角色的临时凭证创建 ECS 客户端（在 ECS 实例上运行的 agent 使用，
+// 凭证从实例元数据服务获取并自动刷新）
+func NewECSClientWithRAMRole(regionID, roleName string) (*ecsclient.Client, error) {
+	cred, err := credentials.NewCredential(&credentials.Config{
+		Type:     teaString("ecs_ram_role"),
+		RoleName: &roleName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ecsclient.NewClient(&openapi.Config{
+		Credential: cred,
+		RegionId:   &regionID,
+	})
+}
+
+// NewECSClientWithSTSToken 使用 STS 临时凭证创建 ECS 客户端（函数计算中运行的唤醒页使用，
+// 凭证来自函数角色）
+func NewECSClientWithSTSToken(regionID, accessKeyID, accessKeySecret, securityToken string) (*ecsclient.Client, error) {
+	return ecsclient.NewClient(&openapi.Config{
+		AccessKeyId:     &accessKeyID,
+		AccessKeySecret: &accessKeySecret,
+		SecurityToken:   &securityToken,
+		RegionId:        &regionID,
+	})
+}
+
 // ClientInterface 统一的客户端访问接口，用于依赖注入
 type ClientInterface interface {
 	STSClient() STSAPI
@@ -5,7 +5,6 @@ import (
 	"strings"
 
 	dnsclient "github.com/alibabacloud-go/alidns-20150109/v4/client"
-	"github.com/alibabacloud-go/tea/tea"
 )
 
 // FindBaseDomain 从用户域名列表中匹配 baseDomain 和主机记录。
@@ -70,11 +69,16 @@ func ListDomains(cli DnsAPI) ([]string, error) {
 // EnsureDNSRecord 创建或更新一条 A 记录。
 // 如果记录已存在且 IP 不同则更新，不存在则创建。
 func EnsureDNSRecord(cli DnsAPI, baseDomain, rr, ip string) error {
+	return EnsureDNSRecordOfType(cli, baseDomain, rr, "A", ip)
+}
+
+// EnsureDNSRecordOfType 创建或更新一条指定类型（A / CNAME / TXT 等）的记录
+func EnsureDNSRecordOfType(cli DnsAPI, baseDomain, rr, recordType, value string) error {
 	// 查询现有记录
 	req := &dnsclient.DescribeDomainRecordsRequest{
 		DomainName: &baseDomain,
 		RRKeyWord:  &rr,
-		Type:       tea.String("A"),
+		Type:       &recordType,
 	}
 	resp, err := cli.DescribeDomainRecords(req)
 	if err != nil {
@@ -84,17 +88,17 @@ func EnsureDNSRecord(cli DnsAPI, baseDomain, rr, ip string) error {
 	// 查找精确匹配的记录
 	if resp.Body != nil && resp.Body.DomainRecords != nil {
 		for _, record := range resp.Body.DomainRecords.Record {
-			if record.RR != nil && *record.RR == rr && record.Type != nil && *record.Type == "A" {
+			if record.RR != nil && *record.RR == rr && record.Type != nil && *record.Type == recordType {
 				// 记录已存在
-				if record.Value != nil && *record.Value == ip {
-					return nil // IP 相同，无需更新
+				if record.Value != nil && *record.Value == value {
+					return nil // 记录值相同，无需更新
 				}
-				// IP 不同，更新记录
+				// 记录值不同，更新记录
 				updateReq := &dnsclient.UpdateDomainRecordRequest{
 					RecordId: record.RecordId,
 					RR:       &rr,
-					Type:     tea.String("A"),
-					Value:    &ip,
+					Type:     &recordType,
+					Value:    &value,
 				}
 				if _, err := cli.UpdateDomainRecord(updateReq); err != nil {
 					return fmt.Errorf("更新 DNS 记录失败: %w", err)
@@ -108,8 +112,8 @@ func EnsureDNSRecord(cli DnsAPI, baseDomain, rr, ip string) error {
 	addReq := &dnsclient.AddDomainRecordRequest{
 		DomainName: &baseDomain,
 		RR:         &rr,
-		Type:       tea.String("A"),
-		Value:      &ip,
+		Type:       &recordType,
+		Value:      &value,
 	}
 	if _, err := cli.AddDomainRecord(addReq); err != nil {
 		return fmt.Errorf("创建 DNS 记录失败: %w", err)
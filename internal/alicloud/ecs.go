@@ -5,7 +5,10 @@ package alicloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -22,6 +25,12 @@ const (
 	DefaultWaitTimeout  = 5 * time.Minute  // 状态等待超时
 )
 
+// UbuntuImagePrefix 公共镜像 Ubuntu 24.04 x64 的镜像 ID 前缀，用于在任意区域查找最新版本
+const UbuntuImagePrefix = "ubuntu_24_04_x64"
+
+// DefaultRegionID DefaultImageID 和 DefaultZonePriority 对应的区域
+const DefaultRegionID = "ap-southeast-1"
+
 // DefaultZonePriority 新加坡区域可用区优先级（按库存充足程度排序）
 var DefaultZonePriority = []string{
 	"ap-southeast-1a",
@@ -29,6 +38,15 @@ var DefaultZonePriority = []string{
 	"ap-southeast-1c",
 }
 
+// SSHKeyNameForEnv 返回环境对应的 SSH 密钥对名称。
+// 同一区域内密钥对名称必须唯一，default 环境沿用旧名称以兼容已有部署。
+func SSHKeyNameForEnv(env string) string {
+	if env == "" || env == "default" {
+		return DefaultSSHKeyName
+	}
+	return DefaultSSHKeyName + "-" + env
+}
+
 // ECSResource ECS 实例资源信息（注意：与 config.ECSResource 不同，这是 SDK 层的返回值）
 type ECSResource struct {
 	ID           string
@@ -36,13 +54,78 @@ type ECSResource struct {
 	PublicIP     string
 	PrivateIP    string
 	ZoneID       string
+	Status       string // 实例状态：Pending / Starting / Running / Stopping / Stopped
 	TempImageID  string // 从快照恢复时创建的临时镜像 ID，调用方应清理
+
+	// 以下字段仅查询时返回
+	VpcID            string
+	VSwitchID        string
+	SecurityGroupIDs []string
+	KeyPairName      string
+	ImageID          string
+	Tags             map[string]string
+}
+
+// InstanceSpec 实例规格配置，零值字段使用默认值
+type InstanceSpec struct {
+	InstanceType       string
+	ImageID            string
+	SystemDiskSize     int    // GB
+	SystemDiskCategory string // cloud_essd / cloud_ssd / cloud_efficiency / cloud_auto
+	Spot               bool    // 抢占式实例（可能被回收，价格低于按量付费）
+	SpotPriceLimit     float64 // 抢占式实例每小时最高出价，0 表示跟随市场价
+}
+
+// SpotStrategy 返回 CreateInstance 的 SpotStrategy 参数
+func (s InstanceSpec) SpotStrategy() string {
+	switch {
+	case !s.Spot:
+		return "NoSpot"
+	case s.SpotPriceLimit > 0:
+		return "SpotWithPriceLimit"
+	default:
+		return "SpotAsPriceGo"
+	}
+}
+
+// WithDefaults 返回填充默认值后的规格
+func (s InstanceSpec) WithDefaults() InstanceSpec {
+	if s.InstanceType == "" {
+		s.InstanceType = DefaultInstanceType
+	}
+	if s.ImageID == "" {
+		s.ImageID = DefaultImageID
+	}
+	if s.SystemDiskSize == 0 {
+		s.SystemDiskSize = DefaultSystemDiskSize
+	}
+	if s.SystemDiskCategory == "" {
+		s.SystemDiskCategory = DefaultSystemDiskCategory
+	}
+	return s
 }
 
 // ZoneInfo 可用区信息
 type ZoneInfo struct {
-	ZoneID    string
-	Available bool // 是否支持创建 ECS 实例
+	ZoneID        string
+	Available     bool     // 是否支持创建 ECS 实例
+	InstanceTypes []string // 可售卖的实例规格（API 未返回时为空，视为不限制）
+}
+
+// Supports 判断可用区是否可创建指定规格的实例
+func (z ZoneInfo) Supports(instanceType string) bool {
+	if !z.Available {
+		return false
+	}
+	if instanceType == "" || len(z.InstanceTypes) == 0 {
+		return true
+	}
+	for _, t := range z.InstanceTypes {
+		if t == instanceType {
+			return true
+		}
+	}
+	return false
 }
 
 // DescribeAvailableZones 查询指定区域的可用区列表及其资源可用性
@@ -72,30 +155,48 @@ func DescribeAvailableZones(ecsCli ECSAPI, regionID, instanceType string) ([]Zon
 				}
 			}
 		}
-		zones = append(zones, ZoneInfo{
+		info := ZoneInfo{
 			ZoneID:    *z.ZoneId,
 			Available: available,
-		})
+		}
+		if z.AvailableInstanceTypes != nil {
+			for _, t := range z.AvailableInstanceTypes.InstanceTypes {
+				if t != nil {
+					info.InstanceTypes = append(info.InstanceTypes, *t)
+				}
+			}
+		}
+		zones = append(zones, info)
 	}
 
 	return zones, nil
 }
 
-// SelectAvailableZone 按优先级选择一个可用的可用区。
-// 优先使用 preferredZones 列表中靠前的可用区，全部不可用时返回 ErrNoAvailableZone。
+// SelectAvailableZone 按优先级选择一个可创建 instanceType 实例的可用区。
+// 优先使用 preferredZones 列表中靠前的可用区，全部不可用时返回 ErrNoAvailableZone；
+// preferredZones 为空时返回区域内第一个可用的可用区。
 func SelectAvailableZone(ecsCli ECSAPI, regionID, instanceType string, preferredZones []string) (string, error) {
 	zones, err := DescribeAvailableZones(ecsCli, regionID, instanceType)
 	if err != nil {
 		return "", err
 	}
 
-	zoneMap := make(map[string]bool)
+	if len(preferredZones) == 0 {
+		for _, z := range zones {
+			if z.Supports(instanceType) {
+				return z.ZoneID, nil
+			}
+		}
+		return "", ErrNoAvailableZone
+	}
+
+	zoneMap := make(map[string]ZoneInfo)
 	for _, z := range zones {
-		zoneMap[z.ZoneID] = z.Available
+		zoneMap[z.ZoneID] = z
 	}
 
 	for _, zoneID := range preferredZones {
-		if available, ok := zoneMap[zoneID]; ok && available {
+		if z, ok := zoneMap[zoneID]; ok && z.Supports(instanceType) {
 			return zoneID, nil
 		}
 	}
@@ -103,18 +204,49 @@ func SelectAvailableZone(ecsCli ECSAPI, regionID, instanceType string, preferred
 	return "", ErrNoAvailableZone
 }
 
-// CreateECSInstance 创建 ECS 实例（按量付费，不分配公网 IP，通过 EIP 访问）
-// snapshotID 非空时先从快照创建自定义镜像，再用该镜像创建实例。
-// 返回的 ECSResource.ImageID 非空时，调用方应在实例就绪后调用 DeleteImage 清理临时镜像。
-func CreateECSInstance(ecsCli ECSAPI, regionID, zoneID, instanceType, imageID, sgID, vswitchID, sshKeyName, instanceName, snapshotID string) (*ECSResource, error) {
-	if instanceType == "" {
-		instanceType = DefaultInstanceType
+// FindLatestUbuntuImage 查询区域内最新的 Ubuntu 24.04 x64 公共镜像 ID
+// （公共镜像 ID 带发布日期，且各区域发布节奏不同，不能跨区域复用）
+func FindLatestUbuntuImage(ecsCli ECSAPI, regionID string) (string, error) {
+	req := &ecsclient.DescribeImagesRequest{
+		RegionId:        &regionID,
+		ImageOwnerAlias: teaString("system"),
+		ImageName:       teaString(UbuntuImagePrefix),
+		OSType:          teaString("linux"),
+		Architecture:    teaString("x86_64"),
+		Status:          teaString("Available"),
+		PageSize:        teaInt32(100),
 	}
-	if imageID == "" {
-		imageID = DefaultImageID
+	resp, err := ecsCli.DescribeImages(req)
+	if err != nil {
+		return "", fmt.Errorf("查询镜像失败: %w", err)
 	}
 
-	diskCategory := DefaultSystemDiskCategory
+	var latestID, latestTime string
+	if resp != nil && resp.Body != nil && resp.Body.Images != nil {
+		for _, img := range resp.Body.Images.Image {
+			if img == nil || img.ImageId == nil || !strings.HasPrefix(*img.ImageId, UbuntuImagePrefix) {
+				continue
+			}
+			created := deref(img.CreationTime)
+			if latestID == "" || created > latestTime || (created == latestTime && *img.ImageId > latestID) {
+				latestID, latestTime = *img.ImageId, created
+			}
+		}
+	}
+	if latestID == "" {
+		return "", fmt.Errorf("区域 %s 未找到 Ubuntu 24.04 公共镜像: %w", regionID, ErrResourceNotFound)
+	}
+	return latestID, nil
+}
+
+// CreateECSInstance 创建 ECS 实例（按量付费，不分配公网 IP，通过 EIP 访问）
+// snapshotID 非空时先从快照创建自定义镜像，再用该镜像创建实例。
+// 返回的 ECSResource.ImageID 非空时，调用方应在实例就绪后调用 DeleteImage 清理临时镜像。
+func CreateECSInstance(ecsCli ECSAPI, regionID, zoneID string, spec InstanceSpec, sgID, vswitchID, sshKeyName, instanceName, snapshotID string, tags ...Tag) (*ECSResource, error) {
+	spec = spec.WithDefaults()
+	instanceType := spec.InstanceType
+	imageID := spec.ImageID
+	diskCategory := spec.SystemDiskCategory
 
 	req := &ecsclient.CreateInstanceRequest{
 		RegionId:                &regionID,
@@ -126,10 +258,21 @@ func CreateECSInstance(ecsCli ECSAPI, regionID, zoneID, instanceType, imageID, s
 		InstanceName:            &instanceName,
 		InternetMaxBandwidthOut: teaInt32(0),
 		SystemDisk: &ecsclient.CreateInstanceRequestSystemDisk{
-			Size:     teaInt32(DefaultSystemDiskSize),
+			Size:     teaInt32(int32(spec.SystemDiskSize)),
 			Category: &diskCategory,
 		},
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &ecsclient.CreateInstanceRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
+	if spec.Spot {
+		// 回收时释放实例（默认行为），由实例上的 agent 在回收通知期间创建快照，CLI 从快照重建
+		req.SpotStrategy = teaString(spec.SpotStrategy())
+		if spec.SpotPriceLimit > 0 {
+			limit := float32(spec.SpotPriceLimit)
+			req.SpotPriceLimit = &limit
+		}
+	}
 
 	if snapshotID != "" {
 		// 从快照创建自定义镜像，再用该镜像创建实例
@@ -191,6 +334,18 @@ func StopECSInstance(ecsCli ECSAPI, instanceID string, stopCharging bool) error
 	return err
 }
 
+// ModifyInstanceSpec 变更已停止实例的规格（按量付费实例，需先停机且非 StopCharging 模式）
+func ModifyInstanceSpec(ecsCli ECSAPI, instanceID, instanceType string) error {
+	req := &ecsclient.ModifyInstanceSpecRequest{
+		InstanceId:   &instanceID,
+		InstanceType: &instanceType,
+	}
+	if _, err := ecsCli.ModifyInstanceSpec(req); err != nil {
+		return fmt.Errorf("变更实例规格失败: %w", err)
+	}
+	return nil
+}
+
 // DeleteECSInstance 强制删除 ECS 实例（Force=true 会自动停止运行中的实例）
 func DeleteECSInstance(ecsCli ECSAPI, instanceID string) error {
 	req := &ecsclient.DeleteInstanceRequest{
@@ -218,24 +373,91 @@ func DescribeECSInstance(ecsCli ECSAPI, instanceID, regionID string) (*ECSResour
 		return nil, ErrResourceNotFound
 	}
 
-	inst := resp.Body.Instances.Instance[0]
-	var publicIP, privateIP string
+	return ecsFromInstance(resp.Body.Instances.Instance[0]), nil
+}
+
+// ListInstancesByName 按实例名称查询 ECS 实例（同名实例可能有多个）
+func ListInstancesByName(ecsCli ECSAPI, regionID, instanceName string) ([]ECSResource, error) {
+	var instances []ECSResource
+	for page := int32(1); ; page++ {
+		resp, err := ecsCli.DescribeInstances(&ecsclient.DescribeInstancesRequest{
+			InstanceName: &instanceName,
+			RegionId:     &regionID,
+			PageNumber:   teaInt32(page),
+			PageSize:     teaInt32(50),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Instances == nil {
+			break
+		}
+		for _, inst := range resp.Body.Instances.Instance {
+			if inst == nil || inst.InstanceId == nil {
+				continue
+			}
+			instances = append(instances, *ecsFromInstance(inst))
+		}
+		if !hasNextPage(page, 50, len(resp.Body.Instances.Instance), resp.Body.TotalCount) {
+			break
+		}
+	}
+	return instances, nil
+}
+
+// ecsFromInstance 将 DescribeInstances 返回的实例转换为 ECSResource（缺失字段留空）
+func ecsFromInstance(inst *ecsclient.DescribeInstancesResponseBodyInstancesInstance) *ECSResource {
+	result := &ECSResource{}
+	if inst.InstanceId != nil {
+		result.ID = *inst.InstanceId
+	}
+	if inst.InstanceType != nil {
+		result.InstanceType = *inst.InstanceType
+	}
+	if inst.ZoneId != nil {
+		result.ZoneID = *inst.ZoneId
+	}
+	if inst.Status != nil {
+		result.Status = *inst.Status
+	}
+	if inst.KeyPairName != nil {
+		result.KeyPairName = *inst.KeyPairName
+	}
+	if inst.ImageId != nil {
+		result.ImageID = *inst.ImageId
+	}
 	if inst.PublicIpAddress != nil && inst.PublicIpAddress.IpAddress != nil && len(inst.PublicIpAddress.IpAddress) > 0 {
-		publicIP = *inst.PublicIpAddress.IpAddress[0]
+		result.PublicIP = *inst.PublicIpAddress.IpAddress[0]
 	}
 	if inst.InnerIpAddress != nil && inst.InnerIpAddress.IpAddress != nil && len(inst.InnerIpAddress.IpAddress) > 0 {
-		privateIP = *inst.InnerIpAddress.IpAddress[0]
+		result.PrivateIP = *inst.InnerIpAddress.IpAddress[0]
 	} else if inst.VpcAttributes != nil && inst.VpcAttributes.PrivateIpAddress != nil && len(inst.VpcAttributes.PrivateIpAddress.IpAddress) > 0 {
-		privateIP = *inst.VpcAttributes.PrivateIpAddress.IpAddress[0]
+		result.PrivateIP = *inst.VpcAttributes.PrivateIpAddress.IpAddress[0]
 	}
-
-	return &ECSResource{
-		ID:           *inst.InstanceId,
-		InstanceType: *inst.InstanceType,
-		PublicIP:     publicIP,
-		PrivateIP:    privateIP,
-		ZoneID:       *inst.ZoneId,
-	}, nil
+	if inst.VpcAttributes != nil {
+		if inst.VpcAttributes.VpcId != nil {
+			result.VpcID = *inst.VpcAttributes.VpcId
+		}
+		if inst.VpcAttributes.VSwitchId != nil {
+			result.VSwitchID = *inst.VpcAttributes.VSwitchId
+		}
+	}
+	if inst.Tags != nil {
+		result.Tags = make(map[string]string)
+		for _, t := range inst.Tags.Tag {
+			if t != nil && t.TagKey != nil {
+				result.Tags[*t.TagKey] = deref(t.TagValue)
+			}
+		}
+	}
+	if inst.SecurityGroupIds != nil {
+		for _, id := range inst.SecurityGroupIds.SecurityGroupId {
+			if id != nil {
+				result.SecurityGroupIDs = append(result.SecurityGroupIDs, *id)
+			}
+		}
+	}
+	return result
 }
 
 // WaitForInstanceStatus 轮询等待 ECS 实例达到指定状态（如 Stopped/Running）。
@@ -346,11 +568,14 @@ type SSHKeyPairResource struct {
 }
 
 // CreateSSHKeyPair 创建 SSH 密钥对。如果同名密钥对已存在，自动删除后重建（因为私钥只在创建时返回）。
-func CreateSSHKeyPair(ecsCli ECSAPI, keyName, regionID string) (*SSHKeyPairResource, error) {
+func CreateSSHKeyPair(ecsCli ECSAPI, keyName, regionID string, tags ...Tag) (*SSHKeyPairResource, error) {
 	req := &ecsclient.CreateKeyPairRequest{
 		KeyPairName: &keyName,
 		RegionId:    &regionID,
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &ecsclient.CreateKeyPairRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
 
 	resp, err := ecsCli.CreateKeyPair(req)
 	if err != nil {
@@ -396,11 +621,43 @@ func DeleteSSHKeyPair(ecsCli ECSAPI, keyName, regionID string) error {
 	return err
 }
 
+// DescribeSSHKeyPair 按名称查询 SSH 密钥对（不含私钥）
+func DescribeSSHKeyPair(ecsCli ECSAPI, keyName, regionID string) (*SSHKeyPairResource, error) {
+	req := &ecsclient.DescribeKeyPairsRequest{
+		KeyPairName: &keyName,
+		RegionId:    &regionID,
+	}
+	resp, err := ecsCli.DescribeKeyPairs(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.Body == nil || resp.Body.KeyPairs == nil {
+		return nil, ErrResourceNotFound
+	}
+	// KeyPairName 为模糊匹配，需要精确比对
+	for _, kp := range resp.Body.KeyPairs.KeyPair {
+		if kp == nil || kp.KeyPairName == nil || *kp.KeyPairName != keyName {
+			continue
+		}
+		result := &SSHKeyPairResource{Name: keyName}
+		if kp.KeyPairFingerPrint != nil {
+			result.FingerPrint = *kp.KeyPairFingerPrint
+		}
+		return result, nil
+	}
+	return nil, ErrResourceNotFound
+}
+
 // ImportSSHKeyPair 导入已有的 SSH 公钥（用于自定义密钥场景）
-func ImportSSHKeyPair(ecsCli ECSAPI, keyName, publicKey string) (*SSHKeyPairResource, error) {
+func ImportSSHKeyPair(ecsCli ECSAPI, keyName, publicKey, regionID string, tags ...Tag) (*SSHKeyPairResource, error) {
 	req := &ecsclient.ImportKeyPairRequest{
 		KeyPairName:   &keyName,
 		PublicKeyBody: &publicKey,
+		RegionId:      &regionID,
+	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &ecsclient.ImportKeyPairRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
 	}
 
 	resp, err := ecsCli.ImportKeyPair(req)
@@ -422,6 +679,18 @@ func ImportSSHKeyPair(ecsCli ECSAPI, keyName, publicKey string) (*SSHKeyPairReso
 	return result, nil
 }
 
+// AttachSSHKeyPair 将密钥对绑定到实例（替换原有密钥对）。
+// 运行中的实例需重启后生效。
+func AttachSSHKeyPair(ecsCli ECSAPI, keyName, instanceID, regionID string) error {
+	req := &ecsclient.AttachKeyPairRequest{
+		KeyPairName: &keyName,
+		InstanceIds: teaString(fmt.Sprintf(`["%s"]`, instanceID)),
+		RegionId:    &regionID,
+	}
+	_, err := ecsCli.AttachKeyPair(req)
+	return err
+}
+
 // GetSystemDiskID 获取 ECS 实例的系统盘 ID
 func GetSystemDiskID(ecsCli ECSAPI, instanceID, regionID string) (string, error) {
 	diskType := "system"
@@ -445,12 +714,28 @@ func GetSystemDiskID(ecsCli ECSAPI, instanceID, regionID string) (string, error)
 	return *disk.DiskId, nil
 }
 
+// ResizeDisk 离线扩容磁盘（只能增大），重启实例后生效；分区和文件系统需在实例内扩展
+func ResizeDisk(ecsCli ECSAPI, diskID string, newSize int) error {
+	req := &ecsclient.ResizeDiskRequest{
+		DiskId:  &diskID,
+		NewSize: teaInt32(int32(newSize)),
+		Type:    teaString("offline"),
+	}
+	if _, err := ecsCli.ResizeDisk(req); err != nil {
+		return fmt.Errorf("扩容磁盘失败: %w", err)
+	}
+	return nil
+}
+
 // CreateDiskSnapshot 创建磁盘快照
-func CreateDiskSnapshot(ecsCli ECSAPI, diskID, snapshotName string) (string, error) {
+func CreateDiskSnapshot(ecsCli ECSAPI, diskID, snapshotName string, tags ...Tag) (string, error) {
 	req := &ecsclient.CreateSnapshotRequest{
 		DiskId:       &diskID,
 		SnapshotName: &snapshotName,
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &ecsclient.CreateSnapshotRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
 	resp, err := ecsCli.CreateSnapshot(req)
 	if err != nil {
 		return "", fmt.Errorf("创建快照失败: %w", err)
@@ -513,6 +798,156 @@ func DeleteSnapshot(ecsCli ECSAPI, snapshotID string) error {
 	return nil
 }
 
+// CopySnapshot 将快照复制到目标区域（跨区域迁移使用），返回目标区域的新快照 ID。
+// 复制是异步的，调用方需在目标区域等待新快照就绪。
+func CopySnapshot(ecsCli ECSAPI, snapshotID, regionID, destRegionID, snapshotName string, tags ...Tag) (string, error) {
+	req := &ecsclient.CopySnapshotRequest{
+		SnapshotId:              &snapshotID,
+		RegionId:                &regionID,
+		DestinationRegionId:     &destRegionID,
+		DestinationSnapshotName: &snapshotName,
+	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &ecsclient.CopySnapshotRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
+	resp, err := ecsCli.CopySnapshot(req)
+	if err != nil {
+		return "", fmt.Errorf("复制快照到 %s 失败: %w", destRegionID, err)
+	}
+	if resp == nil || resp.Body == nil || resp.Body.SnapshotId == nil {
+		return "", fmt.Errorf("复制快照返回无效响应")
+	}
+	return *resp.Body.SnapshotId, nil
+}
+
+// SnapshotStatuses 批量查询快照状态（progressing / accomplished / failed），不存在的快照不在结果中
+func SnapshotStatuses(ecsCli ECSAPI, regionID string, snapshotIDs []string) (map[string]string, error) {
+	statuses := make(map[string]string)
+	for start := 0; start < len(snapshotIDs); start += 100 {
+		end := start + 100
+		if end > len(snapshotIDs) {
+			end = len(snapshotIDs)
+		}
+		ids, _ := json.Marshal(snapshotIDs[start:end])
+		req := &ecsclient.DescribeSnapshotsRequest{
+			SnapshotIds: teaString(string(ids)),
+			RegionId:    &regionID,
+			PageSize:    teaInt32(100),
+		}
+		resp, err := ecsCli.DescribeSnapshots(req)
+		if err != nil {
+			return nil, fmt.Errorf("查询快照失败: %w", err)
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Snapshots == nil {
+			continue
+		}
+		for _, snap := range resp.Body.Snapshots.Snapshot {
+			if snap.SnapshotId != nil && snap.Status != nil {
+				statuses[*snap.SnapshotId] = *snap.Status
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// SnapshotInfo 快照信息
+type SnapshotInfo struct {
+	ID             string
+	Name           string
+	CreatedAt      string // RFC 3339（UTC）
+	SourceDiskSize int    // GB
+}
+
+// LatestDeploymentSnapshot 返回区域内带部署 ID 标签、已完成的最新快照（抢占式实例被回收后重建使用），没有时返回 ErrResourceNotFound
+func LatestDeploymentSnapshot(ecsCli ECSAPI, regionID, deploymentID string) (*SnapshotInfo, error) {
+	resp, err := ecsCli.DescribeSnapshots(&ecsclient.DescribeSnapshotsRequest{
+		RegionId: &regionID,
+		Status:   teaString("accomplished"),
+		PageSize: teaInt32(100),
+		Tag:      []*ecsclient.DescribeSnapshotsRequestTag{{Key: teaString(TagKeyDeploymentID), Value: &deploymentID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询快照失败: %w", err)
+	}
+	var latest *SnapshotInfo
+	if resp != nil && resp.Body != nil && resp.Body.Snapshots != nil {
+		for _, snap := range resp.Body.Snapshots.Snapshot {
+			if snap == nil || snap.SnapshotId == nil || deref(snap.Status) != "accomplished" {
+				continue
+			}
+			info := &SnapshotInfo{ID: *snap.SnapshotId, Name: deref(snap.SnapshotName), CreatedAt: deref(snap.CreationTime)}
+			info.SourceDiskSize, _ = strconv.Atoi(deref(snap.SourceDiskSize))
+			if latest == nil || info.CreatedAt > latest.CreatedAt {
+				latest = info
+			}
+		}
+	}
+	if latest == nil {
+		return nil, ErrResourceNotFound
+	}
+	return latest, nil
+}
+
+// ReplaceSystemDisk 用镜像替换实例的系统盘（实例须已停止），返回新系统盘 ID。原系统盘随之释放，其手动快照保留。
+func ReplaceSystemDisk(ecsCli ECSAPI, instanceID, imageID, keyPairName string, diskSize int) (string, error) {
+	req := &ecsclient.ReplaceSystemDiskRequest{
+		InstanceId: &instanceID,
+		ImageId:    &imageID,
+	}
+	if keyPairName != "" {
+		req.KeyPairName = &keyPairName
+	}
+	if diskSize > 0 {
+		req.SystemDisk = &ecsclient.ReplaceSystemDiskRequestSystemDisk{Size: teaInt32(int32(diskSize))}
+	}
+	resp, err := ecsCli.ReplaceSystemDisk(req)
+	if err != nil {
+		return "", fmt.Errorf("替换系统盘失败: %w", err)
+	}
+	if resp == nil || resp.Body == nil || resp.Body.DiskId == nil {
+		return "", fmt.Errorf("替换系统盘返回无效响应")
+	}
+	return *resp.Body.DiskId, nil
+}
+
+// WaitForSystemDisk 等待实例的系统盘变为 diskID 且处于 In_use（替换系统盘完成）
+func WaitForSystemDisk(ctx context.Context, ecsCli ECSAPI, instanceID, diskID, regionID string, interval, timeout time.Duration) error {
+	if interval == 0 {
+		interval = DefaultWaitInterval
+	}
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待系统盘替换完成超时")
+		case <-ticker.C:
+			req := &ecsclient.DescribeDisksRequest{
+				InstanceId: &instanceID,
+				RegionId:   &regionID,
+				DiskType:   teaString("system"),
+			}
+			resp, err := ecsCli.DescribeDisks(req)
+			if err != nil || resp == nil || resp.Body == nil || resp.Body.Disks == nil {
+				continue
+			}
+			for _, disk := range resp.Body.Disks.Disk {
+				if disk.DiskId != nil && *disk.DiskId == diskID && disk.Status != nil && *disk.Status == "In_use" {
+					return nil
+				}
+			}
+		}
+	}
+}
+
 // CreateImageFromSnapshot 从快照创建自定义镜像
 func CreateImageFromSnapshot(ecsCli ECSAPI, snapshotID, regionID, imageName string) (string, error) {
 	req := &ecsclient.CreateImageRequest{
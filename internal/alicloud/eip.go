@@ -13,13 +13,14 @@ import (
 
 // EIPResource EIP 资源信息
 type EIPResource struct {
-	ID     string // EIP 分配 ID（AllocationId）
-	IP     string // 弹性公网 IP 地址
-	Status string // 状态：Available（未绑定）/ InUse（已绑定）
+	ID         string // EIP 分配 ID（AllocationId）
+	IP         string // 弹性公网 IP 地址
+	Status     string // 状态：Available（未绑定）/ InUse（已绑定）
+	InstanceID string // 绑定的实例 ID（未绑定时为空）
 }
 
 // AllocateEIP 分配一个按流量计费的 EIP（带宽 5Mbps）
-func AllocateEIP(vpcCli VPCAPI, regionID, eipName string) (*EIPResource, error) {
+func AllocateEIP(vpcCli VPCAPI, regionID, eipName string, tags ...Tag) (*EIPResource, error) {
 	req := &vpcclient.AllocateEipAddressRequest{
 		RegionId:           &regionID,
 		Bandwidth:          teaString("5"),
@@ -28,6 +29,9 @@ func AllocateEIP(vpcCli VPCAPI, regionID, eipName string) (*EIPResource, error)
 	if eipName != "" {
 		req.Name = &eipName
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &vpcclient.AllocateEipAddressRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
 
 	resp, err := vpcCli.AllocateEipAddress(req)
 	if err != nil {
@@ -92,17 +96,71 @@ func DescribeEIP(vpcCli VPCAPI, allocationID, regionID string) (*EIPResource, er
 		return nil, ErrResourceNotFound
 	}
 
-	eip := resp.Body.EipAddresses.EipAddress[0]
-	status := ""
-	if eip.Status != nil {
-		status = *eip.Status
+	return eipFromResponse(resp.Body.EipAddresses.EipAddress[0]), nil
+}
+
+// DescribeEIPByInstance 查询绑定到指定实例的 EIP
+func DescribeEIPByInstance(vpcCli VPCAPI, instanceID, regionID string) (*EIPResource, error) {
+	req := &vpcclient.DescribeEipAddressesRequest{
+		AssociatedInstanceId:   &instanceID,
+		AssociatedInstanceType: teaString("EcsInstance"),
+		RegionId:               &regionID,
 	}
 
-	return &EIPResource{
-		ID:     *eip.AllocationId,
-		IP:     *eip.IpAddress,
-		Status: status,
-	}, nil
+	resp, err := vpcCli.DescribeEipAddresses(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.Body == nil || resp.Body.EipAddresses == nil ||
+		resp.Body.EipAddresses.EipAddress == nil || len(resp.Body.EipAddresses.EipAddress) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	return eipFromResponse(resp.Body.EipAddresses.EipAddress[0]), nil
+}
+
+// ListEIPsByName 按名称查询 EIP（同名 EIP 可能有多个）
+func ListEIPsByName(vpcCli VPCAPI, regionID, eipName string) ([]EIPResource, error) {
+	var eips []EIPResource
+	for page := int32(1); ; page++ {
+		resp, err := vpcCli.DescribeEipAddresses(&vpcclient.DescribeEipAddressesRequest{
+			EipName:    &eipName,
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(50),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.EipAddresses == nil {
+			break
+		}
+		for _, eip := range resp.Body.EipAddresses.EipAddress {
+			if eip == nil || eip.AllocationId == nil {
+				continue
+			}
+			eips = append(eips, *eipFromResponse(eip))
+		}
+		if !hasNextPage(page, 50, len(resp.Body.EipAddresses.EipAddress), resp.Body.TotalCount) {
+			break
+		}
+	}
+	return eips, nil
+}
+
+func eipFromResponse(eip *vpcclient.DescribeEipAddressesResponseBodyEipAddressesEipAddress) *EIPResource {
+	result := &EIPResource{ID: *eip.AllocationId}
+	if eip.IpAddress != nil {
+		result.IP = *eip.IpAddress
+	}
+	if eip.Status != nil {
+		result.Status = *eip.Status
+	}
+	if eip.InstanceId != nil {
+		result.InstanceID = *eip.InstanceId
+	}
+	return result
 }
 
 const (
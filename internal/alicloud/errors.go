@@ -24,3 +24,8 @@ func isErrorCode(err error, code string) bool {
 	}
 	return strings.Contains(err.Error(), code)
 }
+
+// IsNotFound 判断错误是否表示资源不存在（如函数计算的 FunctionNotFound、DomainNameNotFound、TriggerNotFound）
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrResourceNotFound) || isErrorCode(err, "NotFound")
+}
@@ -0,0 +1,174 @@
+package alicloud
+
+// fc.go 通过 OpenAPI 通用调用（ROA 风格）访问函数计算 3.0，部署唤醒页函数。
+// 函数以自定义运行时运行 cloudcode 二进制，经 HTTP 触发器和自定义域名对外提供服务。
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	"github.com/alibabacloud-go/tea/dara"
+)
+
+const fcAPIVersion = "2023-03-30"
+
+// FCFunction 自定义运行时函数的配置
+type FCFunction struct {
+	Name        string
+	Description string
+	RoleARN     string            // 函数扮演的 RAM 角色，运行时通过环境变量获得临时凭证
+	Code        []byte            // 代码包（zip）
+	Command     []string          // 启动命令
+	Port        int               // 函数监听的 HTTP 端口
+	MemoryMB    int               // 内存规格
+	Timeout     int               // 请求超时（秒）
+	Env         map[string]string // 环境变量
+}
+
+// FCCustomDomain 自定义域名，所有路径路由到同一个函数；CertPEM 非空时同时开启 HTTPS
+type FCCustomDomain struct {
+	DomainName   string
+	FunctionName string
+	CertName     string
+	CertPEM      string
+	KeyPEM       string
+}
+
+// fcClient 通过 OpenAPI 通用调用实现 FCAPI
+type fcClient struct {
+	cli *openapi.Client
+}
+
+// FCEndpoint 返回函数计算在区域内的服务地址（自定义域名 CNAME 到该地址）
+func FCEndpoint(accountID, regionID string) string {
+	return fmt.Sprintf("%s.%s.fc.aliyuncs.com", accountID, regionID)
+}
+
+// NewFCClient 创建函数计算客户端（服务地址包含账号 ID）
+func NewFCClient(cfg *Config, accountID string) (FCAPI, error) {
+	cli, err := openapi.NewClient(&openapi.Config{
+		AccessKeyId:     &cfg.AccessKeyID,
+		AccessKeySecret: &cfg.AccessKeySecret,
+		RegionId:        &cfg.RegionID,
+		Endpoint:        teaString(FCEndpoint(accountID, cfg.RegionID)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fcClient{cli: cli}, nil
+}
+
+func (c *fcClient) call(action, method, path string, body interface{}) error {
+	params := &openapi.Params{
+		Action:      teaString(action),
+		Version:     teaString(fcAPIVersion),
+		Protocol:    teaString("HTTPS"),
+		Pathname:    teaString("/" + fcAPIVersion + path),
+		Method:      teaString(method),
+		AuthType:    teaString("AK"),
+		Style:       teaString("ROA"),
+		ReqBodyType: teaString("json"),
+		BodyType:    teaString("json"),
+	}
+	req := &openapi.OpenApiRequest{}
+	if body != nil {
+		req.Body = body
+	}
+	_, err := c.cli.CallApi(params, req, &dara.RuntimeOptions{})
+	return err
+}
+
+func (c *fcClient) GetFunction(functionName string) error {
+	return c.call("GetFunction", "GET", "/functions/"+url.PathEscape(functionName), nil)
+}
+
+func (c *fcClient) CreateFunction(fn *FCFunction) error {
+	body := functionBody(fn)
+	body["functionName"] = fn.Name
+	return c.call("CreateFunction", "POST", "/functions", body)
+}
+
+func (c *fcClient) UpdateFunction(fn *FCFunction) error {
+	return c.call("UpdateFunction", "PUT", "/functions/"+url.PathEscape(fn.Name), functionBody(fn))
+}
+
+func (c *fcClient) DeleteFunction(functionName string) error {
+	return c.call("DeleteFunction", "DELETE", "/functions/"+url.PathEscape(functionName), nil)
+}
+
+func (c *fcClient) CreateHTTPTrigger(functionName, triggerName string) error {
+	triggerConfig, _ := json.Marshal(map[string]interface{}{
+		"authType": "anonymous",
+		"methods":  []string{"GET", "POST"},
+	})
+	return c.call("CreateTrigger", "POST", "/functions/"+url.PathEscape(functionName)+"/triggers", map[string]interface{}{
+		"triggerName":   triggerName,
+		"triggerType":   "http",
+		"triggerConfig": string(triggerConfig),
+	})
+}
+
+func (c *fcClient) DeleteTrigger(functionName, triggerName string) error {
+	return c.call("DeleteTrigger", "DELETE", "/functions/"+url.PathEscape(functionName)+"/triggers/"+url.PathEscape(triggerName), nil)
+}
+
+func (c *fcClient) GetCustomDomain(domainName string) error {
+	return c.call("GetCustomDomain", "GET", "/custom-domains/"+url.PathEscape(domainName), nil)
+}
+
+func (c *fcClient) CreateCustomDomain(domain *FCCustomDomain) error {
+	body := customDomainBody(domain)
+	body["domainName"] = domain.DomainName
+	return c.call("CreateCustomDomain", "POST", "/custom-domains", body)
+}
+
+func (c *fcClient) UpdateCustomDomain(domain *FCCustomDomain) error {
+	return c.call("UpdateCustomDomain", "PUT", "/custom-domains/"+url.PathEscape(domain.DomainName), customDomainBody(domain))
+}
+
+func (c *fcClient) DeleteCustomDomain(domainName string) error {
+	return c.call("DeleteCustomDomain", "DELETE", "/custom-domains/"+url.PathEscape(domainName), nil)
+}
+
+func functionBody(fn *FCFunction) map[string]interface{} {
+	return map[string]interface{}{
+		"description": fn.Description,
+		"runtime":     "custom.debian10",
+		"handler":     "index.handler", // 自定义运行时不使用，但为必填项
+		"role":        fn.RoleARN,
+		"memorySize":  fn.MemoryMB,
+		"timeout":     fn.Timeout,
+		"code":        map[string]string{"zipFile": base64.StdEncoding.EncodeToString(fn.Code)},
+		"customRuntimeConfig": map[string]interface{}{
+			"command": fn.Command,
+			"port":    fn.Port,
+		},
+		"environmentVariables": fn.Env,
+	}
+}
+
+func customDomainBody(domain *FCCustomDomain) map[string]interface{} {
+	body := map[string]interface{}{
+		"protocol": "HTTP",
+		"routeConfig": map[string]interface{}{
+			"routes": []map[string]interface{}{{
+				"path":         "/*",
+				"functionName": domain.FunctionName,
+				"qualifier":    "LATEST",
+				"methods":      []string{"GET", "POST"},
+			}},
+		},
+	}
+	if domain.CertPEM != "" {
+		body["protocol"] = "HTTP,HTTPS"
+		body["certConfig"] = map[string]string{
+			"certName":    domain.CertName,
+			"certificate": domain.CertPEM,
+			"privateKey":  domain.KeyPEM,
+		}
+	}
+	return body
+}
@@ -43,12 +43,14 @@ type ECSAPI interface {
 	DescribeInstances(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error)
 	StartInstance(req *ecsclient.StartInstanceRequest) (*ecsclient.StartInstanceResponse, error)
 	StopInstance(req *ecsclient.StopInstanceRequest) (*ecsclient.StopInstanceResponse, error)
+	ModifyInstanceSpec(req *ecsclient.ModifyInstanceSpecRequest) (*ecsclient.ModifyInstanceSpecResponse, error)
 
 	// SSH 密钥对管理
 	CreateKeyPair(req *ecsclient.CreateKeyPairRequest) (*ecsclient.CreateKeyPairResponse, error)
 	DeleteKeyPairs(req *ecsclient.DeleteKeyPairsRequest) (*ecsclient.DeleteKeyPairsResponse, error)
 	DescribeKeyPairs(req *ecsclient.DescribeKeyPairsRequest) (*ecsclient.DescribeKeyPairsResponse, error)
 	ImportKeyPair(req *ecsclient.ImportKeyPairRequest) (*ecsclient.ImportKeyPairResponse, error)
+	AttachKeyPair(req *ecsclient.AttachKeyPairRequest) (*ecsclient.AttachKeyPairResponse, error)
 
 	// 可用区与账号属性查询
 	DescribeZones(req *ecsclient.DescribeZonesRequest) (*ecsclient.DescribeZonesResponse, error)
@@ -62,14 +64,49 @@ type ECSAPI interface {
 
 	// 磁盘与快照管理
 	DescribeDisks(req *ecsclient.DescribeDisksRequest) (*ecsclient.DescribeDisksResponse, error)
+	ResizeDisk(req *ecsclient.ResizeDiskRequest) (*ecsclient.ResizeDiskResponse, error)
 	CreateSnapshot(req *ecsclient.CreateSnapshotRequest) (*ecsclient.CreateSnapshotResponse, error)
 	DescribeSnapshots(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error)
 	DeleteSnapshot(req *ecsclient.DeleteSnapshotRequest) (*ecsclient.DeleteSnapshotResponse, error)
+	CopySnapshot(req *ecsclient.CopySnapshotRequest) (*ecsclient.CopySnapshotResponse, error)
+	ReplaceSystemDisk(req *ecsclient.ReplaceSystemDiskRequest) (*ecsclient.ReplaceSystemDiskResponse, error)
 
 	// 自定义镜像管理（快照恢复时使用）
 	CreateImage(req *ecsclient.CreateImageRequest) (*ecsclient.CreateImageResponse, error)
 	DescribeImages(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error)
 	DeleteImage(req *ecsclient.DeleteImageRequest) (*ecsclient.DeleteImageResponse, error)
+
+	// 实例 RAM 角色（自动停机 agent 使用）
+	AttachInstanceRamRole(req *ecsclient.AttachInstanceRamRoleRequest) (*ecsclient.AttachInstanceRamRoleResponse, error)
+	DetachInstanceRamRole(req *ecsclient.DetachInstanceRamRoleRequest) (*ecsclient.DetachInstanceRamRoleResponse, error)
+	DescribeInstanceRamRole(req *ecsclient.DescribeInstanceRamRoleRequest) (*ecsclient.DescribeInstanceRamRoleResponse, error)
+}
+
+// RAMAPI 访问控制接口，管理自动停机 agent 和唤醒页的角色和权限策略。
+// 项目未引入 RAM SDK，由 ramClient 通过 OpenAPI 通用调用实现。
+type RAMAPI interface {
+	GetRole(roleName string) error
+	CreateRole(roleName, assumeRolePolicy, description string) error
+	DeleteRole(roleName string) error
+	CreatePolicy(policyName, policyDocument, description string) error
+	DeletePolicy(policyName string) error
+	AttachPolicyToRole(policyName, roleName string) error
+	DetachPolicyFromRole(policyName, roleName string) error
+}
+
+// FCAPI 函数计算 3.0 接口，管理唤醒页函数、HTTP 触发器和自定义域名。
+// 项目未引入函数计算 SDK，由 fcClient 通过 OpenAPI 通用调用实现。
+type FCAPI interface {
+	GetFunction(functionName string) error
+	CreateFunction(fn *FCFunction) error
+	UpdateFunction(fn *FCFunction) error
+	DeleteFunction(functionName string) error
+	CreateHTTPTrigger(functionName, triggerName string) error
+	DeleteTrigger(functionName, triggerName string) error
+	GetCustomDomain(domainName string) error
+	CreateCustomDomain(domain *FCCustomDomain) error
+	UpdateCustomDomain(domain *FCCustomDomain) error
+	DeleteCustomDomain(domainName string) error
 }
 
 // DnsAPI 云解析 DNS 接口，管理域名和解析记录
@@ -0,0 +1,300 @@
+package alicloud
+
+// ram.go 管理 CloudCode 创建的 RAM 角色，每个角色带一条同名权限策略，只授权操作部署的那一台实例：
+//   - 自动停机 agent（cloudcode-agent-<部署 ID>）：由 ECS 扮演，只允许停机本实例（抢占式实例另允许为
+//     本区域的磁盘创建快照）。角色绑定到实例后，agent 通过实例元数据获取临时凭证，无需在实例上保存 AccessKey。
+//   - 唤醒页（cloudcode-wake-<部署 ID>）：由函数计算扮演，只允许启动和查询本实例。
+
+import (
+	"encoding/json"
+	"fmt"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/dara"
+)
+
+const (
+	ramEndpoint   = "ram.aliyuncs.com"
+	ramAPIVersion = "2015-05-01"
+
+	// AgentRolePrefix 自动停机 agent 角色名前缀（后接部署 ID）
+	AgentRolePrefix = "cloudcode-agent-"
+	// WakeRolePrefix 唤醒页函数角色名前缀（后接部署 ID）
+	WakeRolePrefix = "cloudcode-wake-"
+)
+
+// assumeRolePolicy 返回只允许指定云服务扮演的角色信任策略
+func assumeRolePolicy(service string) string {
+	return fmt.Sprintf(`{"Statement":[{"Action":"sts:AssumeRole","Effect":"Allow","Principal":{"Service":["%s"]}}],"Version":"1"}`, service)
+}
+
+// ramClient 通过 OpenAPI 通用调用实现 RAMAPI
+type ramClient struct {
+	cli *openapi.Client
+}
+
+// NewRAMClient 创建 RAM 客户端（RAM 为全局服务，不区分区域）
+func NewRAMClient(cfg *Config) (RAMAPI, error) {
+	cli, err := openapi.NewClient(&openapi.Config{
+		AccessKeyId:     &cfg.AccessKeyID,
+		AccessKeySecret: &cfg.AccessKeySecret,
+		Endpoint:        teaString(ramEndpoint),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ramClient{cli: cli}, nil
+}
+
+func (c *ramClient) call(action string, query map[string]string) error {
+	q := make(map[string]*string, len(query))
+	for k, v := range query {
+		q[k] = teaString(v)
+	}
+	params := &openapi.Params{
+		Action:      teaString(action),
+		Version:     teaString(ramAPIVersion),
+		Protocol:    teaString("HTTPS"),
+		Pathname:    teaString("/"),
+		Method:      teaString("POST"),
+		AuthType:    teaString("AK"),
+		Style:       teaString("RPC"),
+		ReqBodyType: teaString("formData"),
+		BodyType:    teaString("json"),
+	}
+	_, err := c.cli.CallApi(params, &openapi.OpenApiRequest{Query: q}, &dara.RuntimeOptions{})
+	return err
+}
+
+func (c *ramClient) GetRole(roleName string) error {
+	return c.call("GetRole", map[string]string{"RoleName": roleName})
+}
+
+func (c *ramClient) CreateRole(roleName, assumeRolePolicy, description string) error {
+	return c.call("CreateRole", map[string]string{
+		"RoleName":                 roleName,
+		"AssumeRolePolicyDocument": assumeRolePolicy,
+		"Description":              description,
+	})
+}
+
+func (c *ramClient) DeleteRole(roleName string) error {
+	return c.call("DeleteRole", map[string]string{"RoleName": roleName})
+}
+
+func (c *ramClient) CreatePolicy(policyName, policyDocument, description string) error {
+	return c.call("CreatePolicy", map[string]string{
+		"PolicyName":     policyName,
+		"PolicyDocument": policyDocument,
+		"Description":    description,
+	})
+}
+
+func (c *ramClient) DeletePolicy(policyName string) error {
+	return c.call("DeletePolicy", map[string]string{"PolicyName": policyName})
+}
+
+func (c *ramClient) AttachPolicyToRole(policyName, roleName string) error {
+	return c.call("AttachPolicyToRole", map[string]string{
+		"PolicyType": "Custom",
+		"PolicyName": policyName,
+		"RoleName":   roleName,
+	})
+}
+
+func (c *ramClient) DetachPolicyFromRole(policyName, roleName string) error {
+	return c.call("DetachPolicyFromRole", map[string]string{
+		"PolicyType": "Custom",
+		"PolicyName": policyName,
+		"RoleName":   roleName,
+	})
+}
+
+// AgentRoleName 返回部署对应的 agent 角色名（权限策略同名）
+func AgentRoleName(deploymentID string) string {
+	return AgentRolePrefix + deploymentID
+}
+
+// WakeRoleName 返回部署对应的唤醒页函数角色名（权限策略同名）
+func WakeRoleName(deploymentID string) string {
+	return WakeRolePrefix + deploymentID
+}
+
+// RoleARN 返回 RAM 角色的 ARN
+func RoleARN(accountID, roleName string) string {
+	return fmt.Sprintf("acs:ram::%s:role/%s", accountID, roleName)
+}
+
+// AgentPolicyDocument 返回只允许停机指定实例的权限策略。spot 时另允许查询磁盘并创建快照（回收通知期间备份系统盘，
+// DescribeDisks / CreateSnapshot 不支持按实例授权，限定在实例所在区域）
+func AgentPolicyDocument(regionID, accountID, instanceID string, spot bool) string {
+	statements := []map[string]interface{}{{
+		"Effect":   "Allow",
+		"Action":   []string{"ecs:StopInstance"},
+		"Resource": []string{instanceARN(regionID, accountID, instanceID)},
+	}}
+	if spot {
+		statements = append(statements, map[string]interface{}{
+			"Effect": "Allow",
+			"Action": []string{"ecs:DescribeDisks", "ecs:CreateSnapshot", "ecs:TagResources"},
+			"Resource": []string{
+				fmt.Sprintf("acs:ecs:%s:%s:disk/*", regionID, accountID),
+				fmt.Sprintf("acs:ecs:%s:%s:snapshot/*", regionID, accountID),
+			},
+		})
+	}
+	return policyDocument(statements...)
+}
+
+// WakePolicyDocument 返回只允许启动指定实例的权限策略（DescribeInstances 不支持按实例授权，限定在实例所在区域）
+func WakePolicyDocument(regionID, accountID, instanceID string) string {
+	return policyDocument(map[string]interface{}{
+		"Effect":   "Allow",
+		"Action":   []string{"ecs:StartInstance"},
+		"Resource": []string{instanceARN(regionID, accountID, instanceID)},
+	}, map[string]interface{}{
+		"Effect":   "Allow",
+		"Action":   []string{"ecs:DescribeInstances"},
+		"Resource": []string{instanceARN(regionID, accountID, "*")},
+	})
+}
+
+func instanceARN(regionID, accountID, instanceID string) string {
+	return fmt.Sprintf("acs:ecs:%s:%s:instance/%s", regionID, accountID, instanceID)
+}
+
+func policyDocument(statements ...map[string]interface{}) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"Version":   "1",
+		"Statement": statements,
+	})
+	return string(data)
+}
+
+// ensureRoleWithPolicy 创建（或复用）可被 service 扮演的角色，并以 policy 重建同名权限策略。
+// 权限策略按当前实例 ID 重新创建，实例重建（如快照恢复）后仍只能操作新实例。
+func ensureRoleWithPolicy(ramCli RAMAPI, roleName, service, policy, description string) error {
+	if err := ramCli.GetRole(roleName); err != nil {
+		if !isErrorCode(err, "EntityNotExist") {
+			return fmt.Errorf("查询 RAM 角色失败: %w", err)
+		}
+		if err := ramCli.CreateRole(roleName, assumeRolePolicy(service), description); err != nil {
+			return fmt.Errorf("创建 RAM 角色失败: %w", err)
+		}
+	}
+
+	if err := deleteRolePolicy(ramCli, roleName); err != nil {
+		return err
+	}
+	if err := ramCli.CreatePolicy(roleName, policy, description); err != nil {
+		return fmt.Errorf("创建权限策略失败: %w", err)
+	}
+	if err := ramCli.AttachPolicyToRole(roleName, roleName); err != nil && !isErrorCode(err, "EntityAlreadyExists") {
+		return fmt.Errorf("授权 RAM 角色失败: %w", err)
+	}
+	return nil
+}
+
+// deleteRoleWithPolicy 删除角色及同名权限策略（不存在时跳过）
+func deleteRoleWithPolicy(ramCli RAMAPI, roleName string) error {
+	if err := deleteRolePolicy(ramCli, roleName); err != nil {
+		return err
+	}
+	if err := ramCli.DeleteRole(roleName); err != nil && !isErrorCode(err, "EntityNotExist") {
+		return fmt.Errorf("删除 RAM 角色失败: %w", err)
+	}
+	return nil
+}
+
+// EnsureAgentRole 创建（或更新）agent 角色和权限策略，并绑定到实例
+func EnsureAgentRole(ramCli RAMAPI, ecsCli ECSAPI, roleName, regionID, accountID, instanceID string, spot bool) error {
+	policy := AgentPolicyDocument(regionID, accountID, instanceID, spot)
+	if err := ensureRoleWithPolicy(ramCli, roleName, "ecs.aliyuncs.com", policy, "CloudCode 自动停机: 仅允许停机 "+instanceID); err != nil {
+		return err
+	}
+
+	attached, err := DescribeInstanceRAMRole(ecsCli, instanceID, regionID)
+	if err != nil {
+		return fmt.Errorf("查询实例 RAM 角色失败: %w", err)
+	}
+	switch attached {
+	case roleName:
+		return nil
+	case "":
+		_, err := ecsCli.AttachInstanceRamRole(&ecsclient.AttachInstanceRamRoleRequest{
+			InstanceIds: teaString(fmt.Sprintf(`["%s"]`, instanceID)),
+			RamRoleName: &roleName,
+			RegionId:    &regionID,
+		})
+		if err != nil {
+			return fmt.Errorf("绑定实例 RAM 角色失败: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("实例已绑定其他 RAM 角色 %s，请先在控制台解绑", attached)
+	}
+}
+
+// DeleteAgentRole 解绑并删除 agent 角色和权限策略（资源不存在时跳过；instanceID 为空表示实例已删除）
+func DeleteAgentRole(ramCli RAMAPI, ecsCli ECSAPI, roleName, regionID, instanceID string) error {
+	if instanceID != "" {
+		attached, err := DescribeInstanceRAMRole(ecsCli, instanceID, regionID)
+		if err != nil {
+			return fmt.Errorf("查询实例 RAM 角色失败: %w", err)
+		}
+		if attached == roleName {
+			_, err := ecsCli.DetachInstanceRamRole(&ecsclient.DetachInstanceRamRoleRequest{
+				InstanceIds: teaString(fmt.Sprintf(`["%s"]`, instanceID)),
+				RamRoleName: &roleName,
+				RegionId:    &regionID,
+			})
+			if err != nil {
+				return fmt.Errorf("解绑实例 RAM 角色失败: %w", err)
+			}
+		}
+	}
+	return deleteRoleWithPolicy(ramCli, roleName)
+}
+
+// EnsureWakeRole 创建（或更新）唤醒页函数角色和权限策略
+func EnsureWakeRole(ramCli RAMAPI, roleName, regionID, accountID, instanceID string) error {
+	policy := WakePolicyDocument(regionID, accountID, instanceID)
+	return ensureRoleWithPolicy(ramCli, roleName, "fc.aliyuncs.com", policy, "CloudCode 唤醒页: 仅允许启动 "+instanceID)
+}
+
+// DeleteWakeRole 删除唤醒页函数角色和权限策略（不存在时跳过）
+func DeleteWakeRole(ramCli RAMAPI, roleName string) error {
+	return deleteRoleWithPolicy(ramCli, roleName)
+}
+
+// deleteRolePolicy 从角色上解除并删除同名权限策略（不存在时跳过）
+func deleteRolePolicy(ramCli RAMAPI, roleName string) error {
+	if err := ramCli.DetachPolicyFromRole(roleName, roleName); err != nil && !isErrorCode(err, "EntityNotExist") {
+		return fmt.Errorf("解除权限策略失败: %w", err)
+	}
+	if err := ramCli.DeletePolicy(roleName); err != nil && !isErrorCode(err, "EntityNotExist") {
+		return fmt.Errorf("删除权限策略失败: %w", err)
+	}
+	return nil
+}
+
+// DescribeInstanceRAMRole 返回实例绑定的 RAM 角色名（未绑定时为空）
+func DescribeInstanceRAMRole(ecsCli ECSAPI, instanceID, regionID string) (string, error) {
+	resp, err := ecsCli.DescribeInstanceRamRole(&ecsclient.DescribeInstanceRamRoleRequest{
+		InstanceIds: teaString(fmt.Sprintf(`["%s"]`, instanceID)),
+		RegionId:    &regionID,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || resp.Body == nil || resp.Body.InstanceRamRoleSets == nil {
+		return "", nil
+	}
+	for _, set := range resp.Body.InstanceRamRoleSets.InstanceRamRoleSet {
+		if set != nil && deref(set.InstanceId) == instanceID {
+			return deref(set.RamRoleName), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,298 @@
+package alicloud
+
+// 本文件定义 CloudCode 云资源标签，以及按标签查找资源（用于 cloudcode gc 清理泄漏资源）。
+// 所有创建接口都会打上部署 ID、环境名和 CloudCode 版本三个标签，便于成本归属和孤儿资源发现。
+
+import (
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+)
+
+// 标签键
+const (
+	TagKeyDeploymentID = "cloudcode-deployment-id"
+	TagKeyEnv          = "cloudcode-env"
+	TagKeyVersion      = "cloudcode-version"
+)
+
+// 资源类型（TaggedResource.Type）
+const (
+	ResourceTypeVPC           = "vpc"
+	ResourceTypeVSwitch       = "vswitch"
+	ResourceTypeSecurityGroup = "security_group"
+	ResourceTypeSSHKeyPair    = "ssh_key_pair"
+	ResourceTypeECS           = "ecs"
+	ResourceTypeEIP           = "eip"
+	ResourceTypeSnapshot      = "snapshot"
+)
+
+// Tag 资源标签
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// ResourceTags 生成 CloudCode 资源标签（空值的标签会被省略）
+func ResourceTags(deploymentID, env, version string) []Tag {
+	var tags []Tag
+	for _, t := range []Tag{
+		{TagKeyDeploymentID, deploymentID},
+		{TagKeyEnv, env},
+		{TagKeyVersion, version},
+	} {
+		if t.Value != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// TaggedResource 带 CloudCode 标签的云资源
+type TaggedResource struct {
+	Type       string
+	ID         string // 资源 ID（密钥对为名称）
+	Name       string
+	Status     string
+	InstanceID string // 仅 EIP：绑定的实例 ID
+	Tags       map[string]string
+}
+
+// ListTaggedResources 查找区域内所有带 cloudcode-deployment-id 标签的资源（逐页查询直到取完），
+// 结果按删除依赖顺序排列：EIP → ECS → 密钥对 → 安全组 → 交换机 → VPC → 快照。
+func ListTaggedResources(ecsCli ECSAPI, vpcCli VPCAPI, regionID string) ([]TaggedResource, error) {
+	var result []TaggedResource
+
+	// EIP
+	for page := int32(1); ; page++ {
+		resp, err := vpcCli.DescribeEipAddresses(&vpcclient.DescribeEipAddressesRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(100),
+			Tag:        []*vpcclient.DescribeEipAddressesRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.EipAddresses == nil {
+			break
+		}
+		for _, e := range resp.Body.EipAddresses.EipAddress {
+			if e == nil || e.AllocationId == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeEIP, ID: *e.AllocationId, Name: deref(e.IpAddress), Status: deref(e.Status), InstanceID: deref(e.InstanceId), Tags: map[string]string{}}
+			if e.Tags != nil {
+				for _, t := range e.Tags.Tag {
+					r.Tags[deref(t.Key)] = deref(t.Value)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 100, len(resp.Body.EipAddresses.EipAddress), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	// ECS 实例
+	for page := int32(1); ; page++ {
+		resp, err := ecsCli.DescribeInstances(&ecsclient.DescribeInstancesRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(100),
+			Tag:        []*ecsclient.DescribeInstancesRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Instances == nil {
+			break
+		}
+		for _, inst := range resp.Body.Instances.Instance {
+			if inst == nil || inst.InstanceId == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeECS, ID: *inst.InstanceId, Name: deref(inst.InstanceName), Status: deref(inst.Status), Tags: map[string]string{}}
+			if inst.Tags != nil {
+				for _, t := range inst.Tags.Tag {
+					r.Tags[deref(t.TagKey)] = deref(t.TagValue)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 100, len(resp.Body.Instances.Instance), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	// SSH 密钥对
+	for page := int32(1); ; page++ {
+		resp, err := ecsCli.DescribeKeyPairs(&ecsclient.DescribeKeyPairsRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(50),
+			Tag:        []*ecsclient.DescribeKeyPairsRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.KeyPairs == nil {
+			break
+		}
+		for _, kp := range resp.Body.KeyPairs.KeyPair {
+			if kp == nil || kp.KeyPairName == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeSSHKeyPair, ID: *kp.KeyPairName, Name: *kp.KeyPairName, Tags: map[string]string{}}
+			if kp.Tags != nil {
+				for _, t := range kp.Tags.Tag {
+					r.Tags[deref(t.TagKey)] = deref(t.TagValue)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 50, len(resp.Body.KeyPairs.KeyPair), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	// 安全组
+	for page := int32(1); ; page++ {
+		resp, err := ecsCli.DescribeSecurityGroups(&ecsclient.DescribeSecurityGroupsRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(50),
+			Tag:        []*ecsclient.DescribeSecurityGroupsRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.SecurityGroups == nil {
+			break
+		}
+		for _, sg := range resp.Body.SecurityGroups.SecurityGroup {
+			if sg == nil || sg.SecurityGroupId == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeSecurityGroup, ID: *sg.SecurityGroupId, Name: deref(sg.SecurityGroupName), Tags: map[string]string{}}
+			if sg.Tags != nil {
+				for _, t := range sg.Tags.Tag {
+					r.Tags[deref(t.TagKey)] = deref(t.TagValue)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 50, len(resp.Body.SecurityGroups.SecurityGroup), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	// 交换机
+	for page := int32(1); ; page++ {
+		resp, err := vpcCli.DescribeVSwitches(&vpcclient.DescribeVSwitchesRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(50),
+			Tag:        []*vpcclient.DescribeVSwitchesRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.VSwitches == nil {
+			break
+		}
+		for _, vsw := range resp.Body.VSwitches.VSwitch {
+			if vsw == nil || vsw.VSwitchId == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeVSwitch, ID: *vsw.VSwitchId, Name: deref(vsw.VSwitchName), Status: deref(vsw.Status), Tags: map[string]string{}}
+			if vsw.Tags != nil {
+				for _, t := range vsw.Tags.Tag {
+					r.Tags[deref(t.Key)] = deref(t.Value)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 50, len(resp.Body.VSwitches.VSwitch), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	// VPC
+	for page := int32(1); ; page++ {
+		resp, err := vpcCli.DescribeVpcs(&vpcclient.DescribeVpcsRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(50),
+			Tag:        []*vpcclient.DescribeVpcsRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Vpcs == nil {
+			break
+		}
+		for _, vpc := range resp.Body.Vpcs.Vpc {
+			if vpc == nil || vpc.VpcId == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeVPC, ID: *vpc.VpcId, Name: deref(vpc.VpcName), Status: deref(vpc.Status), Tags: map[string]string{}}
+			if vpc.Tags != nil {
+				for _, t := range vpc.Tags.Tag {
+					r.Tags[deref(t.Key)] = deref(t.Value)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 50, len(resp.Body.Vpcs.Vpc), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	// 快照
+	for page := int32(1); ; page++ {
+		resp, err := ecsCli.DescribeSnapshots(&ecsclient.DescribeSnapshotsRequest{
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(100),
+			Tag:        []*ecsclient.DescribeSnapshotsRequestTag{{Key: teaString(TagKeyDeploymentID)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Snapshots == nil {
+			break
+		}
+		for _, snap := range resp.Body.Snapshots.Snapshot {
+			if snap == nil || snap.SnapshotId == nil {
+				continue
+			}
+			r := TaggedResource{Type: ResourceTypeSnapshot, ID: *snap.SnapshotId, Name: deref(snap.SnapshotName), Status: deref(snap.Status), Tags: map[string]string{}}
+			if snap.Tags != nil {
+				for _, t := range snap.Tags.Tag {
+					r.Tags[deref(t.TagKey)] = deref(t.TagValue)
+				}
+			}
+			result = append(result, r)
+		}
+		if !hasNextPage(page, 100, len(resp.Body.Snapshots.Snapshot), resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// hasNextPage 判断分页查询是否还有下一页：本页已满且未达到 TotalCount
+func hasNextPage(page, pageSize int32, count int, total *int32) bool {
+	if count < int(pageSize) || total == nil {
+		return false
+	}
+	return page*pageSize < *total
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -26,15 +26,17 @@ type VSwitchResource struct {
 	ID     string
 	ZoneID string
 	CIDR   string
+	VpcID  string // 所属 VPC（仅查询时返回）
 }
 
 // SecurityGroupResource 安全组资源信息（控制 ECS 实例的入站/出站规则）
 type SecurityGroupResource struct {
-	ID string
+	ID    string
+	VpcID string // 所属 VPC（仅查询时返回）
 }
 
 // CreateVPC 创建 VPC（默认网段 192.168.0.0/16）
-func CreateVPC(vpcCli VPCAPI, regionID, vpcName string) (*VPCResource, error) {
+func CreateVPC(vpcCli VPCAPI, regionID, vpcName string, tags ...Tag) (*VPCResource, error) {
 	cidr := DefaultVPCCIDR
 	req := &vpcclient.CreateVpcRequest{
 		RegionId:  &regionID,
@@ -43,6 +45,9 @@ func CreateVPC(vpcCli VPCAPI, regionID, vpcName string) (*VPCResource, error) {
 	if vpcName != "" {
 		req.VpcName = &vpcName
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &vpcclient.CreateVpcRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
 
 	resp, err := vpcCli.CreateVpc(req)
 	if err != nil {
@@ -115,8 +120,41 @@ func DescribeVPC(vpcCli VPCAPI, vpcID, regionID string) (*VPCResource, error) {
 	}, nil
 }
 
+// ListVPCsByName 按名称查询 VPC（同名 VPC 可能有多个）
+func ListVPCsByName(vpcCli VPCAPI, regionID, vpcName string) ([]VPCResource, error) {
+	var vpcs []VPCResource
+	for page := int32(1); ; page++ {
+		resp, err := vpcCli.DescribeVpcs(&vpcclient.DescribeVpcsRequest{
+			VpcName:    &vpcName,
+			RegionId:   &regionID,
+			PageNumber: teaInt32(page),
+			PageSize:   teaInt32(50),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Vpcs == nil {
+			break
+		}
+		for _, vpc := range resp.Body.Vpcs.Vpc {
+			if vpc == nil || vpc.VpcId == nil {
+				continue
+			}
+			r := VPCResource{ID: *vpc.VpcId}
+			if vpc.CidrBlock != nil {
+				r.CIDR = *vpc.CidrBlock
+			}
+			vpcs = append(vpcs, r)
+		}
+		if !hasNextPage(page, 50, len(resp.Body.Vpcs.Vpc), resp.Body.TotalCount) {
+			break
+		}
+	}
+	return vpcs, nil
+}
+
 // CreateVSwitch 在指定 VPC 和可用区内创建交换机（子网）
-func CreateVSwitch(vpcCli VPCAPI, vpcID, zoneID, cidr, vswitchName string) (*VSwitchResource, error) {
+func CreateVSwitch(vpcCli VPCAPI, vpcID, zoneID, cidr, vswitchName string, tags ...Tag) (*VSwitchResource, error) {
 	req := &vpcclient.CreateVSwitchRequest{
 		VpcId:     &vpcID,
 		ZoneId:    &zoneID,
@@ -125,6 +163,9 @@ func CreateVSwitch(vpcCli VPCAPI, vpcID, zoneID, cidr, vswitchName string) (*VSw
 	if vswitchName != "" {
 		req.VSwitchName = &vswitchName
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &vpcclient.CreateVSwitchRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
 
 	resp, err := vpcCli.CreateVSwitch(req)
 	if err != nil {
@@ -151,8 +192,38 @@ func DeleteVSwitch(vpcCli VPCAPI, vswitchID string) error {
 	return err
 }
 
+// DescribeVSwitch 查询交换机详情（所属 VPC、可用区、网段）
+func DescribeVSwitch(vpcCli VPCAPI, vswitchID, regionID string) (*VSwitchResource, error) {
+	req := &vpcclient.DescribeVSwitchesRequest{
+		VSwitchId: &vswitchID,
+		RegionId:  &regionID,
+	}
+	resp, err := vpcCli.DescribeVSwitches(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.Body == nil || resp.Body.VSwitches == nil ||
+		resp.Body.VSwitches.VSwitch == nil || len(resp.Body.VSwitches.VSwitch) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	vsw := resp.Body.VSwitches.VSwitch[0]
+	result := &VSwitchResource{ID: vswitchID}
+	if vsw.ZoneId != nil {
+		result.ZoneID = *vsw.ZoneId
+	}
+	if vsw.CidrBlock != nil {
+		result.CIDR = *vsw.CidrBlock
+	}
+	if vsw.VpcId != nil {
+		result.VpcID = *vsw.VpcId
+	}
+	return result, nil
+}
+
 // CreateSecurityGroup 在指定 VPC 内创建安全组（注意：安全组 API 属于 ECS SDK）
-func CreateSecurityGroup(ecsCli ECSAPI, vpcID, regionID, sgName string) (*SecurityGroupResource, error) {
+func CreateSecurityGroup(ecsCli ECSAPI, vpcID, regionID, sgName string, tags ...Tag) (*SecurityGroupResource, error) {
 	req := &ecsclient.CreateSecurityGroupRequest{
 		VpcId:    &vpcID,
 		RegionId: &regionID,
@@ -160,6 +231,9 @@ func CreateSecurityGroup(ecsCli ECSAPI, vpcID, regionID, sgName string) (*Securi
 	if sgName != "" {
 		req.SecurityGroupName = &sgName
 	}
+	for _, t := range tags {
+		req.Tag = append(req.Tag, &ecsclient.CreateSecurityGroupRequestTag{Key: teaString(t.Key), Value: teaString(t.Value)})
+	}
 
 	resp, err := ecsCli.CreateSecurityGroup(req)
 	if err != nil {
@@ -185,6 +259,30 @@ func DeleteSecurityGroup(ecsCli ECSAPI, sgID, regionID string) error {
 	return err
 }
 
+// DescribeSecurityGroup 查询安全组详情（所属 VPC）
+func DescribeSecurityGroup(ecsCli ECSAPI, sgID, regionID string) (*SecurityGroupResource, error) {
+	req := &ecsclient.DescribeSecurityGroupsRequest{
+		SecurityGroupId: &sgID,
+		RegionId:        &regionID,
+	}
+	resp, err := ecsCli.DescribeSecurityGroups(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.Body == nil || resp.Body.SecurityGroups == nil ||
+		resp.Body.SecurityGroups.SecurityGroup == nil || len(resp.Body.SecurityGroups.SecurityGroup) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	sg := resp.Body.SecurityGroups.SecurityGroup[0]
+	result := &SecurityGroupResource{ID: sgID}
+	if sg.VpcId != nil {
+		result.VpcID = *sg.VpcId
+	}
+	return result, nil
+}
+
 // SecurityGroupRule 安全组入站规则
 type SecurityGroupRule struct {
 	Protocol    string // 协议：TCP/UDP/ICMP
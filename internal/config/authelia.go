@@ -0,0 +1,68 @@
+package config
+
+// authelia.go 管理 Authelia 的 session 密钥和 storage 加密密钥。
+// 两者只在首次部署时生成，之后从实例上的 configuration.yml 读回复用：
+// session 密钥变化会使所有登录会话失效，storage 加密密钥变化会使 db.sqlite3（Passkey、TOTP）无法解密。
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AutheliaSecrets Authelia 密钥
+type AutheliaSecrets struct {
+	SessionSecret        string
+	StorageEncryptionKey string
+}
+
+// NewAutheliaSecrets 生成一组新密钥（仅首次部署使用）
+func NewAutheliaSecrets() (*AutheliaSecrets, error) {
+	sessionSecret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	storageKey, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &AutheliaSecrets{SessionSecret: sessionSecret, StorageEncryptionKey: storageKey}, nil
+}
+
+// AutheliaWatchesUsers 判断 Authelia 配置是否开启了 users_database.yml 自动重新加载
+// （旧版部署未开启，修改用户后需要重启 Authelia）
+func AutheliaWatchesUsers(data []byte) bool {
+	var cfg struct {
+		AuthenticationBackend struct {
+			File struct {
+				Watch bool `yaml:"watch"`
+			} `yaml:"file"`
+		} `yaml:"authentication_backend"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return false
+	}
+	return cfg.AuthenticationBackend.File.Watch
+}
+
+// ParseAutheliaSecrets 从 Authelia configuration.yml 内容中读取密钥
+func ParseAutheliaSecrets(data []byte) (*AutheliaSecrets, error) {
+	var cfg struct {
+		Session struct {
+			Secret string `yaml:"secret"`
+		} `yaml:"session"`
+		Storage struct {
+			EncryptionKey string `yaml:"encryption_key"`
+		} `yaml:"storage"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析 Authelia 配置失败: %w", err)
+	}
+	if cfg.Session.Secret == "" || cfg.Storage.EncryptionKey == "" {
+		return nil, fmt.Errorf("Authelia 配置缺少 session.secret 或 storage.encryption_key")
+	}
+	return &AutheliaSecrets{
+		SessionSecret:        cfg.Session.Secret,
+		StorageEncryptionKey: cfg.Storage.EncryptionKey,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package config
+
+// autosuspend.go 定义自动停机策略：闲置超过 idle 后停机，或在 active_hours 时段之外停机。
+// 策略由实例上的 cloudcode agent 执行（见 internal/agent），本地 state 只记录当前配置。
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAutoSuspendTimezone = "Asia/Shanghai"
+	MinAutoSuspendIdle         = 10 * time.Minute // 闲置时长下限，避免刚恢复就被停机
+	OutsideHoursGrace          = 10 * time.Minute // 活跃时段之外闲置超过该时长即停机
+)
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// AutoSuspendPolicy 自动停机策略（idle 和 active_hours 至少设置一项）
+type AutoSuspendPolicy struct {
+	Idle        string `yaml:"idle,omitempty" json:"idle,omitempty"`                 // 闲置时长，如 2h、90m
+	ActiveHours string `yaml:"active_hours,omitempty" json:"active_hours,omitempty"` // 活跃时段，如 "Mon-Fri 09:00-19:00"
+	Timezone    string `yaml:"timezone,omitempty" json:"timezone,omitempty"`         // active_hours 的时区，默认 Asia/Shanghai
+}
+
+// Enabled 判断是否配置了任一停机条件
+func (p *AutoSuspendPolicy) Enabled() bool {
+	return p != nil && (p.Idle != "" || p.ActiveHours != "")
+}
+
+// Validate 校验策略，错误信息带字段名
+func (p *AutoSuspendPolicy) Validate() error {
+	var errs []error
+	if p.Idle != "" {
+		d, err := time.ParseDuration(p.Idle)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("idle: %q 不是有效的时长（如 2h、90m）", p.Idle))
+		} else if d < MinAutoSuspendIdle {
+			errs = append(errs, fmt.Errorf("idle: 不能短于 %s", MinAutoSuspendIdle))
+		}
+	}
+	if p.ActiveHours != "" {
+		if _, err := ParseActiveHours(p.ActiveHours); err != nil {
+			errs = append(errs, fmt.Errorf("active_hours: %v", err))
+		}
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("timezone: %q 不是有效的时区（如 Asia/Shanghai）", p.Timezone))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IdleDuration 返回闲置时长（未设置时为 0）
+func (p *AutoSuspendPolicy) IdleDuration() time.Duration {
+	d, _ := time.ParseDuration(p.Idle)
+	return d
+}
+
+// Location 返回 active_hours 使用的时区
+func (p *AutoSuspendPolicy) Location() (*time.Location, error) {
+	tz := p.Timezone
+	if tz == "" {
+		tz = DefaultAutoSuspendTimezone
+	}
+	return time.LoadLocation(tz)
+}
+
+// String 返回策略的可读描述
+func (p *AutoSuspendPolicy) String() string {
+	if !p.Enabled() {
+		return "未开启"
+	}
+	var parts []string
+	if p.Idle != "" {
+		parts = append(parts, "闲置 "+p.Idle+" 后停机")
+	}
+	if p.ActiveHours != "" {
+		tz := p.Timezone
+		if tz == "" {
+			tz = DefaultAutoSuspendTimezone
+		}
+		parts = append(parts, fmt.Sprintf("活跃时段 %s (%s) 之外停机", p.ActiveHours, tz))
+	}
+	return strings.Join(parts, "，")
+}
+
+// ActiveHours 解析后的活跃时段；End 小于等于 Start 时跨越午夜（如 22:00-02:00），星期按开始时间所在的那天计算
+type ActiveHours struct {
+	Days  [7]bool // 按 time.Weekday 索引
+	Start int     // 开始时间（距 0 点的分钟数）
+	End   int     // 结束时间（距 0 点的分钟数）
+}
+
+// ParseActiveHours 解析活跃时段，格式为 "[星期] HH:MM-HH:MM"，星期可写作 Mon-Fri、Sat,Sun，省略表示每天
+func ParseActiveHours(s string) (*ActiveHours, error) {
+	fields := strings.Fields(s)
+	var h ActiveHours
+	var timeRange string
+	switch len(fields) {
+	case 1:
+		for i := range h.Days {
+			h.Days[i] = true
+		}
+		timeRange = fields[0]
+	case 2:
+		if err := parseWeekdays(fields[0], &h.Days); err != nil {
+			return nil, err
+		}
+		timeRange = fields[1]
+	default:
+		return nil, fmt.Errorf("%q 格式应为 \"[星期] HH:MM-HH:MM\"（如 Mon-Fri 09:00-19:00）", s)
+	}
+
+	start, end, ok := strings.Cut(timeRange, "-")
+	if !ok {
+		return nil, fmt.Errorf("%q 缺少时间范围（如 09:00-19:00）", s)
+	}
+	var err error
+	if h.Start, err = parseClock(start); err != nil {
+		return nil, err
+	}
+	if h.End, err = parseClock(end); err != nil {
+		return nil, err
+	}
+	if h.Start == h.End {
+		return nil, fmt.Errorf("%q 开始时间与结束时间相同", s)
+	}
+	return &h, nil
+}
+
+// Contains 判断 t（已转换到策略时区）是否处于活跃时段
+func (h *ActiveHours) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := int(t.Weekday())
+	if h.Start < h.End {
+		return h.Days[day] && minute >= h.Start && minute < h.End
+	}
+	// 跨越午夜：开始当天的 Start 之后，或次日的 End 之前
+	if minute >= h.Start {
+		return h.Days[day]
+	}
+	return minute < h.End && h.Days[(day+6)%7]
+}
+
+func parseWeekdays(s string, days *[7]bool) error {
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start := weekdayIndex(from)
+		end := start
+		if isRange {
+			end = weekdayIndex(to)
+		}
+		if start < 0 || end < 0 {
+			return fmt.Errorf("%q 不是有效的星期（如 Mon-Fri、Sat,Sun）", s)
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func weekdayIndex(s string) int {
+	for i, name := range weekdayNames {
+		if s == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	h, errH := strconv.Atoi(hh)
+	m, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || len(mm) != 2 || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q 不是有效的时间（HH:MM）", s)
+	}
+	return h*60 + m, nil
+}
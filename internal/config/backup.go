@@ -5,24 +5,36 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 )
 
-const BackupFileName = "backup.json"
+const (
+	BackupFileName        = "backup.json"  // 恢复点：destroy 后 deploy 从该快照恢复
+	BackupCatalogFileName = "backups.json" // cloudcode backup 创建的所有快照
+)
 
 // Backup 快照备份元数据
 type Backup struct {
-	CloudCodeVersion string `json:"cloudcode_version"`
-	SnapshotID       string `json:"snapshot_id"`
-	CreatedAt        string `json:"created_at"`
-	Region           string `json:"region"`
-	DiskSize         int    `json:"disk_size"`
-	Domain           string `json:"domain"`
-	Username         string `json:"username"`
+	CloudCodeVersion string             `json:"cloudcode_version"`
+	SnapshotID       string             `json:"snapshot_id"`
+	CreatedAt        string             `json:"created_at"`
+	Label            string             `json:"label,omitempty"`
+	Quiesced         bool               `json:"quiesced,omitempty"` // 快照前已停止容器
+	Region           string             `json:"region"`
+	DiskSize         int                `json:"disk_size"`
+	DiskCategory     string             `json:"disk_category,omitempty"`
+	InstanceType     string             `json:"instance_type,omitempty"`
+	Domain           string             `json:"domain"`
+	Username         string             `json:"username"`
+	DevboxMode       string             `json:"devbox_mode,omitempty"`
+	AutoSuspend      *AutoSuspendPolicy `json:"auto_suspend,omitempty"`
+	Authelia         *AutheliaSettings  `json:"authelia,omitempty"`
 }
 
-// LoadBackup 从默认路径加载备份文件
+// LoadBackup 从当前环境加载备份文件
 func LoadBackup() (*Backup, error) {
-	stateDir, err := GetStateDir()
+	stateDir, err := GetActiveEnvDir()
 	if err != nil {
 		return nil, err
 	}
@@ -46,9 +58,9 @@ func LoadBackupFrom(dir string) (*Backup, error) {
 	return &backup, nil
 }
 
-// SaveBackup 保存备份文件到默认路径
+// SaveBackup 保存备份文件到当前环境
 func SaveBackup(backup *Backup) error {
-	stateDir, err := GetStateDir()
+	stateDir, err := GetActiveEnvDir()
 	if err != nil {
 		return err
 	}
@@ -68,9 +80,9 @@ func SaveBackupTo(dir string, backup *Backup) error {
 	return os.WriteFile(path, data, 0600)
 }
 
-// DeleteBackup 删除备份文件
+// DeleteBackup 删除当前环境的备份文件
 func DeleteBackup() error {
-	stateDir, err := GetStateDir()
+	stateDir, err := GetActiveEnvDir()
 	if err != nil {
 		return err
 	}
@@ -85,3 +97,150 @@ func DeleteBackupFrom(dir string) error {
 	}
 	return nil
 }
+
+// BackupCatalog 快照备份目录（backups.json），按创建时间升序
+type BackupCatalog struct {
+	Backups []Backup `json:"backups"`
+}
+
+// LoadBackupCatalogFrom 从指定目录加载备份目录，文件不存在时返回空目录
+func LoadBackupCatalogFrom(dir string) (*BackupCatalog, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BackupCatalogFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &BackupCatalog{}, nil
+		}
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+	var catalog BackupCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("解析备份目录失败: %w", err)
+	}
+	return &catalog, nil
+}
+
+// SaveBackupCatalogTo 保存备份目录到指定目录
+func SaveBackupCatalogTo(dir string, catalog *BackupCatalog) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, BackupCatalogFileName), data, 0600)
+}
+
+// Add 追加备份（同一快照只记录一次）
+func (c *BackupCatalog) Add(b Backup) {
+	for i := range c.Backups {
+		if c.Backups[i].SnapshotID == b.SnapshotID {
+			c.Backups[i] = b
+			return
+		}
+	}
+	c.Backups = append(c.Backups, b)
+	sort.SliceStable(c.Backups, func(i, j int) bool { return c.Backups[i].CreatedAt < c.Backups[j].CreatedAt })
+}
+
+// Remove 删除指定快照的记录
+func (c *BackupCatalog) Remove(snapshotID string) {
+	kept := c.Backups[:0]
+	for _, b := range c.Backups {
+		if b.SnapshotID != snapshotID {
+			kept = append(kept, b)
+		}
+	}
+	c.Backups = kept
+}
+
+// Contains 判断快照是否在目录中
+func (c *BackupCatalog) Contains(snapshotID string) bool {
+	for _, b := range c.Backups {
+		if b.SnapshotID == snapshotID {
+			return true
+		}
+	}
+	return false
+}
+
+// Find 按快照 ID 或标签查找备份（标签重复时取最新的一份）
+func (c *BackupCatalog) Find(ref string) (*Backup, error) {
+	var found *Backup
+	for i := range c.Backups {
+		b := &c.Backups[i]
+		if b.SnapshotID == ref {
+			return b, nil
+		}
+		if b.Label == ref {
+			found = b
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("未找到备份 %q（cloudcode backup list 查看所有备份）", ref)
+	}
+	return found, nil
+}
+
+// RetentionPolicy 备份保留策略：保留最近 KeepLast 份，以及最近 KeepDaily 天、KeepWeekly 周中每天 / 每周最新的一份。
+// 天和周按 loc 时区划分，只统计有备份的天 / 周。
+type RetentionPolicy struct {
+	KeepLast   int
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// IsZero 判断策略是否未设置任何保留条件
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0
+}
+
+// Apply 返回按策略应删除的备份（按创建时间升序）。策略为空时不删除任何备份；最新的一份总是保留。
+func (p RetentionPolicy) Apply(backups []Backup, loc *time.Location) []Backup {
+	if p.IsZero() || len(backups) == 0 {
+		return nil
+	}
+	sorted := append([]Backup(nil), backups...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt > sorted[j].CreatedAt })
+
+	keep := make(map[string]bool)
+	keep[sorted[0].SnapshotID] = true
+	for i := 0; i < p.KeepLast && i < len(sorted); i++ {
+		keep[sorted[i].SnapshotID] = true
+	}
+	keepBuckets := func(n int, bucket func(time.Time) string) {
+		seen := make(map[string]bool)
+		for _, b := range sorted {
+			if len(seen) >= n {
+				return
+			}
+			t, err := time.Parse(time.RFC3339, b.CreatedAt)
+			if err != nil {
+				keep[b.SnapshotID] = true // 时间无法解析时保守保留
+				continue
+			}
+			key := bucket(t.In(loc))
+			if !seen[key] {
+				seen[key] = true
+				keep[b.SnapshotID] = true
+			}
+		}
+	}
+	if p.KeepDaily > 0 {
+		keepBuckets(p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+	}
+	if p.KeepWeekly > 0 {
+		keepBuckets(p.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		})
+	}
+
+	var prune []Backup
+	for _, b := range backups {
+		if !keep[b.SnapshotID] {
+			prune = append(prune, b)
+		}
+	}
+	return prune
+}
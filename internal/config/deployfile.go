@@ -0,0 +1,419 @@
+package config
+
+// deployfile.go 解析部署配置文件（cloudcode deploy --config cloudcode.yaml）。
+// 文件提供交互部署时询问的全部信息（域名、管理员账号、API Key、Authelia 设置）以及 cloud 段的
+// 实例规格，命令行参数优先于文件。字符串值支持环境变量插值，密钥不必写入文件：
+//   ${VAR}          引用环境变量，未设置时报错
+//   ${VAR:-default} 未设置或为空时使用 default
+//   $$              字面量 $
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 系统盘大小范围（GB）：Ubuntu 公共镜像至少 20GB，ECS 系统盘上限 2048GB
+const (
+	MinSystemDiskSize = 20
+	MaxSystemDiskSize = 2048
+)
+
+// ValidDiskCategories 支持的系统盘类型
+var ValidDiskCategories = []string{"cloud_essd", "cloud_essd_entry", "cloud_auto", "cloud_ssd", "cloud_efficiency"}
+
+var ErrInvalidDeployFile = errors.New("invalid deploy file")
+
+var (
+	instanceTypePattern = regexp.MustCompile(`^ecs\.[a-z0-9-]+\.[a-z0-9]+$`)
+	zonePattern         = regexp.MustCompile(`^[a-z]+-[a-z0-9-]+-[a-z0-9]+$`)
+	domainPattern       = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+	usernamePattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,31}$`)
+	durationPattern     = regexp.MustCompile(`^[0-9]+[smhdwMy]$`) // Authelia 时长格式，如 30m、12h、7d
+	envVarPattern       = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+)
+
+// MinPasswordLength 配置文件中管理员密码的最小长度
+const MinPasswordLength = 8
+
+// Authelia 访问策略
+var ValidAccessPolicies = []string{"two_factor", "one_factor"}
+
+// devbox 模式：所有账号共用一个 devbox，或每个账号一个独立 devbox
+const (
+	DevboxModeShared  = "shared"
+	DevboxModePerUser = "per-user"
+)
+
+// ValidDevboxModes 支持的 devbox 模式
+var ValidDevboxModes = []string{DevboxModeShared, DevboxModePerUser}
+
+// ValidateDevboxMode 校验 devbox 模式（空表示沿用当前模式）
+func ValidateDevboxMode(mode string) error {
+	if mode != "" && !containsString(ValidDevboxModes, mode) {
+		return fmt.Errorf("不支持的 devbox 模式 %q，可选 %v", mode, ValidDevboxModes)
+	}
+	return nil
+}
+
+// ValidateDomain 校验域名格式（小写）
+func ValidateDomain(domain string) error {
+	if !domainPattern.MatchString(domain) {
+		return fmt.Errorf("%q 不是有效的域名", domain)
+	}
+	return nil
+}
+
+// CloudSpec 云资源规格（零值字段使用默认值）
+type CloudSpec struct {
+	InstanceType string   `yaml:"instance_type,omitempty"` // 如 ecs.e-c1m2.large
+	Image        string   `yaml:"image,omitempty"`         // 镜像 ID，留空自动查找区域内最新的 Ubuntu 24.04
+	DiskSize     int      `yaml:"disk_size,omitempty"`     // 系统盘大小（GB）
+	DiskCategory string   `yaml:"disk_category,omitempty"` // 系统盘类型
+	Zones        []string `yaml:"zones,omitempty"`         // 可用区优先级，留空自动选择
+
+	Spot           bool    `yaml:"spot,omitempty"`             // 抢占式实例（可能被回收，回收后从快照重建）
+	SpotPriceLimit float64 `yaml:"spot_price_limit,omitempty"` // 抢占式实例每小时最高出价，留空跟随市场价
+}
+
+// Merge 用 override 中的非零字段覆盖当前规格（命令行参数覆盖配置文件）
+func (c CloudSpec) Merge(override CloudSpec) CloudSpec {
+	if override.InstanceType != "" {
+		c.InstanceType = override.InstanceType
+	}
+	if override.Image != "" {
+		c.Image = override.Image
+	}
+	if override.DiskSize != 0 {
+		c.DiskSize = override.DiskSize
+	}
+	if override.DiskCategory != "" {
+		c.DiskCategory = override.DiskCategory
+	}
+	if len(override.Zones) > 0 {
+		c.Zones = override.Zones
+	}
+	if override.Spot {
+		c.Spot = true
+	}
+	if override.SpotPriceLimit != 0 {
+		c.SpotPriceLimit = override.SpotPriceLimit
+	}
+	return c
+}
+
+// Validate 校验规格字段，错误信息带字段路径（如 cloud.disk_size）
+func (c CloudSpec) Validate() error {
+	var errs []error
+	if c.InstanceType != "" && !instanceTypePattern.MatchString(c.InstanceType) {
+		errs = append(errs, fmt.Errorf("cloud.instance_type: %q 不是有效的实例规格（如 ecs.e-c1m2.large）", c.InstanceType))
+	}
+	if c.DiskSize != 0 && (c.DiskSize < MinSystemDiskSize || c.DiskSize > MaxSystemDiskSize) {
+		errs = append(errs, fmt.Errorf("cloud.disk_size: %d 超出范围 %d-%d", c.DiskSize, MinSystemDiskSize, MaxSystemDiskSize))
+	}
+	if c.DiskCategory != "" && !containsString(ValidDiskCategories, c.DiskCategory) {
+		errs = append(errs, fmt.Errorf("cloud.disk_category: 不支持 %q，可选 %v", c.DiskCategory, ValidDiskCategories))
+	}
+	for i, z := range c.Zones {
+		if !zonePattern.MatchString(z) {
+			errs = append(errs, fmt.Errorf("cloud.zones[%d]: %q 不是有效的可用区 ID（如 ap-southeast-1a）", i, z))
+		}
+	}
+	if c.SpotPriceLimit < 0 {
+		errs = append(errs, fmt.Errorf("cloud.spot_price_limit: 不能为负数"))
+	} else if c.SpotPriceLimit > 0 && !c.Spot {
+		errs = append(errs, fmt.Errorf("cloud.spot_price_limit: 需同时设置 cloud.spot: true"))
+	}
+	return errors.Join(errs...)
+}
+
+// AdminSpec Authelia 管理员账号
+type AdminSpec struct {
+	Username string `yaml:"username,omitempty"` // 默认 admin
+	Password string `yaml:"password,omitempty"` // 建议使用 ${CLOUDCODE_ADMIN_PASSWORD}
+	Email    string `yaml:"email,omitempty"`    // 默认 <username>@localhost
+}
+
+// APIKeysSpec 写入 devbox .env 的 API Key
+type APIKeysSpec struct {
+	OpenAI        string            `yaml:"openai,omitempty"`
+	OpenAIBaseURL string            `yaml:"openai_base_url,omitempty"`
+	Anthropic     string            `yaml:"anthropic,omitempty"`
+	Extra         map[string]string `yaml:"extra,omitempty"` // 其他 provider 的环境变量，如 GEMINI_API_KEY
+}
+
+// Secrets 转换为 .env 变量（空值跳过）
+func (s APIKeysSpec) Secrets() map[string]string {
+	secrets := make(map[string]string)
+	for name, value := range s.Extra {
+		if value != "" {
+			secrets[name] = value
+		}
+	}
+	for name, value := range map[string]string{
+		"OPENAI_API_KEY":    s.OpenAI,
+		"OPENAI_BASE_URL":   s.OpenAIBaseURL,
+		"ANTHROPIC_API_KEY": s.Anthropic,
+	} {
+		if value != "" {
+			secrets[name] = value
+		}
+	}
+	return secrets
+}
+
+// AutheliaSpec Authelia 会话与访问策略
+type AutheliaSpec struct {
+	SessionExpiration string `yaml:"session_expiration,omitempty"` // 默认 12h
+	SessionInactivity string `yaml:"session_inactivity,omitempty"` // 默认 30m
+	Policy            string `yaml:"policy,omitempty"`             // two_factor（默认）/ one_factor
+}
+
+// DeployFile 部署配置文件
+type DeployFile struct {
+	Domain   string       `yaml:"domain,omitempty"` // 留空使用 EIP.nip.io
+	SSHIP    string       `yaml:"ssh_ip,omitempty"` // SSH 源地址限制（IP 或 CIDR），留空不限制
+	Admin    AdminSpec    `yaml:"admin,omitempty"`
+	APIKeys  APIKeysSpec  `yaml:"api_keys,omitempty"`
+	Authelia AutheliaSpec `yaml:"authelia,omitempty"`
+	Cloud    CloudSpec    `yaml:"cloud,omitempty"`
+
+	DevboxMode  string             `yaml:"devbox_mode,omitempty"`  // shared（默认）/ per-user
+	AutoSuspend *AutoSuspendPolicy `yaml:"auto_suspend,omitempty"` // 自动停机策略
+}
+
+// Validate 校验全部字段，一次返回所有错误（每条带字段路径）
+func (f *DeployFile) Validate() error {
+	var errs []error
+	if f.Domain != "" && !domainPattern.MatchString(f.Domain) {
+		errs = append(errs, fmt.Errorf("domain: %q 不是有效的域名", f.Domain))
+	}
+	if f.SSHIP != "" {
+		if _, err := NormalizeCIDR(f.SSHIP); err != nil {
+			errs = append(errs, fmt.Errorf("ssh_ip: %v", err))
+		}
+	}
+	if f.Admin.Username != "" && !usernamePattern.MatchString(f.Admin.Username) {
+		errs = append(errs, fmt.Errorf("admin.username: %q 只能包含小写字母、数字和 _.-，最长 32 位", f.Admin.Username))
+	}
+	if f.Admin.Password != "" && len(f.Admin.Password) < MinPasswordLength {
+		errs = append(errs, fmt.Errorf("admin.password: 长度不能少于 %d 位", MinPasswordLength))
+	}
+	if f.Admin.Email != "" && !strings.Contains(f.Admin.Email, "@") {
+		errs = append(errs, fmt.Errorf("admin.email: %q 不是有效的邮箱", f.Admin.Email))
+	}
+	if f.APIKeys.OpenAIBaseURL != "" {
+		if u, err := url.Parse(f.APIKeys.OpenAIBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("api_keys.openai_base_url: %q 不是有效的 http(s) 地址", f.APIKeys.OpenAIBaseURL))
+		}
+	}
+	for _, name := range SortedSecretNames(f.APIKeys.Extra) {
+		if err := ValidateSecretName(name); err != nil {
+			errs = append(errs, fmt.Errorf("api_keys.extra.%s: %v", name, err))
+		} else if strings.ContainsAny(f.APIKeys.Extra[name], "\r\n") {
+			errs = append(errs, fmt.Errorf("api_keys.extra.%s: 值不能包含换行", name))
+		}
+	}
+	if v := f.Authelia.SessionExpiration; v != "" && !durationPattern.MatchString(v) {
+		errs = append(errs, fmt.Errorf("authelia.session_expiration: %q 不是有效的时长（如 12h、7d）", v))
+	}
+	if v := f.Authelia.SessionInactivity; v != "" && !durationPattern.MatchString(v) {
+		errs = append(errs, fmt.Errorf("authelia.session_inactivity: %q 不是有效的时长（如 30m、2h）", v))
+	}
+	if v := f.Authelia.Policy; v != "" && !containsString(ValidAccessPolicies, v) {
+		errs = append(errs, fmt.Errorf("authelia.policy: 不支持 %q，可选 %v", v, ValidAccessPolicies))
+	}
+	if err := ValidateDevboxMode(f.DevboxMode); err != nil {
+		errs = append(errs, fmt.Errorf("devbox_mode: %v", err))
+	}
+	if f.AutoSuspend != nil {
+		if err := f.AutoSuspend.Validate(); err != nil {
+			errs = append(errs, prefixErrors("auto_suspend.", err))
+		}
+	}
+	if err := f.Cloud.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// prefixErrors 为 errors.Join 合并的每条错误加上字段路径前缀
+func prefixErrors(prefix string, err error) error {
+	var errs []error
+	for _, line := range strings.Split(err.Error(), "\n") {
+		errs = append(errs, errors.New(prefix+line))
+	}
+	return errors.Join(errs...)
+}
+
+// NormalizeCIDR 将 IP 或 CIDR 规范化为 CIDR（单个 IPv4 地址补 /32）
+func NormalizeCIDR(s string) (string, error) {
+	if _, ipNet, err := net.ParseCIDR(s); err == nil {
+		return ipNet.String(), nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return "", fmt.Errorf("%q 不是有效的 IP 或 CIDR", s)
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
+
+// LoadDeployFile 读取、插值并校验部署配置文件（未知字段视为错误，避免拼写错误被静默忽略）
+func LoadDeployFile(path string) (*DeployFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	file, err := ParseDeployFile(data, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s:\n%v", ErrInvalidDeployFile, path, err)
+	}
+	return file, nil
+}
+
+// ParseDeployFile 解析配置文件内容，lookupEnv 用于环境变量插值（测试可注入）
+func ParseDeployFile(data []byte, lookupEnv func(string) (string, bool)) (*DeployFile, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	var file DeployFile
+	if root.Kind == 0 {
+		return &file, nil // 空文件
+	}
+
+	// 在 YAML 节点上原地插值和检查字段，错误信息带字段路径和原始行号
+	if err := checkKnownFields(&root, reflect.TypeOf(file), ""); err != nil {
+		return nil, err
+	}
+	if err := interpolateNode(&root, "", lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := root.Decode(&file); err != nil {
+		return nil, err
+	}
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// checkKnownFields 检查映射中的键都对应结构体的 yaml 字段
+func checkKnownFields(n *yaml.Node, t reflect.Type, path string) error {
+	if n.Kind == yaml.DocumentNode {
+		for _, c := range n.Content {
+			if err := checkKnownFields(c, t, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		fields[name] = t.Field(i).Type
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i]
+		full := key.Value
+		if path != "" {
+			full = path + "." + key.Value
+		}
+		ft, ok := fields[key.Value]
+		if !ok {
+			errs = append(errs, fmt.Errorf("第 %d 行: %s: 未知字段", key.Line, full))
+			continue
+		}
+		if err := checkKnownFields(n.Content[i+1], ft, full); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// interpolateNode 递归替换标量中的 ${VAR} 引用
+func interpolateNode(n *yaml.Node, path string, lookupEnv func(string) (string, bool)) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			if err := interpolateNode(c, path, lookupEnv); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		var errs []error
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			if err := interpolateNode(n.Content[i+1], key, lookupEnv); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			if err := interpolateNode(c, path+"["+strconv.Itoa(i)+"]", lookupEnv); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "$") {
+			return nil
+		}
+		var missing []string
+		value := envVarPattern.ReplaceAllStringFunc(n.Value, func(m string) string {
+			if m == "$$" {
+				return "$"
+			}
+			sub := envVarPattern.FindStringSubmatch(m)
+			name, hasDefault, def := sub[1], sub[2] != "", sub[3]
+			if v, ok := lookupEnv(name); ok && (v != "" || !hasDefault) {
+				return v
+			}
+			if hasDefault {
+				return def
+			}
+			missing = append(missing, name)
+			return ""
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("第 %d 行: %s: 环境变量 %s 未设置", n.Line, path, strings.Join(missing, ", "))
+		}
+		// 替换后按普通标量重新解析（${DISK_SIZE} 可用于数字字段）
+		n.Value = value
+		n.Tag = ""
+		n.Style = 0
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+package config
+
+// env.go 管理多环境（environment）：每个环境拥有独立的 state.json、backup.json 和 SSH 私钥，
+// 存放在 ~/.cloudcode/envs/<name>/ 下。凭证文件（credentials）仍为全局共享。
+//
+// 当前环境的解析优先级：--env 参数 → CLOUDCODE_ENV 环境变量 → ~/.cloudcode/current_env → default。
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultEnvName     = "default"       // 默认环境名
+	EnvsDirName        = "envs"          // 环境目录，位于 ~/.cloudcode/ 下
+	CurrentEnvFileName = "current_env"   // 记录 cloudcode env use 选择的环境
+	EnvVarName         = "CLOUDCODE_ENV" // 环境变量覆盖
+	SSHKeyFileName     = "ssh_key"       // SSH 私钥文件名
+	MigrateDirName     = "migrate"       // 迁移过程中目标区域 state 所在的子目录（位于环境目录下）
+)
+
+var (
+	ErrEnvNotFound    = errors.New("environment not found")
+	ErrInvalidEnvName = errors.New("环境名只能包含小写字母、数字和连字符，且以字母或数字开头（最长 32 位）")
+)
+
+var envNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// activeEnv 由 --env 参数设置，优先级最高
+var activeEnv string
+
+// SetActiveEnv 设置当前命令使用的环境（来自 --env 参数，空字符串表示未指定）
+func SetActiveEnv(name string) {
+	activeEnv = name
+}
+
+// ValidateEnvName 校验环境名（同时用于目录名和云资源名后缀）
+func ValidateEnvName(name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidEnvName, name)
+	}
+	return nil
+}
+
+// ActiveEnv 返回当前生效的环境名
+func ActiveEnv() string {
+	if activeEnv != "" {
+		return activeEnv
+	}
+	if name := os.Getenv(EnvVarName); name != "" {
+		return name
+	}
+	if name, err := readCurrentEnv(); err == nil && name != "" {
+		return name
+	}
+	return DefaultEnvName
+}
+
+// GetEnvDir 返回指定环境的目录路径（~/.cloudcode/envs/<name>/）
+func GetEnvDir(name string) (string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, EnvsDirName, name), nil
+}
+
+// GetActiveEnvDir 返回当前环境的目录路径
+func GetActiveEnvDir() (string, error) {
+	return GetEnvDir(ActiveEnv())
+}
+
+// EnvKeyRelPath 返回指定环境 SSH 私钥相对于 home 的路径（写入 state 的 private_key_path）
+func EnvKeyRelPath(name string) string {
+	return filepath.Join(StateDirName, EnvsDirName, name, SSHKeyFileName)
+}
+
+// EnvInfo 环境概要信息，用于 cloudcode env list
+type EnvInfo struct {
+	Name    string
+	Current bool
+	Status  string // state.json 中的状态，无 state 时为空
+	Region  string
+	Domain  string
+}
+
+// ListEnvs 列出所有已存在的环境（按名称排序）
+func ListEnvs() ([]EnvInfo, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(stateDir, EnvsDirName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取环境目录失败: %w", err)
+	}
+
+	current := ActiveEnv()
+	var envs []EnvInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info := EnvInfo{Name: e.Name(), Current: e.Name() == current}
+		if state, err := LoadStateFrom(filepath.Join(stateDir, EnvsDirName, e.Name())); err == nil {
+			info.Status = state.Status
+			info.Region = state.Region
+			info.Domain = state.CloudCode.Domain
+		}
+		envs = append(envs, info)
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	return envs, nil
+}
+
+// stateDirs 返回所有环境目录，以及迁移中断时残留的目标区域 state 目录（<env>/migrate）
+func stateDirs() ([]string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(stateDir, EnvsDirName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取环境目录失败: %w", err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(stateDir, EnvsDirName, e.Name())
+		dirs = append(dirs, dir)
+		if info, err := os.Stat(filepath.Join(dir, MigrateDirName)); err == nil && info.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, MigrateDirName))
+		}
+	}
+	return dirs, nil
+}
+
+// KnownDeploymentIDs 返回所有环境 state 中记录的部署 ID（cloudcode-deployment-id 标签值）。
+// 抢占式实例被回收后，agent 创建的回收快照在恢复之前只能通过部署 ID 认出归属。
+func KnownDeploymentIDs() (map[string]bool, error) {
+	dirs, err := stateDirs()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, dir := range dirs {
+		if state, err := LoadStateFrom(dir); err == nil && state.DeploymentID != "" {
+			known[state.DeploymentID] = true
+		}
+	}
+	return known, nil
+}
+
+// KnownResourceIDs 返回所有环境 state（含迁移中的目标区域 state）中记录的云资源 ID / 名称集合（含 backup.json 和 backups.json 中的快照），
+// 用于区分"其他环境的资源"和"不属于任何环境的孤儿资源"。
+func KnownResourceIDs() (map[string]bool, error) {
+	dirs, err := stateDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, dir := range dirs {
+		if backup, _ := LoadBackupFrom(dir); backup != nil && backup.SnapshotID != "" {
+			known[backup.SnapshotID] = true
+		}
+		if catalog, _ := LoadBackupCatalogFrom(dir); catalog != nil {
+			for _, b := range catalog.Backups {
+				known[b.SnapshotID] = true
+			}
+		}
+		state, err := LoadStateFrom(dir)
+		if err != nil {
+			continue
+		}
+		r := state.Resources
+		for _, id := range []string{r.VPC.ID, r.VSwitch.ID, r.SecurityGroup.ID, r.ECS.ID, r.EIP.ID, r.SSHKeyPair.Name} {
+			if id != "" {
+				known[id] = true
+			}
+		}
+	}
+	return known, nil
+}
+
+// UseEnv 将指定环境设为默认环境（写入 ~/.cloudcode/current_env）。
+// 环境目录不存在时自动创建，便于先切换再 deploy。
+func UseEnv(name string) error {
+	if err := ValidateEnvName(name); err != nil {
+		return err
+	}
+	dir, err := GetEnvDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建环境目录失败: %w", err)
+	}
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(stateDir, CurrentEnvFileName)
+	if err := os.WriteFile(path, []byte(name+"\n"), 0600); err != nil {
+		return fmt.Errorf("保存当前环境失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveEnv 删除指定环境的本地目录（state、backup、SSH 私钥）。
+// 不会删除云资源，调用方应先确认该环境已 destroy。
+// 删除的是当前环境时，current_env 回落到 default。
+func RemoveEnv(name string) error {
+	if err := ValidateEnvName(name); err != nil {
+		return err
+	}
+	dir, err := GetEnvDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrEnvNotFound, name)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("删除环境目录失败: %w", err)
+	}
+	if current, err := readCurrentEnv(); err == nil && current == name {
+		stateDir, err := GetStateDir()
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(filepath.Join(stateDir, CurrentEnvFileName))
+	}
+	return nil
+}
+
+// MigrateLegacyState 将旧版单环境布局（~/.cloudcode/state.json、backup.json、ssh_key）
+// 迁移到 default 环境目录。default 环境已有 state 时不做任何操作。
+// 返回 true 表示发生了迁移。
+func MigrateLegacyState() (bool, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return false, err
+	}
+	legacyState := filepath.Join(stateDir, StateFileName)
+	legacyBackup := filepath.Join(stateDir, BackupFileName)
+	if !fileExists(legacyState) && !fileExists(legacyBackup) {
+		return false, nil
+	}
+
+	envDir := filepath.Join(stateDir, EnvsDirName, DefaultEnvName)
+	if fileExists(filepath.Join(envDir, StateFileName)) {
+		return false, nil
+	}
+	if err := os.MkdirAll(envDir, 0700); err != nil {
+		return false, fmt.Errorf("创建环境目录失败: %w", err)
+	}
+
+	for _, name := range []string{StateFileName, BackupFileName, SSHKeyFileName} {
+		src := filepath.Join(stateDir, name)
+		if !fileExists(src) {
+			continue
+		}
+		if err := os.Rename(src, filepath.Join(envDir, name)); err != nil {
+			return false, fmt.Errorf("迁移 %s 失败: %w", name, err)
+		}
+	}
+
+	// 修正 state 中记录的私钥相对路径
+	state, err := LoadStateFrom(envDir)
+	if err == nil && state.Resources.SSHKeyPair.PrivateKeyPath != "" {
+		state.Resources.SSHKeyPair.PrivateKeyPath = EnvKeyRelPath(DefaultEnvName)
+		if err := SaveStateTo(envDir, state); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func readCurrentEnv() (string, error) {
+	stateDir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(stateDir, CurrentEnvFileName))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
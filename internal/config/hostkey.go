@@ -0,0 +1,51 @@
+package config
+
+// hostkey.go 在 state 中记录实例的 SSH 主机公钥（Resources.ECS.HostKey），供 remote 包校验主机身份。
+
+// HostKeyStore 以 Dir 下的 state.json 存储主机公钥（实现 remote.HostKeyStore）。
+// 只认 state 中记录的 EIP：其他地址（如迁移中的目标实例）不读取也不保存记录。
+type HostKeyStore struct {
+	Dir string
+}
+
+// LoadHostKey 返回 host 的主机公钥，未记录时返回空
+func (s HostKeyStore) LoadHostKey(host string) (string, error) {
+	state, err := LoadStateFrom(s.Dir)
+	if err == ErrStateNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if state.Resources.EIP.IP != host {
+		return "", nil
+	}
+	return state.Resources.ECS.HostKey, nil
+}
+
+// SaveHostKey 记录 host 的主机公钥
+func (s HostKeyStore) SaveHostKey(host, key string) error {
+	state, err := LoadStateFrom(s.Dir)
+	if err == ErrStateNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if state.Resources.EIP.IP != host {
+		return nil
+	}
+	state.Resources.ECS.HostKey = key
+	return writeStateFile(s.Dir, state)
+}
+
+// ClearHostKey 清除 Dir 下 state 中的主机公钥记录（实例重建 / 系统盘替换后主机公钥会变化），
+// 下次连接时重新记录
+func ClearHostKey(dir string) error {
+	state, err := LoadStateFrom(dir)
+	if err != nil {
+		return err
+	}
+	state.Resources.ECS.HostKey = ""
+	return writeStateFile(dir, state)
+}
@@ -0,0 +1,135 @@
+package config
+
+// secrets.go 管理写入 devbox .env 的 LLM provider API Key 等环境变量。
+// 每个环境一份 ~/.cloudcode/envs/<name>/secrets.env（KEY=value，权限 600），
+// 是 .env 的唯一来源：deploy / deploy --app / cloudcode secrets 都从这里渲染 .env。
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const SecretsFileName = "secrets.env"
+
+// ProviderKey 常用 provider 的环境变量（仅用于提示，secrets 可以是任意合法变量名）
+type ProviderKey struct {
+	Name        string
+	Description string
+	Secret      bool // 是否为密钥（输入时掩码、列出时脱敏）
+}
+
+// KnownProviderKeys OpenCode 识别的常用 provider 环境变量
+var KnownProviderKeys = []ProviderKey{
+	{"OPENAI_API_KEY", "OpenAI", true},
+	{"OPENAI_BASE_URL", "OpenAI 兼容接口地址", false},
+	{"ANTHROPIC_API_KEY", "Anthropic", true},
+	{"GEMINI_API_KEY", "Google Gemini", true},
+	{"DEEPSEEK_API_KEY", "DeepSeek", true},
+	{"OPENROUTER_API_KEY", "OpenRouter", true},
+	{"AZURE_API_KEY", "Azure OpenAI", true},
+	{"AZURE_RESOURCE_NAME", "Azure OpenAI 资源名", false},
+}
+
+var secretNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// ValidateSecretName 校验变量名（大写字母开头，仅含大写字母、数字、下划线）
+func ValidateSecretName(name string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("变量名 %q 无效，需由大写字母开头，仅含大写字母、数字和下划线（如 GEMINI_API_KEY）", name)
+	}
+	return nil
+}
+
+// ValidateSecretValue 校验变量值（非空、单行，.env 不支持多行值）
+func ValidateSecretValue(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s 的值不能为空", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s 的值不能包含换行", name)
+	}
+	return nil
+}
+
+// IsSecretValue 判断变量是否需要脱敏显示（已知的非密钥变量如 OPENAI_BASE_URL 原样显示）
+func IsSecretValue(name string) bool {
+	for _, k := range KnownProviderKeys {
+		if k.Name == name {
+			return k.Secret
+		}
+	}
+	return true
+}
+
+// MaskSecret 脱敏显示：保留首尾各 4 个字符
+func MaskSecret(value string) string {
+	if len(value) <= 12 {
+		return "********"
+	}
+	return value[:4] + "..." + value[len(value)-4:]
+}
+
+// SortedSecretNames 返回按名称排序的变量名
+func SortedSecretNames(secrets map[string]string) []string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadSecretsFrom 从指定目录加载 secrets.env，文件不存在时返回空 map
+func LoadSecretsFrom(dir string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	f, err := os.Open(filepath.Join(dir, SecretsFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return secrets, nil
+		}
+		return nil, fmt.Errorf("读取 secrets 文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			continue
+		}
+		secrets[strings.TrimSpace(line[:idx])] = line[idx+1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 secrets 文件失败: %w", err)
+	}
+	return secrets, nil
+}
+
+// SaveSecretsTo 将 secrets 按名称排序保存到指定目录，权限 600
+func SaveSecretsTo(dir string, secrets map[string]string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	var b strings.Builder
+	for _, name := range SortedSecretNames(secrets) {
+		fmt.Fprintf(&b, "%s=%s\n", name, secrets[name])
+	}
+	path := filepath.Join(dir, SecretsFileName)
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("保存 secrets 文件失败: %w", err)
+	}
+	// WriteFile 不会修改已有文件的权限
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("设置 secrets 文件权限失败: %w", err)
+	}
+	return nil
+}
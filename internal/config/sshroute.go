@@ -0,0 +1,154 @@
+package config
+
+// sshroute.go 按环境记录连接实例 SSH 的网络路径：直连、经跳板机（ProxyJump）或经 SOCKS5 / HTTP CONNECT 代理，
+// 存放在 ~/.cloudcode/envs/<name>/ssh_route.json。
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SSHRouteFileName SSH 连接路径文件名（位于环境目录）
+const SSHRouteFileName = "ssh_route.json"
+
+// SSHRoute SSH 连接路径。Proxy 和 Jump 可同时设置：经代理连接跳板机，再由跳板机连接实例。
+type SSHRoute struct {
+	Proxy       string `json:"proxy,omitempty"`         // socks5://[user:pass@]host:port 或 http://[user:pass@]host:port
+	Jump        string `json:"jump,omitempty"`          // 跳板机 [user@]host[:port]（默认 root、22）
+	JumpKey     string `json:"jump_key,omitempty"`      // 跳板机私钥路径（空表示使用实例的 SSH 私钥）
+	JumpHostKey string `json:"jump_host_key,omitempty"` // 跳板机 SSH 主机公钥（首次连接时记录）
+}
+
+// IsDirect 是否直连（未设置代理和跳板机）
+func (r *SSHRoute) IsDirect() bool {
+	return r.Proxy == "" && r.Jump == ""
+}
+
+// Validate 校验代理 URL 和跳板机地址
+func (r *SSHRoute) Validate() error {
+	if r.Proxy != "" {
+		u, err := url.Parse(r.Proxy)
+		if err != nil {
+			return fmt.Errorf("代理地址格式错误: %w", err)
+		}
+		switch u.Scheme {
+		case "socks5", "socks5h", "http":
+		default:
+			return fmt.Errorf("不支持的代理协议 %q（支持 socks5、http）", u.Scheme)
+		}
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return fmt.Errorf("代理地址需要包含端口: %s", r.Proxy)
+		}
+	}
+	if r.Jump != "" {
+		if _, _, _, err := ParseJumpHost(r.Jump); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseJumpHost 解析 [user@]host[:port]，user 默认 root，port 默认 22
+func ParseJumpHost(s string) (user, host string, port int, err error) {
+	user, port = "root", 22
+	hostport := s
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		user, hostport = s[:i], s[i+1:]
+		if user == "" {
+			return "", "", 0, fmt.Errorf("跳板机用户名为空: %s", s)
+		}
+	}
+	host = hostport
+	if h, p, splitErr := net.SplitHostPort(hostport); splitErr == nil {
+		host = h
+		if port, err = strconv.Atoi(p); err != nil || port <= 0 || port > 65535 {
+			return "", "", 0, fmt.Errorf("跳板机端口无效: %s", s)
+		}
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return "", "", 0, fmt.Errorf("跳板机地址无效: %s", s)
+	}
+	return user, host, port, nil
+}
+
+// LoadSSHRouteFrom 从 dir 加载 SSH 连接路径，文件不存在时返回直连
+func LoadSSHRouteFrom(dir string) (*SSHRoute, error) {
+	data, err := os.ReadFile(filepath.Join(dir, SSHRouteFileName))
+	if os.IsNotExist(err) {
+		return &SSHRoute{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 SSH 连接路径失败: %w", err)
+	}
+	var route SSHRoute
+	if err := json.Unmarshal(data, &route); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", SSHRouteFileName, err)
+	}
+	return &route, nil
+}
+
+// SaveSSHRouteTo 将 SSH 连接路径写入 dir（直连时删除文件）
+func SaveSSHRouteTo(dir string, route *SSHRoute) error {
+	path := filepath.Join(dir, SSHRouteFileName)
+	if route.IsDirect() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(route, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("保存 SSH 连接路径失败: %w", err)
+	}
+	return nil
+}
+
+// JumpHostKeyStore 以 Dir 下的 ssh_route.json 存储跳板机的主机公钥（实现 remote.HostKeyStore），只认当前配置的跳板机
+type JumpHostKeyStore struct {
+	Dir string
+}
+
+// LoadHostKey 返回跳板机的主机公钥，未记录时返回空
+func (s JumpHostKeyStore) LoadHostKey(host string) (string, error) {
+	route, err := LoadSSHRouteFrom(s.Dir)
+	if err != nil {
+		return "", err
+	}
+	if !route.isJumpHost(host) {
+		return "", nil
+	}
+	return route.JumpHostKey, nil
+}
+
+// SaveHostKey 记录跳板机的主机公钥
+func (s JumpHostKeyStore) SaveHostKey(host, key string) error {
+	route, err := LoadSSHRouteFrom(s.Dir)
+	if err != nil {
+		return err
+	}
+	if !route.isJumpHost(host) {
+		return nil
+	}
+	route.JumpHostKey = key
+	return SaveSSHRouteTo(s.Dir, route)
+}
+
+func (r *SSHRoute) isJumpHost(host string) bool {
+	if r.Jump == "" {
+		return false
+	}
+	_, jumpHost, _, err := ParseJumpHost(r.Jump)
+	return err == nil && jumpHost == host
+}
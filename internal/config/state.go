@@ -1,8 +1,11 @@
 // Package config 管理 CloudCode 的持久化状态和用户交互。
 // 状态文件（state.json）记录所有已创建的云资源 ID，支持幂等部署和中断恢复。
+// 每个环境（见 env.go）拥有独立的状态文件。
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -46,11 +49,15 @@ type SecurityGroupResource struct {
 
 // ECSResource ECS 实例资源
 type ECSResource struct {
-	ID             string `json:"id"`
-	InstanceType   string `json:"instance_type"`
-	SystemDiskSize int    `json:"system_disk_size"`
-	PublicIP       string `json:"public_ip"`
-	PrivateIP      string `json:"private_ip"`
+	ID                 string  `json:"id"`
+	InstanceType       string  `json:"instance_type"`
+	SystemDiskSize     int     `json:"system_disk_size"`
+	SystemDiskCategory string  `json:"system_disk_category,omitempty"`
+	PublicIP           string  `json:"public_ip"`
+	PrivateIP          string  `json:"private_ip"`
+	Spot               bool    `json:"spot,omitempty"`             // 抢占式实例
+	SpotPriceLimit     float64 `json:"spot_price_limit,omitempty"` // 抢占式实例每小时最高出价（0 表示跟随市场价）
+	HostKey            string  `json:"host_key,omitempty"`         // SSH 主机公钥（authorized_keys 格式），首次连接时记录，见 hostkey.go
 }
 
 // EIPResource 弹性公网 IP 资源
@@ -65,6 +72,21 @@ type SSHKeyPairResource struct {
 	PrivateKeyPath string `json:"private_key_path"`
 }
 
+// RAMRoleResource 自动停机 agent 使用的 RAM 角色（角色与同名权限策略，仅允许停机本实例）
+type RAMRoleResource struct {
+	Name string `json:"name,omitempty"`
+}
+
+// WakeResource 唤醒页：函数计算函数（函数、RAM 角色和权限策略同名）及其自定义域名。
+// KeyHash 为唤醒密钥的 SHA-256（密钥本身只在开启时显示一次），CookieSecret 用于签发登录 cookie。
+type WakeResource struct {
+	FunctionName  string `json:"function_name,omitempty"`
+	Domain        string `json:"domain,omitempty"`
+	KeyHash       string `json:"key_hash,omitempty"`
+	CookieSecret  string `json:"cookie_secret,omitempty"`
+	CertExpiresAt string `json:"cert_expires_at,omitempty"` // HTTPS 证书到期时间（RFC 3339）
+}
+
 // Resources 所有云资源的集合
 type Resources struct {
 	VPC           VPCResource           `json:"vpc"`
@@ -73,26 +95,49 @@ type Resources struct {
 	ECS           ECSResource           `json:"ecs"`
 	EIP           EIPResource           `json:"eip"`
 	SSHKeyPair    SSHKeyPairResource    `json:"ssh_key_pair"`
+	RAMRole       RAMRoleResource       `json:"ram_role,omitempty"`
+	Wake          WakeResource          `json:"wake,omitempty"`
 }
 
 // CloudCodeConfig 应用层配置（域名、用户名等）
 type CloudCodeConfig struct {
-	Username string `json:"username"`
-	Domain   string `json:"domain"`
+	Username     string `json:"username"`
+	Domain       string `json:"domain"`
+	DevboxMode   string `json:"devbox_mode,omitempty"`   // shared（空）/ per-user
+	ImageVersion string `json:"image_version,omitempty"` // 部署的 devbox 镜像版本（cloudcode user 重新渲染 compose 时沿用）
+
+	AutoSuspend *AutoSuspendPolicy `json:"auto_suspend,omitempty"` // 自动停机策略（nil 表示未开启）
+	Authelia    *AutheliaSettings  `json:"authelia,omitempty"`     // 部署时的 Authelia 设置（nil 表示模板默认值）
+}
+
+// AutheliaSettings 部署配置文件中的 Authelia 设置。--app、快照恢复、回收恢复和迁移重新渲染
+// configuration.yml 时，未提供配置文件则沿用记录的设置，避免被重置为默认值。
+type AutheliaSettings struct {
+	SessionExpiration string `json:"session_expiration,omitempty"`
+	SessionInactivity string `json:"session_inactivity,omitempty"`
+	AccessPolicy      string `json:"access_policy,omitempty"`
+}
+
+// StatusChange 运行状态变更记录（cloudcode cost 据此统计运行 / 停机时长）
+type StatusChange struct {
+	Status string `json:"status"`
+	At     string `json:"at"` // 变更时间（RFC 3339）
 }
 
-// State 部署状态，序列化为 ~/.cloudcode/state.json
+// State 部署状态，序列化为 ~/.cloudcode/envs/<env>/state.json
 type State struct {
-	Version   string          `json:"version"`
-	CreatedAt string          `json:"created_at"`
-	Region    string          `json:"region"`
-	OSImage   string          `json:"os_image"`
-	Status    string          `json:"status,omitempty"` // running / suspended / destroyed
-	Resources Resources       `json:"resources"`
-	CloudCode CloudCodeConfig `json:"cloudcode"`
+	Version       string          `json:"version"`
+	CreatedAt     string          `json:"created_at"`
+	Region        string          `json:"region"`
+	OSImage       string          `json:"os_image"`
+	DeploymentID  string          `json:"deployment_id,omitempty"` // 写入云资源标签 cloudcode-deployment-id
+	Status        string          `json:"status,omitempty"`        // running / suspended / destroyed / reclaimed（抢占式实例被回收）
+	StatusHistory []StatusChange  `json:"status_history,omitempty"`
+	Resources     Resources       `json:"resources"`
+	CloudCode     CloudCodeConfig `json:"cloudcode"`
 }
 
-// GetStateDir 返回状态目录路径（~/.cloudcode/）
+// GetStateDir 返回 CloudCode 根目录路径（~/.cloudcode/），存放全局凭证和各环境目录
 func GetStateDir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -101,32 +146,43 @@ func GetStateDir() (string, error) {
 	return filepath.Join(home, StateDirName), nil
 }
 
-// GetStatePath 返回状态文件完整路径（~/.cloudcode/state.json）
+// GetStatePath 返回当前环境状态文件完整路径（~/.cloudcode/envs/<env>/state.json）
 func GetStatePath() (string, error) {
-	stateDir, err := GetStateDir()
+	envDir, err := GetActiveEnvDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(stateDir, StateFileName), nil
+	return filepath.Join(envDir, StateFileName), nil
 }
 
-// EnsureStateDir 确保状态目录存在（权限 0700，仅当前用户可访问）
+// EnsureStateDir 确保当前环境目录存在（权限 0700，仅当前用户可访问）
 func EnsureStateDir() error {
 	stateDir, err := GetStateDir()
 	if err != nil {
 		return err
 	}
-	return os.MkdirAll(stateDir, 0700)
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+	envDir, err := GetActiveEnvDir()
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(envDir, 0700)
 }
 
-// LoadState 从默认路径加载状态文件
+// LoadState 从当前环境加载状态文件
 func LoadState() (*State, error) {
-	statePath, err := GetStatePath()
+	envDir, err := GetActiveEnvDir()
 	if err != nil {
 		return nil, err
 	}
+	return LoadStateFrom(envDir)
+}
 
-	data, err := os.ReadFile(statePath)
+// LoadStateFrom 从指定目录加载状态文件
+func LoadStateFrom(dir string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(dir, StateFileName))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrStateNotFound
@@ -142,30 +198,52 @@ func LoadState() (*State, error) {
 	return &state, nil
 }
 
-// SaveState 将状态写入默认路径（自动创建目录，权限 0600）
+// SaveState 将状态写入当前环境（自动创建目录，权限 0600）
 func SaveState(state *State) error {
 	if err := EnsureStateDir(); err != nil {
 		return err
 	}
 
-	statePath, err := GetStatePath()
+	envDir, err := GetActiveEnvDir()
 	if err != nil {
 		return err
 	}
+	return SaveStateTo(envDir, state)
+}
+
+// SaveStateTo 将状态写入指定目录（自动创建目录，权限 0600）。
+// 主机公钥由 SSH 连接直接写入文件，调用方内存中的 state 可能是连接前加载的：
+// 同一实例在内存中没有主机公钥时沿用文件中的记录，避免被覆盖。
+func SaveStateTo(dir string, state *State) error {
+	ecs := state.Resources.ECS
+	if ecs.HostKey == "" && ecs.ID != "" {
+		if old, err := LoadStateFrom(dir); err == nil && old.Resources.ECS.ID == ecs.ID && old.Resources.ECS.HostKey != "" {
+			merged := *state
+			merged.Resources.ECS.HostKey = old.Resources.ECS.HostKey
+			return writeStateFile(dir, &merged)
+		}
+	}
+	return writeStateFile(dir, state)
+}
+
+func writeStateFile(dir string, state *State) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
 
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := os.WriteFile(statePath, data, 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(dir, StateFileName), data, 0600); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
 	return nil
 }
 
-// DeleteState 删除状态文件
+// DeleteState 删除当前环境的状态文件
 func DeleteState() error {
 	statePath, err := GetStatePath()
 	if err != nil {
@@ -204,6 +282,18 @@ func (s *State) HasSSHKeyPair() bool {
 	return s.Resources.SSHKeyPair.Name != ""
 }
 
+// SetStatus 更新运行状态，状态变化时追加变更记录
+func (s *State) SetStatus(status string) {
+	if s.Status == status {
+		return
+	}
+	s.Status = status
+	s.StatusHistory = append(s.StatusHistory, StatusChange{
+		Status: status,
+		At:     time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
 // IsComplete 判断所有云资源是否已创建完毕
 func (s *State) IsComplete() bool {
 	return s.HasVPC() && s.HasVSwitch() && s.HasSecurityGroup() &&
@@ -222,10 +312,20 @@ func ResolveKeyPath(relativePath string) (string, error) {
 // NewState 创建新的空状态（自动填充版本号和创建时间）
 func NewState(region, osImage string) *State {
 	return &State{
-		Version:   StateFileVersion,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-		Region:    region,
-		OSImage:   osImage,
-		Resources: Resources{},
+		Version:      StateFileVersion,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		Region:       region,
+		OSImage:      osImage,
+		DeploymentID: NewDeploymentID(),
+		Resources:    Resources{},
+	}
+}
+
+// NewDeploymentID 生成随机部署 ID（16 位十六进制），同一部署的所有云资源共享该 ID
+func NewDeploymentID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
 	}
+	return hex.EncodeToString(b)
 }
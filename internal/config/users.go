@@ -0,0 +1,109 @@
+package config
+
+// users.go 读写 Authelia 文件认证后端的 users_database.yml。
+// 只修改目标用户的条目，其他用户以及未识别的字段原样保留。
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AdminGroup 管理员组（首次部署的管理员所在组）
+const AdminGroup = "admins"
+
+var groupPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// AutheliaUser users_database.yml 中的一个用户
+type AutheliaUser struct {
+	DisplayName string                 `yaml:"displayname"`
+	Password    string                 `yaml:"password"` // Argon2id 哈希
+	Email       string                 `yaml:"email,omitempty"`
+	Groups      []string               `yaml:"groups,omitempty"`
+	Disabled    bool                   `yaml:"disabled,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline"`
+}
+
+// UsersDatabase Authelia 用户数据库
+type UsersDatabase struct {
+	Users map[string]*AutheliaUser `yaml:"users"`
+	Extra map[string]interface{}   `yaml:",inline"`
+}
+
+// ParseUsersDatabase 解析 users_database.yml
+func ParseUsersDatabase(data []byte) (*UsersDatabase, error) {
+	var db UsersDatabase
+	if err := yaml.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("解析 users_database.yml 失败: %w", err)
+	}
+	if db.Users == nil {
+		db.Users = make(map[string]*AutheliaUser)
+	}
+	return &db, nil
+}
+
+// Marshal 序列化为 YAML（用户按名称排序）
+func (db *UsersDatabase) Marshal() ([]byte, error) {
+	return yaml.Marshal(db)
+}
+
+// SortedUsernames 返回按名称排序的用户名
+func (db *UsersDatabase) SortedUsernames() []string {
+	names := make([]string, 0, len(db.Users))
+	for name := range db.Users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnabledUsernames 返回未禁用的用户名（按名称排序）
+func (db *UsersDatabase) EnabledUsernames() []string {
+	var names []string
+	for _, name := range db.SortedUsernames() {
+		if !db.Users[name].Disabled {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Admins 返回 admins 组中未禁用的用户
+func (db *UsersDatabase) Admins() []string {
+	var admins []string
+	for _, name := range db.SortedUsernames() {
+		u := db.Users[name]
+		if !u.Disabled && containsString(u.Groups, AdminGroup) {
+			admins = append(admins, name)
+		}
+	}
+	return admins
+}
+
+// ValidateUsername 校验用户名（小写字母、数字和 _.-，最长 32 位）
+func ValidateUsername(name string) error {
+	if !usernamePattern.MatchString(name) {
+		return fmt.Errorf("用户名 %q 只能包含小写字母、数字和 _.-，最长 32 位", name)
+	}
+	return nil
+}
+
+// ValidateGroups 校验组名
+func ValidateGroups(groups []string) error {
+	for _, g := range groups {
+		if !groupPattern.MatchString(g) {
+			return fmt.Errorf("组名 %q 只能包含小写字母、数字和 _-，最长 32 位", g)
+		}
+	}
+	return nil
+}
+
+// ValidatePassword 校验密码长度
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("密码长度不能少于 %d 位", MinPasswordLength)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cost
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+// HoursPerMonth 按月估算时使用的小时数（365 × 24 / 12）
+const HoursPerMonth = 730
+
+// Spec 估算对象
+type Spec struct {
+	Region       string
+	InstanceType string
+	DiskSize     int // GB
+	DiskCategory string
+	EIP          bool
+	SnapshotGB   int // 保留的快照（0 表示无快照），按源磁盘容量估算
+}
+
+// Estimate 各项资源的每小时费用。Missing 列出价格表中缺少的条目（对应费用按 0 计）。
+type Estimate struct {
+	Currency     string
+	Instance     float64 // 仅运行时收费（StopCharging 停机不收实例费）
+	Disk         float64
+	EIP          float64
+	Snapshot     float64
+	EIPTrafficGB float64 // 公网出流量单价，不计入合计
+	Missing      []string
+}
+
+// Estimate 按价格表估算 spec 的每小时费用
+func (t *PriceTable) Estimate(spec Spec) *Estimate {
+	e := &Estimate{Currency: t.Currency}
+	p := t.Regions[spec.Region]
+	if p == nil {
+		e.Missing = append(e.Missing, fmt.Sprintf("区域 %s", spec.Region))
+		return e
+	}
+
+	if spec.InstanceType != "" {
+		if price, ok := p.InstanceTypes[spec.InstanceType]; ok {
+			e.Instance = price
+		} else {
+			e.Missing = append(e.Missing, fmt.Sprintf("实例规格 %s", spec.InstanceType))
+		}
+	}
+	if spec.DiskSize > 0 {
+		if price, ok := p.Disks[spec.DiskCategory]; ok {
+			e.Disk = price * float64(spec.DiskSize) / HoursPerMonth
+		} else {
+			e.Missing = append(e.Missing, fmt.Sprintf("云盘类型 %s", spec.DiskCategory))
+		}
+	}
+	if spec.EIP {
+		e.EIP = p.EIPHourly
+		e.EIPTrafficGB = p.EIPTrafficGB
+	}
+	if spec.SnapshotGB > 0 {
+		e.Snapshot = p.SnapshotGBMonth * float64(spec.SnapshotGB) / HoursPerMonth
+	}
+	return e
+}
+
+// Hourly 返回指定运行状态下的每小时费用：running 收全部费用，suspended 不收实例费，
+// destroyed 只剩快照费用
+func (e *Estimate) Hourly(status string) float64 {
+	switch status {
+	case "suspended":
+		return e.Disk + e.EIP + e.Snapshot
+	case "destroyed":
+		return e.Snapshot
+	case "reclaimed":
+		// 抢占式实例被回收：系统盘随实例释放，保留 EIP 和快照
+		return e.EIP + e.Snapshot
+	default:
+		return e.Instance + e.Disk + e.EIP + e.Snapshot
+	}
+}
+
+// Monthly 返回指定运行状态下的每月费用
+func (e *Estimate) Monthly(status string) float64 {
+	return e.Hourly(status) * HoursPerMonth
+}
+
+// Usage 自部署以来各运行状态的累计时长
+type Usage struct {
+	Since     time.Time
+	Durations map[string]time.Duration // running / suspended / destroyed
+	Recorded  bool                     // state 中有状态变更记录（旧版本 state 没有，全部时长按 running 计）
+}
+
+// UsageOf 根据 state 的创建时间和状态变更记录统计累计时长。
+// 部署从 running 开始；state 中空状态视为 running。
+func UsageOf(state *config.State, now time.Time) (*Usage, error) {
+	since, err := time.Parse(time.RFC3339, state.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("state 创建时间 %q 格式错误: %w", state.CreatedAt, err)
+	}
+	u := &Usage{Since: since, Durations: map[string]time.Duration{}, Recorded: len(state.StatusHistory) > 0}
+
+	status, from := "running", since
+	for _, c := range state.StatusHistory {
+		at, err := time.Parse(time.RFC3339, c.At)
+		if err != nil {
+			return nil, fmt.Errorf("状态变更时间 %q 格式错误: %w", c.At, err)
+		}
+		if at.After(from) {
+			u.Durations[status] += at.Sub(from)
+			from = at
+		}
+		status = normalizeStatus(c.Status)
+	}
+	if now.After(from) {
+		u.Durations[status] += now.Sub(from)
+	}
+	return u, nil
+}
+
+// Cost 按 e 的单价计算累计费用（不含流量）
+func (u *Usage) Cost(e *Estimate) float64 {
+	var total float64
+	for status, d := range u.Durations {
+		total += e.Hourly(status) * d.Hours()
+	}
+	return total
+}
+
+func normalizeStatus(status string) string {
+	if status == "" {
+		return "running"
+	}
+	return status
+}
@@ -0,0 +1,122 @@
+// Package cost 估算部署费用：按区域价格表计算实例、系统盘、EIP 和快照的每小时 / 每月费用，
+// 并根据 state 中的运行状态变更记录统计累计运行 / 停机时长（cloudcode cost）。
+// 价格表内置于二进制（prices.yaml），可由用户文件覆盖或补充。
+package cost
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PricesFileName 用户价格文件名，位于 ~/.cloudcode/ 下（所有环境共用）
+const PricesFileName = "prices.yaml"
+
+//go:embed prices.yaml
+var builtinPrices []byte
+
+// RegionPrices 单个区域的按量付费价格
+type RegionPrices struct {
+	InstanceTypes   map[string]float64 `yaml:"instance_types,omitempty"`    // 实例规格 → 每小时
+	Disks           map[string]float64 `yaml:"disks,omitempty"`             // 云盘类型 → 每 GB 每月
+	EIPHourly       float64            `yaml:"eip_hourly,omitempty"`        // EIP 保有费（每小时）
+	EIPTrafficGB    float64            `yaml:"eip_traffic_gb,omitempty"`    // 公网出流量（每 GB）
+	SnapshotGBMonth float64            `yaml:"snapshot_gb_month,omitempty"` // 快照存储（每 GB 每月）
+}
+
+// PriceTable 价格表
+type PriceTable struct {
+	Currency string                   `yaml:"currency,omitempty"`
+	Regions  map[string]*RegionPrices `yaml:"regions,omitempty"`
+}
+
+// ParsePrices 解析价格表（未知字段报错，避免拼写错误被静默忽略）
+func ParsePrices(data []byte) (*PriceTable, error) {
+	var t PriceTable
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&t); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// BuiltinPrices 返回内置价格表
+func BuiltinPrices() *PriceTable {
+	t, err := ParsePrices(builtinPrices)
+	if err != nil {
+		panic(fmt.Sprintf("内置价格表格式错误: %v", err))
+	}
+	return t
+}
+
+// LoadPrices 加载内置价格表，并用 path 中的价格覆盖。
+// path 为空时只使用内置价格；文件不存在时 required 为 true 报错，否则忽略。
+func LoadPrices(path string, required bool) (*PriceTable, error) {
+	t := BuiltinPrices()
+	if path == "" {
+		return t, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return t, nil
+		}
+		return nil, fmt.Errorf("读取价格文件失败: %w", err)
+	}
+	override, err := ParsePrices(data)
+	if err != nil {
+		return nil, fmt.Errorf("价格文件 %s 格式错误: %w", path, err)
+	}
+	t.Merge(override)
+	return t, nil
+}
+
+// Merge 用 o 中出现的条目覆盖 t（未出现的区域、规格和价格保持不变）
+func (t *PriceTable) Merge(o *PriceTable) {
+	if o.Currency != "" {
+		t.Currency = o.Currency
+	}
+	if t.Regions == nil {
+		t.Regions = map[string]*RegionPrices{}
+	}
+	for region, op := range o.Regions {
+		if op == nil {
+			continue
+		}
+		p := t.Regions[region]
+		if p == nil {
+			p = &RegionPrices{}
+			t.Regions[region] = p
+		}
+		p.InstanceTypes = mergeMap(p.InstanceTypes, op.InstanceTypes)
+		p.Disks = mergeMap(p.Disks, op.Disks)
+		if op.EIPHourly != 0 {
+			p.EIPHourly = op.EIPHourly
+		}
+		if op.EIPTrafficGB != 0 {
+			p.EIPTrafficGB = op.EIPTrafficGB
+		}
+		if op.SnapshotGBMonth != 0 {
+			p.SnapshotGBMonth = op.SnapshotGBMonth
+		}
+	}
+}
+
+func mergeMap(dst, src map[string]float64) map[string]float64 {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = map[string]float64{}
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
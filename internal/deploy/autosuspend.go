@@ -0,0 +1,362 @@
+package deploy
+
+// autosuspend.go 配置自动停机：创建只能停机本实例的 RAM 角色并绑定到实例，
+// 在实例上安装 cloudcode 二进制、写入 agent 配置并以 systemd 服务（cloudcode-agent）运行 cloudcode agent。
+// 抢占式实例同样需要 agent（监测回收通知并创建快照），此时策略可以为空。
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/hwuu/cloudcode/internal/agent"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+	tmpl "github.com/hwuu/cloudcode/internal/template"
+)
+
+// 实例上的 agent 文件路径和服务名
+const (
+	remoteAgentBinaryPath = "/usr/local/bin/cloudcode"
+	remoteAgentUnitPath   = "/etc/systemd/system/cloudcode-agent.service"
+	agentServiceName      = "cloudcode-agent"
+)
+
+// releaseVersionPattern 发布版本号（其他版本如 dev、分支名从 latest release 下载 agent）
+var releaseVersionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// AgentBinaryFunc 返回可在实例上运行的 cloudcode 二进制（arch 为 amd64 / arm64），
+// 返回 nil 表示由实例从 GitHub Release 下载
+type AgentBinaryFunc func(arch string) ([]byte, error)
+
+// AutoSuspendManager 自动停机策略管理器
+type AutoSuspendManager struct {
+	ECS         alicloud.ECSAPI
+	STS         alicloud.STSAPI
+	RAM         alicloud.RAMAPI
+	Output      io.Writer
+	Region      string
+	StateDir    string // 覆盖默认 state 目录（测试用）
+	Env         string // 写入 agent 配置（回收快照的标签），默认当前环境
+	SSHDialFunc SSHDialFactory
+	SFTPFactory SFTPClientFactory
+	Version     string          // 本地 cloudcode 版本（实例上安装同版本的 agent）
+	AgentBinary AgentBinaryFunc // 默认本地为相同架构的 linux 时上传自身，否则由实例下载
+}
+
+func (m *AutoSuspendManager) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.Output, format, args...)
+}
+
+func (m *AutoSuspendManager) envName() string {
+	if m.Env != "" {
+		return m.Env
+	}
+	return config.ActiveEnv()
+}
+
+// Set 开启或更新自动停机策略
+func (m *AutoSuspendManager) Set(ctx context.Context, policy *config.AutoSuspendPolicy) error {
+	if !policy.Enabled() {
+		return fmt.Errorf("请至少指定 --idle 或 --active-hours")
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	state, privateKey, err := m.loadRunning()
+	if err != nil {
+		return err
+	}
+	if err := m.apply(ctx, state, privateKey, policy); err != nil {
+		return err
+	}
+	if err := saveStateTo(m.getStateDir(), state); err != nil {
+		return err
+	}
+	m.printf("✅ 自动停机已开启: %s\n", policy.String())
+	m.printf("  停机后运行 cloudcode resume 恢复，本地状态会在下次运行 cloudcode 时自动同步\n")
+	return nil
+}
+
+// Disable 关闭自动停机：停止 agent，解绑并删除 RAM 角色
+func (m *AutoSuspendManager) Disable(ctx context.Context) error {
+	state, privateKey, err := m.loadRunning()
+	if err != nil {
+		return err
+	}
+	if !state.CloudCode.AutoSuspend.Enabled() && state.Resources.RAMRole.Name == "" {
+		m.printf("自动停机未开启。\n")
+		return nil
+	}
+	if state.Resources.ECS.Spot {
+		// 抢占式实例仍需 agent 监测回收通知，只清空停机策略
+		if err := m.apply(ctx, state, privateKey, nil); err != nil {
+			return err
+		}
+		if err := saveStateTo(m.getStateDir(), state); err != nil {
+			return err
+		}
+		m.printf("✅ 自动停机已关闭（agent 继续监测抢占式实例回收通知）\n")
+		return nil
+	}
+
+	disableCmd := fmt.Sprintf("systemctl disable --now %s 2>/dev/null; rm -f %s %s; systemctl daemon-reload",
+		agentServiceName, agent.DefaultConfigPath, remoteAgentUnitPath)
+	if err := m.runCommands(ctx, state, privateKey, disableCmd); err != nil {
+		return err
+	}
+	m.printf("  ✓ agent 已停止\n")
+
+	if name := state.Resources.RAMRole.Name; name != "" {
+		if err := alicloud.DeleteAgentRole(m.RAM, m.ECS, name, m.Region, state.Resources.ECS.ID); err != nil {
+			return err
+		}
+		m.printf("  ✓ RAM 角色 %s 已删除\n", name)
+	}
+
+	state.Resources.RAMRole = config.RAMRoleResource{}
+	state.CloudCode.AutoSuspend = nil
+	if err := saveStateTo(m.getStateDir(), state); err != nil {
+		return err
+	}
+	m.printf("✅ 自动停机已关闭\n")
+	return nil
+}
+
+// Status 显示自动停机策略和 agent 运行状态
+func (m *AutoSuspendManager) Status(ctx context.Context) error {
+	state, err := loadStateFrom(m.getStateDir())
+	if err != nil {
+		return fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	m.printf("自动停机: %s\n", state.CloudCode.AutoSuspend.String())
+	if name := state.Resources.RAMRole.Name; name != "" {
+		m.printf("RAM 角色: %s\n", name)
+	}
+	if !state.CloudCode.AutoSuspend.Enabled() {
+		return nil
+	}
+	if state.Status == "suspended" {
+		m.printf("实例已停机。\n")
+		return nil
+	}
+
+	privateKey, err := readSSHKeyFrom(m.getStateDir(), state)
+	if err != nil {
+		return err
+	}
+	dialFunc := m.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	defer sshClient.Close()
+	output, err := sshClient.RunCommand(ctx, fmt.Sprintf("systemctl is-active %s; journalctl -u %s -n 5 --no-pager -o cat 2>/dev/null", agentServiceName, agentServiceName))
+	if err != nil && output == "" {
+		return fmt.Errorf("查询 agent 状态失败: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	m.printf("agent: %s\n", lines[0])
+	for _, line := range lines[1:] {
+		m.printf("  %s\n", line)
+	}
+	return nil
+}
+
+// apply 创建 RAM 角色并在实例上安装 agent，更新 state 中的角色和策略（由调用方保存）。
+// policy 为空时只用于抢占式实例的回收监测。
+func (m *AutoSuspendManager) apply(ctx context.Context, state *config.State, privateKey []byte, policy *config.AutoSuspendPolicy) error {
+	identity, err := alicloud.GetCallerIdentity(m.STS)
+	if err != nil {
+		return err
+	}
+	roleName := state.Resources.RAMRole.Name
+	if roleName == "" {
+		id := state.DeploymentID
+		if id == "" {
+			id = state.Resources.ECS.ID
+		}
+		roleName = alicloud.AgentRoleName(id)
+	}
+	spot := state.Resources.ECS.Spot
+	if err := alicloud.EnsureAgentRole(m.RAM, m.ECS, roleName, m.Region, identity.AccountID, state.Resources.ECS.ID, spot); err != nil {
+		return err
+	}
+	state.Resources.RAMRole.Name = roleName
+	if spot {
+		m.printf("  ✓ RAM 角色 %s（仅允许停机 %s 和为其系统盘创建快照）\n", roleName, state.Resources.ECS.ID)
+	} else {
+		m.printf("  ✓ RAM 角色 %s（仅允许停机 %s）\n", roleName, state.Resources.ECS.ID)
+	}
+
+	if !policy.Enabled() {
+		policy = nil
+	}
+	cfg := agent.Config{
+		InstanceID:   state.Resources.ECS.ID,
+		Region:       m.Region,
+		RoleName:     roleName,
+		Spot:         spot,
+		DeploymentID: state.DeploymentID,
+		Env:          m.envName(),
+	}
+	if policy != nil {
+		cfg.Policy = *policy
+	}
+	agentCfg, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	unit, err := tmpl.GetStaticFile("templates/cloudcode-agent.service")
+	if err != nil {
+		return err
+	}
+
+	eip := state.Resources.EIP.IP
+	dialFunc := m.SSHDialFunc(eip, 22, "root", privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	defer sshClient.Close()
+	sftpClient, err := m.SFTPFactory(eip, 22, "root", privateKey)
+	if err != nil {
+		return fmt.Errorf("SFTP 连接失败: %w", err)
+	}
+	defer sftpClient.Close()
+
+	// 安装与本地相同版本的 cloudcode（agent 即 cloudcode agent 子命令）
+	arch, err := sshClient.RunCommand(ctx, "uname -m")
+	if err != nil {
+		return fmt.Errorf("查询实例架构失败: %w", err)
+	}
+	installCmd, err := m.installAgentCmd(sftpClient, goArch(strings.TrimSpace(arch)))
+	if err != nil {
+		return err
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, remote.DockerInstallTimeout)
+	defer cancel()
+	if _, err := sshClient.RunCommand(cmdCtx, installCmd); err != nil {
+		return fmt.Errorf("安装 agent 失败: %w", err)
+	}
+
+	if err := remote.UploadFiles(sftpClient, map[string][]byte{
+		agent.DefaultConfigPath: agentCfg,
+		remoteAgentUnitPath:     unit,
+	}); err != nil {
+		return fmt.Errorf("上传 agent 配置失败: %w", err)
+	}
+	startCmd := fmt.Sprintf("systemctl daemon-reload && systemctl enable %s && systemctl restart %s", agentServiceName, agentServiceName)
+	if _, err := sshClient.RunCommand(ctx, startCmd); err != nil {
+		return fmt.Errorf("启动 agent 失败: %w", err)
+	}
+	m.printf("  ✓ agent 已启动（systemd 服务 %s）\n", agentServiceName)
+
+	state.CloudCode.AutoSuspend = policy
+	return nil
+}
+
+// installAgentCmd 上传本地二进制或返回从 GitHub Release 下载的命令
+func (m *AutoSuspendManager) installAgentCmd(sftpClient remote.SFTPClient, arch string) (string, error) {
+	binaryFunc := m.AgentBinary
+	if binaryFunc == nil {
+		binaryFunc = localAgentBinary
+	}
+	binary, err := binaryFunc(arch)
+	if err != nil {
+		return "", err
+	}
+	tmpPath := remoteAgentBinaryPath + ".new"
+	if binary != nil {
+		if err := sftpClient.UploadFile(binary, tmpPath); err != nil {
+			return "", fmt.Errorf("上传 cloudcode 失败: %w", err)
+		}
+		return fmt.Sprintf("chmod +x %s && mv %s %s", tmpPath, tmpPath, remoteAgentBinaryPath), nil
+	}
+
+	url := releaseBinaryURL(m.Version, arch)
+	return fmt.Sprintf("curl -fsSL -o %s %s && chmod +x %s && mv %s %s", tmpPath, url, tmpPath, tmpPath, remoteAgentBinaryPath), nil
+}
+
+// releaseBinaryURL 返回与 version 对应的 GitHub Release 中 linux 二进制的下载地址
+func releaseBinaryURL(version, arch string) string {
+	if releaseVersionPattern.MatchString(version) {
+		return fmt.Sprintf("https://github.com/hwuu/cloudcode/releases/download/v%s/cloudcode-linux-%s", strings.TrimPrefix(version, "v"), arch)
+	}
+	return "https://github.com/hwuu/cloudcode/releases/latest/download/cloudcode-linux-" + arch
+}
+
+// localAgentBinary 本地就是相同架构的 linux 时返回自身，否则返回 nil（由实例下载）
+func localAgentBinary(arch string) ([]byte, error) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != arch {
+		return nil, nil
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
+
+// goArch 将 uname -m 转换为 Go 架构名
+func goArch(machine string) string {
+	switch machine {
+	case "x86_64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	}
+	return machine
+}
+
+// runCommands 通过一个 SSH 连接依次执行命令
+func (m *AutoSuspendManager) runCommands(ctx context.Context, state *config.State, privateKey []byte, cmds ...string) error {
+	dialFunc := m.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	defer sshClient.Close()
+	for _, cmd := range cmds {
+		if _, err := sshClient.RunCommand(ctx, cmd); err != nil {
+			return fmt.Errorf("执行 %q 失败: %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// loadRunning 加载 state 和 SSH 私钥，要求实例处于运行状态
+func (m *AutoSuspendManager) loadRunning() (*config.State, []byte, error) {
+	dir := m.getStateDir()
+	state, err := loadStateFrom(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	switch {
+	case state.Status == "suspended":
+		return nil, nil, fmt.Errorf("实例已停机，请先运行 cloudcode resume")
+	case state.Status == "destroyed" || !state.HasECS():
+		return nil, nil, fmt.Errorf("实例不存在，请先运行 cloudcode deploy")
+	case state.Resources.EIP.IP == "":
+		return nil, nil, fmt.Errorf("EIP 未分配，请先完成部署")
+	}
+	privateKey, err := readSSHKeyFrom(dir, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	return state, privateKey, nil
+}
+
+func (m *AutoSuspendManager) getStateDir() string {
+	if m.StateDir != "" {
+		return m.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
@@ -0,0 +1,451 @@
+package deploy
+
+// backup.go 实现 cloudcode backup：运行中为系统盘创建快照（可先停止容器保证一致性），
+// 在 backups.json 中记录所有快照，按保留策略清理，并从任意快照恢复：
+// 实例存在时通过快照创建镜像并替换系统盘，实例已销毁时将其设为 deploy 的恢复点（backup.json）。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// 快照前后在实例上执行的命令（--quiesce）
+const (
+	quiesceCommand   = "cd ~/cloudcode && docker compose stop && sync"
+	unquiesceCommand = "cd ~/cloudcode && docker compose start"
+)
+
+// 自动创建的备份标签
+const (
+	BackupLabelPreRestore = "pre-restore" // backup restore 替换系统盘前
+	BackupLabelDestroy    = "destroy"     // destroy 保留的快照（只保留最新一份）
+	BackupLabelMigrate    = "migrate"     // migrate 复制到目标区域的快照
+	BackupLabelSpot       = "spot"        // 抢占式实例被回收后重建使用的快照
+)
+
+// BackupManager 快照备份管理器
+type BackupManager struct {
+	ECS          alicloud.ECSAPI
+	Prompter     *config.Prompter
+	Output       io.Writer
+	Region       string
+	StateDir     string // 覆盖默认 state 目录（测试用）
+	Env          string // 环境名（写入快照的 cloudcode-env 标签，空表示当前环境）
+	Version      string // CloudCode 版本号（写入备份记录）
+	SSHDialFunc  SSHDialFactory
+	WaitInterval time.Duration // 轮询间隔（测试用）
+	WaitTimeout  time.Duration // 等待超时（测试用）
+}
+
+func (m *BackupManager) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.Output, format, args...)
+}
+
+func (m *BackupManager) envName() string {
+	if m.Env != "" {
+		return m.Env
+	}
+	return config.ActiveEnv()
+}
+
+func (m *BackupManager) getStateDir() string {
+	if m.StateDir != "" {
+		return m.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
+
+// loadInstance 加载 state，要求实例存在（running 或 suspended）
+func (m *BackupManager) loadInstance() (*config.State, error) {
+	state, err := loadStateFrom(m.getStateDir())
+	if err != nil {
+		return nil, fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	if state.Status == "destroyed" || !state.HasECS() {
+		return nil, fmt.Errorf("实例已销毁，无法创建备份")
+	}
+	return state, nil
+}
+
+// Create 为系统盘创建快照并记录到备份目录。quiesce 时快照前停止容器、快照创建后立即启动。
+func (m *BackupManager) Create(ctx context.Context, label string, quiesce bool) (*config.Backup, error) {
+	state, err := m.loadInstance()
+	if err != nil {
+		return nil, err
+	}
+	if state.Status == "suspended" {
+		quiesce = false // 停机中磁盘本身一致
+	}
+
+	var sshClient remote.SSHClient
+	if quiesce {
+		privateKey, err := readSSHKeyFrom(m.getStateDir(), state)
+		if err != nil {
+			return nil, err
+		}
+		dialFunc := m.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+		sshClient, err = remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+		if err != nil {
+			return nil, fmt.Errorf("SSH 连接失败: %w", err)
+		}
+		defer sshClient.Close()
+		m.printf("  停止容器...\n")
+		if _, err := sshClient.RunCommand(ctx, quiesceCommand); err != nil {
+			sshClient.RunCommand(ctx, unquiesceCommand)
+			return nil, fmt.Errorf("停止容器失败: %w", err)
+		}
+	}
+
+	m.printf("  创建快照...\n")
+	backup, snapErr := m.snapshot(state, label)
+	if quiesce {
+		// 快照在 CreateSnapshot 返回时已确定数据时间点，无需等待完成即可恢复服务
+		if _, err := sshClient.RunCommand(ctx, unquiesceCommand); err != nil {
+			m.printf("  ⚠ 启动容器失败，请运行 cloudcode deploy --app: %v\n", err)
+		} else {
+			m.printf("  ✓ 容器已恢复\n")
+		}
+	}
+	if snapErr != nil {
+		return nil, snapErr
+	}
+	backup.Quiesced = quiesce
+
+	if err := alicloud.WaitForSnapshotReady(ctx, m.ECS, backup.SnapshotID, m.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+		return nil, err
+	}
+	if err := m.record(*backup); err != nil {
+		return nil, err
+	}
+	m.printf("✅ 备份已创建: %s", backup.SnapshotID)
+	if label != "" {
+		m.printf("（%s）", label)
+	}
+	m.printf("\n")
+	return backup, nil
+}
+
+// snapshot 为实例系统盘创建快照（不等待完成），返回备份记录
+func (m *BackupManager) snapshot(state *config.State, label string) (*config.Backup, error) {
+	diskID, err := alicloud.GetSystemDiskID(m.ECS, state.Resources.ECS.ID, m.Region)
+	if err != nil {
+		return nil, err
+	}
+	return createBackupSnapshot(m.ECS, state, diskID, m.Region, m.envName(), m.Version, label)
+}
+
+func (m *BackupManager) record(backup config.Backup) error {
+	dir := m.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil {
+		return err
+	}
+	catalog.Add(backup)
+	return config.SaveBackupCatalogTo(dir, catalog)
+}
+
+// List 列出备份目录中的快照及其云上状态
+func (m *BackupManager) List() error {
+	dir := m.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil {
+		return err
+	}
+	if len(catalog.Backups) == 0 {
+		m.printf("暂无备份。创建备份: cloudcode backup create\n")
+		return nil
+	}
+	restorePoint := ""
+	if b, _ := config.LoadBackupFrom(dir); b != nil {
+		restorePoint = b.SnapshotID
+	}
+
+	var statuses map[string]string
+	if m.ECS != nil {
+		ids := make([]string, 0, len(catalog.Backups))
+		for _, b := range catalog.Backups {
+			ids = append(ids, b.SnapshotID)
+		}
+		if statuses, err = alicloud.SnapshotStatuses(m.ECS, m.Region, ids); err != nil {
+			m.printf("⚠ %v\n", err)
+		}
+	}
+
+	m.printf("%-26s %-17s %-16s %6s  %s\n", "快照", "时间", "标签", "磁盘", "状态")
+	for _, b := range catalog.Backups {
+		status := "-"
+		if statuses != nil {
+			if status = statuses[b.SnapshotID]; status == "" {
+				status = "已删除"
+			}
+		}
+		var notes []string
+		if b.Quiesced {
+			notes = append(notes, "已停容器")
+		}
+		if b.SnapshotID == restorePoint {
+			notes = append(notes, "deploy 恢复点")
+		}
+		if len(notes) > 0 {
+			status += "（" + strings.Join(notes, "，") + "）"
+		}
+		label := b.Label
+		if label == "" {
+			label = "-"
+		}
+		m.printf("%-26s %-17s %-16s %4dGB  %s\n", b.SnapshotID, formatBackupTime(b.CreatedAt), label, b.DiskSize, status)
+	}
+	return nil
+}
+
+// Prune 按保留策略删除快照。deploy 恢复点（backup.json）始终保留。
+func (m *BackupManager) Prune(policy config.RetentionPolicy, dryRun, force bool) error {
+	if policy.IsZero() {
+		return fmt.Errorf("请至少指定 --keep-last、--keep-daily 或 --keep-weekly")
+	}
+	dir := m.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil {
+		return err
+	}
+	restorePoint := ""
+	if b, _ := config.LoadBackupFrom(dir); b != nil {
+		restorePoint = b.SnapshotID
+	}
+
+	var prune []config.Backup
+	for _, b := range policy.Apply(catalog.Backups, time.Local) {
+		if b.SnapshotID != restorePoint {
+			prune = append(prune, b)
+		}
+	}
+	if len(prune) == 0 {
+		m.printf("没有需要清理的备份（共 %d 份）。\n", len(catalog.Backups))
+		return nil
+	}
+
+	m.printf("将删除 %d 份备份（保留 %d 份）:\n", len(prune), len(catalog.Backups)-len(prune))
+	for _, b := range prune {
+		m.printf("  - %s  %s  %s\n", b.SnapshotID, formatBackupTime(b.CreatedAt), b.Label)
+	}
+	if dryRun {
+		m.printf("\n(dry-run 模式，不会实际删除)\n")
+		return nil
+	}
+	if !force {
+		confirmed, err := m.Prompter.PromptConfirm("确认删除?", false)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			m.printf("已取消。\n")
+			return nil
+		}
+	}
+
+	var failed int
+	for _, b := range prune {
+		if err := alicloud.DeleteSnapshot(m.ECS, b.SnapshotID); err != nil && !alicloud.IsNotFound(err) {
+			m.printf("  ⚠ %s: %v\n", b.SnapshotID, err)
+			failed++
+			continue
+		}
+		catalog.Remove(b.SnapshotID)
+		m.printf("  ✓ 已删除 %s\n", b.SnapshotID)
+	}
+	if err := config.SaveBackupCatalogTo(dir, catalog); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 份备份删除失败", failed)
+	}
+	return nil
+}
+
+// Restore 从备份恢复。实例存在时替换系统盘（先为当前磁盘创建 pre-restore 备份，除非 skipBackup），
+// 实例已销毁时将该备份设为 deploy 恢复点，返回 true 表示需要运行 deploy 完成恢复。
+func (m *BackupManager) Restore(ctx context.Context, ref string, force, skipBackup bool) (bool, error) {
+	dir := m.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil {
+		return false, err
+	}
+	backup, err := catalog.Find(ref)
+	if err != nil {
+		return false, err
+	}
+	if backup.Region != "" && backup.Region != m.Region {
+		return false, fmt.Errorf("备份 %s 位于区域 %s，当前区域为 %s", backup.SnapshotID, backup.Region, m.Region)
+	}
+
+	state, err := loadStateFrom(dir)
+	if err != nil || state.Status == "destroyed" {
+		// 实例不存在：设为 deploy 恢复点，沿用 deploy 的快照恢复流程
+		if err != nil {
+			state = config.NewState(m.Region, "")
+			state.SetStatus("destroyed")
+			if err := saveStateTo(dir, state); err != nil {
+				return false, err
+			}
+		}
+		if err := config.SaveBackupTo(dir, backup); err != nil {
+			return false, err
+		}
+		m.printf("✅ 已将 %s 设为恢复点\n", backup.SnapshotID)
+		return true, nil
+	}
+	if !state.HasECS() {
+		return false, fmt.Errorf("部署未完成，请先完成 cloudcode deploy 或运行 cloudcode destroy")
+	}
+
+	m.printf("将用备份 %s（%s", backup.SnapshotID, formatBackupTime(backup.CreatedAt))
+	if backup.Label != "" {
+		m.printf("，%s", backup.Label)
+	}
+	m.printf("）替换实例 %s 的系统盘。\n", state.Resources.ECS.ID)
+	if !force {
+		question := "当前系统盘数据将被替换，确认恢复?"
+		if !skipBackup {
+			question = "当前系统盘会先备份（标签 " + BackupLabelPreRestore + "），确认恢复?"
+		}
+		confirmed, err := m.Prompter.PromptConfirm(question, false)
+		if err != nil {
+			return false, err
+		}
+		if !confirmed {
+			m.printf("已取消。\n")
+			return false, nil
+		}
+	}
+
+	if err := m.replaceSystemDisk(ctx, state, backup, skipBackup); err != nil {
+		return false, err
+	}
+	m.printf("✅ 已从备份 %s 恢复\n", backup.SnapshotID)
+	m.printf("  访问地址: https://%s\n", state.CloudCode.Domain)
+	return false, nil
+}
+
+// replaceSystemDisk 停机 →（备份当前磁盘）→ 快照创建镜像 → 替换系统盘 → 启动 → 删除临时镜像
+func (m *BackupManager) replaceSystemDisk(ctx context.Context, state *config.State, backup *config.Backup, skipBackup bool) error {
+	instanceID := state.Resources.ECS.ID
+	dir := m.getStateDir()
+
+	m.printf("  停机中...\n")
+	if err := alicloud.StopECSInstance(m.ECS, instanceID, false); err != nil {
+		return fmt.Errorf("停机失败: %w", err)
+	}
+	if err := alicloud.WaitForInstanceStatus(ctx, m.ECS, instanceID, m.Region, "Stopped", m.WaitInterval, m.WaitTimeout); err != nil {
+		return fmt.Errorf("等待停机失败: %w", err)
+	}
+	state.SetStatus("suspended")
+	if err := saveStateTo(dir, state); err != nil {
+		return err
+	}
+	m.printf("  ✓ 已停机\n")
+
+	if !skipBackup {
+		pre, err := m.snapshot(state, BackupLabelPreRestore)
+		if err != nil {
+			return err
+		}
+		if err := alicloud.WaitForSnapshotReady(ctx, m.ECS, pre.SnapshotID, m.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+			return err
+		}
+		if err := m.record(*pre); err != nil {
+			return err
+		}
+		m.printf("  ✓ 当前系统盘已备份 (%s)\n", pre.SnapshotID)
+	}
+
+	imageID, err := alicloud.CreateImageFromSnapshot(m.ECS, backup.SnapshotID, m.Region, "cloudcode-restore-"+time.Now().UTC().Format("20060102-150405"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := alicloud.DeleteImage(m.ECS, imageID, m.Region); err != nil {
+			m.printf("  ⚠ 临时镜像 %s 删除失败，请手动清理: %v\n", imageID, err)
+		}
+	}()
+	if err := alicloud.WaitForImageReady(ctx, m.ECS, imageID, m.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+		return err
+	}
+	m.printf("  ✓ 已从快照创建镜像 (%s)\n", imageID)
+
+	diskSize := state.Resources.ECS.SystemDiskSize
+	if backup.DiskSize > diskSize {
+		diskSize = backup.DiskSize
+	}
+	diskID, err := alicloud.ReplaceSystemDisk(m.ECS, instanceID, imageID, state.Resources.SSHKeyPair.Name, diskSize)
+	if err != nil {
+		return err
+	}
+	if err := alicloud.WaitForSystemDisk(ctx, m.ECS, instanceID, diskID, m.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+		return err
+	}
+	if diskSize > 0 {
+		state.Resources.ECS.SystemDiskSize = diskSize
+	}
+	// 新系统盘首次启动时 cloud-init 可能重新生成主机密钥，清除记录，下次连接时重新记录
+	state.Resources.ECS.HostKey = ""
+	if err := config.ClearHostKey(dir); err != nil {
+		return err
+	}
+	m.printf("  ✓ 系统盘已替换 (%s)\n", diskID)
+
+	if err := alicloud.StartECSInstance(m.ECS, instanceID); err != nil {
+		return fmt.Errorf("启动失败: %w", err)
+	}
+	if _, err := alicloud.WaitForInstanceRunning(ctx, m.ECS, instanceID, m.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+		return fmt.Errorf("等待启动完成失败: %w", err)
+	}
+	state.SetStatus("running")
+	if err := saveStateTo(dir, state); err != nil {
+		return err
+	}
+	m.printf("  ✓ ECS 已启动\n")
+	return nil
+}
+
+// createBackupSnapshot 为 diskID 创建快照（不等待完成，快照标签记录环境 env），返回带部署信息的备份记录
+func createBackupSnapshot(ecsCli alicloud.ECSAPI, state *config.State, diskID, region, env, version, label string) (*config.Backup, error) {
+	now := time.Now().UTC()
+	snapshotName := fmt.Sprintf("cloudcode-%s", now.Format("20060102-150405"))
+	tags := alicloud.ResourceTags(state.DeploymentID, env, version)
+	snapshotID, err := alicloud.CreateDiskSnapshot(ecsCli, diskID, snapshotName, tags...)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Backup{
+		CloudCodeVersion: version,
+		SnapshotID:       snapshotID,
+		CreatedAt:        now.Format(time.RFC3339),
+		Label:            label,
+		Region:           region,
+		DiskSize:         state.Resources.ECS.SystemDiskSize,
+		DiskCategory:     state.Resources.ECS.SystemDiskCategory,
+		InstanceType:     state.Resources.ECS.InstanceType,
+		Domain:           state.CloudCode.Domain,
+		Username:         state.CloudCode.Username,
+		DevboxMode:       state.CloudCode.DevboxMode,
+		AutoSuspend:      state.CloudCode.AutoSuspend,
+		Authelia:         state.CloudCode.Authelia,
+	}, nil
+}
+
+// formatBackupTime 将 RFC 3339 时间转为本地时间显示
+func formatBackupTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
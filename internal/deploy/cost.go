@@ -0,0 +1,217 @@
+package deploy
+
+// cost.go 实现 cloudcode cost：按价格表估算当前部署（或指定规格）的每小时 / 每月费用，
+// 并根据 state 中的状态变更记录统计自部署以来的运行 / 停机时长和累计费用。
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
+)
+
+// CostRunner 费用估算
+type CostRunner struct {
+	Output   io.Writer
+	StateDir string
+	Region   string           // 无部署记录时估算的区域
+	Prices   *cost.PriceTable // 默认使用内置价格表
+	Spec     config.CloudSpec // 覆盖部署记录中的规格（估算变更规格后的费用）
+	Now      func() time.Time // 测试用，默认 time.Now
+}
+
+func (c *CostRunner) printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.Output, format, args...)
+}
+
+// Run 输出费用估算
+func (c *CostRunner) Run() error {
+	stateDir := c.StateDir
+	if stateDir == "" {
+		var err error
+		if stateDir, err = config.GetActiveEnvDir(); err != nil {
+			return err
+		}
+	}
+	prices := c.Prices
+	if prices == nil {
+		prices = cost.BuiltinPrices()
+	}
+
+	state, err := loadStateFrom(stateDir)
+	if err != nil && err != config.ErrStateNotFound {
+		return err
+	}
+	backup, _ := config.LoadBackupFrom(stateDir)
+	catalog, _ := config.LoadBackupCatalogFrom(stateDir)
+
+	var spec cost.Spec
+	status := "running"
+	if state != nil {
+		spec = costSpecOf(state, backup, catalog)
+		status = state.Status
+		if status == "" {
+			status = "running"
+		}
+	} else {
+		spec = cost.Spec{
+			Region:       c.Region,
+			InstanceType: alicloud.DefaultInstanceType,
+			DiskSize:     alicloud.DefaultSystemDiskSize,
+			DiskCategory: alicloud.DefaultSystemDiskCategory,
+			EIP:          true,
+		}
+		c.printf("未找到部署记录，按默认规格估算。\n")
+	}
+	if c.Spec.InstanceType != "" {
+		spec.InstanceType = c.Spec.InstanceType
+	}
+	if c.Spec.DiskSize != 0 {
+		spec.DiskSize = c.Spec.DiskSize
+	}
+	if c.Spec.DiskCategory != "" {
+		spec.DiskCategory = c.Spec.DiskCategory
+	}
+
+	e := prices.Estimate(spec)
+	money := func(v float64) string { return formatMoney(e.Currency, v, 2) }
+
+	c.printf("费用估算（%s，按量付费参考价，以阿里云账单为准）\n\n", spec.Region)
+	line := func(name string, hourly float64, note string) {
+		c.printf("  %-32s %10s/小时 %10s/月", name, formatMoney(e.Currency, hourly, 4), money(hourly*cost.HoursPerMonth))
+		if note != "" {
+			c.printf("  %s", note)
+		}
+		c.printf("\n")
+	}
+	instanceNote := "停机时不收费"
+	if state != nil && state.Resources.ECS.Spot {
+		instanceNote = "抢占式实例按市场价计费，通常远低于此价"
+	}
+	line("ECS 实例 "+spec.InstanceType, e.Instance, instanceNote)
+	line(fmt.Sprintf("系统盘 %s %dGB", spec.DiskCategory, spec.DiskSize), e.Disk, "")
+	if spec.EIP {
+		line("EIP", e.EIP, fmt.Sprintf("另按流量 %s/GB", formatMoney(e.Currency, e.EIPTrafficGB, 3)))
+	}
+	if spec.SnapshotGB > 0 {
+		line(fmt.Sprintf("快照 %dGB", spec.SnapshotGB), e.Snapshot, "含所有备份，按源磁盘容量估算")
+	}
+
+	c.printf("\n")
+	total := func(label, s string) {
+		marker := ""
+		if s == status {
+			marker = "  ← 当前"
+		}
+		c.printf("  %-8s %s/月%s\n", label, money(e.Monthly(s)), marker)
+	}
+	total("运行中:", "running")
+	total("停机:", "suspended")
+	if spec.SnapshotGB > 0 {
+		total("已销毁:", "destroyed")
+	}
+	if status == "reclaimed" {
+		total("已回收:", "reclaimed")
+	}
+	if len(e.Missing) > 0 {
+		c.printf("\n⚠ 价格表中缺少以下条目（按 0 计）:\n")
+		for _, m := range e.Missing {
+			c.printf("  - %s\n", m)
+		}
+		c.printf("  可在 ~/.cloudcode/%s 中补充（格式同内置价格表，见 README）\n", cost.PricesFileName)
+	}
+
+	if state == nil {
+		return nil
+	}
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	usage, err := cost.UsageOf(state, now())
+	if err != nil {
+		return err
+	}
+	c.printf("\n累计（自 %s 起）:\n", usage.Since.Local().Format("2006-01-02 15:04"))
+	c.printf("  运行 %.1f 小时，停机 %.1f 小时", usage.Durations["running"].Hours(), usage.Durations["suspended"].Hours())
+	if d := usage.Durations["destroyed"]; d > 0 {
+		c.printf("，已销毁 %.1f 小时", d.Hours())
+	}
+	c.printf("\n  估算费用: %s（不含流量）\n", money(usage.Cost(e)))
+	if !usage.Recorded && status != "running" {
+		c.printf("  ⚠ 旧版本未记录停机 / 恢复时间，累计时长全部按运行计算\n")
+	}
+	return nil
+}
+
+// costSpecOf 从 state、backup.json 和备份目录构造估算对象（旧版 state 缺少的规格按默认值）
+func costSpecOf(state *config.State, backup *config.Backup, catalog *config.BackupCatalog) cost.Spec {
+	spec := cost.Spec{
+		Region:       state.Region,
+		InstanceType: state.Resources.ECS.InstanceType,
+		DiskSize:     state.Resources.ECS.SystemDiskSize,
+		DiskCategory: state.Resources.ECS.SystemDiskCategory,
+		EIP:          state.HasEIP(),
+	}
+	if state.Status == "destroyed" && backup != nil {
+		spec.InstanceType = backup.InstanceType
+		spec.DiskSize = backup.DiskSize
+		spec.DiskCategory = backup.DiskCategory
+		spec.EIP = true
+	}
+	if spec.InstanceType == "" {
+		spec.InstanceType = alicloud.DefaultInstanceType
+	}
+	if spec.DiskSize == 0 {
+		spec.DiskSize = alicloud.DefaultSystemDiskSize
+	}
+	if spec.DiskCategory == "" {
+		spec.DiskCategory = alicloud.DefaultSystemDiskCategory
+	}
+
+	// 快照：备份目录中的所有快照，以及不在目录中的 backup.json 快照（旧版 destroy 创建）
+	snapshotGB := func(b *config.Backup) int {
+		if b.DiskSize > 0 {
+			return b.DiskSize
+		}
+		return spec.DiskSize
+	}
+	if catalog != nil {
+		for i := range catalog.Backups {
+			spec.SnapshotGB += snapshotGB(&catalog.Backups[i])
+		}
+	}
+	if backup != nil && backup.SnapshotID != "" && (catalog == nil || !catalog.Contains(backup.SnapshotID)) {
+		spec.SnapshotGB += snapshotGB(backup)
+	}
+	return spec
+}
+
+// suspendedMonthly 返回停机后的每月费用提示（如 "~$4.12/月"），价格表缺少条目时返回空
+func suspendedMonthly(prices *cost.PriceTable, state *config.State, stateDir string) string {
+	if prices == nil {
+		prices = cost.BuiltinPrices()
+	}
+	backup, _ := config.LoadBackupFrom(stateDir)
+	catalog, _ := config.LoadBackupCatalogFrom(stateDir)
+	e := prices.Estimate(costSpecOf(state, backup, catalog))
+	if len(e.Missing) > 0 {
+		return ""
+	}
+	return "~" + formatMoney(e.Currency, e.Monthly("suspended"), 2) + "/月"
+}
+
+// formatMoney 按币种格式化金额，decimals 为小数位数
+func formatMoney(currency string, v float64, decimals int) string {
+	switch currency {
+	case "USD", "":
+		return fmt.Sprintf("$%.*f", decimals, v)
+	case "CNY":
+		return fmt.Sprintf("¥%.*f", decimals, v)
+	default:
+		return fmt.Sprintf("%.*f %s", decimals, v, currency)
+	}
+}
@@ -12,7 +12,7 @@ package deploy
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -26,16 +26,77 @@ import (
 	tmpl "github.com/hwuu/cloudcode/internal/template"
 )
 
+// 云资源名称（deploy 创建时使用，drift/import 按名称查找）
+const (
+	ResourceNameVPC           = "cloudcode-vpc"
+	ResourceNameVSwitch       = "cloudcode-vswitch"
+	ResourceNameSecurityGroup = "cloudcode-sg"
+	ResourceNameECS           = "cloudcode-ecs"
+	ResourceNameEIP           = "cloudcode-eip"
+)
+
+// remoteAutheliaConfigPath 实例上 Authelia 配置文件路径（DeployApp 从中读回已有密钥）
+const remoteAutheliaConfigPath = "/root/cloudcode/authelia/configuration.yml"
+
 // DeployConfig 保存交互收集的部署配置（阶段 2 的输出，阶段 4 的输入）
 type DeployConfig struct {
-	Domain          string // 域名（留空则使用 EIP.nip.io）
-	Username        string // Authelia 管理员用户名
-	Password        string // 管理员密码（明文，部署时哈希）
-	Email           string // 管理员邮箱
-	OpenAIAPIKey    string // OpenAI API Key
-	OpenAIBaseURL   string // OpenAI Base URL（可选，兼容第三方）
-	AnthropicAPIKey string // Anthropic API Key（可选）
-	SSHIP           string // SSH 安全组源 IP 限制（CIDR 格式，空表示不限制）
+	Domain   string            // 域名（留空则使用 EIP.nip.io）
+	Username string            // Authelia 管理员用户名
+	Password string            // 管理员密码（明文，部署时哈希）
+	Email    string            // 管理员邮箱
+	Secrets  map[string]string // 新增 / 覆盖的 provider API Key（合并到本地 secrets.env 后写入 .env）
+	SSHIP    string            // SSH 安全组源 IP 限制（CIDR 格式，空表示不限制）
+
+	SessionExpiration string // Authelia 会话有效期（空使用模板默认值）
+	SessionInactivity string // Authelia 会话闲置超时
+	AccessPolicy      string // Authelia 主域名访问策略
+}
+
+// NewDeployConfigFromFile 将部署配置文件转换为 DeployConfig（调用方需先通过 LoadDeployFile 校验）
+func NewDeployConfigFromFile(f *config.DeployFile) *DeployConfig {
+	cfg := &DeployConfig{
+		Domain:            f.Domain,
+		Username:          f.Admin.Username,
+		Password:          f.Admin.Password,
+		Email:             f.Admin.Email,
+		Secrets:           f.APIKeys.Secrets(),
+		SessionExpiration: f.Authelia.SessionExpiration,
+		SessionInactivity: f.Authelia.SessionInactivity,
+		AccessPolicy:      f.Authelia.Policy,
+	}
+	if f.SSHIP != "" {
+		cfg.SSHIP, _ = config.NormalizeCIDR(f.SSHIP)
+	}
+	if cfg.Username == "" {
+		cfg.Username = "admin"
+	}
+	if cfg.Email == "" {
+		cfg.Email = cfg.Username + "@localhost"
+	}
+	return cfg
+}
+
+// autheliaSettings 返回写入 state 的 Authelia 设置（全部为默认值时返回 nil）
+func (cfg *DeployConfig) autheliaSettings() *config.AutheliaSettings {
+	s := config.AutheliaSettings{
+		SessionExpiration: cfg.SessionExpiration,
+		SessionInactivity: cfg.SessionInactivity,
+		AccessPolicy:      cfg.AccessPolicy,
+	}
+	if s == (config.AutheliaSettings{}) {
+		return nil
+	}
+	return &s
+}
+
+// setAutheliaSettings 使用记录的 Authelia 设置（s 为 nil 时保持默认值）
+func (cfg *DeployConfig) setAutheliaSettings(s *config.AutheliaSettings) {
+	if s == nil {
+		return
+	}
+	cfg.SessionExpiration = s.SessionExpiration
+	cfg.SessionInactivity = s.SessionInactivity
+	cfg.AccessPolicy = s.AccessPolicy
 }
 
 // SSHDialFactory 创建 SSH DialFunc 的工厂函数
@@ -49,22 +110,30 @@ type GetPublicIPFunc func() (string, error)
 
 // Deployer 部署编排器，通过依赖注入支持测试
 type Deployer struct {
-	ECS              alicloud.ECSAPI
-	VPC              alicloud.VPCAPI
-	STS              alicloud.STSAPI
-	DNS              alicloud.DnsAPI
-	Prompter         *config.Prompter
-	Output           io.Writer
-	Region           string
-	StateDir         string // 覆盖默认 state 目录（测试用）
-	SSHDialFunc      SSHDialFactory
-	SFTPFactory      SFTPClientFactory
-	GetPublicIP      GetPublicIPFunc
-	WaitInterval     time.Duration // ECS 等待轮询间隔（测试用，默认 5s）
-	WaitTimeout      time.Duration // ECS 等待超时（测试用，默认 5min）
-	Version          string        // Docker 镜像版本号
-	DNSWaitTimeout   time.Duration // DNS 生效等待超时（默认 5min）
-	SnapshotID       string        // 从快照恢复时的快照 ID
+	ECS            alicloud.ECSAPI
+	VPC            alicloud.VPCAPI
+	STS            alicloud.STSAPI
+	DNS            alicloud.DnsAPI
+	RAM            alicloud.RAMAPI
+	Prompter       *config.Prompter
+	Output         io.Writer
+	Region         string
+	StateDir       string // 覆盖默认 state 目录（测试用）
+	Env            string // 环境名（决定 SSH 密钥对名称，空表示当前环境）
+	SSHDialFunc    SSHDialFactory
+	SFTPFactory    SFTPClientFactory
+	GetPublicIP    GetPublicIPFunc
+	WaitInterval   time.Duration    // ECS 等待轮询间隔（测试用，默认 5s）
+	WaitTimeout    time.Duration    // ECS 等待超时（测试用，默认 5min）
+	Version        string           // Docker 镜像版本号
+	DNSWaitTimeout time.Duration    // DNS 生效等待超时（默认 5min）
+	SnapshotID     string           // 从快照恢复时的快照 ID
+	Spec           config.CloudSpec // 实例规格、镜像、系统盘、可用区（零值字段使用默认值）
+	Config         *DeployConfig    // 配置文件提供的部署配置（--config）
+	NonInteractive bool             // 禁止交互提示，缺少必填项时直接失败
+	DevboxMode     string           // shared / per-user（空表示沿用 state 中记录的模式）
+	AutoSuspend    *config.AutoSuspendPolicy // 配置文件中的自动停机策略（nil 表示沿用 state 中记录的策略）
+	AgentBinary    AgentBinaryFunc           // 自动停机 agent 二进制（测试用）
 }
 
 func (d *Deployer) printf(format string, args ...interface{}) {
@@ -105,32 +174,123 @@ func (d *Deployer) PromptConfig(ctx context.Context) (*DeployConfig, error) {
 	cfg.Username = username
 
 	// 密码
+	if cfg.Password, err = d.promptPassword(); err != nil {
+		return nil, err
+	}
+
+	// 邮箱使用默认值
+	cfg.Email = username + "@localhost"
+
+	// LLM provider API Key（已配置过的环境不再询问）
+	if cfg.Secrets, err = d.promptMissingKeys(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// promptFileConfig 以配置文件为基础，只询问文件中缺少的管理员密码和 API Key
+func (d *Deployer) promptFileConfig() (*DeployConfig, error) {
+	preset := *d.Config
+	cfg := &preset
+	d.printf("\n[2/5] 使用配置文件（补充缺少的配置）:\n")
+	if cfg.Domain != "" {
+		d.printf("  域名: %s\n", cfg.Domain)
+	} else {
+		d.printf("  域名: <EIP>.nip.io\n")
+	}
+	d.printf("  用户名: %s\n", cfg.Username)
+
+	var err error
+	if cfg.Password == "" {
+		if cfg.Password, err = d.promptPassword(); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Secrets) == 0 {
+		if cfg.Secrets, err = d.promptMissingKeys(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// promptPassword 询问并确认管理员密码
+func (d *Deployer) promptPassword() (string, error) {
 	password, err := d.Prompter.PromptPassword("请输入管理员密码: ")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	confirmPassword, err := d.Prompter.PromptPassword("请确认管理员密码: ")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	if password != confirmPassword {
-		return nil, fmt.Errorf("两次输入的密码不一致")
+		return "", fmt.Errorf("两次输入的密码不一致")
 	}
-	cfg.Password = password
+	return password, nil
+}
 
-	// 邮箱使用默认值
-	cfg.Email = username + "@localhost"
+// promptMissingKeys 环境中还没有 API Key 时询问常用 provider 的 API Key
+func (d *Deployer) promptMissingKeys() (map[string]string, error) {
+	existing, err := config.LoadSecretsFrom(d.getStateDir())
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		d.printf("  已配置 %d 个 API Key（cloudcode secrets list 查看）\n", len(existing))
+		return nil, nil
+	}
+	return d.promptProviderKeys()
+}
 
-	return cfg, nil
+// promptProviderKeys 询问常用 provider 的 API Key，均可留空
+func (d *Deployer) promptProviderKeys() (map[string]string, error) {
+	d.printf("\n配置 LLM Provider API Key（留空跳过；Gemini、DeepSeek 等可稍后用 cloudcode secrets set 添加）:\n")
+	secrets := make(map[string]string)
+
+	openAIKey, err := d.Prompter.PromptPassword("OpenAI API Key: ")
+	if err != nil {
+		return nil, err
+	}
+	if openAIKey != "" {
+		secrets["OPENAI_API_KEY"] = openAIKey
+		baseURL, err := d.Prompter.Prompt("OpenAI Base URL (留空使用官方接口): ")
+		if err != nil {
+			return nil, err
+		}
+		if baseURL != "" {
+			secrets["OPENAI_BASE_URL"] = baseURL
+		}
+	}
+
+	anthropicKey, err := d.Prompter.PromptPassword("Anthropic API Key: ")
+	if err != nil {
+		return nil, err
+	}
+	if anthropicKey != "" {
+		secrets["ANTHROPIC_API_KEY"] = anthropicKey
+	}
+
+	if len(secrets) == 0 {
+		d.printf("  ⚠ 未配置任何 API Key，OpenCode 启动后需要先运行 cloudcode secrets set <NAME>\n")
+	}
+	return secrets, nil
 }
 
 // CreateResources 创建云资源（幂等：跳过已存在的资源）
 func (d *Deployer) CreateResources(ctx context.Context, state *config.State, sshIP string) error {
 	d.printf("\n[3/5] 创建云资源:\n")
 
+	// 旧版 state 没有部署 ID，补齐后再创建资源
+	if state.DeploymentID == "" {
+		state.DeploymentID = config.NewDeploymentID()
+	}
+	tags := alicloud.ResourceTags(state.DeploymentID, d.envName(), d.Version)
+
 	// VPC
 	if !state.HasVPC() {
-		vpc, err := alicloud.CreateVPC(d.VPC, d.Region, "cloudcode-vpc")
+		vpc, err := alicloud.CreateVPC(d.VPC, d.Region, ResourceNameVPC, tags...)
 		if err != nil {
 			return err
 		}
@@ -146,19 +306,21 @@ func (d *Deployer) CreateResources(ctx context.Context, state *config.State, ssh
 		d.printf("  ✓ VPC 已存在 (%s)\n", state.Resources.VPC.ID)
 	}
 
+	spec := d.instanceSpec()
+
 	// 可用区选择
 	zoneID := state.Resources.VSwitch.ZoneID
 	if zoneID == "" {
 		var err error
-		zoneID, err = alicloud.SelectAvailableZone(d.ECS, d.Region, alicloud.DefaultInstanceType, alicloud.DefaultZonePriority)
+		zoneID, err = alicloud.SelectAvailableZone(d.ECS, d.Region, spec.InstanceType, d.preferredZones())
 		if err != nil {
-			return err
+			return fmt.Errorf("选择可用区失败（规格 %s）: %w", spec.InstanceType, err)
 		}
 	}
 
 	// VSwitch
 	if !state.HasVSwitch() {
-		vswitch, err := alicloud.CreateVSwitch(d.VPC, state.Resources.VPC.ID, zoneID, "192.168.1.0/24", "cloudcode-vswitch")
+		vswitch, err := alicloud.CreateVSwitch(d.VPC, state.Resources.VPC.ID, zoneID, "192.168.1.0/24", ResourceNameVSwitch, tags...)
 		if err != nil {
 			return err
 		}
@@ -173,7 +335,7 @@ func (d *Deployer) CreateResources(ctx context.Context, state *config.State, ssh
 
 	// 安全组
 	if !state.HasSecurityGroup() {
-		sg, err := alicloud.CreateSecurityGroup(d.ECS, state.Resources.VPC.ID, d.Region, "cloudcode-sg")
+		sg, err := alicloud.CreateSecurityGroup(d.ECS, state.Resources.VPC.ID, d.Region, ResourceNameSecurityGroup, tags...)
 		if err != nil {
 			return err
 		}
@@ -196,18 +358,21 @@ func (d *Deployer) CreateResources(ctx context.Context, state *config.State, ssh
 
 	// SSH 密钥对
 	if !state.HasSSHKeyPair() {
-		keyPair, err := alicloud.CreateSSHKeyPair(d.ECS, alicloud.DefaultSSHKeyName, d.Region)
+		keyPair, err := alicloud.CreateSSHKeyPair(d.ECS, alicloud.SSHKeyNameForEnv(d.envName()), d.Region, tags...)
 		if err != nil {
 			return err
 		}
 		// 保存私钥到本地
-		keyPath := filepath.Join(d.getStateDir(), "ssh_key")
+		if err := os.MkdirAll(d.getStateDir(), 0700); err != nil {
+			return fmt.Errorf("创建状态目录失败: %w", err)
+		}
+		keyPath := filepath.Join(d.getStateDir(), config.SSHKeyFileName)
 		if err := os.WriteFile(keyPath, []byte(keyPair.PrivateKey), 0600); err != nil {
 			return fmt.Errorf("保存 SSH 私钥失败: %w", err)
 		}
 		state.Resources.SSHKeyPair = config.SSHKeyPairResource{
 			Name:           keyPair.Name,
-			PrivateKeyPath: ".cloudcode/ssh_key",
+			PrivateKeyPath: config.EnvKeyRelPath(d.envName()),
 		}
 		if err := d.saveState(state); err != nil {
 			return err
@@ -218,20 +383,37 @@ func (d *Deployer) CreateResources(ctx context.Context, state *config.State, ssh
 	}
 
 	// ECS 实例
-	if !state.HasECS() {
+	createdECS := !state.HasECS()
+	if createdECS {
+		// 未指定镜像时查找区域内最新的 Ubuntu 24.04（从快照恢复时使用快照镜像）
+		if spec.ImageID == "" && d.SnapshotID == "" {
+			imageID, err := alicloud.FindLatestUbuntuImage(d.ECS, d.Region)
+			if err != nil {
+				return fmt.Errorf("%w（可用 --image 指定镜像 ID）", err)
+			}
+			spec.ImageID = imageID
+			d.printf("  ✓ 使用镜像 %s\n", imageID)
+		}
+		spec = spec.WithDefaults()
+
 		ecs, err := alicloud.CreateECSInstance(
-			d.ECS, d.Region, zoneID,
-			alicloud.DefaultInstanceType, alicloud.DefaultImageID,
+			d.ECS, d.Region, zoneID, spec,
 			state.Resources.SecurityGroup.ID, state.Resources.VSwitch.ID,
-			state.Resources.SSHKeyPair.Name, "cloudcode-ecs", d.SnapshotID,
+			state.Resources.SSHKeyPair.Name, ResourceNameECS, d.SnapshotID, tags...,
 		)
 		if err != nil {
 			return err
 		}
+		if d.SnapshotID == "" {
+			state.OSImage = spec.ImageID
+		}
 		state.Resources.ECS = config.ECSResource{
-			ID:             ecs.ID,
-			InstanceType:   ecs.InstanceType,
-			SystemDiskSize: alicloud.DefaultSystemDiskSize,
+			ID:                 ecs.ID,
+			InstanceType:       ecs.InstanceType,
+			SystemDiskSize:     spec.SystemDiskSize,
+			SystemDiskCategory: spec.SystemDiskCategory,
+			Spot:               spec.Spot,
+			SpotPriceLimit:     spec.SpotPriceLimit,
 		}
 		if err := d.saveState(state); err != nil {
 			return err
@@ -270,7 +452,7 @@ func (d *Deployer) CreateResources(ctx context.Context, state *config.State, ssh
 
 	// EIP
 	if !state.HasEIP() {
-		eip, err := alicloud.AllocateEIP(d.VPC, d.Region, "cloudcode-eip")
+		eip, err := alicloud.AllocateEIP(d.VPC, d.Region, ResourceNameEIP, tags...)
 		if err != nil {
 			return err
 		}
@@ -284,6 +466,16 @@ func (d *Deployer) CreateResources(ctx context.Context, state *config.State, ssh
 			return err
 		}
 		d.printf("  ✓ 分配 EIP (%s) - IP: %s\n", eip.ID, eip.IP)
+	} else if createdECS {
+		// 抢占式实例被回收后重建：保留的 EIP 绑定到新实例，IP 和域名不变
+		if err := alicloud.AssociateEIPToInstance(d.VPC, state.Resources.EIP.ID, state.Resources.ECS.ID, d.Region); err != nil {
+			return fmt.Errorf("绑定 EIP 失败: %w", err)
+		}
+		state.Resources.ECS.PublicIP = state.Resources.EIP.IP
+		if err := d.saveState(state); err != nil {
+			return err
+		}
+		d.printf("  ✓ EIP 已绑定到新实例 (%s) - IP: %s\n", state.Resources.EIP.ID, state.Resources.EIP.IP)
 	} else {
 		d.printf("  ✓ EIP 已存在 (%s) - IP: %s\n", state.Resources.EIP.ID, state.Resources.EIP.IP)
 	}
@@ -384,7 +576,7 @@ func (d *Deployer) DeployApp(ctx context.Context, state *config.State, cfg *Depl
 	dockerCmd := "which docker > /dev/null 2>&1 || (curl -fsSL https://get.docker.com | sh -s -- --mirror Aliyun && systemctl enable docker && systemctl start docker)"
 	cmdCtx, cancel := context.WithTimeout(ctx, remote.DockerInstallTimeout)
 	defer cancel()
-	if _, err := sshClient.RunCommand(cmdCtx, dockerCmd); err != nil {
+	if err := d.runWithProgress(cmdCtx, sshClient, dockerCmd); err != nil {
 		return fmt.Errorf("安装 Docker 失败: %w", err)
 	}
 	d.printf("  ✓ Docker 已就绪\n")
@@ -401,34 +593,66 @@ func (d *Deployer) DeployApp(ctx context.Context, state *config.State, cfg *Depl
 		domain = eipIP + ".nip.io"
 	}
 
-	// 哈希密码
-	hashedPassword, err := config.HashPassword(cfg.Password)
-	if err != nil {
-		return fmt.Errorf("密码哈希失败: %w", err)
+	// 哈希密码（仅首次部署时写入 users_database.yml）
+	var hashedPassword string
+	if cfg.Password != "" {
+		hashedPassword, err = config.HashPassword(cfg.Password)
+		if err != nil {
+			return fmt.Errorf("密码哈希失败: %w", err)
+		}
 	}
 
-	// 生成 secrets
-	sessionSecret, err := config.GenerateSecret()
+	// 合并 provider API Key：本地 secrets.env 为准，本次配置提供的覆盖同名变量
+	envVars, err := d.mergeSecrets(cfg.Secrets)
 	if err != nil {
 		return err
 	}
-	storageKey, err := config.GenerateSecret()
+
+	// Authelia 密钥：沿用实例上已有的，首次部署时生成
+	autheliaSecrets, err := d.autheliaSecrets(ctx, sshClient)
 	if err != nil {
 		return err
 	}
 
+	sftpClient, err := d.SFTPFactory(eipIP, 22, "root", privateKey)
+	if err != nil {
+		return fmt.Errorf("SFTP 连接失败: %w", err)
+	}
+	defer sftpClient.Close()
+
+	// --app 模式或快照恢复时跳过 users_database.yml（账号和密码哈希已在磁盘上）；
+	// configuration.yml 使用沿用的密钥重新渲染，Authelia 设置变更可以随 --app 生效
+	skipUsers := cfg.Password == "" || d.SnapshotID != ""
+
+	// 独立 devbox 模式：每个 Authelia 账号一个 devbox
+	var devboxUsers []tmpl.DevboxUser
+	if state.CloudCode.DevboxMode == config.DevboxModePerUser {
+		usernames := []string{cfg.Username}
+		if skipUsers {
+			if usernames, err = readDevboxUsernames(sftpClient, cfg.Username); err != nil {
+				return err
+			}
+		}
+		if devboxUsers, err = tmpl.NewDevboxUsers(usernames); err != nil {
+			return err
+		}
+		d.printf("  ✓ 独立 devbox 模式: %s\n", strings.Join(usernames, ", "))
+	}
+
 	// 渲染模板
 	templateData := &tmpl.TemplateData{
 		Domain:               domain,
 		Username:             cfg.Username,
 		HashedPassword:       hashedPassword,
 		Email:                cfg.Email,
-		SessionSecret:        sessionSecret,
-		StorageEncryptionKey: storageKey,
-		OpenAIAPIKey:         cfg.OpenAIAPIKey,
-		OpenAIBaseURL:        cfg.OpenAIBaseURL,
-		AnthropicAPIKey:      cfg.AnthropicAPIKey,
+		SessionSecret:        autheliaSecrets.SessionSecret,
+		StorageEncryptionKey: autheliaSecrets.StorageEncryptionKey,
+		Env:                  envVars,
+		SessionExpiration:    cfg.SessionExpiration,
+		SessionInactivity:    cfg.SessionInactivity,
+		AccessPolicy:         cfg.AccessPolicy,
 		Version:              d.Version,
+		DevboxUsers:          devboxUsers,
 	}
 
 	files, err := tmpl.RenderAll(templateData)
@@ -438,23 +662,15 @@ func (d *Deployer) DeployApp(ctx context.Context, state *config.State, cfg *Depl
 	d.printf("  ✓ 配置文件已渲染\n")
 
 	// 上传文件（将 ~/cloudcode 替换为绝对路径）
-	// --app 模式或快照恢复时跳过 authelia 配置（密码哈希、secrets、db 已在磁盘上）
-	skipAuthelia := cfg.Password == "" || d.SnapshotID != ""
 	uploadFiles := make(map[string][]byte)
 	for path, content := range files {
-		if skipAuthelia && strings.Contains(path, "authelia/") {
+		if skipUsers && strings.HasSuffix(path, "authelia/users_database.yml") {
 			continue
 		}
 		remotePath := strings.Replace(path, "~/cloudcode", "/root/cloudcode", 1)
 		uploadFiles[remotePath] = content
 	}
 
-	sftpClient, err := d.SFTPFactory(eipIP, 22, "root", privateKey)
-	if err != nil {
-		return fmt.Errorf("SFTP 连接失败: %w", err)
-	}
-	defer sftpClient.Close()
-
 	if err := remote.UploadFiles(sftpClient, uploadFiles); err != nil {
 		return fmt.Errorf("上传文件失败: %w", err)
 	}
@@ -467,13 +683,13 @@ func (d *Deployer) DeployApp(ctx context.Context, state *config.State, cfg *Depl
 	}{
 		{"Caddy", "caddy"},
 		{"Authelia", "authelia"},
-		{"Devbox", "devbox"},
+		{"Devbox", devboxPullService(devboxUsers)},
 	}
 	for i, img := range images {
 		d.printf("  * 正在拉取 Docker 镜像 (%d/%d) %s...\n", i+1, len(images), img.name)
 		pullCmd := fmt.Sprintf("cd ~/cloudcode && docker compose pull %s", img.service)
 		pullCtx, pullCancel := context.WithTimeout(ctx, 10*time.Minute)
-		if _, err := sshClient.RunCommand(pullCtx, pullCmd); err != nil {
+		if err := d.runWithProgress(pullCtx, sshClient, pullCmd); err != nil {
 			pullCancel()
 			return fmt.Errorf("拉取 %s 镜像失败: %w", img.name, err)
 		}
@@ -481,21 +697,87 @@ func (d *Deployer) DeployApp(ctx context.Context, state *config.State, cfg *Depl
 	}
 	d.printf("  ✓ Docker 镜像已拉取\n")
 
-	// docker compose up（--force-recreate 确保配置变更生效）
-	upCmd := "cd ~/cloudcode && docker compose up -d --force-recreate"
+	// docker compose up（--force-recreate 确保配置变更生效，--remove-orphans 清理切换模式 / 删除账号后多余的 devbox，volume 保留）
+	upCmd := "cd ~/cloudcode && docker compose up -d --force-recreate --remove-orphans"
 	upCtx, upCancel := context.WithTimeout(ctx, remote.DockerInstallTimeout)
 	defer upCancel()
-	if _, err := sshClient.RunCommand(upCtx, upCmd); err != nil {
+	if err := d.runWithProgress(upCtx, sshClient, upCmd); err != nil {
 		return fmt.Errorf("启动 Docker Compose 失败: %w", err)
 	}
 	d.printf("  ✓ Docker Compose 已启动\n")
 
-	// 更新 state 中的域名
+	// 更新 state 中的域名、镜像版本和 Authelia 设置
 	state.CloudCode.Domain = domain
+	state.CloudCode.ImageVersion = templateData.Version
+	state.CloudCode.Authelia = cfg.autheliaSettings()
 
 	return nil
 }
 
+// runWithProgress 执行耗时较长的远程命令（安装 Docker、拉取镜像等），stdout / stderr 逐行缩进显示，
+// 避免长时间没有输出看起来像卡住
+func (d *Deployer) runWithProgress(ctx context.Context, sshClient remote.SSHClient, cmd string) error {
+	w := remote.NewLineWriter(func(line string) {
+		if strings.TrimSpace(line) != "" {
+			d.printf("    %s\n", line)
+		}
+	})
+	err := remote.RunCommandStreaming(ctx, sshClient, cmd, w, w)
+	w.Flush()
+	return err
+}
+
+// autheliaSecrets 从实例上的 configuration.yml 读回 Authelia 密钥；文件不存在时（首次部署）生成新密钥。
+// 文件存在但无法解析时报错而不是重新生成，避免已有会话失效、db.sqlite3 无法解密。
+func (d *Deployer) autheliaSecrets(ctx context.Context, sshClient remote.SSHClient) (*config.AutheliaSecrets, error) {
+	output, err := sshClient.RunCommand(ctx, "cat "+remoteAutheliaConfigPath+" 2>/dev/null || true")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Authelia 配置失败: %w", err)
+	}
+	if strings.TrimSpace(output) == "" {
+		secrets, err := config.NewAutheliaSecrets()
+		if err != nil {
+			return nil, err
+		}
+		d.printf("  ✓ 已生成 Authelia 密钥\n")
+		return secrets, nil
+	}
+	secrets, err := config.ParseAutheliaSecrets([]byte(output))
+	if err != nil {
+		return nil, fmt.Errorf("%w（为避免 db.sqlite3 无法解密，不会重新生成密钥，请检查 %s）", err, remoteAutheliaConfigPath)
+	}
+	d.printf("  ✓ 沿用已有的 Authelia 密钥\n")
+	return secrets, nil
+}
+
+// readDevboxUsernames 读回实例上的 Authelia 账号（未禁用的），作为独立 devbox 的用户列表；
+// 用户数据库不存在时只有部署时的管理员
+func readDevboxUsernames(sftpClient remote.SFTPClient, admin string) ([]string, error) {
+	data, err := sftpClient.ReadFile(remoteUsersDBPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{admin}, nil
+		}
+		return nil, fmt.Errorf("读取用户数据库失败: %w", err)
+	}
+	db, err := config.ParseUsersDatabase(data)
+	if err != nil {
+		return nil, err
+	}
+	if usernames := db.EnabledUsernames(); len(usernames) > 0 {
+		return usernames, nil
+	}
+	return []string{admin}, nil
+}
+
+// devboxPullService 返回拉取 devbox 镜像使用的服务名（独立模式下所有用户共用同一镜像）
+func devboxPullService(users []tmpl.DevboxUser) string {
+	if len(users) > 0 {
+		return users[0].Service
+	}
+	return "devbox"
+}
+
 // HealthCheck 健康检查：通过 SSH 检查容器状态
 func (d *Deployer) HealthCheck(ctx context.Context, state *config.State) error {
 	d.printf("\n[5/5] 验证服务:\n")
@@ -560,6 +842,10 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 			Username: state.CloudCode.Username,
 			Email:    state.CloudCode.Username + "@localhost",
 		}
+		d.applyPresetKeys(cfg, state.CloudCode.Authelia)
+		if d.DevboxMode != "" {
+			state.CloudCode.DevboxMode = d.DevboxMode
+		}
 
 		d.printf("重新部署应用层...\n")
 		d.printf("  域名: %s\n", cfg.Domain)
@@ -568,6 +854,7 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 		if err := d.DeployApp(ctx, state, cfg); err != nil {
 			return err
 		}
+		d.setupAutoSuspend(ctx, state)
 		if err := d.saveState(state); err != nil {
 			return err
 		}
@@ -586,7 +873,7 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 	// 加载或创建 state
 	state, err := d.loadState()
 	if err != nil {
-		state = config.NewState(d.Region, alicloud.DefaultImageID)
+		state = config.NewState(d.Region, d.Spec.Image)
 	}
 
 	// 检查已有实例状态
@@ -599,6 +886,9 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 	if state.Status == "suspended" {
 		return fmt.Errorf("实例已停机，请使用 cloudcode resume 恢复运行")
 	}
+	if state.Status == "reclaimed" {
+		return d.recoverReclaimed(ctx, state)
+	}
 
 	// 从快照恢复
 	var backupCfg *config.Backup
@@ -608,9 +898,25 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 		if backupCfg != nil && backupCfg.SnapshotID != "" {
 			d.printf("\n检测到快照 (%s)，将从快照恢复部署。\n", backupCfg.SnapshotID)
 			d.SnapshotID = backupCfg.SnapshotID
+			// 沿用快照时的规格，命令行 / 配置文件中指定的字段优先
+			d.Spec = config.CloudSpec{
+				InstanceType: backupCfg.InstanceType,
+				DiskSize:     backupCfg.DiskSize,
+				DiskCategory: backupCfg.DiskCategory,
+			}.Merge(d.Spec)
+			if d.Spec.DiskSize < backupCfg.DiskSize {
+				return fmt.Errorf("系统盘 %dGB 小于快照磁盘 %dGB，从快照恢复时不能缩小系统盘", d.Spec.DiskSize, backupCfg.DiskSize)
+			}
+			if d.DevboxMode == "" {
+				d.DevboxMode = backupCfg.DevboxMode
+			}
+		}
+		// 重置资源（destroyed 状态下资源已删除），沿用原部署 ID 以便快照与新资源归属同一部署
+		deploymentID := state.DeploymentID
+		state = config.NewState(d.Region, d.Spec.Image)
+		if deploymentID != "" {
+			state.DeploymentID = deploymentID
 		}
-		// 重置资源（destroyed 状态下资源已删除）
-		state = config.NewState(d.Region, alicloud.DefaultImageID)
 	}
 
 	if state.IsComplete() {
@@ -630,6 +936,27 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 			Username: backupCfg.Username,
 			Email:    backupCfg.Username + "@localhost",
 		}
+		d.applyPresetKeys(cfg, backupCfg.Authelia)
+	} else if d.NonInteractive || (d.Config != nil && d.Config.Password != "") {
+		// 配置文件提供了完整配置，或非交互模式：缺少必填项时直接失败
+		if d.Config == nil || d.Config.Password == "" {
+			return fmt.Errorf("admin.password: 非交互部署必须在配置文件中提供管理员密码（可写作 ${CLOUDCODE_ADMIN_PASSWORD}）")
+		}
+		preset := *d.Config
+		cfg = &preset
+		d.printf("\n[2/5] 使用配置文件:\n")
+		if cfg.Domain != "" {
+			d.printf("  域名: %s\n", cfg.Domain)
+		} else {
+			d.printf("  域名: <EIP>.nip.io\n")
+		}
+		d.printf("  用户名: %s\n", cfg.Username)
+	} else if d.Config != nil {
+		// 配置文件缺少管理员密码：沿用文件中的其他字段，只询问缺少的项
+		var err error
+		if cfg, err = d.promptFileConfig(); err != nil {
+			return err
+		}
 	} else {
 		var err error
 		cfg, err = d.PromptConfig(ctx)
@@ -660,13 +987,22 @@ func (d *Deployer) Run(ctx context.Context, appOnly bool) error {
 	// 更新 state
 	state.CloudCode.Username = cfg.Username
 	state.CloudCode.Domain = cfg.Domain
-	state.Status = "running"
+	if d.DevboxMode != "" {
+		state.CloudCode.DevboxMode = d.DevboxMode
+	}
+	if backupCfg != nil {
+		state.CloudCode.AutoSuspend = backupCfg.AutoSuspend
+	}
+	state.SetStatus("running")
 
 	// 阶段 4: 部署应用
 	if err := d.DeployApp(ctx, state, cfg); err != nil {
 		return err
 	}
 
+	// 自动停机（失败不阻塞部署）
+	d.setupAutoSuspend(ctx, state)
+
 	// 保存最终 state
 	if err := d.saveState(state); err != nil {
 		return err
@@ -707,14 +1043,136 @@ func (d *Deployer) printSuccess(state *config.State, cfg *DeployConfig) {
 
 // --- 内部辅助方法 ---
 
+// setupAutoSuspend 按配置文件（优先）或 state 中的策略安装自动停机 agent，抢占式实例没有策略时也安装（监测回收通知）；
+// 失败仅警告，可稍后运行 cloudcode autosuspend set 重试
+func (d *Deployer) setupAutoSuspend(ctx context.Context, state *config.State) {
+	policy := state.CloudCode.AutoSuspend
+	if d.AutoSuspend != nil {
+		policy = d.AutoSuspend
+	}
+	spot := state.Resources.ECS.Spot
+	if !policy.Enabled() && !spot {
+		return
+	}
+	if policy.Enabled() {
+		d.printf("\n配置自动停机:\n")
+	} else {
+		d.printf("\n配置抢占式实例回收监测:\n")
+	}
+	if d.RAM == nil {
+		d.printf("  ⚠ 未初始化 RAM 客户端，跳过\n")
+		return
+	}
+	privateKey, err := d.readSSHKey(state)
+	if err == nil {
+		m := &AutoSuspendManager{
+			ECS:         d.ECS,
+			STS:         d.STS,
+			RAM:         d.RAM,
+			Output:      d.Output,
+			Region:      d.Region,
+			Env:         d.envName(),
+			SSHDialFunc: d.SSHDialFunc,
+			SFTPFactory: d.SFTPFactory,
+			Version:     d.Version,
+			AgentBinary: d.AgentBinary,
+		}
+		err = m.apply(ctx, state, privateKey, policy)
+	}
+	if err != nil {
+		d.printf("  ⚠ agent 配置失败: %v（可稍后运行 cloudcode autosuspend set 重试）\n", err)
+		return
+	}
+	if policy.Enabled() {
+		d.printf("  ✓ %s\n", policy.String())
+	}
+}
+
 func (d *Deployer) getStateDir() string {
 	if d.StateDir != "" {
 		return d.StateDir
 	}
-	dir, _ := config.GetStateDir()
+	dir, _ := config.GetEnvDir(d.envName())
 	return dir
 }
 
+// applyPresetKeys 将预置配置中的 API Key 和 Authelia 设置合并到 cfg（--app 和快照恢复时域名、账号沿用记录）。
+// Authelia 设置以 saved 中的记录为基础，预置配置只覆盖非空字段，交互输入的值不会被清空。
+func (d *Deployer) applyPresetKeys(cfg *DeployConfig, saved *config.AutheliaSettings) {
+	cfg.setAutheliaSettings(saved)
+	if d.Config == nil {
+		return
+	}
+	for name, value := range d.Config.Secrets {
+		if value == "" {
+			continue
+		}
+		if cfg.Secrets == nil {
+			cfg.Secrets = make(map[string]string)
+		}
+		cfg.Secrets[name] = value
+	}
+	if d.Config.SessionExpiration != "" {
+		cfg.SessionExpiration = d.Config.SessionExpiration
+	}
+	if d.Config.SessionInactivity != "" {
+		cfg.SessionInactivity = d.Config.SessionInactivity
+	}
+	if d.Config.AccessPolicy != "" {
+		cfg.AccessPolicy = d.Config.AccessPolicy
+	}
+}
+
+// mergeSecrets 将 overrides 合并到本地 secrets.env 并保存，返回写入 .env 的完整变量
+func (d *Deployer) mergeSecrets(overrides map[string]string) (map[string]string, error) {
+	dir := d.getStateDir()
+	secrets, err := config.LoadSecretsFrom(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(overrides) == 0 {
+		return secrets, nil
+	}
+	for name, value := range overrides {
+		secrets[name] = value
+	}
+	if err := config.SaveSecretsTo(dir, secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// instanceSpec 返回实例规格；镜像留空，由 CreateResources 按区域查找
+func (d *Deployer) instanceSpec() alicloud.InstanceSpec {
+	spec := alicloud.InstanceSpec{
+		InstanceType:       d.Spec.InstanceType,
+		SystemDiskSize:     d.Spec.DiskSize,
+		SystemDiskCategory: d.Spec.DiskCategory,
+	}.WithDefaults()
+	spec.ImageID = d.Spec.Image
+	spec.Spot = d.Spec.Spot
+	spec.SpotPriceLimit = d.Spec.SpotPriceLimit
+	return spec
+}
+
+// preferredZones 返回可用区优先级；未指定时新加坡区域使用内置顺序，其他区域自动选择
+func (d *Deployer) preferredZones() []string {
+	if len(d.Spec.Zones) > 0 {
+		return d.Spec.Zones
+	}
+	if d.Region == alicloud.DefaultRegionID {
+		return alicloud.DefaultZonePriority
+	}
+	return nil
+}
+
+func (d *Deployer) envName() string {
+	if d.Env != "" {
+		return d.Env
+	}
+	return config.ActiveEnv()
+}
+
 func (d *Deployer) loadState() (*config.State, error) {
 	if d.StateDir != "" {
 		return loadStateFrom(d.StateDir)
@@ -738,12 +1196,12 @@ func readSSHKeyFrom(stateDir string, state *config.State) ([]byte, error) {
 	dir := stateDir
 	if dir == "" {
 		var err error
-		dir, err = config.GetStateDir()
+		dir, err = config.GetActiveEnvDir()
 		if err != nil {
 			return nil, err
 		}
 	}
-	keyPath := filepath.Join(dir, "ssh_key")
+	keyPath := filepath.Join(dir, config.SSHKeyFileName)
 	data, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, fmt.Errorf("读取 SSH 私钥失败: %w", err)
@@ -752,29 +1210,9 @@ func readSSHKeyFrom(stateDir string, state *config.State) ([]byte, error) {
 }
 
 func loadStateFrom(dir string) (*config.State, error) {
-	path := filepath.Join(dir, config.StateFileName)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, config.ErrStateNotFound
-		}
-		return nil, err
-	}
-	var state config.State
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, err
-	}
-	return &state, nil
+	return config.LoadStateFrom(dir)
 }
 
 func saveStateTo(dir string, state *config.State) error {
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return err
-	}
-	path := filepath.Join(dir, config.StateFileName)
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, data, 0600)
+	return config.SaveStateTo(dir, state)
 }
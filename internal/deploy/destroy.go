@@ -2,7 +2,7 @@ package deploy
 
 // destroy.go 按序销毁所有云资源，支持 --force（跳过确认）和 --dry-run（仅预览）。
 // 可选保留磁盘快照，下次 deploy 可从快照恢复。
-// 删除顺序：解绑EIP → 释放EIP → 删除ECS → 删除SSH密钥对 → 删除安全组 → 删除VSwitch → 删除VPC。
+// 删除顺序：解绑EIP → 释放EIP → 删除ECS → 删除RAM角色 → 删除唤醒页 → 删除SSH密钥对 → 删除安全组 → 删除VSwitch → 删除VPC。
 // 每步删除成功后立即更新 state，支持中断后重新执行（跳过已删除的资源）。
 // 单个资源删除失败不阻塞后续删除，最后汇总输出失败资源。
 
@@ -22,10 +22,13 @@ import (
 type Destroyer struct {
 	ECS          alicloud.ECSAPI
 	VPC          alicloud.VPCAPI
+	RAM          alicloud.RAMAPI // 为 nil 时跳过自动停机 RAM 角色
+	FC           alicloud.FCAPI  // 为 nil 时跳过唤醒页
 	Prompter     *config.Prompter
 	Output       io.Writer
 	Region       string
 	StateDir     string
+	Env          string        // 环境名（写入快照的 cloudcode-env 标签，空表示当前环境）
 	Version      string        // CloudCode 版本号（写入 backup.json）
 	WaitInterval time.Duration // 快照等待轮询间隔（测试用）
 	WaitTimeout  time.Duration // 快照等待超时（测试用）
@@ -35,6 +38,13 @@ func (d *Destroyer) printf(format string, args ...interface{}) {
 	fmt.Fprintf(d.Output, format, args...)
 }
 
+func (d *Destroyer) envName() string {
+	if d.Env != "" {
+		return d.Env
+	}
+	return config.ActiveEnv()
+}
+
 func (d *Destroyer) loadState() (*config.State, error) {
 	if d.StateDir != "" {
 		return loadStateFrom(d.StateDir)
@@ -53,7 +63,7 @@ func (d *Destroyer) getStateDir() string {
 	if d.StateDir != "" {
 		return d.StateDir
 	}
-	dir, _ := config.GetStateDir()
+	dir, _ := config.GetActiveEnvDir()
 	return dir
 }
 
@@ -69,6 +79,8 @@ func (d *Destroyer) Run(ctx context.Context, force, dryRun bool) error {
 	d.printf("将要删除以下资源:\n")
 	d.printIfSet("EIP", state.Resources.EIP.ID)
 	d.printIfSet("ECS 实例", state.Resources.ECS.ID)
+	d.printIfSet("RAM 角色", state.Resources.RAMRole.Name)
+	d.printIfSet("唤醒页", state.Resources.Wake.Domain)
 	d.printIfSet("SSH 密钥对", state.Resources.SSHKeyPair.Name)
 	d.printIfSet("安全组", state.Resources.SecurityGroup.ID)
 	d.printIfSet("交换机", state.Resources.VSwitch.ID)
@@ -118,6 +130,49 @@ func (d *Destroyer) Run(ctx context.Context, force, dryRun bool) error {
 
 	d.printf("\n开始删除资源...\n")
 
+	failedResources := d.deleteResources(state)
+
+	// 8. 删除本地 SSH 私钥
+	keyPath := filepath.Join(d.getStateDir(), config.SSHKeyFileName)
+	_ = os.Remove(keyPath)
+
+	// 9. 处理 state 和 backup
+	if keepSnapshot {
+		// 保留快照：state 标记为 destroyed
+		state.SetStatus("destroyed")
+		_ = d.saveState(state)
+	} else {
+		// 不保留快照：删除 state 和 backup
+		_ = d.deleteState()
+		_ = d.deleteBackup()
+	}
+
+	if len(failedResources) > 0 {
+		d.printf("\n⚠ 以下资源删除失败，请手动清理:\n")
+		for _, msg := range failedResources {
+			d.printf("  - %s\n", msg)
+		}
+		return fmt.Errorf("%d 个资源删除失败", len(failedResources))
+	}
+
+	d.printf("\n✅ 所有资源已清理完毕。\n")
+	if keepSnapshot {
+		d.printf("  快照已保留，下次 cloudcode deploy 可从快照恢复。\n")
+	}
+	if catalog, _ := config.LoadBackupCatalogFrom(d.getStateDir()); catalog != nil {
+		others := len(catalog.Backups)
+		if keepSnapshot {
+			others-- // 本次保留的快照
+		}
+		if others > 0 {
+			d.printf("  备份快照仍保留（按快照容量计费）: cloudcode backup list 查看，backup prune 清理\n")
+		}
+	}
+	return nil
+}
+
+// deleteResources 按序删除 state 中记录的云资源，每步成功后更新 state，返回删除失败的资源说明
+func (d *Destroyer) deleteResources(state *config.State) []string {
 	var failedResources []string
 
 	// 1. 解绑 EIP
@@ -159,6 +214,32 @@ func (d *Destroyer) Run(ctx context.Context, force, dryRun bool) error {
 		}
 	}
 
+	// 删除自动停机 agent 的 RAM 角色（实例已删除，角色随之解绑）
+	if state.Resources.RAMRole.Name != "" && d.RAM != nil {
+		d.printf("  删除 RAM 角色 (%s)...", state.Resources.RAMRole.Name)
+		if err := alicloud.DeleteAgentRole(d.RAM, d.ECS, state.Resources.RAMRole.Name, d.Region, state.Resources.ECS.ID); err != nil {
+			d.printf(" ⚠ %v\n", err)
+			failedResources = append(failedResources, fmt.Sprintf("删除 RAM 角色 %s: %v", state.Resources.RAMRole.Name, err))
+		} else {
+			state.Resources.RAMRole = config.RAMRoleResource{}
+			_ = d.saveState(state)
+			d.printf(" ✓\n")
+		}
+	}
+
+	// 删除唤醒页（自定义域名、函数、函数角色）
+	if state.Resources.Wake.FunctionName != "" && d.FC != nil && d.RAM != nil {
+		d.printf("  删除唤醒页 (%s)...", state.Resources.Wake.FunctionName)
+		if err := deleteWake(d.FC, d.RAM, state.Resources.Wake); err != nil {
+			d.printf(" ⚠ %v\n", err)
+			failedResources = append(failedResources, fmt.Sprintf("删除唤醒页 %s: %v", state.Resources.Wake.FunctionName, err))
+		} else {
+			state.Resources.Wake = config.WakeResource{}
+			_ = d.saveState(state)
+			d.printf(" ✓\n")
+		}
+	}
+
 	// 4. 删除 SSH 密钥对
 	if state.Resources.SSHKeyPair.Name != "" {
 		d.printf("  删除 SSH 密钥对 (%s)...", state.Resources.SSHKeyPair.Name)
@@ -212,34 +293,7 @@ func (d *Destroyer) Run(ctx context.Context, force, dryRun bool) error {
 		}
 	}
 
-	// 8. 删除本地 SSH 私钥
-	keyPath := filepath.Join(d.getStateDir(), "ssh_key")
-	_ = os.Remove(keyPath)
-
-	// 9. 处理 state 和 backup
-	if keepSnapshot {
-		// 保留快照：state 标记为 destroyed
-		state.Status = "destroyed"
-		_ = d.saveState(state)
-	} else {
-		// 不保留快照：删除 state 和 backup
-		_ = d.deleteState()
-		_ = d.deleteBackup()
-	}
-
-	if len(failedResources) > 0 {
-		d.printf("\n⚠ 以下资源删除失败，请手动清理:\n")
-		for _, msg := range failedResources {
-			d.printf("  - %s\n", msg)
-		}
-		return fmt.Errorf("%d 个资源删除失败", len(failedResources))
-	}
-
-	d.printf("\n✅ 所有资源已清理完毕。\n")
-	if keepSnapshot {
-		d.printf("  快照已保留，下次 cloudcode deploy 可从快照恢复。\n")
-	}
-	return nil
+	return failedResources
 }
 
 // createSnapshot 停机 → 获取系统盘 → 创建快照 → 等待完成 → 保存 backup.json
@@ -264,39 +318,37 @@ func (d *Destroyer) createSnapshot(ctx context.Context, state *config.State) err
 
 	// 创建快照
 	d.printf("  创建快照...\n")
-	snapshotName := fmt.Sprintf("cloudcode-%s", time.Now().UTC().Format("20060102-150405"))
-	snapshotID, err := alicloud.CreateDiskSnapshot(d.ECS, diskID, snapshotName)
+	backup, err := createBackupSnapshot(d.ECS, state, diskID, d.Region, d.envName(), d.Version, BackupLabelDestroy)
 	if err != nil {
 		return err
 	}
 
 	// 等待快照完成
-	if err := alicloud.WaitForSnapshotReady(ctx, d.ECS, snapshotID, d.Region, d.WaitInterval, d.WaitTimeout); err != nil {
+	if err := alicloud.WaitForSnapshotReady(ctx, d.ECS, backup.SnapshotID, d.Region, d.WaitInterval, d.WaitTimeout); err != nil {
 		return err
 	}
-	d.printf("  ✓ 快照已创建 (%s)\n", snapshotID)
+	d.printf("  ✓ 快照已创建 (%s)\n", backup.SnapshotID)
 
-	// 删除旧快照（只保留最新一份）
+	// 删除上一次 destroy 保留的快照（只保留最新一份；cloudcode backup 创建的快照不删除）
 	dir := d.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil {
+		return err
+	}
 	oldBackup, _ := config.LoadBackupFrom(dir)
-	if oldBackup != nil && oldBackup.SnapshotID != "" && oldBackup.SnapshotID != snapshotID {
-		_ = alicloud.DeleteSnapshot(d.ECS, oldBackup.SnapshotID)
+	if oldBackup != nil && oldBackup.SnapshotID != "" && oldBackup.SnapshotID != backup.SnapshotID {
+		if old, err := catalog.Find(oldBackup.SnapshotID); err != nil || old.Label == BackupLabelDestroy {
+			_ = alicloud.DeleteSnapshot(d.ECS, oldBackup.SnapshotID)
+			catalog.Remove(oldBackup.SnapshotID)
+		}
 	}
 
-	// 保存 backup.json
-	backup := &config.Backup{
-		CloudCodeVersion: d.Version,
-		SnapshotID:       snapshotID,
-		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
-		Region:           d.Region,
-		DiskSize:         state.Resources.ECS.SystemDiskSize,
-		Domain:           state.CloudCode.Domain,
-		Username:         state.CloudCode.Username,
-	}
-	if d.StateDir != "" {
-		return config.SaveBackupTo(d.StateDir, backup)
+	// 保存 backup.json 并记录到备份目录
+	catalog.Add(*backup)
+	if err := config.SaveBackupCatalogTo(dir, catalog); err != nil {
+		return err
 	}
-	return config.SaveBackup(backup)
+	return config.SaveBackupTo(dir, backup)
 }
 
 func (d *Destroyer) printIfSet(name, id string) {
@@ -3,6 +3,7 @@ package deploy
 import (
 	"fmt"
 	"net"
+	"strings"
 	"time"
 )
 
@@ -27,3 +28,26 @@ func waitForDNS(domain, expectedIP string, timeout time.Duration, printf func(st
 
 	return fmt.Errorf("DNS 解析超时（%v），%s 未解析到 %s", timeout, domain, expectedIP)
 }
+
+// waitForCNAME 轮询等待 domain 的 CNAME 指向 target（lookup 默认 net.LookupCNAME）
+func waitForCNAME(domain, target string, timeout time.Duration, lookup func(string) (string, error), printf func(string, ...interface{})) error {
+	if lookup == nil {
+		lookup = net.LookupCNAME
+	}
+	deadline := time.Now().Add(timeout)
+	interval := 5 * time.Second
+
+	for {
+		cname, err := lookup(domain)
+		if err == nil && strings.EqualFold(strings.TrimSuffix(cname, "."), target) {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		printf("  等待 DNS 生效... (%s → 期望 CNAME %s)\n", domain, target)
+		time.Sleep(interval)
+	}
+
+	return fmt.Errorf("DNS 解析超时（%v），%s 未 CNAME 到 %s", timeout, domain, target)
+}
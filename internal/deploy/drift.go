@@ -0,0 +1,239 @@
+package deploy
+
+// drift.go 核对 state.json 记录与阿里云上的实际资源（drift 检测）。
+// 逐个调用 Describe* 接口，报告三类差异：
+//   - missing：state 中记录的资源在云上已不存在
+//   - mismatch：资源存在但属性不一致（规格变更、EIP 未绑定、实例状态与 state 不符等）
+//   - extra：云上存在按 cloudcode 命名、但不属于任何环境 state 的资源
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+// drift 类型
+const (
+	DriftMissing  = "missing"
+	DriftMismatch = "mismatch"
+	DriftExtra    = "extra"
+)
+
+// DriftItem 单条差异
+type DriftItem struct {
+	Resource string `json:"resource" yaml:"resource"` // vpc / vswitch / security_group / ssh_key_pair / ecs / eip
+	ID       string `json:"id" yaml:"id"`
+	Kind     string `json:"kind" yaml:"kind"`
+	Detail   string `json:"detail" yaml:"detail"`
+}
+
+func (i DriftItem) String() string {
+	return fmt.Sprintf("[%s] %s %s: %s", i.Kind, i.Resource, i.ID, i.Detail)
+}
+
+// DriftChecker 云端资源核对器
+type DriftChecker struct {
+	ECS      alicloud.ECSAPI
+	VPC      alicloud.VPCAPI
+	Region   string
+	KnownIDs map[string]bool // 所有环境 state 中记录的资源 ID，检测 extra 时排除
+}
+
+// Check 核对 state 与云上资源，返回差异列表（无差异时为空）。
+// 查询接口本身失败（网络、权限）时返回 error，不把失败误报为 missing。
+func (c *DriftChecker) Check(state *config.State) ([]DriftItem, error) {
+	if c.ECS == nil || c.VPC == nil {
+		return nil, errors.New("核对云上资源需要阿里云凭证，请运行 cloudcode init")
+	}
+	region := state.Region
+	if region == "" {
+		region = c.Region
+	}
+	r := state.Resources
+	var items []DriftItem
+
+	add := func(resource, id, kind, format string, args ...interface{}) {
+		items = append(items, DriftItem{Resource: resource, ID: id, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	// VPC
+	if r.VPC.ID != "" {
+		vpc, err := alicloud.DescribeVPC(c.VPC, r.VPC.ID, region)
+		switch {
+		case errors.Is(err, alicloud.ErrResourceNotFound):
+			add("vpc", r.VPC.ID, DriftMissing, "VPC 已不存在")
+		case err != nil:
+			return nil, fmt.Errorf("查询 VPC 失败: %w", err)
+		case r.VPC.CIDR != "" && vpc.CIDR != r.VPC.CIDR:
+			add("vpc", r.VPC.ID, DriftMismatch, "网段 %s，state 记录为 %s", vpc.CIDR, r.VPC.CIDR)
+		}
+	}
+
+	// VSwitch
+	if r.VSwitch.ID != "" {
+		vsw, err := alicloud.DescribeVSwitch(c.VPC, r.VSwitch.ID, region)
+		switch {
+		case errors.Is(err, alicloud.ErrResourceNotFound):
+			add("vswitch", r.VSwitch.ID, DriftMissing, "交换机已不存在")
+		case err != nil:
+			return nil, fmt.Errorf("查询交换机失败: %w", err)
+		default:
+			if r.VSwitch.ZoneID != "" && vsw.ZoneID != r.VSwitch.ZoneID {
+				add("vswitch", r.VSwitch.ID, DriftMismatch, "可用区 %s，state 记录为 %s", vsw.ZoneID, r.VSwitch.ZoneID)
+			}
+			if r.VPC.ID != "" && vsw.VpcID != "" && vsw.VpcID != r.VPC.ID {
+				add("vswitch", r.VSwitch.ID, DriftMismatch, "属于 VPC %s，state 记录为 %s", vsw.VpcID, r.VPC.ID)
+			}
+		}
+	}
+
+	// 安全组
+	if r.SecurityGroup.ID != "" {
+		sg, err := alicloud.DescribeSecurityGroup(c.ECS, r.SecurityGroup.ID, region)
+		switch {
+		case errors.Is(err, alicloud.ErrResourceNotFound):
+			add("security_group", r.SecurityGroup.ID, DriftMissing, "安全组已不存在")
+		case err != nil:
+			return nil, fmt.Errorf("查询安全组失败: %w", err)
+		case r.VPC.ID != "" && sg.VpcID != "" && sg.VpcID != r.VPC.ID:
+			add("security_group", r.SecurityGroup.ID, DriftMismatch, "属于 VPC %s，state 记录为 %s", sg.VpcID, r.VPC.ID)
+		}
+	}
+
+	// SSH 密钥对
+	if r.SSHKeyPair.Name != "" {
+		_, err := alicloud.DescribeSSHKeyPair(c.ECS, r.SSHKeyPair.Name, region)
+		switch {
+		case errors.Is(err, alicloud.ErrResourceNotFound):
+			add("ssh_key_pair", r.SSHKeyPair.Name, DriftMissing, "SSH 密钥对已不存在")
+		case err != nil:
+			return nil, fmt.Errorf("查询 SSH 密钥对失败: %w", err)
+		}
+	}
+
+	// ECS 实例
+	if r.ECS.ID != "" {
+		inst, err := alicloud.DescribeECSInstance(c.ECS, r.ECS.ID, region)
+		switch {
+		case errors.Is(err, alicloud.ErrResourceNotFound):
+			add("ecs", r.ECS.ID, DriftMissing, "ECS 实例已不存在")
+		case err != nil:
+			return nil, fmt.Errorf("查询 ECS 实例失败: %w", err)
+		default:
+			if r.ECS.InstanceType != "" && inst.InstanceType != r.ECS.InstanceType {
+				add("ecs", r.ECS.ID, DriftMismatch, "规格 %s，state 记录为 %s", inst.InstanceType, r.ECS.InstanceType)
+			}
+			if expected := expectedInstanceStatus(state.Status); expected != "" && inst.Status != expected {
+				add("ecs", r.ECS.ID, DriftMismatch, "实例状态 %s，state 状态为 %s", inst.Status, stateStatus(state))
+			}
+			if r.VSwitch.ID != "" && inst.VSwitchID != "" && inst.VSwitchID != r.VSwitch.ID {
+				add("ecs", r.ECS.ID, DriftMismatch, "位于交换机 %s，state 记录为 %s", inst.VSwitchID, r.VSwitch.ID)
+			}
+			if r.SecurityGroup.ID != "" && len(inst.SecurityGroupIDs) > 0 && !contains(inst.SecurityGroupIDs, r.SecurityGroup.ID) {
+				add("ecs", r.ECS.ID, DriftMismatch, "未加入安全组 %s", r.SecurityGroup.ID)
+			}
+		}
+	}
+
+	// EIP
+	if r.EIP.ID != "" {
+		eip, err := alicloud.DescribeEIP(c.VPC, r.EIP.ID, region)
+		switch {
+		case errors.Is(err, alicloud.ErrResourceNotFound):
+			add("eip", r.EIP.ID, DriftMissing, "EIP 已不存在")
+		case err != nil:
+			return nil, fmt.Errorf("查询 EIP 失败: %w", err)
+		default:
+			if r.EIP.IP != "" && eip.IP != r.EIP.IP {
+				add("eip", r.EIP.ID, DriftMismatch, "IP %s，state 记录为 %s", eip.IP, r.EIP.IP)
+			}
+			if r.ECS.ID != "" && eip.InstanceID != r.ECS.ID {
+				if eip.InstanceID == "" {
+					add("eip", r.EIP.ID, DriftMismatch, "未绑定到实例 %s", r.ECS.ID)
+				} else {
+					add("eip", r.EIP.ID, DriftMismatch, "绑定到实例 %s，state 记录为 %s", eip.InstanceID, r.ECS.ID)
+				}
+			}
+		}
+	}
+
+	extras, err := c.findExtras(state, region)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, extras...), nil
+}
+
+// findExtras 按 cloudcode 命名查找不属于任何环境的资源（通常是中断部署或手动删除 state 留下的孤儿）
+func (c *DriftChecker) findExtras(state *config.State, region string) ([]DriftItem, error) {
+	owned := func(id string) bool {
+		if c.KnownIDs[id] {
+			return true
+		}
+		r := state.Resources
+		return id == r.VPC.ID || id == r.ECS.ID || id == r.EIP.ID
+	}
+
+	var items []DriftItem
+
+	vpcs, err := alicloud.ListVPCsByName(c.VPC, region, ResourceNameVPC)
+	if err != nil {
+		return nil, fmt.Errorf("查询 VPC 列表失败: %w", err)
+	}
+	for _, vpc := range vpcs {
+		if !owned(vpc.ID) {
+			items = append(items, DriftItem{Resource: "vpc", ID: vpc.ID, Kind: DriftExtra, Detail: "未被任何环境记录的 " + ResourceNameVPC})
+		}
+	}
+
+	instances, err := alicloud.ListInstancesByName(c.ECS, region, ResourceNameECS)
+	if err != nil {
+		return nil, fmt.Errorf("查询 ECS 实例列表失败: %w", err)
+	}
+	for _, inst := range instances {
+		if !owned(inst.ID) {
+			items = append(items, DriftItem{Resource: "ecs", ID: inst.ID, Kind: DriftExtra, Detail: fmt.Sprintf("未被任何环境记录的 %s（%s）", ResourceNameECS, inst.Status)})
+		}
+	}
+
+	eips, err := alicloud.ListEIPsByName(c.VPC, region, ResourceNameEIP)
+	if err != nil {
+		return nil, fmt.Errorf("查询 EIP 列表失败: %w", err)
+	}
+	for _, eip := range eips {
+		if !owned(eip.ID) {
+			items = append(items, DriftItem{Resource: "eip", ID: eip.ID, Kind: DriftExtra, Detail: fmt.Sprintf("未被任何环境记录的 %s（%s）", ResourceNameEIP, eip.IP)})
+		}
+	}
+
+	return items, nil
+}
+
+// expectedInstanceStatus 根据 state.Status 推断 ECS 实例应处的状态（destroyed 无实例，返回空）
+func expectedInstanceStatus(status string) string {
+	switch status {
+	case "", "running":
+		return "Running"
+	case "suspended":
+		return "Stopped"
+	}
+	return ""
+}
+
+func stateStatus(state *config.State) string {
+	if state.Status == "" {
+		return "running"
+	}
+	return state.Status
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package deploy
+
+// gc.go 按 cloudcode-deployment-id 标签查找云上资源，找出不被任何本地环境 state 引用的孤儿资源
+// （中断的部署、手动删除 state 等留下的），列出并可选地删除。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+// DefaultGCWaitInterval 解绑 EIP / 删除实例后等待云端状态生效的间隔
+const DefaultGCWaitInterval = 5 * time.Second
+
+// GarbageCollector 孤儿资源清理器
+type GarbageCollector struct {
+	ECS              alicloud.ECSAPI
+	VPC              alicloud.VPCAPI
+	Prompter         *config.Prompter
+	Output           io.Writer
+	Region           string
+	KnownIDs         map[string]bool // 所有环境 state / backup 中记录的资源 ID，不视为孤儿
+	KnownDeployments map[string]bool // 所有环境 state 中记录的部署 ID，这些部署的快照不视为孤儿（如恢复前的抢占式回收快照）
+	WaitInterval     time.Duration   // 依赖资源删除后的等待间隔（测试用，默认 5s）
+}
+
+func (g *GarbageCollector) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.Output, format, args...)
+}
+
+// FindOrphans 返回带 CloudCode 标签、但不被任何本地 state 引用的资源（按删除依赖顺序）
+func (g *GarbageCollector) FindOrphans() ([]alicloud.TaggedResource, error) {
+	resources, err := alicloud.ListTaggedResources(g.ECS, g.VPC, g.Region)
+	if err != nil {
+		return nil, fmt.Errorf("查询带标签的资源失败: %w", err)
+	}
+	var orphans []alicloud.TaggedResource
+	for _, r := range resources {
+		if g.KnownIDs[r.ID] {
+			continue
+		}
+		// 快照可能是唯一的恢复点，属于本地已知部署的一律保留
+		if r.Type == alicloud.ResourceTypeSnapshot && g.KnownDeployments[r.Tags[alicloud.TagKeyDeploymentID]] {
+			continue
+		}
+		orphans = append(orphans, r)
+	}
+	return orphans, nil
+}
+
+// Run 列出孤儿资源；del 为 true 时确认后删除（force 跳过确认）
+func (g *GarbageCollector) Run(ctx context.Context, del, force bool) error {
+	g.printf("查找带 CloudCode 标签的资源 (%s)...\n", g.Region)
+	orphans, err := g.FindOrphans()
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		g.printf("\n✅ 没有发现未被引用的资源。\n")
+		return nil
+	}
+
+	g.printf("\n以下 %d 个资源不被任何本地环境引用:\n", len(orphans))
+	for _, r := range orphans {
+		g.printf("  - %-15s %-26s %s\n", r.Type, r.ID, describeOrphan(r))
+	}
+
+	if !del {
+		g.printf("\n使用 cloudcode gc --delete 删除以上资源。\n")
+		g.printf("注意: 同一账号下其他人（或其他机器）部署的资源也会出现在列表中。\n")
+		return nil
+	}
+
+	if !force {
+		confirmed, err := g.Prompter.PromptConfirm("\n确认删除以上资源? 同一账号下他人的部署也会被删除", false)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			g.printf("已取消。\n")
+			return nil
+		}
+	}
+
+	g.printf("\n删除资源:\n")
+	var failed []string
+	for i, r := range orphans {
+		g.printf("  删除 %s (%s)...", r.Type, r.ID)
+		if err := g.deleteResource(ctx, r); err != nil {
+			g.printf(" ⚠ %v\n", err)
+			failed = append(failed, fmt.Sprintf("%s %s: %v", r.Type, r.ID, err))
+			continue
+		}
+		g.printf(" ✓\n")
+
+		// 实例删除是异步的，等待后再删除其依赖的安全组 / 交换机
+		if r.Type == alicloud.ResourceTypeECS && (i+1 == len(orphans) || orphans[i+1].Type != alicloud.ResourceTypeECS) {
+			g.wait(ctx)
+		}
+	}
+
+	if len(failed) > 0 {
+		g.printf("\n⚠ 以下资源删除失败，请稍后重试或手动清理:\n")
+		for _, msg := range failed {
+			g.printf("  - %s\n", msg)
+		}
+		return fmt.Errorf("%d 个资源删除失败", len(failed))
+	}
+
+	g.printf("\n✅ 已删除 %d 个资源。\n", len(orphans))
+	return nil
+}
+
+func (g *GarbageCollector) deleteResource(ctx context.Context, r alicloud.TaggedResource) error {
+	switch r.Type {
+	case alicloud.ResourceTypeEIP:
+		if r.InstanceID != "" {
+			if err := alicloud.UnassociateEIPFromInstance(g.VPC, r.ID, r.InstanceID, g.Region); err != nil {
+				return fmt.Errorf("解绑失败: %w", err)
+			}
+			g.wait(ctx)
+		}
+		return alicloud.ReleaseEIP(g.VPC, r.ID)
+	case alicloud.ResourceTypeECS:
+		return alicloud.DeleteECSInstance(g.ECS, r.ID)
+	case alicloud.ResourceTypeSSHKeyPair:
+		return alicloud.DeleteSSHKeyPair(g.ECS, r.ID, g.Region)
+	case alicloud.ResourceTypeSecurityGroup:
+		return alicloud.DeleteSecurityGroup(g.ECS, r.ID, g.Region)
+	case alicloud.ResourceTypeVSwitch:
+		return alicloud.DeleteVSwitch(g.VPC, r.ID)
+	case alicloud.ResourceTypeVPC:
+		return alicloud.DeleteVPC(g.VPC, r.ID)
+	case alicloud.ResourceTypeSnapshot:
+		return alicloud.DeleteSnapshot(g.ECS, r.ID)
+	}
+	return fmt.Errorf("不支持的资源类型 %s", r.Type)
+}
+
+func (g *GarbageCollector) wait(ctx context.Context) {
+	interval := g.WaitInterval
+	if interval == 0 {
+		interval = DefaultGCWaitInterval
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(interval):
+	}
+}
+
+// describeOrphan 生成资源的简要说明：名称、环境、部署 ID、状态
+func describeOrphan(r alicloud.TaggedResource) string {
+	desc := r.Name
+	if env := r.Tags[alicloud.TagKeyEnv]; env != "" {
+		desc += fmt.Sprintf(" env=%s", env)
+	}
+	if id := r.Tags[alicloud.TagKeyDeploymentID]; id != "" {
+		desc += fmt.Sprintf(" deployment=%s", id)
+	}
+	if r.Status != "" {
+		desc += fmt.Sprintf(" (%s)", r.Status)
+	}
+	return desc
+}
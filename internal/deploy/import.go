@@ -0,0 +1,340 @@
+package deploy
+
+// import.go 将云上已有的 CloudCode 资源重新纳入本地 state（cloudcode import）。
+// 适用于丢失 ~/.cloudcode 目录的场景：按名称（cloudcode-ecs 等）找到实例，
+// 再从实例反查 VPC、交换机、安全组、EIP，重建 state.json。
+//
+// SSH 私钥无法从云上取回，两种方式恢复访问：
+//   - 提供已有私钥文件（--ssh-key），直接复制到环境目录
+//   - 生成新密钥对，通过 ImportKeyPair 导入并绑定到实例（运行中的实例需重启生效）
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// Importer 云资源导入器
+type Importer struct {
+	ECS          alicloud.ECSAPI
+	VPC          alicloud.VPCAPI
+	Prompter     *config.Prompter
+	Output       io.Writer
+	Region       string
+	StateDir     string          // 覆盖默认 state 目录（测试用）
+	Env          string          // 环境名（决定新密钥对名称，空表示当前环境）
+	InstanceID   string          // 指定要导入的实例（同名实例有多个时使用）
+	KeyFile      string          // 已有 SSH 私钥路径，为空则生成新密钥
+	Domain       string          // 应用域名，为空则交互输入（默认 EIP.nip.io）
+	Username     string          // 管理员用户名，为空则交互输入（默认 admin）
+	KnownIDs     map[string]bool // 其他环境已记录的资源 ID，不参与自动发现
+	Version      string          // CloudCode 版本号（写入新密钥对的标签）
+	WaitInterval time.Duration
+	WaitTimeout  time.Duration
+}
+
+func (im *Importer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(im.Output, format, args...)
+}
+
+func (im *Importer) envName() string {
+	if im.Env != "" {
+		return im.Env
+	}
+	return config.ActiveEnv()
+}
+
+func (im *Importer) getStateDir() string {
+	if im.StateDir != "" {
+		return im.StateDir
+	}
+	dir, _ := config.GetEnvDir(im.envName())
+	return dir
+}
+
+// Run 执行导入流程
+func (im *Importer) Run(ctx context.Context) error {
+	stateDir := im.getStateDir()
+	if existing, err := loadStateFrom(stateDir); err == nil && existing.Status != "destroyed" && existing.HasECS() {
+		return fmt.Errorf("环境 %s 已有部署记录（实例 %s），请换一个环境（--env）导入", im.envName(), existing.Resources.ECS.ID)
+	}
+
+	im.printf("查找云上资源...\n")
+	inst, err := im.findInstance()
+	if err != nil {
+		return err
+	}
+	im.printf("  ✓ ECS 实例 (%s) - %s, %s\n", inst.ID, inst.InstanceType, inst.Status)
+
+	state := config.NewState(im.Region, inst.ImageID)
+	// 沿用实例上的部署 ID 标签，使 gc 仍能将原有资源识别为同一部署
+	if id := inst.Tags[alicloud.TagKeyDeploymentID]; id != "" {
+		state.DeploymentID = id
+	}
+	state.Resources.ECS = config.ECSResource{
+		ID:           inst.ID,
+		InstanceType: inst.InstanceType,
+		PublicIP:     inst.PublicIP,
+		PrivateIP:    inst.PrivateIP,
+	}
+	switch inst.Status {
+	case "Running":
+		state.SetStatus("running")
+	case "Stopped":
+		state.SetStatus("suspended")
+	default:
+		return fmt.Errorf("实例状态为 %s，请等待其变为 Running 或 Stopped 后重试", inst.Status)
+	}
+
+	// 从实例反查网络资源
+	if inst.VpcID != "" {
+		vpc, err := alicloud.DescribeVPC(im.VPC, inst.VpcID, im.Region)
+		if err != nil {
+			return fmt.Errorf("查询 VPC %s 失败: %w", inst.VpcID, err)
+		}
+		state.Resources.VPC = config.VPCResource{ID: vpc.ID, CIDR: vpc.CIDR}
+		im.printf("  ✓ VPC (%s)\n", vpc.ID)
+	}
+	if inst.VSwitchID != "" {
+		vsw, err := alicloud.DescribeVSwitch(im.VPC, inst.VSwitchID, im.Region)
+		if err != nil {
+			return fmt.Errorf("查询交换机 %s 失败: %w", inst.VSwitchID, err)
+		}
+		state.Resources.VSwitch = config.VSwitchResource{ID: vsw.ID, ZoneID: vsw.ZoneID, CIDR: vsw.CIDR}
+		im.printf("  ✓ 交换机 (%s)\n", vsw.ID)
+	}
+	if len(inst.SecurityGroupIDs) > 0 {
+		state.Resources.SecurityGroup = config.SecurityGroupResource{ID: inst.SecurityGroupIDs[0]}
+		im.printf("  ✓ 安全组 (%s)\n", inst.SecurityGroupIDs[0])
+	}
+
+	eip, err := alicloud.DescribeEIPByInstance(im.VPC, inst.ID, im.Region)
+	switch {
+	case errors.Is(err, alicloud.ErrResourceNotFound):
+		im.printf("  ⚠ 未找到绑定到该实例的 EIP\n")
+	case err != nil:
+		return fmt.Errorf("查询 EIP 失败: %w", err)
+	default:
+		state.Resources.EIP = config.EIPResource{ID: eip.ID, IP: eip.IP}
+		state.Resources.ECS.PublicIP = eip.IP
+		im.printf("  ✓ EIP (%s) - IP: %s\n", eip.ID, eip.IP)
+	}
+
+	// SSH 密钥
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return fmt.Errorf("创建状态目录失败: %w", err)
+	}
+	tags := alicloud.ResourceTags(state.DeploymentID, im.envName(), im.Version)
+	keyName, err := im.setupSSHKey(ctx, inst, stateDir, tags)
+	if err != nil {
+		return err
+	}
+	state.Resources.SSHKeyPair = config.SSHKeyPairResource{
+		Name:           keyName,
+		PrivateKeyPath: config.EnvKeyRelPath(im.envName()),
+	}
+
+	// 应用配置
+	if err := im.promptAppConfig(state); err != nil {
+		return err
+	}
+
+	if err := saveStateTo(stateDir, state); err != nil {
+		return err
+	}
+
+	im.printf("\n✅ 已导入到环境 %s\n", im.envName())
+	im.printf("  查看状态: cloudcode status --check-cloud\n")
+	if state.Status == "suspended" {
+		im.printf("  恢复运行: cloudcode resume\n")
+	}
+	return nil
+}
+
+// findInstance 定位要导入的 ECS 实例：优先使用 InstanceID，否则按名称查找未被其他环境记录的实例
+func (im *Importer) findInstance() (*alicloud.ECSResource, error) {
+	if im.InstanceID != "" {
+		inst, err := alicloud.DescribeECSInstance(im.ECS, im.InstanceID, im.Region)
+		if err != nil {
+			return nil, fmt.Errorf("查询实例 %s 失败: %w", im.InstanceID, err)
+		}
+		return inst, nil
+	}
+
+	instances, err := alicloud.ListInstancesByName(im.ECS, im.Region, ResourceNameECS)
+	if err != nil {
+		return nil, fmt.Errorf("查询 ECS 实例失败: %w", err)
+	}
+	var candidates []alicloud.ECSResource
+	for _, inst := range instances {
+		if !im.KnownIDs[inst.ID] {
+			candidates = append(candidates, inst)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("区域 %s 中未找到可导入的 %s 实例", im.Region, ResourceNameECS)
+	case 1:
+		// 列表接口返回的字段可能不全，再查一次详情
+		return alicloud.DescribeECSInstance(im.ECS, candidates[0].ID, im.Region)
+	}
+
+	if im.Prompter == nil {
+		return nil, fmt.Errorf("找到 %d 个 %s 实例，请使用 --instance 指定", len(candidates), ResourceNameECS)
+	}
+	options := make([]string, len(candidates))
+	for i, inst := range candidates {
+		options[i] = fmt.Sprintf("%s (%s, %s, %s)", inst.ID, inst.Status, inst.ZoneID, inst.PublicIP)
+	}
+	idx, err := im.Prompter.PromptSelect(fmt.Sprintf("找到 %d 个 %s 实例，请选择要导入的实例:", len(candidates), ResourceNameECS), options)
+	if err != nil {
+		return nil, err
+	}
+	return alicloud.DescribeECSInstance(im.ECS, candidates[idx].ID, im.Region)
+}
+
+// setupSSHKey 准备 SSH 私钥并返回实例绑定的密钥对名称
+func (im *Importer) setupSSHKey(ctx context.Context, inst *alicloud.ECSResource, stateDir string, tags []alicloud.Tag) (string, error) {
+	keyPath := filepath.Join(stateDir, config.SSHKeyFileName)
+
+	if im.KeyFile != "" {
+		data, err := os.ReadFile(im.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("读取 SSH 私钥失败: %w", err)
+		}
+		if _, err := ssh.ParsePrivateKey(data); err != nil {
+			return "", fmt.Errorf("SSH 私钥格式无效: %w", err)
+		}
+		if err := os.WriteFile(keyPath, data, 0600); err != nil {
+			return "", fmt.Errorf("保存 SSH 私钥失败: %w", err)
+		}
+		im.printf("  ✓ 使用已有 SSH 私钥 (%s)\n", im.KeyFile)
+		return inst.KeyPairName, nil
+	}
+
+	// 生成新密钥并绑定到实例
+	if inst.Status == "Running" && im.Prompter != nil {
+		confirmed, err := im.Prompter.PromptConfirm("未提供 SSH 私钥，将生成新密钥并绑定到实例，需要重启实例。继续?", false)
+		if err != nil {
+			return "", err
+		}
+		if !confirmed {
+			return "", fmt.Errorf("已取消。可使用 --ssh-key 提供已有私钥")
+		}
+	}
+
+	privateKey, publicKey, err := generateSSHKey()
+	if err != nil {
+		return "", err
+	}
+
+	keyName := alicloud.SSHKeyNameForEnv(im.envName())
+	if _, err := alicloud.DescribeSSHKeyPair(im.ECS, keyName, im.Region); err == nil {
+		// 同名密钥对已存在（通常就是实例原来绑定的那个，私钥已丢失），删除后重新导入
+		if err := alicloud.DeleteSSHKeyPair(im.ECS, keyName, im.Region); err != nil {
+			return "", fmt.Errorf("删除旧密钥对 %s 失败: %w", keyName, err)
+		}
+	}
+	if _, err := alicloud.ImportSSHKeyPair(im.ECS, keyName, publicKey, im.Region, tags...); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(keyPath, privateKey, 0600); err != nil {
+		return "", fmt.Errorf("保存 SSH 私钥失败: %w", err)
+	}
+	im.printf("  ✓ 导入新 SSH 密钥对 (%s)\n", keyName)
+
+	if err := alicloud.AttachSSHKeyPair(im.ECS, keyName, inst.ID, im.Region); err != nil {
+		return "", fmt.Errorf("绑定密钥对到实例失败: %w", err)
+	}
+
+	if inst.Status == "Running" {
+		im.printf("  重启实例使密钥生效...\n")
+		if err := im.restartInstance(ctx, inst.ID); err != nil {
+			return "", err
+		}
+		im.printf("  ✓ 实例已重启\n")
+	}
+	return keyName, nil
+}
+
+func (im *Importer) restartInstance(ctx context.Context, instanceID string) error {
+	if err := alicloud.StopECSInstance(im.ECS, instanceID, false); err != nil {
+		return fmt.Errorf("停止实例失败: %w", err)
+	}
+	if err := alicloud.WaitForInstanceStatus(ctx, im.ECS, instanceID, im.Region, "Stopped", im.WaitInterval, im.WaitTimeout); err != nil {
+		return fmt.Errorf("等待实例停止失败: %w", err)
+	}
+	if err := alicloud.StartECSInstance(im.ECS, instanceID); err != nil {
+		return fmt.Errorf("启动实例失败: %w", err)
+	}
+	if _, err := alicloud.WaitForInstanceRunning(ctx, im.ECS, instanceID, im.Region, im.WaitInterval, im.WaitTimeout); err != nil {
+		return err
+	}
+	return nil
+}
+
+// promptAppConfig 填充 state 中的域名和用户名（应用层配置无法从云 API 获取）
+func (im *Importer) promptAppConfig(state *config.State) error {
+	domain := im.Domain
+	username := im.Username
+
+	defaultDomain := ""
+	if state.Resources.EIP.IP != "" {
+		defaultDomain = state.Resources.EIP.IP + ".nip.io"
+	}
+
+	if im.Prompter != nil {
+		var err error
+		if domain == "" {
+			if domain, err = im.Prompter.PromptWithDefault("请输入部署时使用的域名", defaultDomain); err != nil {
+				return err
+			}
+		}
+		if username == "" {
+			if username, err = im.Prompter.PromptWithDefault("请输入管理员用户名", "admin"); err != nil {
+				return err
+			}
+		}
+	}
+	if domain == "" {
+		domain = defaultDomain
+	}
+	if username == "" {
+		username = "admin"
+	}
+
+	state.CloudCode.Domain = strings.TrimSpace(domain)
+	state.CloudCode.Username = strings.TrimSpace(username)
+	return nil
+}
+
+// generateSSHKey 生成 RSA 密钥对，返回 PEM 格式私钥和 authorized_keys 格式公钥
+func generateSSHKey() ([]byte, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 3072)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成 SSH 密钥失败: %w", err)
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成 SSH 公钥失败: %w", err)
+	}
+	return privatePEM, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))), nil
+}
@@ -0,0 +1,417 @@
+package deploy
+
+// migrate.go 实现 cloudcode migrate --to-region：将部署迁移到其他区域。
+// 流程：停机 → 系统盘快照 → 复制快照到目标区域 → 导入 SSH 公钥 → CreateResources 从快照创建
+// VPC / 交换机 / 安全组 / ECS / EIP → 更新 DNS → 重新部署应用层 → 健康检查 → 切换 state → 删除源区域资源。
+// 切换前目标区域的 state 保存在 <env>/migrate/，原部署的 state 不变：中断后重新运行同一命令继续
+// （已创建的目标资源会复用），目标实例创建之前失败时自动重新启动源实例。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+	"golang.org/x/crypto/ssh"
+)
+
+// MigrateDirName 迁移过程中目标区域 state 所在的子目录（位于环境目录下）
+const MigrateDirName = config.MigrateDirName
+
+// 迁移后健康检查的重试次数（容器启动需要时间）
+const migrateHealthAttempts = 10
+
+// Migrator 跨区域迁移器：ECS / VPC / FC 为源区域客户端，Target 为目标区域的部署器
+type Migrator struct {
+	ECS          alicloud.ECSAPI
+	VPC          alicloud.VPCAPI
+	RAM          alicloud.RAMAPI
+	FC           alicloud.FCAPI // 为 nil 时跳过源区域唤醒页
+	Target       *Deployer      // Region、客户端均为目标区域
+	Prompter     *config.Prompter
+	Output       io.Writer
+	Region       string // 源区域
+	StateDir     string
+	Version      string
+	SSHIP        string        // 目标安全组的 SSH 源地址限制（空表示不限制）
+	WaitInterval time.Duration // 快照 / 实例 / 健康检查轮询间隔（测试用）
+	WaitTimeout  time.Duration // 快照 / 实例等待超时（测试用）
+}
+
+func (m *Migrator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.Output, format, args...)
+}
+
+func (m *Migrator) getStateDir() string {
+	if m.StateDir != "" {
+		return m.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
+
+func (m *Migrator) migrateDir() string {
+	return filepath.Join(m.getStateDir(), MigrateDirName)
+}
+
+// Run 执行迁移。force 跳过确认。
+func (m *Migrator) Run(ctx context.Context, force bool) error {
+	target := m.Target.Region
+	state, err := loadStateFrom(m.getStateDir())
+	if err != nil {
+		return fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	if state.Region == target {
+		return fmt.Errorf("部署已在区域 %s", target)
+	}
+	if state.Region != "" && state.Region != m.Region {
+		return fmt.Errorf("部署位于区域 %s，当前配置的区域为 %s（检查 ALICLOUD_REGION）", state.Region, m.Region)
+	}
+	if !state.IsComplete() {
+		return fmt.Errorf("云资源不完整，请先运行 cloudcode deploy 完成部署")
+	}
+
+	// 上次迁移未完成：目标区域相同时继续，否则要求先完成上次迁移
+	pending, err := loadStateFrom(m.migrateDir())
+	if err != nil {
+		pending = nil
+	}
+	if pending != nil && pending.Region != target {
+		return fmt.Errorf("存在未完成的迁移（目标区域 %s），请运行 cloudcode migrate --to-region %s 继续", pending.Region, pending.Region)
+	}
+
+	m.printf("将部署从 %s 迁移到 %s:\n", m.Region, target)
+	m.printf("  1. 停机并创建系统盘快照，复制到 %s\n", target)
+	m.printf("  2. 在 %s 从快照创建 VPC / 交换机 / 安全组 / ECS / EIP\n", target)
+	m.printf("  3. 更新 DNS、重新部署应用层并检查健康状态\n")
+	m.printf("  4. 删除 %s 的资源（EIP 会变更）\n", m.Region)
+	if pending != nil {
+		m.printf("检测到未完成的迁移，将复用已创建的目标区域资源。\n")
+	}
+	if !force {
+		confirmed, err := m.Prompter.PromptConfirm("迁移期间服务不可用，确认迁移?", false)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			m.printf("已取消。\n")
+			return nil
+		}
+	}
+
+	newState := pending
+	if newState == nil {
+		if err := m.prepareDir(); err != nil {
+			return err
+		}
+		newState = config.NewState(target, state.OSImage)
+		newState.DeploymentID = state.DeploymentID
+		newState.CloudCode = state.CloudCode
+		newState.Resources.RAMRole = state.Resources.RAMRole // RAM 角色是全局资源，由新实例沿用
+	}
+
+	// 目标区域沿用原实例规格，命令行指定的字段优先
+	m.Target.StateDir = m.migrateDir()
+	m.Target.Spec = config.CloudSpec{
+		InstanceType: state.Resources.ECS.InstanceType,
+		DiskSize:     state.Resources.ECS.SystemDiskSize,
+		DiskCategory: state.Resources.ECS.SystemDiskCategory,
+	}.Merge(m.Target.Spec)
+
+	if err := m.importKeyPair(state, newState); err != nil {
+		return err
+	}
+
+	// 目标实例尚未创建：快照并复制到目标区域，失败时重新启动源实例
+	if !newState.HasECS() {
+		stopped, err := m.copySnapshot(ctx, state)
+		if err == nil {
+			err = m.Target.CreateResources(ctx, newState, m.SSHIP)
+		}
+		if err != nil && !newState.HasECS() {
+			if m.Target.SnapshotID != "" {
+				_ = alicloud.DeleteSnapshot(m.Target.ECS, m.Target.SnapshotID)
+			}
+			if stopped {
+				m.restartSource(ctx, state)
+			}
+			return fmt.Errorf("迁移失败，原部署未受影响: %w", err)
+		}
+		if err != nil {
+			return m.resumeHint(err)
+		}
+		m.recordSnapshot(newState)
+	} else if err := m.Target.CreateResources(ctx, newState, m.SSHIP); err != nil {
+		return m.resumeHint(err)
+	}
+
+	// DNS 与应用层：nip.io 域名随 EIP 变化，自有域名更新 A 记录
+	cfg := &DeployConfig{
+		Domain:   newState.CloudCode.Domain,
+		Username: newState.CloudCode.Username,
+		Email:    newState.CloudCode.Username + "@localhost",
+	}
+	cfg.setAutheliaSettings(newState.CloudCode.Authelia)
+	if strings.HasSuffix(cfg.Domain, ".nip.io") {
+		cfg.Domain = ""
+	} else if err := m.Target.SetupDNS(ctx, cfg.Domain, newState.Resources.EIP.IP); err != nil {
+		return m.resumeHint(err)
+	}
+	newState.SetStatus("running")
+	if err := m.Target.DeployApp(ctx, newState, cfg); err != nil {
+		return m.resumeHint(err)
+	}
+	m.Target.setupAutoSuspend(ctx, newState)
+	if err := m.Target.saveState(newState); err != nil {
+		return err
+	}
+
+	m.printf("\n检查目标实例:\n")
+	if err := m.verify(ctx, newState); err != nil {
+		return m.resumeHint(fmt.Errorf("健康检查失败: %w", err))
+	}
+
+	// 切换 state 后删除源区域资源
+	if err := saveStateTo(m.getStateDir(), newState); err != nil {
+		return err
+	}
+	failed := m.teardown(state)
+	_ = os.RemoveAll(m.migrateDir())
+
+	m.printf("\n✅ 已迁移到 %s\n", target)
+	m.printf("  访问地址: https://%s\n", newState.CloudCode.Domain)
+	m.printf("  新 EIP: %s\n", newState.Resources.EIP.IP)
+	if state.Resources.Wake.FunctionName != "" {
+		m.printf("  唤醒页已随源区域删除，可运行 cloudcode wake enable 重新创建\n")
+	}
+	if len(failed) > 0 {
+		m.printf("\n⚠ 以下源区域资源删除失败，请手动清理:\n")
+		for _, msg := range failed {
+			m.printf("  - %s\n", msg)
+		}
+		return fmt.Errorf("%d 个源区域资源删除失败", len(failed))
+	}
+	return nil
+}
+
+// prepareDir 创建迁移目录，复制 SSH 私钥和 secrets.env（目标部署器从该目录读取）
+func (m *Migrator) prepareDir() error {
+	dir := m.migrateDir()
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建迁移目录失败: %w", err)
+	}
+	for _, name := range []string{config.SSHKeyFileName, config.SecretsFileName} {
+		data, err := os.ReadFile(filepath.Join(m.getStateDir(), name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importKeyPair 将现有 SSH 公钥导入目标区域（密钥对是区域资源），私钥保持不变
+func (m *Migrator) importKeyPair(state, newState *config.State) error {
+	if newState.HasSSHKeyPair() {
+		return nil
+	}
+	privateKey, err := readSSHKeyFrom(m.getStateDir(), state)
+	if err != nil {
+		return err
+	}
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("解析 SSH 私钥失败: %w", err)
+	}
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	name := state.Resources.SSHKeyPair.Name
+	tags := alicloud.ResourceTags(state.DeploymentID, m.Target.envName(), m.Version)
+	if _, err := alicloud.ImportSSHKeyPair(m.Target.ECS, name, publicKey, m.Target.Region, tags...); err != nil {
+		return err
+	}
+	newState.Resources.SSHKeyPair = state.Resources.SSHKeyPair
+	if err := saveStateTo(m.migrateDir(), newState); err != nil {
+		return err
+	}
+	m.printf("  ✓ 导入 SSH 密钥对到 %s (%s)\n", m.Target.Region, name)
+	return nil
+}
+
+// copySnapshot 停机 → 系统盘快照 → 复制到目标区域，设置 Target.SnapshotID。
+// 返回是否停止了源实例（失败时据此重新启动）。源区域快照在复制完成后删除。
+func (m *Migrator) copySnapshot(ctx context.Context, state *config.State) (bool, error) {
+	instanceID := state.Resources.ECS.ID
+	m.printf("\n迁移系统盘:\n")
+
+	stopped := false
+	if state.Status != "suspended" {
+		m.printf("  停机中（确保数据一致性）...\n")
+		if err := alicloud.StopECSInstance(m.ECS, instanceID, false); err != nil {
+			return false, fmt.Errorf("停机失败: %w", err)
+		}
+		stopped = true
+		if err := alicloud.WaitForInstanceStatus(ctx, m.ECS, instanceID, m.Region, "Stopped", m.WaitInterval, m.WaitTimeout); err != nil {
+			return stopped, fmt.Errorf("等待停机失败: %w", err)
+		}
+		m.printf("  ✓ 已停机\n")
+	}
+
+	diskID, err := alicloud.GetSystemDiskID(m.ECS, instanceID, m.Region)
+	if err != nil {
+		return stopped, err
+	}
+	name := fmt.Sprintf("cloudcode-migrate-%s", time.Now().UTC().Format("20060102-150405"))
+	tags := alicloud.ResourceTags(state.DeploymentID, m.Target.envName(), m.Version)
+	snapshotID, err := alicloud.CreateDiskSnapshot(m.ECS, diskID, name, tags...)
+	if err != nil {
+		return stopped, err
+	}
+	defer alicloud.DeleteSnapshot(m.ECS, snapshotID)
+	if err := alicloud.WaitForSnapshotReady(ctx, m.ECS, snapshotID, m.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+		return stopped, err
+	}
+	m.printf("  ✓ 快照已创建 (%s)\n", snapshotID)
+
+	m.printf("  复制快照到 %s（耗时取决于磁盘数据量）...\n", m.Target.Region)
+	copied, err := alicloud.CopySnapshot(m.ECS, snapshotID, m.Region, m.Target.Region, name, tags...)
+	if err != nil {
+		return stopped, err
+	}
+	m.Target.SnapshotID = copied
+	if err := alicloud.WaitForSnapshotReady(ctx, m.Target.ECS, copied, m.Target.Region, m.WaitInterval, m.WaitTimeout); err != nil {
+		return stopped, err
+	}
+	m.printf("  ✓ 快照已复制 (%s)\n", copied)
+	return stopped, nil
+}
+
+// recordSnapshot 将目标区域的快照记入备份目录（标签 migrate），可用 cloudcode backup 恢复或清理
+func (m *Migrator) recordSnapshot(newState *config.State) {
+	dir := m.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil {
+		return
+	}
+	catalog.Add(config.Backup{
+		CloudCodeVersion: m.Version,
+		SnapshotID:       m.Target.SnapshotID,
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+		Label:            BackupLabelMigrate,
+		Region:           newState.Region,
+		DiskSize:         newState.Resources.ECS.SystemDiskSize,
+		DiskCategory:     newState.Resources.ECS.SystemDiskCategory,
+		InstanceType:     newState.Resources.ECS.InstanceType,
+		Domain:           newState.CloudCode.Domain,
+		Username:         newState.CloudCode.Username,
+		DevboxMode:       newState.CloudCode.DevboxMode,
+		AutoSuspend:      newState.CloudCode.AutoSuspend,
+		Authelia:         newState.CloudCode.Authelia,
+	})
+	_ = config.SaveBackupCatalogTo(dir, catalog)
+}
+
+// restartSource 目标实例创建前失败时重新启动源实例（失败仅警告）
+func (m *Migrator) restartSource(ctx context.Context, state *config.State) {
+	instanceID := state.Resources.ECS.ID
+	err := alicloud.StartECSInstance(m.ECS, instanceID)
+	if err == nil {
+		_, err = alicloud.WaitForInstanceRunning(ctx, m.ECS, instanceID, m.Region, m.WaitInterval, m.WaitTimeout)
+	}
+	if err != nil {
+		m.printf("  ⚠ 重新启动源实例失败，请运行 cloudcode resume: %v\n", err)
+		return
+	}
+	m.printf("  ✓ 源实例已重新启动\n")
+}
+
+// resumeHint 目标实例已创建后失败：源区域资源保留（源实例保持停机），提示重新运行继续
+func (m *Migrator) resumeHint(err error) error {
+	return fmt.Errorf("%w\n源区域资源未删除，排查后重新运行 cloudcode migrate --to-region %s 继续", err, m.Target.Region)
+}
+
+// verify 通过 SSH 确认目标实例上所有容器都在运行
+func (m *Migrator) verify(ctx context.Context, state *config.State) error {
+	privateKey, err := readSSHKeyFrom(m.migrateDir(), state)
+	if err != nil {
+		return err
+	}
+	dialFunc := m.Target.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 30 * time.Second})
+	if err != nil {
+		return fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	defer sshClient.Close()
+
+	interval := m.WaitInterval
+	if interval == 0 {
+		interval = alicloud.DefaultWaitInterval
+	}
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		output, err := sshClient.RunCommand(ctx, "cd ~/cloudcode && docker compose ps --all --format '{{.Name}} {{.State}}'")
+		if err == nil {
+			var running, notRunning []string
+			for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+				parts := strings.Fields(line)
+				if len(parts) < 2 {
+					continue
+				}
+				if parts[1] == "running" {
+					running = append(running, parts[0])
+				} else {
+					notRunning = append(notRunning, parts[0]+" ("+parts[1]+")")
+				}
+			}
+			if len(running) > 0 && len(notRunning) == 0 {
+				m.printf("  ✓ %d 个容器运行正常\n", len(running))
+				return nil
+			}
+			lastErr = fmt.Errorf("容器未运行: %s", strings.Join(notRunning, ", "))
+			if len(running) == 0 && len(notRunning) == 0 {
+				lastErr = fmt.Errorf("没有运行中的容器")
+			}
+		} else {
+			lastErr = fmt.Errorf("检查容器状态失败: %w", err)
+		}
+		if attempt >= migrateHealthAttempts {
+			return lastErr
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// teardown 删除源区域资源，返回删除失败的资源说明。RAM 角色已由新实例沿用，不删除。
+func (m *Migrator) teardown(state *config.State) []string {
+	m.printf("\n删除 %s 的资源:\n", m.Region)
+	source := *state
+	source.Resources.RAMRole = config.RAMRoleResource{}
+	d := &Destroyer{
+		ECS:      m.ECS,
+		VPC:      m.VPC,
+		RAM:      m.RAM,
+		FC:       m.FC,
+		Output:   m.Output,
+		Region:   m.Region,
+		StateDir: filepath.Join(m.migrateDir(), "source"),
+		Env:      m.Target.envName(),
+	}
+	return d.deleteResources(&source)
+}
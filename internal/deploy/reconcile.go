@@ -0,0 +1,64 @@
+package deploy
+
+// reconcile.go 将 state 中的运行状态与云上实例对齐：自动停机 agent 在实例上直接调用 StopInstance，
+// 本地 state 仍记录为 running；下一次 CLI 调用时查询 DescribeInstances 更新为 suspended（反之亦然）。
+// 抢占式实例被回收后实例不再存在，state 更新为 reclaimed，由 cloudcode deploy 从快照重建。
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+// ReconcileStatus 按云上实例状态更新 stateDir 中的 running / suspended，返回是否有变更。
+// 实例处于中间状态（Starting / Stopping）时不做修改。抢占式实例不存在时标记为 reclaimed。
+func ReconcileStatus(ecsCli alicloud.ECSAPI, regionID, stateDir string, out io.Writer) (bool, error) {
+	state, err := loadStateFrom(stateDir)
+	if err != nil {
+		return false, nil
+	}
+	if !state.HasECS() || state.Status == "destroyed" {
+		return false, nil
+	}
+
+	info, err := alicloud.DescribeECSInstance(ecsCli, state.Resources.ECS.ID, regionID)
+	if errors.Is(err, alicloud.ErrResourceNotFound) && state.Resources.ECS.Spot {
+		markReclaimed(state)
+		fmt.Fprintf(out, "ℹ 抢占式实例已被回收，本地状态已更新为 reclaimed；从最新快照重建: cloudcode deploy\n")
+		if err := config.SaveStateTo(stateDir, state); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("查询 ECS 实例失败: %w", err)
+	}
+
+	switch {
+	case info.Status == "Stopped" && state.Status != "suspended":
+		state.SetStatus("suspended")
+		fmt.Fprintf(out, "ℹ 实例已停机（自动停机或控制台操作），本地状态已更新为 suspended；恢复运行: cloudcode resume\n")
+	case info.Status == "Running" && state.Status == "suspended":
+		state.SetStatus("running")
+		fmt.Fprintf(out, "ℹ 实例已在运行，本地状态已更新为 running\n")
+	default:
+		return false, nil
+	}
+	if err := config.SaveStateTo(stateDir, state); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// markReclaimed 清除已回收实例的 ID 和地址（保留规格供重建），VPC / 安全组 / EIP 等资源保留
+func markReclaimed(state *config.State) {
+	ecs := &state.Resources.ECS
+	ecs.ID = ""
+	ecs.PrivateIP = ""
+	ecs.PublicIP = ""
+	ecs.HostKey = ""
+	state.SetStatus("reclaimed")
+}
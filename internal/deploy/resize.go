@@ -0,0 +1,232 @@
+package deploy
+
+// resize.go 实现 cloudcode resize：原地变更实例规格 / 扩容系统盘。
+// 流程：停机（普通模式，保留资源）→ ModifyInstanceSpec / ResizeDisk（离线）→ 更新 state → 启动 →
+// SSH 扩展分区和根文件系统。--dry-run 只输出变更内容和每月费用差额。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// growRootFSScript 扩展根分区和文件系统（growpart 无需扩展时返回非 0，忽略；cloud-init 可能已在启动时扩展）
+const growRootFSScript = `set -e
+src=$(findmnt -n -o SOURCE /)
+disk=/dev/$(lsblk -no PKNAME "$src")
+part=$(cat /sys/class/block/$(basename "$src")/partition)
+growpart "$disk" "$part" >/dev/null || true
+case $(findmnt -n -o FSTYPE /) in
+  xfs) xfs_growfs / >/dev/null ;;
+  *) resize2fs "$src" >/dev/null 2>&1 ;;
+esac
+df -h --output=size / | tail -1 | tr -d ' '`
+
+// Resizer 规格变更器
+type Resizer struct {
+	ECS          alicloud.ECSAPI
+	Prompter     *config.Prompter
+	Output       io.Writer
+	Region       string
+	StateDir     string
+	SSHDialFunc  SSHDialFactory
+	Prices       *cost.PriceTable // 费用差额估算使用的价格表，默认内置价格表
+	Spec         config.CloudSpec // 目标规格，仅使用 InstanceType / DiskSize，零值表示不变
+	WaitInterval time.Duration
+	WaitTimeout  time.Duration
+}
+
+func (r *Resizer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(r.Output, format, args...)
+}
+
+func (r *Resizer) getStateDir() string {
+	if r.StateDir != "" {
+		return r.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
+
+// Run 执行变更。dryRun 只输出计划，force 跳过确认。
+func (r *Resizer) Run(ctx context.Context, dryRun, force bool) error {
+	if r.Spec.InstanceType == "" && r.Spec.DiskSize == 0 {
+		return fmt.Errorf("请用 --instance-type 或 --disk-size 指定新规格")
+	}
+	stateDir := r.getStateDir()
+	state, err := loadStateFrom(stateDir)
+	if err != nil {
+		return fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	switch state.Status {
+	case "destroyed":
+		return fmt.Errorf("实例已销毁，可用 cloudcode deploy --instance-type / --disk-size 从快照以新规格恢复")
+	case "suspended":
+		return fmt.Errorf("实例已停机，请先运行 cloudcode resume")
+	}
+	if !state.HasECS() {
+		return fmt.Errorf("未找到 ECS 实例")
+	}
+
+	backup, _ := config.LoadBackupFrom(stateDir)
+	catalog, _ := config.LoadBackupCatalogFrom(stateDir)
+	current := costSpecOf(state, backup, catalog)
+	target := current
+	if r.Spec.InstanceType != "" {
+		target.InstanceType = r.Spec.InstanceType
+	}
+	if r.Spec.DiskSize != 0 {
+		if r.Spec.DiskSize < current.DiskSize {
+			return fmt.Errorf("系统盘只能扩容，当前 %dGB，指定 %dGB", current.DiskSize, r.Spec.DiskSize)
+		}
+		target.DiskSize = r.Spec.DiskSize
+	}
+	changeType := target.InstanceType != current.InstanceType
+	growDisk := target.DiskSize != current.DiskSize
+	if !changeType && !growDisk {
+		r.printf("规格未变化: %s，系统盘 %dGB。\n", current.InstanceType, current.DiskSize)
+		return nil
+	}
+
+	r.printf("变更部署规格:\n")
+	if changeType {
+		r.printf("  实例规格: %s → %s\n", current.InstanceType, target.InstanceType)
+	}
+	if growDisk {
+		r.printf("  系统盘:   %dGB → %dGB (%s)\n", current.DiskSize, target.DiskSize, target.DiskCategory)
+	}
+	r.printCostDiff(current, target)
+
+	if dryRun {
+		r.printf("\n（dry-run，未做任何变更）\n")
+		return nil
+	}
+	if !force {
+		confirmed, err := r.Prompter.PromptConfirm("变更期间实例停机几分钟，确认变更?", true)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			r.printf("已取消。\n")
+			return nil
+		}
+	}
+
+	ecs := &state.Resources.ECS
+	r.printf("停机中...\n")
+	if err := alicloud.StopECSInstance(r.ECS, ecs.ID, false); err != nil {
+		return fmt.Errorf("停机失败: %w", err)
+	}
+	if err := alicloud.WaitForInstanceStatus(ctx, r.ECS, ecs.ID, r.Region, "Stopped", r.WaitInterval, r.WaitTimeout); err != nil {
+		return fmt.Errorf("等待停机完成失败: %w", err)
+	}
+	r.printf("  ✓ ECS 已停机\n")
+
+	// 变更失败时仍启动实例（已完成的变更写入 state），避免留下停机的实例
+	changeErr := r.modify(state, target, changeType, growDisk)
+	if err := saveStateTo(stateDir, state); err != nil {
+		return err
+	}
+
+	r.printf("启动中...\n")
+	if err := alicloud.StartECSInstance(r.ECS, ecs.ID); err != nil {
+		return fmt.Errorf("启动失败: %w（可运行 cloudcode resume 重试）", err)
+	}
+	if _, err := alicloud.WaitForInstanceRunning(ctx, r.ECS, ecs.ID, r.Region, r.WaitInterval, r.WaitTimeout); err != nil {
+		return fmt.Errorf("等待启动完成失败: %w", err)
+	}
+	r.printf("  ✓ ECS 已启动\n")
+	if changeErr != nil {
+		return changeErr
+	}
+
+	if growDisk {
+		r.growFilesystem(ctx, state)
+	}
+
+	r.printf("✅ 规格变更完成: %s，系统盘 %dGB\n", ecs.InstanceType, ecs.SystemDiskSize)
+	return nil
+}
+
+// modify 在停机状态下变更规格和系统盘，成功的变更同步到 state
+func (r *Resizer) modify(state *config.State, target cost.Spec, changeType, growDisk bool) error {
+	ecs := &state.Resources.ECS
+	if changeType {
+		if err := alicloud.ModifyInstanceSpec(r.ECS, ecs.ID, target.InstanceType); err != nil {
+			return err
+		}
+		ecs.InstanceType = target.InstanceType
+		r.printf("  ✓ 实例规格已变更: %s\n", target.InstanceType)
+	}
+	if growDisk {
+		diskID, err := alicloud.GetSystemDiskID(r.ECS, ecs.ID, r.Region)
+		if err != nil {
+			return err
+		}
+		if err := alicloud.ResizeDisk(r.ECS, diskID, target.DiskSize); err != nil {
+			return err
+		}
+		ecs.SystemDiskSize = target.DiskSize
+		r.printf("  ✓ 系统盘已扩容: %dGB\n", target.DiskSize)
+	}
+	return nil
+}
+
+// growFilesystem 通过 SSH 扩展根分区和文件系统，失败时提示手动执行
+func (r *Resizer) growFilesystem(ctx context.Context, state *config.State) {
+	manual := "  手动执行: ssh 登录后运行 growpart /dev/vda 1 && resize2fs /dev/vda1\n"
+	privateKey, err := readSSHKeyFrom(r.getStateDir(), state)
+	if err != nil {
+		r.printf("  ⚠ 无法读取 SSH 私钥，未扩展文件系统\n%s", manual)
+		return
+	}
+	dialFunc := r.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{})
+	if err != nil {
+		r.printf("  ⚠ SSH 连接失败: %v\n%s", err, manual)
+		return
+	}
+	defer sshClient.Close()
+
+	output, err := sshClient.RunCommand(ctx, growRootFSScript)
+	if err != nil {
+		r.printf("  ⚠ 扩展文件系统失败: %v\n%s", err, manual)
+		return
+	}
+	r.printf("  ✓ 文件系统已扩展（/ 共 %s）\n", strings.TrimSpace(output))
+}
+
+// printCostDiff 输出运行状态下的每月费用差额，价格表缺少条目时给出提示
+func (r *Resizer) printCostDiff(current, target cost.Spec) {
+	prices := r.Prices
+	if prices == nil {
+		prices = cost.BuiltinPrices()
+	}
+	before := prices.Estimate(current)
+	after := prices.Estimate(target)
+	var missing []string
+	for _, m := range append(before.Missing, after.Missing...) {
+		if !contains(missing, m) {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		r.printf("  ⚠ 价格表中缺少 %s，无法估算费用差额\n", strings.Join(missing, ", "))
+		return
+	}
+	money := func(v float64) string { return formatMoney(after.Currency, v, 2) }
+	diff := after.Monthly("running") - before.Monthly("running")
+	sign := "+"
+	if diff < 0 {
+		sign, diff = "-", -diff
+	}
+	r.printf("  费用估算（运行中）: %s/月 → %s/月（%s%s/月）\n",
+		money(before.Monthly("running")), money(after.Monthly("running")), sign, money(diff))
+}
@@ -58,6 +58,9 @@ func (r *Resumer) Run(ctx context.Context) error {
 		if state.Status == "destroyed" {
 			return fmt.Errorf("实例已销毁，请使用 cloudcode deploy 从快照恢复或重新部署")
 		}
+		if state.Status == "reclaimed" {
+			return fmt.Errorf("抢占式实例已被回收，请使用 cloudcode deploy 从快照重建")
+		}
 		return fmt.Errorf("实例状态为 %s，无法恢复", state.Status)
 	}
 
@@ -114,7 +117,7 @@ func (r *Resumer) Run(ctx context.Context) error {
 	}
 
 	// 更新 state
-	state.Status = "running"
+	state.SetStatus("running")
 	if err := r.saveState(state); err != nil {
 		return err
 	}
@@ -128,6 +131,6 @@ func (r *Resumer) getStateDir() string {
 	if r.StateDir != "" {
 		return r.StateDir
 	}
-	dir, _ := config.GetStateDir()
+	dir, _ := config.GetActiveEnvDir()
 	return dir
 }
@@ -0,0 +1,170 @@
+package deploy
+
+// secrets.go 管理 devbox 的 provider API Key：修改本地 secrets.env 后重新渲染 .env 上传到实例，
+// 仅重建 devbox 容器（Caddy / Authelia 不受影响，登录会话保持）。
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+	tmpl "github.com/hwuu/cloudcode/internal/template"
+)
+
+// devbox .env 在实例上的路径，以及仅重建 devbox 的命令（env_file 变更需要重建容器才能生效；
+// 独立 devbox 模式下有多个 devbox-<user> 服务，一并重建）
+const (
+	remoteEnvPath     = "/root/cloudcode/.env"
+	recreateDevboxCmd = "cd ~/cloudcode && docker compose up -d --no-deps --force-recreate $(docker compose config --services | grep '^devbox')"
+)
+
+// SecretsManager provider API Key 管理器
+type SecretsManager struct {
+	Output      io.Writer
+	StateDir    string // 覆盖默认 state 目录（测试用）
+	SSHDialFunc SSHDialFactory
+	SFTPFactory SFTPClientFactory
+	LocalOnly   bool // 仅修改本地 secrets.env，不推送到实例
+}
+
+func (m *SecretsManager) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.Output, format, args...)
+}
+
+// List 列出已配置的变量（密钥脱敏显示）及未配置的常用 provider
+func (m *SecretsManager) List() error {
+	secrets, err := config.LoadSecretsFrom(m.getStateDir())
+	if err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		m.printf("暂未配置任何 API Key。\n")
+	}
+	for _, name := range config.SortedSecretNames(secrets) {
+		value := secrets[name]
+		if config.IsSecretValue(name) {
+			value = config.MaskSecret(value)
+		}
+		m.printf("  %-24s %s\n", name, value)
+	}
+
+	var unset []config.ProviderKey
+	for _, k := range config.KnownProviderKeys {
+		if _, ok := secrets[k.Name]; !ok {
+			unset = append(unset, k)
+		}
+	}
+	if len(unset) > 0 {
+		m.printf("\n可选的常用变量（cloudcode secrets set <NAME> 设置）:\n")
+		for _, k := range unset {
+			m.printf("  %-24s %s\n", k.Name, k.Description)
+		}
+	}
+	return nil
+}
+
+// Set 设置变量并推送到实例
+func (m *SecretsManager) Set(ctx context.Context, name, value string) error {
+	if err := config.ValidateSecretName(name); err != nil {
+		return err
+	}
+	if err := config.ValidateSecretValue(name, value); err != nil {
+		return err
+	}
+	dir := m.getStateDir()
+	secrets, err := config.LoadSecretsFrom(dir)
+	if err != nil {
+		return err
+	}
+	if secrets[name] == value {
+		m.printf("%s 未变化。\n", name)
+		return nil
+	}
+	secrets[name] = value
+	if err := config.SaveSecretsTo(dir, secrets); err != nil {
+		return err
+	}
+	m.printf("✓ 已保存 %s\n", name)
+	return m.push(ctx, secrets)
+}
+
+// Unset 删除变量并推送到实例
+func (m *SecretsManager) Unset(ctx context.Context, name string) error {
+	dir := m.getStateDir()
+	secrets, err := config.LoadSecretsFrom(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("未配置 %s（cloudcode secrets list 查看已配置的变量）", name)
+	}
+	delete(secrets, name)
+	if err := config.SaveSecretsTo(dir, secrets); err != nil {
+		return err
+	}
+	m.printf("✓ 已删除 %s\n", name)
+	return m.push(ctx, secrets)
+}
+
+// push 重新渲染 .env 上传到实例，并重建 devbox 容器；实例未运行时仅提示
+func (m *SecretsManager) push(ctx context.Context, secrets map[string]string) error {
+	if m.LocalOnly {
+		m.printf("  未推送到实例（--local），运行 cloudcode deploy --app 后生效\n")
+		return nil
+	}
+	dir := m.getStateDir()
+	state, err := loadStateFrom(dir)
+	if err != nil || !state.HasEIP() || state.Resources.EIP.IP == "" || state.Status == "destroyed" {
+		m.printf("  当前环境未部署，将在下次 cloudcode deploy 时写入实例\n")
+		return nil
+	}
+	if state.Status == "suspended" {
+		m.printf("  实例已停机，恢复后运行 cloudcode deploy --app 生效\n")
+		return nil
+	}
+
+	content, err := tmpl.RenderTemplate("templates/env.tmpl", &tmpl.TemplateData{Env: secrets})
+	if err != nil {
+		return fmt.Errorf("渲染 .env 失败: %w", err)
+	}
+
+	privateKey, err := readSSHKeyFrom(dir, state)
+	if err != nil {
+		return err
+	}
+	eipIP := state.Resources.EIP.IP
+
+	sftpClient, err := m.SFTPFactory(eipIP, 22, "root", privateKey)
+	if err != nil {
+		return fmt.Errorf("SFTP 连接失败: %w", err)
+	}
+	defer sftpClient.Close()
+	if err := sftpClient.UploadFile(content, remoteEnvPath); err != nil {
+		return fmt.Errorf("上传 .env 失败: %w", err)
+	}
+	m.printf("  ✓ .env 已更新\n")
+
+	dialFunc := m.SSHDialFunc(eipIP, 22, "root", privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	defer sshClient.Close()
+	cmdCtx, cancel := context.WithTimeout(ctx, remote.DefaultCommandTimeout)
+	defer cancel()
+	if _, err := sshClient.RunCommand(cmdCtx, recreateDevboxCmd); err != nil {
+		return fmt.Errorf("重启 devbox 失败: %w", err)
+	}
+	m.printf("  ✓ devbox 已重启\n")
+	return nil
+}
+
+func (m *SecretsManager) getStateDir() string {
+	if m.StateDir != "" {
+		return m.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
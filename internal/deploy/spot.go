@@ -0,0 +1,113 @@
+package deploy
+
+// spot.go 实现抢占式实例被回收后的重建：实例上的 agent 在回收通知时创建了带部署标签的系统盘快照，
+// deploy 检测到 reclaimed 状态后从最新快照创建新实例，沿用 VPC / 安全组 / EIP，IP 和域名不变。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+// recoverReclaimed 从最新快照重建被回收的抢占式实例
+func (d *Deployer) recoverReclaimed(ctx context.Context, state *config.State) error {
+	if state.DeploymentID == "" {
+		return fmt.Errorf("state 缺少部署 ID，无法查找回收快照")
+	}
+	snap, err := alicloud.LatestDeploymentSnapshot(d.ECS, d.Region, state.DeploymentID)
+	if errors.Is(err, alicloud.ErrResourceNotFound) {
+		return fmt.Errorf("未找到本部署的快照（回收快照可能仍在创建中，稍后重试），可运行 cloudcode destroy 后重新部署")
+	}
+	if err != nil {
+		return err
+	}
+	d.printf("\n抢占式实例已被回收，从快照 %s（%s）重建。\n", snap.ID, formatBackupTime(snap.CreatedAt))
+	d.SnapshotID = snap.ID
+
+	// 沿用原实例规格，命令行 / 配置文件中指定的字段优先；系统盘不能小于快照源盘
+	ecs := state.Resources.ECS
+	diskSize := ecs.SystemDiskSize
+	if snap.SourceDiskSize > diskSize {
+		diskSize = snap.SourceDiskSize
+	}
+	d.Spec = config.CloudSpec{
+		InstanceType:   ecs.InstanceType,
+		DiskSize:       diskSize,
+		DiskCategory:   ecs.SystemDiskCategory,
+		Spot:           true,
+		SpotPriceLimit: ecs.SpotPriceLimit,
+	}.Merge(d.Spec)
+	if d.Spec.DiskSize < snap.SourceDiskSize {
+		return fmt.Errorf("系统盘 %dGB 小于快照磁盘 %dGB，从快照恢复时不能缩小系统盘", d.Spec.DiskSize, snap.SourceDiskSize)
+	}
+
+	cfg := &DeployConfig{
+		Domain:   state.CloudCode.Domain,
+		Username: state.CloudCode.Username,
+		Email:    state.CloudCode.Username + "@localhost",
+	}
+	d.applyPresetKeys(cfg, state.CloudCode.Authelia)
+	d.printf("  域名: %s\n", cfg.Domain)
+	d.printf("  用户名: %s\n", cfg.Username)
+
+	if err := d.CreateResources(ctx, state, ""); err != nil {
+		return err
+	}
+
+	state.SetStatus("running")
+	if err := d.DeployApp(ctx, state, cfg); err != nil {
+		return err
+	}
+	d.setupAutoSuspend(ctx, state)
+	if err := d.saveState(state); err != nil {
+		return err
+	}
+	if err := d.HealthCheck(ctx, state); err != nil {
+		d.printf("  ⚠ 健康检查失败: %v\n", err)
+	}
+
+	// agent 创建的回收快照记入备份目录，便于 cloudcode backup list / prune 管理
+	d.recordSpotSnapshot(state, snap)
+
+	d.printf("\n✅ 已从快照重建: https://%s\n", state.CloudCode.Domain)
+	if state.Resources.Wake.FunctionName != "" {
+		// 唤醒函数的实例 ID 和角色权限仍是已释放的旧实例
+		d.printf("  ⚠ 唤醒页仍指向已回收的实例，请运行 cloudcode wake enable 更新\n")
+	}
+	return nil
+}
+
+// recordSpotSnapshot 将快照加入备份目录（已记录时跳过），失败仅警告
+func (d *Deployer) recordSpotSnapshot(state *config.State, snap *alicloud.SnapshotInfo) {
+	dir := d.getStateDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err == nil && !catalog.Contains(snap.ID) {
+		createdAt := snap.CreatedAt
+		if t, perr := time.Parse(time.RFC3339, createdAt); perr == nil {
+			createdAt = t.UTC().Format(time.RFC3339)
+		}
+		catalog.Add(config.Backup{
+			CloudCodeVersion: d.Version,
+			SnapshotID:       snap.ID,
+			CreatedAt:        createdAt,
+			Label:            BackupLabelSpot,
+			Region:           d.Region,
+			DiskSize:         snap.SourceDiskSize,
+			DiskCategory:     state.Resources.ECS.SystemDiskCategory,
+			InstanceType:     state.Resources.ECS.InstanceType,
+			Domain:           state.CloudCode.Domain,
+			Username:         state.CloudCode.Username,
+			DevboxMode:       state.CloudCode.DevboxMode,
+			AutoSuspend:      state.CloudCode.AutoSuspend,
+			Authelia:         state.CloudCode.Authelia,
+		})
+		err = config.SaveBackupCatalogTo(dir, catalog)
+	}
+	if err != nil {
+		d.printf("  ⚠ 记录快照到备份目录失败: %v\n", err)
+	}
+}
@@ -2,24 +2,106 @@ package deploy
 
 // status.go 查询并展示当前部署状态：云资源信息 + 容器运行状态。
 // 通过 SSH 连接 ECS 执行 docker compose ps 获取容器状态。
+// 支持 text（默认，面向人）和 json/yaml（面向脚本，结构见 StatusReport）三种输出格式。
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/hwuu/cloudcode/internal/alicloud"
 	"github.com/hwuu/cloudcode/internal/config"
 	"github.com/hwuu/cloudcode/internal/remote"
+	"gopkg.in/yaml.v3"
 )
 
+// 输出格式
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// StatusSchemaVersion 机器可读状态文档的版本号，字段发生不兼容变更时递增
+const StatusSchemaVersion = "1"
+
+// ErrUnhealthy 机器可读输出模式下，部署存在异常时返回（调用方据此设置非零退出码）
+var ErrUnhealthy = errors.New("deployment is unhealthy")
+
 // StatusRunner 状态查询器
 type StatusRunner struct {
 	Output      io.Writer
 	StateDir    string
 	SSHDialFunc SSHDialFactory
+	ECS         alicloud.ECSAPI // 可选：查询实例实时状态
+	VPC         alicloud.VPCAPI // CheckCloud 时必需
+	Format      string          // 输出格式：text（默认）/ json / yaml
+	Env         string          // 环境名（仅用于输出）
+	CheckCloud  bool            // 核对云上资源与 state 是否一致（drift 检测）
+	KnownIDs    map[string]bool // 所有环境记录的资源 ID，drift 检测时不视为 extra
+}
+
+// StatusReport 机器可读的状态文档（json/yaml 输出）
+type StatusReport struct {
+	SchemaVersion string            `json:"schema_version" yaml:"schema_version"`
+	Env           string            `json:"env,omitempty" yaml:"env,omitempty"`
+	Deployed      bool              `json:"deployed" yaml:"deployed"`
+	Status        string            `json:"status" yaml:"status"`
+	Region        string            `json:"region,omitempty" yaml:"region,omitempty"`
+	CreatedAt     string            `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	Resources     StatusResources   `json:"resources" yaml:"resources"`
+	Instance      *InstanceStatus   `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Drift         *DriftReport      `json:"drift,omitempty" yaml:"drift,omitempty"`
+	App           *AppStatus        `json:"app,omitempty" yaml:"app,omitempty"`
+	Containers    []ContainerStatus `json:"containers" yaml:"containers"`
+	Healthy       bool              `json:"healthy" yaml:"healthy"`
+	Problems      []string          `json:"problems" yaml:"problems"`
+}
+
+// StatusResources state.json 中记录的云资源 ID
+type StatusResources struct {
+	VPCID           string `json:"vpc_id" yaml:"vpc_id"`
+	VSwitchID       string `json:"vswitch_id" yaml:"vswitch_id"`
+	ZoneID          string `json:"zone_id" yaml:"zone_id"`
+	SecurityGroupID string `json:"security_group_id" yaml:"security_group_id"`
+	SSHKeyPair      string `json:"ssh_key_pair" yaml:"ssh_key_pair"`
+	ECSID           string `json:"ecs_id" yaml:"ecs_id"`
+	InstanceType    string `json:"instance_type" yaml:"instance_type"`
+	Image           string `json:"image" yaml:"image"`
+	DiskSize        int    `json:"disk_size" yaml:"disk_size"`
+	DiskCategory    string `json:"disk_category" yaml:"disk_category"`
+	EIPID           string `json:"eip_id" yaml:"eip_id"`
+	EIP             string `json:"eip" yaml:"eip"`
+}
+
+// InstanceStatus ECS 实例的实时状态（来自 DescribeInstances）
+type InstanceStatus struct {
+	State string `json:"state,omitempty" yaml:"state,omitempty"` // Running / Stopped / ...
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DriftReport 云端核对结果（仅 --check-cloud 时输出）
+type DriftReport struct {
+	Items []DriftItem `json:"items" yaml:"items"`
+	Error string      `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// AppStatus 应用层信息
+type AppStatus struct {
+	Domain   string `json:"domain" yaml:"domain"`
+	URL      string `json:"url" yaml:"url"`
+	Username string `json:"username" yaml:"username"`
+}
+
+// ContainerStatus 单个容器状态（来自 docker compose ps）
+type ContainerStatus struct {
+	Name  string `json:"name" yaml:"name"`
+	State string `json:"state" yaml:"state"`
 }
 
 func (s *StatusRunner) printf(format string, args ...interface{}) {
@@ -35,6 +117,145 @@ func (s *StatusRunner) loadState() (*config.State, error) {
 
 // Run 执行状态查询
 func (s *StatusRunner) Run(ctx context.Context) error {
+	switch s.Format {
+	case "", OutputText:
+		return s.runText(ctx)
+	case OutputJSON, OutputYAML:
+		report := s.Collect(ctx)
+		if err := s.writeReport(report); err != nil {
+			return err
+		}
+		if !report.Healthy {
+			return ErrUnhealthy
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的输出格式: %s（可选 text/json/yaml）", s.Format)
+	}
+}
+
+// Collect 收集完整状态（state + 实例实时状态 + 容器状态），并判定是否健康
+func (s *StatusRunner) Collect(ctx context.Context) *StatusReport {
+	report := &StatusReport{
+		SchemaVersion: StatusSchemaVersion,
+		Env:           s.Env,
+		Containers:    []ContainerStatus{},
+		Problems:      []string{},
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		report.Status = "not_deployed"
+		report.Problems = append(report.Problems, "未找到部署记录")
+		return report
+	}
+
+	report.Deployed = true
+	report.Status = stateStatus(state)
+	report.Region = state.Region
+	report.CreatedAt = state.CreatedAt
+	report.Resources = StatusResources{
+		VPCID:           state.Resources.VPC.ID,
+		VSwitchID:       state.Resources.VSwitch.ID,
+		ZoneID:          state.Resources.VSwitch.ZoneID,
+		SecurityGroupID: state.Resources.SecurityGroup.ID,
+		SSHKeyPair:      state.Resources.SSHKeyPair.Name,
+		ECSID:           state.Resources.ECS.ID,
+		InstanceType:    state.Resources.ECS.InstanceType,
+		Image:           state.OSImage,
+		DiskSize:        state.Resources.ECS.SystemDiskSize,
+		DiskCategory:    state.Resources.ECS.SystemDiskCategory,
+		EIPID:           state.Resources.EIP.ID,
+		EIP:             state.Resources.EIP.IP,
+	}
+	if state.CloudCode.Domain != "" {
+		report.App = &AppStatus{
+			Domain:   state.CloudCode.Domain,
+			URL:      "https://" + state.CloudCode.Domain,
+			Username: state.CloudCode.Username,
+		}
+	}
+
+	if s.CheckCloud {
+		report.Drift = &DriftReport{Items: []DriftItem{}}
+		items, err := s.driftChecker().Check(state)
+		if err != nil {
+			report.Drift.Error = err.Error()
+			report.Problems = append(report.Problems, fmt.Sprintf("云端核对失败: %v", err))
+		} else {
+			report.Drift.Items = items
+			for _, item := range items {
+				report.Problems = append(report.Problems, item.String())
+			}
+		}
+	}
+
+	if report.Status != "running" {
+		report.Problems = append(report.Problems, fmt.Sprintf("实例状态为 %s", report.Status))
+		report.Healthy = false
+		return report
+	}
+
+	if !state.IsComplete() {
+		report.Problems = append(report.Problems, "云资源不完整")
+	}
+
+	// 实例实时状态
+	if s.ECS != nil && state.HasECS() {
+		report.Instance = &InstanceStatus{}
+		info, err := alicloud.DescribeECSInstance(s.ECS, state.Resources.ECS.ID, state.Region)
+		if err != nil {
+			report.Instance.Error = err.Error()
+			report.Problems = append(report.Problems, fmt.Sprintf("查询 ECS 实例失败: %v", err))
+		} else {
+			report.Instance.State = info.Status
+			if info.Status != "Running" {
+				report.Problems = append(report.Problems, fmt.Sprintf("ECS 实例状态为 %s", info.Status))
+			}
+		}
+	}
+
+	// 容器状态
+	if state.Resources.EIP.IP != "" && state.Resources.SSHKeyPair.Name != "" && s.SSHDialFunc != nil {
+		containers, err := s.checkContainers(ctx, state)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("无法获取容器状态: %v", err))
+		} else {
+			report.Containers = containers
+			if len(containers) == 0 {
+				report.Problems = append(report.Problems, "没有运行中的容器")
+			}
+			for _, c := range containers {
+				if c.State != "running" {
+					report.Problems = append(report.Problems, fmt.Sprintf("容器 %s 状态为 %s", c.Name, c.State))
+				}
+			}
+		}
+	}
+
+	report.Healthy = len(report.Problems) == 0
+	return report
+}
+
+func (s *StatusRunner) writeReport(report *StatusReport) error {
+	if s.Format == OutputYAML {
+		enc := yaml.NewEncoder(s.Output)
+		enc.SetIndent(2)
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("输出 YAML 失败: %w", err)
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(s.Output)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("输出 JSON 失败: %w", err)
+	}
+	return nil
+}
+
+// runText 输出面向人的文本格式
+func (s *StatusRunner) runText(ctx context.Context) error {
 	state, err := s.loadState()
 	if err != nil {
 		s.printf("未找到部署记录。请先运行 cloudcode deploy\n")
@@ -43,6 +264,9 @@ func (s *StatusRunner) Run(ctx context.Context) error {
 
 	s.printf("CloudCode 部署状态\n")
 	s.printf("─────────────────────────────────────────\n")
+	if s.Env != "" {
+		s.printf("%s %s\n", padRight("环境:", 14), s.Env)
+	}
 	s.printf("%s %s\n", padRight("区域:", 14), state.Region)
 	s.printf("%s %s\n", padRight("创建时间:", 14), state.CreatedAt)
 	s.printf("\n")
@@ -54,11 +278,45 @@ func (s *StatusRunner) Run(ctx context.Context) error {
 	s.printResource("安全组", state.Resources.SecurityGroup.ID)
 	s.printResource("SSH 密钥对", state.Resources.SSHKeyPair.Name)
 	s.printResource("ECS 实例", state.Resources.ECS.ID)
+	if state.Resources.ECS.InstanceType != "" {
+		spec := state.Resources.ECS.InstanceType
+		if state.Resources.ECS.SystemDiskSize > 0 {
+			spec += fmt.Sprintf(", 系统盘 %dGB", state.Resources.ECS.SystemDiskSize)
+			if state.Resources.ECS.SystemDiskCategory != "" {
+				spec += " " + state.Resources.ECS.SystemDiskCategory
+			}
+		}
+		s.printf("  %s %s\n", padRight("规格", 12), spec)
+	}
+	if state.OSImage != "" {
+		s.printf("  %s %s\n", padRight("镜像", 12), state.OSImage)
+	}
 	if state.Resources.EIP.ID != "" {
 		s.printf("  %s %s (IP: %s)\n", padRight("EIP", 12), state.Resources.EIP.ID, state.Resources.EIP.IP)
 	} else {
 		s.printf("  %s ❌ 未创建\n", padRight("EIP", 12))
 	}
+	if s.ECS != nil && state.HasECS() {
+		if info, err := alicloud.DescribeECSInstance(s.ECS, state.Resources.ECS.ID, state.Region); err != nil {
+			s.printf("  %s ⚠ 查询失败: %v\n", padRight("实例状态", 12), err)
+		} else {
+			s.printf("  %s %s\n", padRight("实例状态", 12), info.Status)
+		}
+	}
+
+	// 云端核对
+	if s.CheckCloud {
+		s.printf("\n云端核对:\n")
+		items, err := s.driftChecker().Check(state)
+		if err != nil {
+			s.printf("  ⚠ 核对失败: %v\n", err)
+		} else if len(items) == 0 {
+			s.printf("  ✓ 云上资源与 state 一致\n")
+		}
+		for _, item := range items {
+			s.printf("  ✗ %s\n", item)
+		}
+	}
 
 	// 应用信息
 	if state.CloudCode.Domain != "" {
@@ -77,15 +335,23 @@ func (s *StatusRunner) Run(ctx context.Context) error {
 		s.printf("  重新部署: cloudcode deploy\n")
 	} else if state.Resources.EIP.IP != "" && state.Resources.SSHKeyPair.Name != "" && s.SSHDialFunc != nil {
 		s.printf("\n容器状态:\n")
-		if err := s.checkContainers(ctx, state); err != nil {
+		containers, err := s.checkContainers(ctx, state)
+		if err != nil {
 			s.printf("  ⚠ 无法获取容器状态: %v\n", err)
 		}
+		for _, c := range containers {
+			s.printf("  %s %s\n", padRight(c.Name, 12), c.State)
+		}
 	}
 
 	s.printf("─────────────────────────────────────────\n")
 	return nil
 }
 
+func (s *StatusRunner) driftChecker() *DriftChecker {
+	return &DriftChecker{ECS: s.ECS, VPC: s.VPC, KnownIDs: s.KnownIDs}
+}
+
 func (s *StatusRunner) printResource(name, id string) {
 	padded := padRight(name, 12)
 	if id != "" {
@@ -118,10 +384,10 @@ func padRight(s string, width int) string {
 	return s + strings.Repeat(" ", width-dw)
 }
 
-func (s *StatusRunner) checkContainers(ctx context.Context, state *config.State) error {
+func (s *StatusRunner) checkContainers(ctx context.Context, state *config.State) ([]ContainerStatus, error) {
 	privateKey, err := readSSHKeyFrom(s.StateDir, state)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	dialFunc := s.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
@@ -129,25 +395,31 @@ func (s *StatusRunner) checkContainers(ctx context.Context, state *config.State)
 		Timeout: 10 * time.Second,
 	})
 	if err != nil {
-		return fmt.Errorf("SSH 连接失败: %w", err)
+		return nil, fmt.Errorf("SSH 连接失败: %w", err)
 	}
 	defer sshClient.Close()
 
 	output, err := sshClient.RunCommand(ctx, "cd ~/cloudcode && docker compose ps --format '{{.Name}} {{.State}}'")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return parseComposePS(output), nil
+}
+
+// parseComposePS 解析 docker compose ps --format '{{.Name}} {{.State}}' 的输出
+func parseComposePS(output string) []ContainerStatus {
+	var containers []ContainerStatus
 	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
-		if line != "" {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				s.printf("  %s %s\n", padRight(parts[0], 12), strings.Join(parts[1:], " "))
-			} else {
-				s.printf("  %s\n", line)
-			}
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			continue
+		}
+		c := ContainerStatus{Name: parts[0]}
+		if len(parts) >= 2 {
+			c.State = strings.Join(parts[1:], " ")
 		}
+		containers = append(containers, c)
 	}
-
-	return nil
+	return containers
 }
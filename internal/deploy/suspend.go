@@ -8,6 +8,7 @@ import (
 
 	"github.com/hwuu/cloudcode/internal/alicloud"
 	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
 )
 
 // Suspender 停机操作器
@@ -17,6 +18,7 @@ type Suspender struct {
 	Output       io.Writer
 	Region       string
 	StateDir     string
+	Prices       *cost.PriceTable // 停机费用提示使用的价格表，默认内置价格表
 	WaitInterval time.Duration
 	WaitTimeout  time.Duration
 }
@@ -54,12 +56,19 @@ func (s *Suspender) Run(ctx context.Context) error {
 	if state.Status == "destroyed" {
 		return fmt.Errorf("实例已销毁，请使用 cloudcode deploy 从快照恢复或重新部署")
 	}
+	if state.Status == "reclaimed" {
+		return fmt.Errorf("抢占式实例已被回收，请使用 cloudcode deploy 从快照重建")
+	}
 
 	if !state.HasECS() {
 		return fmt.Errorf("未找到 ECS 实例")
 	}
 
-	confirmed, err := s.Prompter.PromptConfirm("确认停机? 停机后仅收磁盘费 (~$1.2/月)", true)
+	question := "确认停机? 停机后仅收磁盘和 EIP 费用"
+	if hint := suspendedMonthly(s.Prices, state, s.getStateDir()); hint != "" {
+		question += " (" + hint + ")"
+	}
+	confirmed, err := s.Prompter.PromptConfirm(question, true)
 	if err != nil {
 		return err
 	}
@@ -80,7 +89,7 @@ func (s *Suspender) Run(ctx context.Context) error {
 	}
 
 	// 更新 state
-	state.Status = "suspended"
+	state.SetStatus("suspended")
 	if err := s.saveState(state); err != nil {
 		return err
 	}
@@ -89,3 +98,11 @@ func (s *Suspender) Run(ctx context.Context) error {
 	s.printf("  恢复运行: cloudcode resume\n")
 	return nil
 }
+
+func (s *Suspender) getStateDir() string {
+	if s.StateDir != "" {
+		return s.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
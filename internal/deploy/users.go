@@ -0,0 +1,325 @@
+package deploy
+
+// users.go 管理 Authelia 用户：通过 SFTP 读回实例上的 users_database.yml，修改目标用户后写回，
+// 其他用户保持不变。Authelia 开启了 watch 时自动重新加载，否则重启 authelia 容器。
+// 独立 devbox 模式下增删账号会同步重新渲染 docker-compose.yml / Caddyfile，创建或移除该账号的 devbox。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+	tmpl "github.com/hwuu/cloudcode/internal/template"
+)
+
+// users_database.yml 在实例上的路径，以及旧版部署（未开启 watch）重新加载用户的命令
+const (
+	remoteUsersDBPath  = "/root/cloudcode/authelia/users_database.yml"
+	restartAutheliaCmd = "cd ~/cloudcode && docker compose restart authelia"
+	reloadCaddyCmd     = "docker exec caddy caddy reload --config /etc/caddy/Caddyfile --adapter caddyfile"
+)
+
+// userChange 一次账号修改的结果
+type userChange struct {
+	message       string // 成功提示
+	devboxAdded   string // 独立 devbox 模式下需要创建 devbox 的账号
+	devboxRemoved string // 独立 devbox 模式下需要移除 devbox 的账号
+}
+
+// UserSpec 新增用户的参数
+type UserSpec struct {
+	Username    string
+	Password    string
+	Email       string   // 默认 <username>@localhost
+	DisplayName string   // 默认与用户名相同
+	Groups      []string // 如 admins
+}
+
+// UserManager Authelia 用户管理器
+type UserManager struct {
+	Output      io.Writer
+	StateDir    string // 覆盖默认 state 目录（测试用）
+	SSHDialFunc SSHDialFactory
+	SFTPFactory SFTPClientFactory
+}
+
+func (m *UserManager) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.Output, format, args...)
+}
+
+// List 列出所有用户
+func (m *UserManager) List(ctx context.Context) error {
+	conn, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.sftp.Close()
+
+	db, err := conn.readUsers()
+	if err != nil {
+		return err
+	}
+	for _, name := range db.SortedUsernames() {
+		u := db.Users[name]
+		line := fmt.Sprintf("  %-20s %-28s %s", name, u.Email, strings.Join(u.Groups, ","))
+		if u.Disabled {
+			line += " (已禁用)"
+		}
+		m.printf("%s\n", strings.TrimRight(line, " "))
+	}
+	return nil
+}
+
+// Add 新增用户
+func (m *UserManager) Add(ctx context.Context, spec UserSpec) error {
+	if err := config.ValidateUsername(spec.Username); err != nil {
+		return err
+	}
+	if err := config.ValidatePassword(spec.Password); err != nil {
+		return err
+	}
+	if err := config.ValidateGroups(spec.Groups); err != nil {
+		return err
+	}
+	hashed, err := config.HashPassword(spec.Password)
+	if err != nil {
+		return fmt.Errorf("密码哈希失败: %w", err)
+	}
+	if spec.Email == "" {
+		spec.Email = spec.Username + "@localhost"
+	}
+	if spec.DisplayName == "" {
+		spec.DisplayName = spec.Username
+	}
+
+	return m.edit(ctx, func(db *config.UsersDatabase) (userChange, error) {
+		if _, ok := db.Users[spec.Username]; ok {
+			return userChange{}, fmt.Errorf("用户 %s 已存在（修改密码请使用 cloudcode user passwd）", spec.Username)
+		}
+		// 不论当前是否为独立 devbox 模式都拒绝，避免之后切换模式时冲突
+		if other := tmpl.DevboxServiceConflict(spec.Username, db.SortedUsernames()); other != "" {
+			return userChange{}, fmt.Errorf("用户名 %s 与已有用户 %s 的 devbox 名称相同（%s），请改用其他用户名", spec.Username, other, tmpl.DevboxService(spec.Username))
+		}
+		db.Users[spec.Username] = &config.AutheliaUser{
+			DisplayName: spec.DisplayName,
+			Password:    hashed,
+			Email:       spec.Email,
+			Groups:      spec.Groups,
+		}
+		return userChange{message: fmt.Sprintf("✓ 已添加用户 %s", spec.Username), devboxAdded: spec.Username}, nil
+	})
+}
+
+// Remove 删除用户（不允许删除最后一个管理员）
+func (m *UserManager) Remove(ctx context.Context, username string) error {
+	return m.edit(ctx, func(db *config.UsersDatabase) (userChange, error) {
+		if _, ok := db.Users[username]; !ok {
+			return userChange{}, fmt.Errorf("用户 %s 不存在", username)
+		}
+		if admins := db.Admins(); len(admins) == 1 && admins[0] == username {
+			return userChange{}, fmt.Errorf("%s 是唯一的管理员（%s 组），请先添加其他管理员", username, config.AdminGroup)
+		}
+		delete(db.Users, username)
+		return userChange{message: fmt.Sprintf("✓ 已删除用户 %s", username), devboxRemoved: username}, nil
+	})
+}
+
+// Passwd 修改用户密码
+func (m *UserManager) Passwd(ctx context.Context, username, password string) error {
+	if err := config.ValidatePassword(password); err != nil {
+		return err
+	}
+	hashed, err := config.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("密码哈希失败: %w", err)
+	}
+	return m.edit(ctx, func(db *config.UsersDatabase) (userChange, error) {
+		u, ok := db.Users[username]
+		if !ok {
+			return userChange{}, fmt.Errorf("用户 %s 不存在", username)
+		}
+		u.Password = hashed
+		return userChange{message: fmt.Sprintf("✓ 已修改 %s 的密码", username)}, nil
+	})
+}
+
+// instanceConn 一次用户管理操作使用的实例连接
+type instanceConn struct {
+	state      *config.State
+	eip        string
+	privateKey []byte
+	sftp       remote.SFTPClient
+}
+
+// connect 加载当前环境的 state 和 SSH 私钥，建立 SFTP 连接
+func (m *UserManager) connect() (*instanceConn, error) {
+	dir := m.getStateDir()
+	state, err := loadStateFrom(dir)
+	if err != nil {
+		return nil, fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	switch {
+	case state.Status == "suspended":
+		return nil, fmt.Errorf("实例已停机，请先运行 cloudcode resume")
+	case state.Status == "destroyed":
+		return nil, fmt.Errorf("实例已销毁，请先运行 cloudcode deploy")
+	case state.Resources.EIP.IP == "":
+		return nil, fmt.Errorf("EIP 未分配，请先完成部署")
+	}
+	privateKey, err := readSSHKeyFrom(dir, state)
+	if err != nil {
+		return nil, err
+	}
+	sftpClient, err := m.SFTPFactory(state.Resources.EIP.IP, 22, "root", privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("SFTP 连接失败: %w", err)
+	}
+	return &instanceConn{state: state, eip: state.Resources.EIP.IP, privateKey: privateKey, sftp: sftpClient}, nil
+}
+
+func (c *instanceConn) readUsers() (*config.UsersDatabase, error) {
+	data, err := c.sftp.ReadFile(remoteUsersDBPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("实例上未找到 %s，请先完成部署", remoteUsersDBPath)
+		}
+		return nil, fmt.Errorf("读取用户数据库失败: %w", err)
+	}
+	return config.ParseUsersDatabase(data)
+}
+
+// edit 读回用户数据库，调用 fn 修改后写回，然后同步独立 devbox 并让 Authelia 重新加载
+func (m *UserManager) edit(ctx context.Context, fn func(db *config.UsersDatabase) (userChange, error)) error {
+	conn, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.sftp.Close()
+
+	db, err := conn.readUsers()
+	if err != nil {
+		return err
+	}
+	change, err := fn(db)
+	if err != nil {
+		return err
+	}
+	data, err := db.Marshal()
+	if err != nil {
+		return fmt.Errorf("序列化用户数据库失败: %w", err)
+	}
+	if err := conn.sftp.UploadFile(data, remoteUsersDBPath); err != nil {
+		return fmt.Errorf("写回用户数据库失败: %w", err)
+	}
+	m.printf("%s\n", change.message)
+
+	var cmds []string
+	syncDevbox := conn.state.CloudCode.DevboxMode == config.DevboxModePerUser && (change.devboxAdded != "" || change.devboxRemoved != "")
+	if syncDevbox {
+		devboxCmds, err := m.syncDevboxes(conn, db, change)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, devboxCmds...)
+	}
+
+	// 开启了 watch 的 Authelia 会自动重新加载用户数据库，否则重启 authelia 容器
+	autheliaConfig, err := conn.sftp.ReadFile(remoteAutheliaConfigPath)
+	watching := err == nil && config.AutheliaWatchesUsers(autheliaConfig)
+	if !watching {
+		cmds = append(cmds, restartAutheliaCmd)
+	}
+
+	if err := m.runCommands(ctx, conn, cmds); err != nil {
+		return err
+	}
+	if syncDevbox && change.devboxAdded != "" {
+		m.printf("  ✓ 已创建 %s 的 devbox (%s)\n", change.devboxAdded, tmpl.DevboxService(change.devboxAdded))
+	}
+	if syncDevbox && change.devboxRemoved != "" {
+		m.printf("  ✓ 已移除 %s 的 devbox 容器（工作区 volume 保留）\n", change.devboxRemoved)
+	}
+	if watching {
+		m.printf("  ✓ Authelia 将自动重新加载用户数据库\n")
+	} else {
+		m.printf("  ✓ Authelia 已重启（已登录的会话需要重新登录；运行 cloudcode deploy --app 可开启自动重新加载）\n")
+	}
+	return nil
+}
+
+// syncDevboxes 按新的账号列表重新渲染并上传 docker-compose.yml 和 Caddyfile，返回创建 / 移除 devbox 的命令。
+// 只操作变化的账号，其他账号的 devbox 和 Caddy 容器不会重建。
+func (m *UserManager) syncDevboxes(conn *instanceConn, db *config.UsersDatabase, change userChange) ([]string, error) {
+	version := conn.state.CloudCode.ImageVersion
+	if version == "" {
+		version = "latest"
+	}
+	enabled := db.EnabledUsernames()
+	devboxUsers, err := tmpl.NewDevboxUsers(enabled)
+	if err != nil {
+		return nil, err
+	}
+	data := &tmpl.TemplateData{
+		Domain:      conn.state.CloudCode.Domain,
+		Version:     version,
+		DevboxUsers: devboxUsers,
+	}
+	for src, dst := range map[string]string{
+		"templates/docker-compose.yml.tmpl": "/root/cloudcode/docker-compose.yml",
+		"templates/Caddyfile.tmpl":          "/root/cloudcode/Caddyfile",
+	} {
+		content, err := tmpl.RenderTemplate(src, data)
+		if err != nil {
+			return nil, fmt.Errorf("模板渲染失败: %w", err)
+		}
+		if err := conn.sftp.UploadFile(content, dst); err != nil {
+			return nil, fmt.Errorf("上传 %s 失败: %w", dst, err)
+		}
+	}
+
+	var cmds []string
+	if change.devboxAdded != "" {
+		cmds = append(cmds, "cd ~/cloudcode && docker compose up -d --no-deps "+tmpl.DevboxService(change.devboxAdded))
+	}
+	cmds = append(cmds, reloadCaddyCmd)
+	// 旧版本允许的同名 devbox（a.b 与 a_b）仍属于另一个用户时不删除容器
+	if change.devboxRemoved != "" && tmpl.DevboxServiceConflict(change.devboxRemoved, enabled) == "" {
+		cmds = append(cmds, "docker rm -f "+tmpl.DevboxService(change.devboxRemoved))
+	}
+	return cmds, nil
+}
+
+// runCommands 通过一个 SSH 连接依次执行命令
+func (m *UserManager) runCommands(ctx context.Context, conn *instanceConn, cmds []string) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	dialFunc := m.SSHDialFunc(conn.eip, 22, "root", conn.privateKey)
+	sshClient, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	defer sshClient.Close()
+	for _, cmd := range cmds {
+		cmdCtx, cancel := context.WithTimeout(ctx, remote.DefaultCommandTimeout)
+		_, err := sshClient.RunCommand(cmdCtx, cmd)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("执行 %q 失败: %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+func (m *UserManager) getStateDir() string {
+	if m.StateDir != "" {
+		return m.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
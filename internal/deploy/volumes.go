@@ -0,0 +1,188 @@
+package deploy
+
+// volumes.go 实现 cloudcode backup create/restore --volumes：仅备份数据而非整块系统盘。
+// devbox 的 docker volume（工作区、OpenCode 配置和会话，独立 devbox 模式下每个用户一组）和 Authelia 目录
+// （账号、Passkey / TOTP、密钥）打包为 tar.gz，通过 SSH 流式传输到本地文件或 S3 兼容对象存储；
+// 恢复时写回实例上的 volume，不受区域和实例规格限制，可用于填充一个全新的部署。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/objstore"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// 卷备份 / 恢复在实例上执行的命令。tar 在 caddy:2-alpine 容器中运行（镜像已在实例上，无需额外拉取），
+// 归档内为 ./authelia 和 ./volumes/<compose volume 名>，Caddy 的证书 volume 不备份（会自动重新申请）。
+const (
+	volumeBackupCommand = `set -e
+args="-v /root/cloudcode/authelia:/backup/authelia:ro"
+for v in $(docker volume ls -q --filter label=com.docker.compose.project=cloudcode); do
+  case "$v" in cloudcode_caddy_*) continue ;; esac
+  args="$args -v $v:/backup/volumes/${v#cloudcode_}:ro"
+done
+docker run --rm $args --entrypoint tar caddy:2-alpine czf - -C /backup .`
+
+	remoteVolumeArchive = "/root/cloudcode-volumes-restore.tar.gz"
+
+	// 清空归档中出现的 volume 和 Authelia 目录后解压；完成后容器保持停止，由 deploy --app 重新创建
+	volumeRestoreCommand = `set -e
+f=` + remoteVolumeArchive + `
+tar tzf $f >/dev/null
+cd /root/cloudcode && docker compose stop
+args="-v /root/cloudcode/authelia:/backup/authelia"
+for n in $(tar tzf $f | sed -n 's,^\(\./\)\{0,1\}volumes/\([A-Za-z0-9_.-]*\)/.*,\2,p' | sort -u); do
+  args="$args -v cloudcode_$n:/backup/volumes/$n"
+done
+docker run --rm $args -v $f:/archive.tar.gz:ro --entrypoint sh caddy:2-alpine -c \
+  'for d in /backup/authelia /backup/volumes/*; do [ -d "$d" ] && find "$d" -mindepth 1 -delete; done; tar xzf /archive.tar.gz -C /backup'
+rm -f $f`
+)
+
+// VolumeBackupName 返回卷备份的默认文件名
+func VolumeBackupName(env string, now time.Time) string {
+	return fmt.Sprintf("cloudcode-volumes-%s-%s.tar.gz", env, now.UTC().Format("20060102-150405"))
+}
+
+// connectRunning 连接运行中的实例
+func (m *BackupManager) connectRunning(ctx context.Context) (remote.SSHClient, error) {
+	state, err := m.loadInstance()
+	if err != nil {
+		return nil, err
+	}
+	if state.Status == "suspended" {
+		return nil, fmt.Errorf("实例已停机，请先运行 cloudcode resume")
+	}
+	privateKey, err := readSSHKeyFrom(m.getStateDir(), state)
+	if err != nil {
+		return nil, err
+	}
+	dialFunc := m.SSHDialFunc(state.Resources.EIP.IP, 22, "root", privateKey)
+	client, err := remote.WaitForSSH(ctx, dialFunc, remote.WaitSSHOptions{Timeout: 10 * remote.DefaultInitialInterval})
+	if err != nil {
+		return nil, fmt.Errorf("SSH 连接失败: %w", err)
+	}
+	return client, nil
+}
+
+// BackupVolumes 将 devbox volume 和 Authelia 数据打包写入 store。quiesce 时打包期间停止容器。
+func (m *BackupManager) BackupVolumes(ctx context.Context, store objstore.Store, quiesce bool) error {
+	sshClient, err := m.connectRunning(ctx)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	if quiesce {
+		m.printf("  停止容器...\n")
+		if _, err := sshClient.RunCommand(ctx, quiesceCommand); err != nil {
+			sshClient.RunCommand(ctx, unquiesceCommand)
+			return fmt.Errorf("停止容器失败: %w", err)
+		}
+		defer func() {
+			if _, err := sshClient.RunCommand(ctx, unquiesceCommand); err != nil {
+				m.printf("  ⚠ 启动容器失败，请运行 cloudcode deploy --app: %v\n", err)
+			} else {
+				m.printf("  ✓ 容器已恢复\n")
+			}
+		}()
+	}
+
+	upload, err := store.Create(ctx)
+	if err != nil {
+		return err
+	}
+	m.printf("  打包并传输到 %s...\n", store)
+	counter := &countingWriter{w: upload}
+	if err := remote.StreamCommand(ctx, sshClient, volumeBackupCommand, nil, counter); err != nil {
+		upload.Abort()
+		return fmt.Errorf("打包数据失败: %w", err)
+	}
+	if err := upload.Commit(); err != nil {
+		return err
+	}
+	m.printf("✅ 数据已备份: %s (%s)\n", store, formatBytes(counter.n))
+	return nil
+}
+
+// RestoreVolumes 将 store 中的卷备份写回实例：覆盖归档中的 volume 和 Authelia 目录，容器保持停止。
+// 返回 true 表示已写回，调用方随后需要重新部署应用层（deploy --app），使 Authelia 配置与当前域名一致并启动容器。
+func (m *BackupManager) RestoreVolumes(ctx context.Context, store objstore.Store, force bool) (bool, error) {
+	sshClient, err := m.connectRunning(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer sshClient.Close()
+
+	m.printf("将用 %s 覆盖实例上的工作区、OpenCode 配置 / 会话和 Authelia 账号。\n", store)
+	if !force {
+		confirmed, err := m.Prompter.PromptConfirm("当前数据将被替换，确认恢复?", false)
+		if err != nil {
+			return false, err
+		}
+		if !confirmed {
+			m.printf("已取消。\n")
+			return false, nil
+		}
+	}
+
+	reader, err := store.Open(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+	m.printf("  传输到实例...\n")
+	counter := &countingReader{r: reader}
+	if err := remote.StreamCommand(ctx, sshClient, "cat > "+remoteVolumeArchive, counter, io.Discard); err != nil {
+		return false, fmt.Errorf("传输备份失败: %w", err)
+	}
+	m.printf("  ✓ 已传输 %s\n", formatBytes(counter.n))
+
+	restoreCtx, cancel := context.WithTimeout(ctx, remote.DockerInstallTimeout)
+	defer cancel()
+	if _, err := sshClient.RunCommand(restoreCtx, volumeRestoreCommand); err != nil {
+		sshClient.RunCommand(ctx, "rm -f "+remoteVolumeArchive)
+		return false, fmt.Errorf("恢复数据失败（容器可能已停止，运行 cloudcode deploy --app 重新启动）: %w", err)
+	}
+	m.printf("  ✓ 数据已写回\n")
+	return true, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// formatBytes 格式化字节数（如 12.3 MB）
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,400 @@
+package deploy
+
+// wake.go 管理唤醒页：在函数计算中部署运行 cloudcode wake serve 的函数（函数角色只能启动和查询本实例），
+// 以自定义域名 wake.<域名> 对外提供服务，并通过 ACME 为该域名签发 HTTPS 证书。
+// 函数计算默认域名会强制下载 HTML 响应，因此唤醒页必须使用自定义域名。
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/wake"
+)
+
+const (
+	wakeTriggerName   = "http"
+	wakeACMEKeyFile   = "wake_acme.key" // ACME 账号私钥，保存在环境目录
+	wakeCertRenewDays = 30              // 证书剩余有效期少于该天数时重新签发
+	wakeRoleRetries   = 6               // 新建角色生效前创建函数可能失败，重试次数
+	wakeFunctionMemMB = 128
+)
+
+// CertIssuer 证书签发（默认 ACME，测试用）
+type CertIssuer interface {
+	Thumbprint() (string, error)
+	Issue(ctx context.Context, domain string) (*wake.Certificate, error)
+}
+
+// WakeManager 唤醒页管理器
+type WakeManager struct {
+	ECS            alicloud.ECSAPI
+	STS            alicloud.STSAPI
+	RAM            alicloud.RAMAPI
+	DNS            alicloud.DnsAPI // 为 nil 时提示手动配置 CNAME
+	FC             alicloud.FCAPI
+	Output         io.Writer
+	Region         string
+	StateDir       string // 覆盖默认 state 目录（测试用）
+	Version        string
+	Binary         func() ([]byte, error)            // linux/amd64 的 cloudcode 二进制，默认本地或从 GitHub Release 下载
+	Issuer         CertIssuer                        // 默认 Let's Encrypt
+	LookupCNAME    func(host string) (string, error) // 默认 net.LookupCNAME
+	DNSWaitTimeout time.Duration                     // CNAME 生效等待超时（默认 5min）
+	RetryInterval  time.Duration                     // 创建函数重试间隔（默认 5s）
+}
+
+func (m *WakeManager) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.Output, format, args...)
+}
+
+// Enable 开启或更新唤醒页。domain 为空时使用 wake.<部署域名>；rotateKey 重新生成唤醒密钥（已登录的浏览器需重新登录）。
+// 重复执行会更新函数代码和实例 ID，并在证书即将到期时重新签发。
+func (m *WakeManager) Enable(ctx context.Context, domain string, rotateKey bool) error {
+	dir := m.getStateDir()
+	state, err := loadStateFrom(dir)
+	if err != nil {
+		return fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	if !state.HasECS() || state.Status == "destroyed" {
+		return fmt.Errorf("实例不存在，请先运行 cloudcode deploy")
+	}
+	target := state.CloudCode.Domain
+	if target == "" || strings.HasSuffix(target, ".nip.io") {
+		return fmt.Errorf("唤醒页需要自有域名（nip.io 无法配置 CNAME），请先以自有域名部署")
+	}
+	if domain == "" {
+		domain = state.Resources.Wake.Domain
+	}
+	if domain == "" {
+		domain = "wake." + target
+	}
+	if err := config.ValidateDomain(domain); err != nil {
+		return err
+	}
+	wr := &state.Resources.Wake
+	if wr.Domain != "" && wr.Domain != domain {
+		return fmt.Errorf("唤醒页已使用域名 %s，如需更换请先运行 cloudcode wake off", wr.Domain)
+	}
+
+	identity, err := alicloud.GetCallerIdentity(m.STS)
+	if err != nil {
+		return err
+	}
+	issuer, err := m.issuer(dir)
+	if err != nil {
+		return err
+	}
+	thumbprint, err := issuer.Thumbprint()
+	if err != nil {
+		return err
+	}
+
+	// 唤醒密钥只显示一次，state 中只保存哈希
+	var newKey string
+	if wr.KeyHash == "" || rotateKey {
+		if newKey, err = wake.NewKey(); err != nil {
+			return err
+		}
+		wr.KeyHash = wake.HashKey(newKey)
+		if wr.CookieSecret, err = wake.NewKey(); err != nil {
+			return err
+		}
+	}
+
+	// 1. RAM 角色
+	if wr.FunctionName == "" {
+		id := state.DeploymentID
+		if id == "" {
+			id = state.Resources.ECS.ID
+		}
+		wr.FunctionName = alicloud.WakeRoleName(id)
+	}
+	if err := alicloud.EnsureWakeRole(m.RAM, wr.FunctionName, m.Region, identity.AccountID, state.Resources.ECS.ID); err != nil {
+		return err
+	}
+	m.printf("  ✓ RAM 角色 %s（仅允许启动 %s）\n", wr.FunctionName, state.Resources.ECS.ID)
+	wr.Domain = domain
+	if err := saveStateTo(dir, state); err != nil {
+		return err
+	}
+
+	// 2. 函数 + HTTP 触发器
+	code, err := m.functionCode()
+	if err != nil {
+		return err
+	}
+	wakeCfg := wake.Config{
+		InstanceID:     state.Resources.ECS.ID,
+		Region:         m.Region,
+		Target:         "https://" + target,
+		KeyHash:        wr.KeyHash,
+		CookieSecret:   wr.CookieSecret,
+		ACMEThumbprint: thumbprint,
+	}
+	fn := &alicloud.FCFunction{
+		Name:        wr.FunctionName,
+		Description: "CloudCode 唤醒页 (" + target + ")",
+		RoleARN:     alicloud.RoleARN(identity.AccountID, wr.FunctionName),
+		Code:        code,
+		Command:     []string{"/code/cloudcode", "wake", "serve", "--port", fmt.Sprint(wake.DefaultPort)},
+		Port:        wake.DefaultPort,
+		MemoryMB:    wakeFunctionMemMB,
+		Timeout:     30,
+		Env:         wakeCfg.Env(),
+	}
+	if err := m.ensureFunction(fn); err != nil {
+		return err
+	}
+	m.printf("  ✓ 函数 %s\n", fn.Name)
+
+	// 3. DNS：wake 域名 CNAME 到函数计算服务地址
+	endpoint := alicloud.FCEndpoint(identity.AccountID, m.Region)
+	if err := m.setupCNAME(domain, endpoint); err != nil {
+		return err
+	}
+
+	// 4. 自定义域名（先以 HTTP 创建，ACME 验证经由唤醒页应答）
+	customDomain := &alicloud.FCCustomDomain{DomainName: domain, FunctionName: fn.Name}
+	created := false
+	if err := m.FC.GetCustomDomain(domain); err != nil {
+		if !alicloud.IsNotFound(err) {
+			return fmt.Errorf("查询自定义域名失败: %w", err)
+		}
+		if err := m.FC.CreateCustomDomain(customDomain); err != nil {
+			return fmt.Errorf("创建自定义域名失败: %w", err)
+		}
+		created = true
+	}
+
+	// 5. HTTPS 证书
+	if created || certNeedsRenewal(wr.CertExpiresAt) {
+		m.printf("  签发 HTTPS 证书 (%s)...\n", domain)
+		cert, err := issuer.Issue(ctx, domain)
+		if err != nil {
+			return fmt.Errorf("签发证书失败: %w（可稍后重新运行 cloudcode wake enable）", err)
+		}
+		customDomain.CertName = strings.ReplaceAll(domain, ".", "-")
+		customDomain.CertPEM = cert.CertPEM
+		customDomain.KeyPEM = cert.KeyPEM
+		if err := m.FC.UpdateCustomDomain(customDomain); err != nil {
+			return fmt.Errorf("配置 HTTPS 证书失败: %w", err)
+		}
+		wr.CertExpiresAt = cert.NotAfter.UTC().Format(time.RFC3339)
+		m.printf("  ✓ HTTPS 证书有效期至 %s\n", cert.NotAfter.Format("2006-01-02"))
+	}
+
+	if err := saveStateTo(dir, state); err != nil {
+		return err
+	}
+	m.printf("✅ 唤醒页: https://%s\n", domain)
+	if newKey != "" {
+		m.printf("  唤醒密钥（只显示一次，请妥善保存）: %s\n", newKey)
+	}
+	return nil
+}
+
+// Disable 删除唤醒页（自定义域名、函数、RAM 角色）；CNAME 记录保留，需要时手动删除
+func (m *WakeManager) Disable(ctx context.Context) error {
+	dir := m.getStateDir()
+	state, err := loadStateFrom(dir)
+	if err != nil {
+		return fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	if state.Resources.Wake.FunctionName == "" {
+		m.printf("唤醒页未开启。\n")
+		return nil
+	}
+	domain := state.Resources.Wake.Domain
+	if err := deleteWake(m.FC, m.RAM, state.Resources.Wake); err != nil {
+		return err
+	}
+	state.Resources.Wake = config.WakeResource{}
+	if err := saveStateTo(dir, state); err != nil {
+		return err
+	}
+	m.printf("✅ 唤醒页已删除\n")
+	if domain != "" {
+		m.printf("  DNS 记录 %s 未删除，如不再使用请手动删除\n", domain)
+	}
+	return nil
+}
+
+// Status 显示唤醒页配置
+func (m *WakeManager) Status() error {
+	state, err := loadStateFrom(m.getStateDir())
+	if err != nil {
+		return fmt.Errorf("未找到部署记录，请先运行 cloudcode deploy")
+	}
+	wr := state.Resources.Wake
+	if wr.FunctionName == "" {
+		m.printf("唤醒页: 未开启\n")
+		return nil
+	}
+	m.printf("唤醒页: https://%s\n", wr.Domain)
+	m.printf("函数:   %s\n", wr.FunctionName)
+	if wr.CertExpiresAt != "" {
+		expires, _ := time.Parse(time.RFC3339, wr.CertExpiresAt)
+		m.printf("证书:   有效期至 %s\n", expires.Format("2006-01-02"))
+		if certNeedsRenewal(wr.CertExpiresAt) {
+			m.printf("  ⚠ 证书即将到期，请运行 cloudcode wake enable 续期\n")
+		}
+	} else {
+		m.printf("证书:   未签发，请重新运行 cloudcode wake enable\n")
+	}
+	return nil
+}
+
+// deleteWake 删除唤醒页的函数计算资源和 RAM 角色（不存在时跳过）
+func deleteWake(fc alicloud.FCAPI, ram alicloud.RAMAPI, wr config.WakeResource) error {
+	if wr.Domain != "" {
+		if err := fc.DeleteCustomDomain(wr.Domain); err != nil && !alicloud.IsNotFound(err) {
+			return fmt.Errorf("删除自定义域名失败: %w", err)
+		}
+	}
+	if err := fc.DeleteTrigger(wr.FunctionName, wakeTriggerName); err != nil && !alicloud.IsNotFound(err) {
+		return fmt.Errorf("删除触发器失败: %w", err)
+	}
+	if err := fc.DeleteFunction(wr.FunctionName); err != nil && !alicloud.IsNotFound(err) {
+		return fmt.Errorf("删除函数失败: %w", err)
+	}
+	return alicloud.DeleteWakeRole(ram, wr.FunctionName)
+}
+
+// ensureFunction 创建或更新函数；新建角色生效前创建可能失败，按间隔重试
+func (m *WakeManager) ensureFunction(fn *alicloud.FCFunction) error {
+	err := m.FC.GetFunction(fn.Name)
+	if err == nil {
+		if err := m.FC.UpdateFunction(fn); err != nil {
+			return fmt.Errorf("更新函数失败: %w", err)
+		}
+		return nil
+	}
+	if !alicloud.IsNotFound(err) {
+		return fmt.Errorf("查询函数失败: %w", err)
+	}
+
+	interval := m.RetryInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	for i := 1; ; i++ {
+		err = m.FC.CreateFunction(fn)
+		if err == nil || i == wakeRoleRetries || !strings.Contains(strings.ToLower(err.Error()), "role") {
+			break
+		}
+		time.Sleep(interval)
+	}
+	if err != nil {
+		return fmt.Errorf("创建函数失败: %w", err)
+	}
+	if err := m.FC.CreateHTTPTrigger(fn.Name, wakeTriggerName); err != nil {
+		return fmt.Errorf("创建 HTTP 触发器失败: %w", err)
+	}
+	return nil
+}
+
+// setupCNAME 域名在阿里云 DNS 时自动添加 CNAME，否则提示手动配置；随后等待生效
+func (m *WakeManager) setupCNAME(domain, endpoint string) error {
+	auto := false
+	if m.DNS != nil {
+		if domains, err := alicloud.ListDomains(m.DNS); err == nil {
+			if baseDomain, rr, err := alicloud.FindBaseDomain(domain, domains); err == nil {
+				if err := alicloud.EnsureDNSRecordOfType(m.DNS, baseDomain, rr, "CNAME", endpoint); err != nil {
+					return fmt.Errorf("DNS 记录更新失败: %w", err)
+				}
+				m.printf("  ✓ %s → CNAME %s\n", domain, endpoint)
+				auto = true
+			}
+		}
+	}
+	if !auto {
+		m.printf("  请手动配置 DNS CNAME 记录:\n")
+		m.printf("    %s  →  %s\n", domain, endpoint)
+	}
+
+	timeout := m.DNSWaitTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+	return waitForCNAME(domain, endpoint, timeout, m.LookupCNAME, m.printf)
+}
+
+// functionCode 打包函数代码（zip 中只有 cloudcode 二进制）
+func (m *WakeManager) functionCode() ([]byte, error) {
+	binaryFunc := m.Binary
+	if binaryFunc == nil {
+		binaryFunc = m.linuxBinary
+	}
+	binary, err := binaryFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	header := &zip.FileHeader{Name: "cloudcode", Method: zip.Deflate}
+	header.SetMode(0755)
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(binary); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// linuxBinary 本地为 linux/amd64 时使用自身，否则下载对应版本的 Release
+func (m *WakeManager) linuxBinary() ([]byte, error) {
+	if binary, err := localAgentBinary("amd64"); err != nil || binary != nil {
+		return binary, err
+	}
+	url := releaseBinaryURL(m.Version, "amd64")
+	m.printf("  下载 %s...\n", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("下载 cloudcode 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载 cloudcode 失败: HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (m *WakeManager) issuer(dir string) (CertIssuer, error) {
+	if m.Issuer != nil {
+		return m.Issuer, nil
+	}
+	key, err := wake.LoadOrCreateAccountKey(filepath.Join(dir, wakeACMEKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	return &wake.ACMEIssuer{Key: key}, nil
+}
+
+// certNeedsRenewal 证书未签发或剩余有效期不足 wakeCertRenewDays 天
+func certNeedsRenewal(expiresAt string) bool {
+	expires, err := time.Parse(time.RFC3339, expiresAt)
+	return err != nil || time.Until(expires) < wakeCertRenewDays*24*time.Hour
+}
+
+func (m *WakeManager) getStateDir() string {
+	if m.StateDir != "" {
+		return m.StateDir
+	}
+	dir, _ := config.GetActiveEnvDir()
+	return dir
+}
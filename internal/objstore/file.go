@@ -0,0 +1,72 @@
+package objstore
+
+// file.go 本地文件存储：先写入 <path>.partial，Commit 时重命名，中断的备份不会覆盖已有文件。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileStore 本地文件
+type FileStore struct {
+	Path string
+}
+
+func (s *FileStore) String() string {
+	return s.Path
+}
+
+// Create 创建临时文件（权限 0600，备份包含账号和 API Key 等敏感数据）
+func (s *FileStore) Create(ctx context.Context) (Upload, error) {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmp := s.Path + ".partial"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建备份文件失败: %w", err)
+	}
+	return &fileUpload{f: f, tmp: tmp, path: s.Path}, nil
+}
+
+// Open 打开备份文件
+func (s *FileStore) Open(ctx context.Context) (io.ReadCloser, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %w", err)
+	}
+	return f, nil
+}
+
+type fileUpload struct {
+	f    *os.File
+	tmp  string
+	path string
+}
+
+func (u *fileUpload) Write(p []byte) (int, error) {
+	return u.f.Write(p)
+}
+
+func (u *fileUpload) Commit() error {
+	if err := u.f.Close(); err != nil {
+		os.Remove(u.tmp)
+		return fmt.Errorf("写入备份文件失败: %w", err)
+	}
+	if err := os.Rename(u.tmp, u.path); err != nil {
+		os.Remove(u.tmp)
+		return fmt.Errorf("保存备份文件失败: %w", err)
+	}
+	return nil
+}
+
+func (u *fileUpload) Abort() error {
+	u.f.Close()
+	if err := os.Remove(u.tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package objstore 提供卷备份的存储位置：本地文件或 S3 兼容对象存储（AWS S3、阿里云 OSS、MinIO 等）。
+// 写入以流的方式进行（对象存储使用分片上传），不需要在本地或内存中缓存完整的备份文件。
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store 单个备份文件的存储位置
+type Store interface {
+	// Create 开始写入，写完后调用 Commit 生效，失败时调用 Abort 清理
+	Create(ctx context.Context) (Upload, error)
+	// Open 读取备份文件
+	Open(ctx context.Context) (io.ReadCloser, error)
+	// String 返回可读的位置（本地路径或 s3://bucket/key）
+	String() string
+}
+
+// Upload 进行中的写入
+type Upload interface {
+	io.Writer
+	Commit() error
+	Abort() error
+}
+
+// IsS3URL 判断位置是否为 s3://bucket/key 形式
+func IsS3URL(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// ParseS3URL 解析 s3://bucket/key，key 可为空或以 / 结尾（表示前缀）
+func ParseS3URL(location string) (bucket, key string, err error) {
+	if !IsS3URL(location) {
+		return "", "", fmt.Errorf("无效的对象存储地址 %q，格式为 s3://bucket/key", location)
+	}
+	rest := strings.TrimPrefix(location, "s3://")
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("无效的对象存储地址 %q，缺少 bucket", location)
+	}
+	return bucket, key, nil
+}
+
+// New 按位置创建 Store。location 为目录（已存在或以 / 结尾）、s3://bucket 或以 / 结尾的 s3 前缀时，
+// 在其下使用 defaultName；defaultName 为空时要求 location 指向具体文件。s3 仅在 location 为 s3:// 时使用。
+func New(location, defaultName string, s3 *S3Client) (Store, error) {
+	if IsS3URL(location) {
+		bucket, key, err := ParseS3URL(location)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" || strings.HasSuffix(key, "/") {
+			if defaultName == "" {
+				return nil, fmt.Errorf("请指定对象名: %s", location)
+			}
+			key += defaultName
+		}
+		if s3 == nil {
+			return nil, fmt.Errorf("未配置对象存储")
+		}
+		return &S3Object{Client: s3, Bucket: bucket, Key: key}, nil
+	}
+
+	if location == "" {
+		return nil, fmt.Errorf("未指定备份位置")
+	}
+	isDir := strings.HasSuffix(location, "/") || strings.HasSuffix(location, string(filepath.Separator))
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if isDir {
+		if defaultName == "" {
+			return nil, fmt.Errorf("%s 是目录，请指定备份文件", location)
+		}
+		location = filepath.Join(location, defaultName)
+	}
+	return &FileStore{Path: location}, nil
+}
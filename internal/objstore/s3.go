@@ -0,0 +1,459 @@
+package objstore
+
+// s3.go 最小化的 S3 兼容客户端（AWS Signature V4）：PutObject / GetObject 和分片上传，
+// 可用于 AWS S3、阿里云 OSS（S3 兼容接口）和 MinIO。
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultS3Region = "us-east-1"
+	DefaultPartSize = 16 << 20 // 分片大小（S3 要求除最后一片外不小于 5MB）
+
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Config 对象存储连接配置
+type S3Config struct {
+	Endpoint        string // 如 https://oss-cn-hangzhou.aliyuncs.com、http://localhost:9000；为空时使用 AWS S3
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	VirtualHosted   bool // bucket 作为子域名（OSS 要求）；否则使用路径形式（MinIO 默认）
+}
+
+// S3ConfigFromEnv 从环境变量读取对象存储配置：
+// CLOUDCODE_S3_ENDPOINT / AWS_ENDPOINT_URL_S3 / AWS_ENDPOINT_URL，AWS_REGION / AWS_DEFAULT_REGION，
+// AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、AWS_SESSION_TOKEN
+func S3ConfigFromEnv() S3Config {
+	return S3Config{
+		Endpoint:        firstEnv("CLOUDCODE_S3_ENDPOINT", "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// IsOSSEndpoint 判断是否为阿里云 OSS 地址
+func IsOSSEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(u.Hostname(), ".aliyuncs.com")
+}
+
+// S3Client S3 兼容对象存储客户端
+type S3Client struct {
+	cfg      S3Config
+	endpoint *url.URL
+	HTTP     *http.Client
+	Now      func() time.Time // 测试用，默认 time.Now
+}
+
+// NewS3Client 创建客户端。OSS 和 AWS 地址自动使用子域名形式。
+func NewS3Client(cfg S3Config) (*S3Client, error) {
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("未配置对象存储密钥，请设置 AWS_ACCESS_KEY_ID 和 AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = DefaultS3Region
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if !strings.Contains(cfg.Endpoint, "://") {
+		cfg.Endpoint = "https://" + cfg.Endpoint
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的对象存储地址 %q", cfg.Endpoint)
+	}
+	host := endpoint.Hostname()
+	if strings.HasSuffix(host, ".aliyuncs.com") || strings.HasSuffix(host, ".amazonaws.com") {
+		cfg.VirtualHosted = true
+	}
+	return &S3Client{cfg: cfg, endpoint: endpoint, HTTP: http.DefaultClient}, nil
+}
+
+func (c *S3Client) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// objectURL 返回对象地址（query 为空时不带参数）
+func (c *S3Client) objectURL(bucket, key string, query url.Values) *url.URL {
+	u := *c.endpoint
+	if c.cfg.VirtualHosted {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + key
+	} else {
+		u.Path = "/" + bucket + "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false) // 与签名使用相同的编码
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do 签名并发送请求，非 2xx 时返回解析后的错误
+func (c *S3Client) do(ctx context.Context, method string, u *url.URL, body []byte, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		reader = bytes.NewReader(body)
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if c.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.cfg.SessionToken)
+	}
+	SignV4(req, c.cfg.AccessKeyID, c.cfg.SecretAccessKey, c.cfg.Region, payloadHash, c.now())
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("对象存储请求失败: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, parseS3Error(method, u, resp)
+	}
+	return resp, nil
+}
+
+// s3Error S3 错误响应
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func parseS3Error(method string, u *url.URL, resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var e s3Error
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return fmt.Errorf("对象存储 %s %s 失败 (%d %s): %s", method, u.Path, resp.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Errorf("对象存储 %s %s 失败: %s", method, u.Path, resp.Status)
+}
+
+// PutObject 上传对象
+func (c *S3Client) PutObject(ctx context.Context, bucket, key string, body []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, c.objectURL(bucket, key, nil), body, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObject 下载对象，调用方负责关闭
+func (c *S3Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.objectURL(bucket, key, nil), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// CreateMultipartUpload 开始分片上传，返回 upload ID
+func (c *S3Client) CreateMultipartUpload(ctx context.Context, bucket, key string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, c.objectURL(bucket, key, url.Values{"uploads": {""}}), []byte{}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("对象存储返回无效的分片上传 ID")
+	}
+	return result.UploadID, nil
+}
+
+// UploadPart 上传分片（partNumber 从 1 开始），返回 ETag
+func (c *S3Client) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, body []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	resp, err := c.do(ctx, http.MethodPut, c.objectURL(bucket, key, query), body, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("对象存储未返回分片 %d 的 ETag", partNumber)
+	}
+	return etag, nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// CompleteMultipartUpload 合并分片（etags 按分片顺序）
+func (c *S3Client) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, etags []string) error {
+	var body struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}
+	for i, etag := range etags {
+		body.Parts = append(body.Parts, completedPart{PartNumber: i + 1, ETag: etag})
+	}
+	data, err := xml.Marshal(body)
+	if err != nil {
+		return err
+	}
+	u := c.objectURL(bucket, key, url.Values{"uploadId": {uploadID}})
+	resp, err := c.do(ctx, http.MethodPost, u, data, http.Header{"Content-Type": {"application/xml"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 合并失败时 S3 也可能返回 200，错误在响应体中
+	respData, _ := io.ReadAll(resp.Body)
+	var e s3Error
+	if xml.Unmarshal(respData, &e) == nil && e.Code != "" {
+		return fmt.Errorf("对象存储合并分片失败 (%s): %s", e.Code, e.Message)
+	}
+	return nil
+}
+
+// AbortMultipartUpload 取消分片上传，释放已上传的分片
+func (c *S3Client) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.objectURL(bucket, key, url.Values{"uploadId": {uploadID}}), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SignV4 为请求添加 AWS Signature V4 签名（Authorization、X-Amz-Date、X-Amz-Content-Sha256）。
+// 签名覆盖 Host 和请求中已设置的其他头（User-Agent 等传输层可能改写的头除外）。
+func SignV4(req *http.Request, accessKeyID, secretAccessKey, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		switch lower {
+		case "authorization", "user-agent", "accept-encoding", "content-length":
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery 按 SigV4 规则编码并排序查询参数
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	var pairs []string
+	for k, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode 按 SigV4 规则编码：保留 A-Z a-z 0-9 - _ . ~，encodeSlash 为 false 时保留 /
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// S3Object 对象存储中的一个对象
+type S3Object struct {
+	Client   *S3Client
+	Bucket   string
+	Key      string
+	PartSize int // 分片大小，默认 DefaultPartSize
+}
+
+func (o *S3Object) String() string {
+	return "s3://" + o.Bucket + "/" + o.Key
+}
+
+// Create 开始写入：数据不足一个分片时 Commit 用 PutObject 上传，否则使用分片上传
+func (o *S3Object) Create(ctx context.Context) (Upload, error) {
+	partSize := o.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return &s3Upload{ctx: ctx, obj: o, partSize: partSize}, nil
+}
+
+// Open 下载对象
+func (o *S3Object) Open(ctx context.Context) (io.ReadCloser, error) {
+	return o.Client.GetObject(ctx, o.Bucket, o.Key)
+}
+
+type s3Upload struct {
+	ctx      context.Context
+	obj      *S3Object
+	partSize int
+	buf      []byte
+	uploadID string
+	etags    []string
+}
+
+func (u *s3Upload) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		room := u.partSize - len(u.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		u.buf = append(u.buf, p[:room]...)
+		p = p[room:]
+		if len(u.buf) == u.partSize {
+			if err := u.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush 上传缓冲区中的一个分片
+func (u *s3Upload) flush() error {
+	c := u.obj.Client
+	if u.uploadID == "" {
+		id, err := c.CreateMultipartUpload(u.ctx, u.obj.Bucket, u.obj.Key)
+		if err != nil {
+			return err
+		}
+		u.uploadID = id
+	}
+	etag, err := c.UploadPart(u.ctx, u.obj.Bucket, u.obj.Key, u.uploadID, len(u.etags)+1, u.buf)
+	if err != nil {
+		return err
+	}
+	u.etags = append(u.etags, etag)
+	u.buf = u.buf[:0]
+	return nil
+}
+
+func (u *s3Upload) Commit() error {
+	c := u.obj.Client
+	if u.uploadID == "" {
+		return c.PutObject(u.ctx, u.obj.Bucket, u.obj.Key, u.buf)
+	}
+	if len(u.buf) > 0 {
+		if err := u.flush(); err != nil {
+			u.Abort()
+			return err
+		}
+	}
+	if err := c.CompleteMultipartUpload(u.ctx, u.obj.Bucket, u.obj.Key, u.uploadID, u.etags); err != nil {
+		u.Abort()
+		return err
+	}
+	return nil
+}
+
+func (u *s3Upload) Abort() error {
+	if u.uploadID == "" {
+		return nil
+	}
+	// 写入可能因 ctx 取消而失败，取消上传使用独立的 context
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return u.obj.Client.AbortMultipartUpload(ctx, u.obj.Bucket, u.obj.Key, u.uploadID)
+}
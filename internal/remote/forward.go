@@ -0,0 +1,219 @@
+package remote
+
+// forward.go 实现本地端口转发（cloudcode forward）：本地监听端口上的连接经 SSH 连接（direct-tcpip）
+// 转发到 ECS 上 cloudcode-net Docker 网络中的容器端口。容器 IP 通过 docker inspect 解析。
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ComposeNetwork docker-compose.yml 中容器所在的网络名（Docker 实际网络名带 Compose 项目前缀，如 cloudcode_cloudcode-net）
+const ComposeNetwork = "cloudcode-net"
+
+// ForwardSpec 一条端口转发规则：本地 127.0.0.1:LocalPort → 容器 Container 的 Port 端口
+type ForwardSpec struct {
+	LocalPort int
+	Container string
+	Port      int
+}
+
+func (s ForwardSpec) String() string {
+	return fmt.Sprintf("%d:%s:%d", s.LocalPort, s.Container, s.Port)
+}
+
+var containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ParseForwardSpec 解析 <local>:<container>:<port>；省略 local 时（<container>:<port>）本地使用相同端口，local 为 0 时随机分配
+func ParseForwardSpec(s string) (ForwardSpec, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 {
+		parts = append([]string{parts[1]}, parts...)
+	}
+	if len(parts) != 3 {
+		return ForwardSpec{}, fmt.Errorf("转发规则格式应为 <local>:<container>:<port>: %s", s)
+	}
+	local, err := strconv.Atoi(parts[0])
+	if err != nil || local < 0 || local > 65535 {
+		return ForwardSpec{}, fmt.Errorf("本地端口无效: %s", s)
+	}
+	port, err := strconv.Atoi(parts[2])
+	if err != nil || port <= 0 || port > 65535 {
+		return ForwardSpec{}, fmt.Errorf("容器端口无效: %s", s)
+	}
+	if !containerNameRe.MatchString(parts[1]) {
+		return ForwardSpec{}, fmt.Errorf("容器名无效: %s", s)
+	}
+	return ForwardSpec{LocalPort: local, Container: parts[1], Port: port}, nil
+}
+
+// ContainerIP 通过 docker inspect 解析容器在 cloudcode-net 网络中的 IP
+func ContainerIP(ctx context.Context, client SSHClient, container string) (string, error) {
+	cmd := fmt.Sprintf(`docker inspect -f '{{range $name, $net := .NetworkSettings.Networks}}{{$name}} {{$net.IPAddress}}{{println}}{{end}}' %s`, container)
+	output, err := client.RunCommand(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("查询容器 %s 失败: %w", container, err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[0] == ComposeNetwork || strings.HasSuffix(fields[0], "_"+ComposeNetwork) {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("容器 %s 不在 %s 网络中（容器是否在运行？）", container, ComposeNetwork)
+}
+
+// ContainerDialer 经 SSH 连接到容器端口。容器 IP 首次连接时解析并缓存，
+// 连接失败时重新解析一次（容器重建后 IP 可能变化）。
+type ContainerDialer struct {
+	Client    SSHClient
+	Container string
+	Port      int
+
+	mu sync.Mutex
+	ip string
+}
+
+// Resolve 解析并缓存容器 IP
+func (d *ContainerDialer) Resolve(ctx context.Context) (string, error) {
+	ip, err := ContainerIP(ctx, d.Client, d.Container)
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	d.ip = ip
+	d.mu.Unlock()
+	return ip, nil
+}
+
+// Dial 建立到容器端口的连接
+func (d *ContainerDialer) Dial(ctx context.Context) (net.Conn, error) {
+	d.mu.Lock()
+	ip := d.ip
+	d.mu.Unlock()
+
+	resolved := false
+	if ip == "" {
+		var err error
+		if ip, err = d.Resolve(ctx); err != nil {
+			return nil, err
+		}
+		resolved = true
+	}
+	conn, err := Dial(d.Client, "tcp", net.JoinHostPort(ip, strconv.Itoa(d.Port)))
+	if err == nil || resolved {
+		return conn, err
+	}
+	newIP, rerr := d.Resolve(ctx)
+	if rerr != nil || newIP == ip {
+		return nil, err
+	}
+	return Dial(d.Client, "tcp", net.JoinHostPort(newIP, strconv.Itoa(d.Port)))
+}
+
+// ServeForward 在 ln 上接受本地连接，每个连接经 dial 建立远程连接后双向转发。
+// 单个连接失败时调用 onError（可为 nil），不影响其他连接；ctx 取消后关闭 ln 和所有转发中的连接，等待其结束后返回。
+func ServeForward(ctx context.Context, ln net.Listener, dial func(ctx context.Context) (net.Conn, error), onError func(error)) error {
+	var (
+		mu      sync.Mutex
+		active  = map[net.Conn]struct{}{}
+		closing bool
+		wg      sync.WaitGroup
+	)
+	track := func(c net.Conn) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if closing {
+			return false
+		}
+		active[c] = struct{}{}
+		return true
+	}
+	untrack := func(c net.Conn) {
+		mu.Lock()
+		delete(active, c)
+		mu.Unlock()
+		c.Close()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		ln.Close()
+		mu.Lock()
+		closing = true
+		for c := range active {
+			c.Close()
+		}
+		mu.Unlock()
+	}()
+
+	var err error
+	for {
+		local, acceptErr := ln.Accept()
+		if acceptErr != nil {
+			if ctx.Err() == nil {
+				err = acceptErr
+			}
+			break
+		}
+		if !track(local) {
+			local.Close()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer untrack(local)
+			remoteConn, err := dial(ctx)
+			if err != nil {
+				if onError != nil && ctx.Err() == nil {
+					onError(err)
+				}
+				return
+			}
+			if !track(remoteConn) {
+				remoteConn.Close()
+				return
+			}
+			defer untrack(remoteConn)
+			pipe(local, remoteConn)
+		}()
+	}
+	close(stopped)
+	wg.Wait()
+	return err
+}
+
+// pipe 双向复制数据，一个方向结束时关闭该方向的写端，两个方向都结束后返回
+func pipe(a, b net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		io.Copy(b, a)
+		closeWrite(b)
+		close(done)
+	}()
+	io.Copy(a, b)
+	closeWrite(a)
+	<-done
+}
+
+func closeWrite(c net.Conn) {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+		return
+	}
+	c.Close()
+}
@@ -0,0 +1,155 @@
+package remote
+
+// hostkey.go 校验实例的 SSH 主机公钥：首次连接成功时记录（trust on first use），之后每次连接都与记录比对，
+// 不一致时拒绝连接，不做重试（实例重建后运行 cloudcode ssh --reset-hostkey 重新记录）。
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrHostKeyMismatch 主机公钥与记录不一致
+var ErrHostKeyMismatch = errors.New("SSH 主机公钥与记录不一致")
+
+// HostKeyStore 主机公钥记录，公钥为 authorized_keys 格式（如 "ssh-ed25519 AAAA..."）
+type HostKeyStore interface {
+	LoadHostKey(host string) (string, error) // 未记录时返回空
+	SaveHostKey(host, key string) error
+}
+
+// MemoryHostKeyStore 只在进程内记住主机公钥（未设置持久化存储时的默认值）
+type MemoryHostKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// LoadHostKey 返回 host 的主机公钥，未记录时返回空
+func (s *MemoryHostKeyStore) LoadHostKey(host string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[host], nil
+}
+
+// SaveHostKey 记录 host 的主机公钥
+func (s *MemoryHostKeyStore) SaveHostKey(host, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = map[string]string{}
+	}
+	s.keys[host] = key
+	return nil
+}
+
+var (
+	hostKeyStoreMu sync.Mutex
+	hostKeyStore   HostKeyStore = &MemoryHostKeyStore{}
+)
+
+// SetHostKeyStore 设置 NewSSHDialFunc / NewSFTPClient 使用的主机公钥记录（CLI 启动时设置为当前环境的 state）
+func SetHostKeyStore(store HostKeyStore) {
+	hostKeyStoreMu.Lock()
+	defer hostKeyStoreMu.Unlock()
+	hostKeyStore = store
+}
+
+func currentHostKeyStore() HostKeyStore {
+	hostKeyStoreMu.Lock()
+	defer hostKeyStoreMu.Unlock()
+	return hostKeyStore
+}
+
+// HostKeyMismatchError 主机公钥与记录不一致的详细信息（errors.Is(err, ErrHostKeyMismatch) 成立）
+type HostKeyMismatchError struct {
+	Host string
+	Want string // 记录的公钥指纹（SHA256）
+	Got  string // 实际的公钥指纹（SHA256）
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("%s 的 SSH 主机公钥已变化（记录 %s，实际 %s）。"+
+		"如果实例刚重建或替换过系统盘，运行 cloudcode ssh --reset-hostkey 重新记录；否则可能存在中间人攻击，请勿继续连接",
+		e.Host, e.Want, e.Got)
+}
+
+func (e *HostKeyMismatchError) Unwrap() error {
+	return ErrHostKeyMismatch
+}
+
+// HostKeyCallback 返回按 store 校验主机公钥的回调：未记录时记录本次的公钥，已记录时要求一致
+func HostKeyCallback(store HostKeyStore) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		host := hostOnly(hostname)
+		pinned, err := store.LoadHostKey(host)
+		if err != nil {
+			return fmt.Errorf("读取主机公钥记录失败: %w", err)
+		}
+		if pinned == "" {
+			if err := store.SaveHostKey(host, MarshalHostKey(key)); err != nil {
+				return fmt.Errorf("记录主机公钥失败: %w", err)
+			}
+			return nil
+		}
+		want, err := parseHostKey(pinned)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(want.Marshal(), key.Marshal()) {
+			return &HostKeyMismatchError{Host: host, Want: ssh.FingerprintSHA256(want), Got: ssh.FingerprintSHA256(key)}
+		}
+		return nil
+	}
+}
+
+// MarshalHostKey 将公钥转为 authorized_keys 格式（单行，无换行符）
+func MarshalHostKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+// HostKeyFingerprint 返回 authorized_keys 格式公钥的 SHA256 指纹
+func HostKeyFingerprint(key string) (string, error) {
+	pub, err := parseHostKey(key)
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(pub), nil
+}
+
+func parseHostKey(key string) (ssh.PublicKey, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("主机公钥记录格式错误: %w", err)
+	}
+	return pub, nil
+}
+
+// hostKeyAlgorithms 已记录主机公钥时只协商该类型的主机密钥，否则使用默认顺序
+func hostKeyAlgorithms(store HostKeyStore, host string) []string {
+	pinned, err := store.LoadHostKey(host)
+	if err != nil || pinned == "" {
+		return nil
+	}
+	pub, err := parseHostKey(pinned)
+	if err != nil {
+		return nil
+	}
+	if pub.Type() == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{pub.Type()}
+}
+
+// hostOnly 去掉 host:port 中的端口
+func hostOnly(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport
+	}
+	return host
+}
@@ -0,0 +1,50 @@
+package remote
+
+// lines.go 将远程命令的实时输出按行回调，用于在部署过程中逐行显示进度。
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LineWriter 按行切分写入的数据，每个完整行（去掉行尾的 \n 和 \r）调用一次回调；
+// 可并发写入。命令结束后调用 Flush 回调最后一个不完整的行。
+type LineWriter struct {
+	mu     sync.Mutex
+	onLine func(line string)
+	buf    []byte
+}
+
+// NewLineWriter 创建逐行回调 onLine 的 LineWriter
+func NewLineWriter(onLine func(line string)) *LineWriter {
+	return &LineWriter{onLine: onLine}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 回调尚未以换行结尾的剩余内容
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *LineWriter) emit(line []byte) {
+	w.onLine(string(bytes.TrimRight(line, "\r")))
+}
@@ -0,0 +1,239 @@
+package remote
+
+// pool.go 在一次 CLI 命令运行中复用 SSH 连接：部署、健康检查、恢复等各阶段共享到同一实例的连接，
+// SFTP 作为子系统在同一个连接上打开；定期发送 keepalive，连接断开后下次使用时自动重连。
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultKeepaliveInterval Pool 发送 keepalive 的默认间隔（超过一个间隔未响应视为连接已断开）
+const DefaultKeepaliveInterval = 15 * time.Second
+
+// Pool 按主机、端口、用户和私钥复用 SSH 连接。DialFunc / NewSFTPClient 与 NewSSHDialFunc / NewSFTPClient
+// 签名一致，可直接替换；返回的客户端 Close 只归还连接，Pool.Close 时才真正断开。
+type Pool struct {
+	KeepaliveInterval time.Duration // keepalive 间隔，为零时使用 DefaultKeepaliveInterval
+
+	mu     sync.Mutex
+	conns  map[string]*pooledConn
+	closed bool
+}
+
+// NewPool 创建连接池
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// DialFunc 返回从连接池获取连接的 DialFunc（连接不存在或已断开时建立新连接）
+func (p *Pool) DialFunc(host string, port int, user string, privateKey []byte) DialFunc {
+	return func() (SSHClient, error) {
+		conn, err := p.conn(host, port, user, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.get(); err != nil {
+			return nil, err
+		}
+		return &realSSHClient{pooled: conn}, nil
+	}
+}
+
+// NewSFTPClient 在连接池的 SSH 连接上打开 SFTP 子系统
+func (p *Pool) NewSFTPClient(host string, port int, user string, privateKey []byte) (SFTPClient, error) {
+	conn, err := p.conn(host, port, user, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	client, err := conn.get()
+	if err != nil {
+		return nil, err
+	}
+	sftpConn, err := sftp.NewClient(client)
+	if err != nil {
+		// 连接可能已断开：重连后再试一次
+		conn.drop(client)
+		if client, err = conn.get(); err != nil {
+			return nil, err
+		}
+		if sftpConn, err = sftp.NewClient(client); err != nil {
+			return nil, fmt.Errorf("SFTP 连接失败: %w", err)
+		}
+	}
+	return &realSFTPClient{sftpClient: sftpConn}, nil
+}
+
+// Close 断开连接池中的所有连接
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, c := range conns {
+		if err := c.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *Pool) conn(host string, port int, user string, privateKey []byte) (*pooledConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("SSH 连接池已关闭")
+	}
+	addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	key := fmt.Sprintf("%s@%s/%x", user, addr, sha256.Sum256(privateKey))
+	if c, ok := p.conns[key]; ok {
+		return c, nil
+	}
+	interval := p.KeepaliveInterval
+	if interval <= 0 {
+		interval = DefaultKeepaliveInterval
+	}
+	c := &pooledConn{addr: addr, host: host, user: user, privateKey: privateKey, keepalive: interval}
+	if p.conns == nil {
+		p.conns = map[string]*pooledConn{}
+	}
+	p.conns[key] = c
+	return c, nil
+}
+
+// pooledConn 连接池中到一个主机的连接，断开后由 get 重新建立
+type pooledConn struct {
+	addr       string
+	host       string
+	user       string
+	privateKey []byte
+	keepalive  time.Duration
+
+	mu     sync.Mutex
+	client *ssh.Client
+	closed bool
+}
+
+// get 返回当前连接，未连接或已断开时重新建立
+func (c *pooledConn) get() (*ssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, fmt.Errorf("SSH 连接池已关闭")
+	}
+	if c.client != nil {
+		return c.client, nil
+	}
+	config, err := clientConfig(c.host, c.user, c.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dialSSH(c.addr, config, c.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("SSH 连接失败 (%s): %w", c.addr, err)
+	}
+	c.client = client
+	go c.watch(client)
+	return client, nil
+}
+
+// newSession 在当前连接上创建 session；失败时（连接已断开但尚未察觉）重连后再试一次
+func (c *pooledConn) newSession() (*ssh.Session, error) {
+	client, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+	c.drop(client)
+	if client, err = c.get(); err != nil {
+		return nil, err
+	}
+	return client.NewSession()
+}
+
+// dial 在当前连接上建立 direct-tcpip 连接；连接已断开时重连后再试一次（远程拒绝连接目标时不重试）
+func (c *pooledConn) dial(network, addr string) (net.Conn, error) {
+	client, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.Dial(network, addr)
+	var openErr *ssh.OpenChannelError
+	if err == nil || errors.As(err, &openErr) {
+		return conn, err
+	}
+	c.drop(client)
+	if client, err = c.get(); err != nil {
+		return nil, err
+	}
+	return client.Dial(network, addr)
+}
+
+// drop 丢弃已断开的连接，下次 get 时重连
+func (c *pooledConn) drop(client *ssh.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == client {
+		c.client = nil
+	}
+	client.Close()
+}
+
+func (c *pooledConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.client == nil {
+		return nil
+	}
+	err := c.client.Close()
+	c.client = nil
+	return err
+}
+
+// watch 定期发送 keepalive；一个间隔内没有响应时断开连接，连接断开后从连接池中移除
+func (c *pooledConn) watch(client *ssh.Client) {
+	done := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(c.keepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			c.drop(client)
+			return
+		case <-ticker.C:
+			replied := make(chan error, 1)
+			go func() {
+				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+				replied <- err
+			}()
+			select {
+			case err := <-replied:
+				if err != nil {
+					client.Close()
+				}
+			case <-done:
+			case <-time.After(c.keepalive):
+				client.Close()
+			}
+		}
+	}
+}
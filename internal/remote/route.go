@@ -0,0 +1,238 @@
+package remote
+
+// route.go 按配置的网络路径建立 SSH 连接：直连、经 SOCKS5 / HTTP CONNECT 代理，或经跳板机（ProxyJump 语义：
+// 先 SSH 登录跳板机，再由跳板机建立到实例的 TCP 连接）。代理和跳板机可组合使用（经代理连接跳板机）。
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+)
+
+// Route SSH 连接路径，零值表示直连
+type Route struct {
+	Proxy string    // 代理 URL：socks5://[user:pass@]host:port 或 http://[user:pass@]host:port，空表示不使用代理
+	Jump  *JumpHost // 跳板机，nil 表示不使用
+}
+
+// JumpHost 跳板机
+type JumpHost struct {
+	Host       string
+	Port       int
+	User       string
+	PrivateKey []byte       // 空时使用连接目标主机的私钥
+	HostKeys   HostKeyStore // 跳板机主机公钥记录，nil 时只在进程内记住
+}
+
+// RouteFunc 返回当前的 SSH 连接路径（每次建立连接时调用），返回 nil 表示直连
+type RouteFunc func() (*Route, error)
+
+var (
+	routeMu   sync.Mutex
+	routeFunc RouteFunc
+)
+
+// SetRouteFunc 设置 NewSSHDialFunc / NewSFTPClient / Pool 使用的连接路径（CLI 启动时设置为当前环境的配置）
+func SetRouteFunc(f RouteFunc) {
+	routeMu.Lock()
+	defer routeMu.Unlock()
+	routeFunc = f
+}
+
+func currentRoute() (*Route, error) {
+	routeMu.Lock()
+	f := routeFunc
+	routeMu.Unlock()
+	if f == nil {
+		return nil, nil
+	}
+	return f()
+}
+
+// dialSSH 按当前连接路径建立到 addr 的 SSH 连接；privateKey 用于未单独配置私钥的跳板机
+func dialSSH(addr string, config *ssh.ClientConfig, privateKey []byte) (*ssh.Client, error) {
+	route, err := currentRoute()
+	if err != nil {
+		return nil, err
+	}
+	if route == nil || (route.Proxy == "" && route.Jump == nil) {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	dialer, err := proxyDialer(route.Proxy, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if route.Jump == nil {
+		conn, err := dialer.Dial("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("经代理连接 %s 失败: %w", addr, err)
+		}
+		return newSSHClient(conn, addr, config)
+	}
+
+	jump := route.Jump
+	jumpAddr := net.JoinHostPort(jump.Host, fmt.Sprintf("%d", jump.Port))
+	jumpKey := jump.PrivateKey
+	if len(jumpKey) == 0 {
+		jumpKey = privateKey
+	}
+	signer, err := ssh.ParsePrivateKey(jumpKey)
+	if err != nil {
+		return nil, fmt.Errorf("解析跳板机 SSH 私钥失败: %w", err)
+	}
+	store := jump.HostKeys
+	if store == nil {
+		store = &MemoryHostKeyStore{}
+	}
+	jumpConfig := &ssh.ClientConfig{
+		User:              jump.User,
+		Auth:              []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback:   HostKeyCallback(store),
+		HostKeyAlgorithms: hostKeyAlgorithms(store, jump.Host),
+		Timeout:           config.Timeout,
+	}
+
+	jumpConn, err := dialer.Dial("tcp", jumpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("连接跳板机 %s 失败: %w", jumpAddr, err)
+	}
+	jumpClient, err := newSSHClient(jumpConn, jumpAddr, jumpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("登录跳板机 %s 失败: %w", jumpAddr, err)
+	}
+	conn, err := jumpClient.Dial("tcp", addr)
+	if err != nil {
+		jumpClient.Close()
+		return nil, fmt.Errorf("经跳板机 %s 连接 %s 失败: %w", jumpAddr, addr, err)
+	}
+	client, err := newSSHClient(&jumpedConn{Conn: conn, jump: jumpClient}, addr, config)
+	if err != nil {
+		jumpClient.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// newSSHClient 在已建立的连接上完成 SSH 握手。ssh.NewClientConn 不处理 ClientConfig.Timeout，
+// 握手期间由连接 deadline 限时，握手完成后清除；不支持 deadline 的连接（经跳板机转发的 SSH 通道）超时后直接关闭。
+func newSSHClient(conn net.Conn, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var timer *time.Timer
+	if config.Timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(config.Timeout)); err != nil {
+			timer = time.AfterFunc(config.Timeout, func() { conn.Close() })
+		}
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if timer != nil && !timer.Stop() {
+		c.Close()
+		return nil, fmt.Errorf("与 %s 的 SSH 握手超时", addr)
+	}
+	if config.Timeout > 0 {
+		conn.SetDeadline(time.Time{})
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// jumpedConn 经跳板机转发的连接，关闭时同时断开跳板机
+type jumpedConn struct {
+	net.Conn
+	jump *ssh.Client
+}
+
+func (c *jumpedConn) Close() error {
+	err := c.Conn.Close()
+	c.jump.Close()
+	return err
+}
+
+// proxyDialer 按代理 URL 创建 Dialer，URL 为空时直连
+func proxyDialer(rawURL string, timeout time.Duration) (proxy.Dialer, error) {
+	direct := &net.Dialer{Timeout: timeout}
+	if rawURL == "" {
+		return direct, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址格式错误: %w", err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		return proxy.FromURL(u, direct)
+	case "http":
+		return &httpConnectDialer{addr: u.Host, user: u.User, forward: direct}, nil
+	}
+	return nil, fmt.Errorf("不支持的代理协议 %q（支持 socks5、http）", u.Scheme)
+}
+
+// httpConnectDialer 通过 HTTP CONNECT 代理建立 TCP 连接
+type httpConnectDialer struct {
+	addr    string
+	user    *url.Userinfo
+	forward *net.Dialer
+}
+
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := d.forward.Dial(network, d.addr)
+	if err != nil {
+		return nil, err
+	}
+	if d.forward.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.forward.Timeout))
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.user != nil {
+		password, _ := d.user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(d.user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("发送 CONNECT 请求失败: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取代理响应失败: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("代理拒绝 CONNECT %s: %s", addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读出代理响应之后已缓冲的数据
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -1,14 +1,16 @@
 package remote
 
-// sftp.go 封装 SFTP 文件上传操作，用于将渲染后的配置文件上传到 ECS 实例。
+// sftp.go 封装 SFTP 文件上传 / 读取操作，用于将渲染后的配置文件上传到 ECS 实例，
+// 以及读回实例上的文件（如 Authelia users_database.yml）做增量修改。
 
 import (
 	"fmt"
 )
 
-// SFTPClient 抽象 SFTP 文件上传，支持 mock 测试
+// SFTPClient 抽象 SFTP 文件上传 / 读取，支持 mock 测试
 type SFTPClient interface {
 	UploadFile(localContent []byte, remotePath string) error
+	ReadFile(remotePath string) ([]byte, error) // 文件不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
 	Close() error
 }
 
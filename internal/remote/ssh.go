@@ -6,6 +6,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"time"
 )
 
@@ -28,6 +31,100 @@ type SSHClient interface {
 	Close() error
 }
 
+// StreamClient 支持流式 stdin / stdout 的 SSH 连接，用于传输大文件（如卷备份），不在内存中缓存输出
+type StreamClient interface {
+	StreamCommand(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error
+}
+
+// StreamCommand 在远程执行命令，stdin（可为 nil）和 stdout 以流的方式传输；连接不支持流式传输时返回错误
+func StreamCommand(ctx context.Context, client SSHClient, cmd string, stdin io.Reader, stdout io.Writer) error {
+	sc, ok := client.(StreamClient)
+	if !ok {
+		return fmt.Errorf("SSH 连接不支持流式传输")
+	}
+	return sc.StreamCommand(ctx, cmd, stdin, stdout)
+}
+
+// StreamingOutputClient 支持实时回传 stdout / stderr 的 SSH 连接，用于耗时较长、需要显示进度的命令
+type StreamingOutputClient interface {
+	RunCommandStreaming(ctx context.Context, cmd string, stdout, stderr io.Writer) error
+}
+
+// RunCommandStreaming 在远程执行命令，stdout / stderr 实时写入对应的 Writer（可为同一个，可为 nil），
+// 远程以非零状态退出时返回 *ExitError。连接不支持实时回传时退化为 RunCommand，结束后一次性写入 stdout。
+func RunCommandStreaming(ctx context.Context, client SSHClient, cmd string, stdout, stderr io.Writer) error {
+	if sc, ok := client.(StreamingOutputClient); ok {
+		return sc.RunCommandStreaming(ctx, cmd, stdout, stderr)
+	}
+	output, err := client.RunCommand(ctx, cmd)
+	if stdout != nil && output != "" {
+		if _, werr := io.WriteString(stdout, output); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// ExitError 远程命令以非零状态退出（被信号终止时 Status 为 128 + 信号编号）
+type ExitError struct {
+	Status int
+	Signal string // 终止远程进程的信号名（如 "INT"），正常退出时为空
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != "" {
+		return fmt.Sprintf("远程命令被信号 %s 终止", e.Signal)
+	}
+	return fmt.Sprintf("远程命令退出码 %d", e.Status)
+}
+
+// WindowSize 终端尺寸（字符数）
+type WindowSize struct {
+	Width  int
+	Height int
+}
+
+// SessionOptions 配置交互式会话
+type SessionOptions struct {
+	TTY     bool              // 分配 PTY
+	Term    string            // PTY 的 TERM，空时使用 xterm-256color
+	Size    WindowSize        // PTY 初始尺寸，为零时使用 80x24
+	Resize  <-chan WindowSize // 本地终端尺寸变化，同步到 PTY（可为 nil）
+	Signals <-chan os.Signal  // 本地收到的信号，转发给远程进程（可为 nil）
+	Stdin   io.Reader         // 可为 nil
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// InteractiveClient 支持交互式会话（PTY、窗口尺寸同步、信号转发）的 SSH 连接
+type InteractiveClient interface {
+	RunInteractive(ctx context.Context, cmd string, opts SessionOptions) error
+}
+
+// RunInteractive 在远程运行交互式会话，cmd 为空时启动登录 shell；输入输出直接与 opts 中的流对接，
+// 远程以非零状态退出时返回 *ExitError；连接不支持交互式会话时返回错误
+func RunInteractive(ctx context.Context, client SSHClient, cmd string, opts SessionOptions) error {
+	ic, ok := client.(InteractiveClient)
+	if !ok {
+		return fmt.Errorf("SSH 连接不支持交互式会话")
+	}
+	return ic.RunInteractive(ctx, cmd, opts)
+}
+
+// DialClient 支持由远程主机建立 TCP 连接（direct-tcpip）的 SSH 连接，用于端口转发
+type DialClient interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+// Dial 经 SSH 连接由远程主机建立到 addr 的 TCP 连接；连接不支持时返回错误
+func Dial(client SSHClient, network, addr string) (net.Conn, error) {
+	dc, ok := client.(DialClient)
+	if !ok {
+		return nil, fmt.Errorf("SSH 连接不支持端口转发")
+	}
+	return dc.Dial(network, addr)
+}
+
 // DialFunc 用于建立 SSH 连接的函数类型（工厂模式，每次调用创建新连接）
 type DialFunc func() (SSHClient, error)
 
@@ -50,7 +147,7 @@ func (o *WaitSSHOptions) withDefaults() {
 	}
 }
 
-// WaitForSSH 使用指数退避重试连接 SSH，直到成功或超时
+// WaitForSSH 使用指数退避重试连接 SSH，直到成功或超时；主机公钥不一致时立即返回
 func WaitForSSH(ctx context.Context, dial DialFunc, opts WaitSSHOptions) (SSHClient, error) {
 	opts.withDefaults()
 
@@ -64,6 +161,9 @@ func WaitForSSH(ctx context.Context, dial DialFunc, opts WaitSSHOptions) (SSHCli
 		if err == nil {
 			return client, nil
 		}
+		if errors.Is(err, ErrHostKeyMismatch) {
+			return nil, err
+		}
 
 		select {
 		case <-ctx.Done():
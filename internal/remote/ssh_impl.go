@@ -1,11 +1,12 @@
 package remote
 
 // ssh_impl.go 提供 SSH/SFTP 的真实实现（非 mock），用于连接 ECS 实例。
-// 包括：SSH 命令执行、SFTP 文件上传、公网 IP 获取。
+// 包括：SSH 命令执行（含实时输出）、交互式会话（PTY）、SFTP 文件上传、公网 IP 获取。
 
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -13,6 +14,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/pkg/sftp"
@@ -22,27 +25,45 @@ import (
 // realSSHClient 真实 SSH 客户端实现
 type realSSHClient struct {
 	client *ssh.Client
+	pooled *pooledConn // 非 nil 时连接来自 Pool：Close 只归还连接，连接断开时自动重连
+}
+
+// newSession 在连接上创建 session
+func (c *realSSHClient) newSession() (*ssh.Session, error) {
+	if c.pooled != nil {
+		return c.pooled.newSession()
+	}
+	return c.client.NewSession()
+}
+
+// clientConfig 创建 SSH 客户端配置，主机公钥按 SetHostKeyStore 设置的记录校验
+func clientConfig(host, user string, privateKey []byte) (*ssh.ClientConfig, error) {
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("解析 SSH 私钥失败: %w", err)
+	}
+	store := currentHostKeyStore()
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback:   HostKeyCallback(store),
+		HostKeyAlgorithms: hostKeyAlgorithms(store, host),
+		Timeout:           10 * time.Second,
+	}, nil
 }
 
 // NewSSHDialFunc 创建真实 SSH 连接的 DialFunc
 func NewSSHDialFunc(host string, port int, user string, privateKey []byte) DialFunc {
 	return func() (SSHClient, error) {
-		signer, err := ssh.ParsePrivateKey(privateKey)
+		config, err := clientConfig(host, user, privateKey)
 		if err != nil {
-			return nil, fmt.Errorf("解析 SSH 私钥失败: %w", err)
-		}
-
-		config := &ssh.ClientConfig{
-			User: user,
-			Auth: []ssh.AuthMethod{
-				ssh.PublicKeys(signer),
-			},
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			Timeout:         10 * time.Second,
+			return nil, err
 		}
 
 		addr := fmt.Sprintf("%s:%d", host, port)
-		client, err := ssh.Dial("tcp", addr, config)
+		client, err := dialSSH(addr, config, privateKey)
 		if err != nil {
 			return nil, fmt.Errorf("SSH 连接失败 (%s): %w", addr, err)
 		}
@@ -54,7 +75,7 @@ func NewSSHDialFunc(host string, port int, user string, privateKey []byte) DialF
 // RunCommand 在远程执行命令，支持 context 超时取消。
 // 返回 stdout 内容；失败时错误信息包含 stderr。
 func (c *realSSHClient) RunCommand(ctx context.Context, cmd string) (string, error) {
-	session, err := c.client.NewSession()
+	session, err := c.newSession()
 	if err != nil {
 		return "", fmt.Errorf("创建 SSH session 失败: %w", err)
 	}
@@ -75,40 +96,209 @@ func (c *realSSHClient) RunCommand(ctx context.Context, cmd string) (string, err
 		return "", ctx.Err()
 	case err := <-done:
 		if err != nil {
-			return stdout.String(), fmt.Errorf("命令执行失败: %w\nstderr: %s", err, stderr.String())
+			return stdout.String(), fmt.Errorf("命令执行失败: %w\nstderr: %s", commandError(err), stderr.String())
 		}
 		return stdout.String(), nil
 	}
 }
 
+// StreamCommand 在远程执行命令，stdin / stdout 直接与 session 对接；失败时错误信息包含 stderr
+func (c *realSSHClient) StreamCommand(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("创建 SSH session 失败: %w", err)
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+
+	select {
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGTERM)
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("命令执行失败: %w\nstderr: %s", commandError(err), stderr.String())
+		}
+		return nil
+	}
+}
+
+// RunCommandStreaming 在远程执行命令，stdout / stderr 实时写入对应的 Writer，远程以非零状态退出时返回 *ExitError
+func (c *realSSHClient) RunCommandStreaming(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("创建 SSH session 失败: %w", err)
+	}
+	defer session.Close()
+
+	// stdout / stderr 由两个 goroutine 分别写入，同一个 Writer 时需要加锁
+	if stdout != nil && stdout == stderr {
+		w := &lockedWriter{w: stdout}
+		stdout, stderr = w, w
+	}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+
+	select {
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGTERM)
+		return ctx.Err()
+	case err := <-done:
+		return commandError(err)
+	}
+}
+
+// lockedWriter 串行化并发写入
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// RunInteractive 在远程运行交互式会话：按需分配 PTY，同步窗口尺寸，转发信号，直到远程进程退出或 ctx 取消
+func (c *realSSHClient) RunInteractive(ctx context.Context, cmd string, opts SessionOptions) error {
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("创建 SSH session 失败: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = opts.Stdin
+	session.Stdout = opts.Stdout
+	session.Stderr = opts.Stderr
+
+	if opts.TTY {
+		termName := opts.Term
+		if termName == "" {
+			termName = "xterm-256color"
+		}
+		size := opts.Size
+		if size.Width <= 0 || size.Height <= 0 {
+			size = WindowSize{Width: 80, Height: 24}
+		}
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err := session.RequestPty(termName, size.Height, size.Width, modes); err != nil {
+			return fmt.Errorf("分配 PTY 失败: %w", err)
+		}
+	}
+
+	if cmd == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("启动远程会话失败: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	resize, signals := opts.Resize, opts.Signals
+	for {
+		select {
+		case size, ok := <-resize:
+			if !ok {
+				resize = nil
+				continue
+			}
+			_ = session.WindowChange(size.Height, size.Width)
+		case sig, ok := <-signals:
+			if !ok {
+				signals = nil
+				continue
+			}
+			if s, ok := sshSignal(sig); ok {
+				_ = session.Signal(s)
+			}
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGTERM)
+			return ctx.Err()
+		case err := <-done:
+			return commandError(err)
+		}
+	}
+}
+
+// sshSignal 将本地信号转换为 SSH 协议的信号名
+func sshSignal(sig os.Signal) (ssh.Signal, bool) {
+	switch sig {
+	case os.Interrupt:
+		return ssh.SIGINT, true
+	case syscall.SIGTERM:
+		return ssh.SIGTERM, true
+	case syscall.SIGHUP:
+		return ssh.SIGHUP, true
+	case syscall.SIGQUIT:
+		return ssh.SIGQUIT, true
+	}
+	return "", false
+}
+
+// commandError 将 session 的退出错误转换为 *ExitError，其他错误原样返回
+func commandError(err error) error {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Status: exitErr.ExitStatus(), Signal: exitErr.Signal()}
+	}
+	return err
+}
+
+// Dial 由远程主机建立到 addr 的 TCP 连接（direct-tcpip）
+func (c *realSSHClient) Dial(network, addr string) (net.Conn, error) {
+	if c.pooled != nil {
+		return c.pooled.dial(network, addr)
+	}
+	return c.client.Dial(network, addr)
+}
+
 func (c *realSSHClient) Close() error {
+	if c.pooled != nil {
+		return nil
+	}
 	return c.client.Close()
 }
 
 // realSFTPClient 真实 SFTP 客户端实现
 type realSFTPClient struct {
 	sftpClient *sftp.Client
-	sshClient  *ssh.Client
+	sshClient  *ssh.Client // 为 nil 时 SSH 连接来自 Pool，Close 不关闭
 }
 
 // NewSFTPClient 创建真实 SFTP 客户端
 func NewSFTPClient(host string, port int, user string, privateKey []byte) (SFTPClient, error) {
-	signer, err := ssh.ParsePrivateKey(privateKey)
+	config, err := clientConfig(host, user, privateKey)
 	if err != nil {
-		return nil, fmt.Errorf("解析 SSH 私钥失败: %w", err)
-	}
-
-	config := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
+		return nil, err
 	}
 
 	addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	sshConn, err := ssh.Dial("tcp", addr, config)
+	sshConn, err := dialSSH(addr, config, privateKey)
 	if err != nil {
 		return nil, fmt.Errorf("SSH 连接失败: %w", err)
 	}
@@ -146,9 +336,27 @@ func (c *realSFTPClient) UploadFile(localContent []byte, remotePath string) erro
 	return nil
 }
 
+// ReadFile 读取远程文件内容
+func (c *realSFTPClient) ReadFile(remotePath string) ([]byte, error) {
+	f, err := c.sftpClient.Open(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开远程文件 %s 失败: %w", remotePath, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("读取远程文件 %s 失败: %w", remotePath, err)
+	}
+	return data, nil
+}
+
 func (c *realSFTPClient) Close() error {
-	c.sftpClient.Close()
-	return c.sshClient.Close()
+	err := c.sftpClient.Close()
+	if c.sshClient != nil {
+		return c.sshClient.Close()
+	}
+	return err
 }
 
 // GetPublicIP 通过外部服务（ipify）获取用户公网 IP，用于限制 SSH 安全组规则。
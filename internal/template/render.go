@@ -3,7 +3,7 @@
 //
 // 文件分两类：
 //   - 模板文件（.tmpl）：使用 Go text/template 渲染，注入域名/密码/API Key 等变量
-//   - 静态文件：原样输出（docker-compose.yml、Dockerfile.devbox）
+//   - 静态文件：原样输出（如自动停机 agent 的 systemd unit）
 //
 // RenderAll 将所有文件渲染后映射到 ECS 上的目标路径，供 SFTP 上传。
 package template
@@ -12,6 +12,7 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"strings"
 	"text/template"
 )
 
@@ -20,16 +21,57 @@ var templateFS embed.FS
 
 // TemplateData 包含所有模板渲染所需的字段
 type TemplateData struct {
-	Domain               string // 域名
-	Username             string // 管理员用户名
-	HashedPassword       string // Argon2id 哈希后的密码
-	Email                string // 管理员邮箱
-	SessionSecret        string // Authelia session 密钥
-	StorageEncryptionKey string // Authelia storage 加密密钥
-	OpenAIAPIKey         string // OpenAI API Key
-	OpenAIBaseURL        string // OpenAI Base URL（可选）
-	AnthropicAPIKey      string // Anthropic API Key（可选）
-	Version              string // Docker 镜像版本号
+	Domain               string            // 域名
+	Username             string            // 管理员用户名
+	HashedPassword       string            // Argon2id 哈希后的密码
+	Email                string            // 管理员邮箱
+	SessionSecret        string            // Authelia session 密钥
+	StorageEncryptionKey string            // Authelia storage 加密密钥
+	Env                  map[string]string // devbox .env 环境变量（provider API Key 等，按名称排序输出）
+	SessionExpiration    string            // Authelia 会话有效期（默认 12h）
+	SessionInactivity    string            // Authelia 会话闲置超时（默认 30m）
+	AccessPolicy         string            // 主域名访问策略：two_factor（默认）/ one_factor
+	Version              string            // Docker 镜像版本号
+	DevboxUsers          []DevboxUser      // 非空时每个用户一个独立 devbox（Caddy 按 Remote-User 路由），空表示共用一个 devbox
+}
+
+// DevboxUser 独立 devbox 模式下的一个用户
+type DevboxUser struct {
+	Username string // Authelia 用户名（匹配 forward_auth 返回的 Remote-User）
+	Service  string // compose 服务名 / 容器名 / volume 前缀
+}
+
+// DevboxService 返回用户独立 devbox 的服务名（用户名中的 . 替换为 _，保证可作为主机名解析）。
+// a.b 与 a_b 会得到相同的服务名，由 DevboxServiceConflict 检查。
+func DevboxService(username string) string {
+	return "devbox-" + strings.ReplaceAll(username, ".", "_")
+}
+
+// DevboxServiceConflict 返回 usernames 中与 username 的 devbox 服务名相同的其他用户，没有时返回空
+func DevboxServiceConflict(username string, usernames []string) string {
+	service := DevboxService(username)
+	for _, name := range usernames {
+		if name != username && DevboxService(name) == service {
+			return name
+		}
+	}
+	return ""
+}
+
+// NewDevboxUsers 按用户名列表生成独立 devbox 配置；两个用户的服务名相同时返回错误
+// （否则 compose 服务重复，且两人会共用容器和 volume）
+func NewDevboxUsers(usernames []string) ([]DevboxUser, error) {
+	users := make([]DevboxUser, 0, len(usernames))
+	owners := make(map[string]string, len(usernames))
+	for _, name := range usernames {
+		service := DevboxService(name)
+		if other, ok := owners[service]; ok {
+			return nil, fmt.Errorf("用户 %s 和 %s 的 devbox 名称相同（%s），请删除或改用其他用户名", other, name, service)
+		}
+		owners[service] = name
+		users = append(users, DevboxUser{Username: name, Service: service})
+	}
+	return users, nil
 }
 
 // 模板文件（需要渲染）
@@ -41,8 +83,22 @@ var templateFiles = []string{
 	"templates/docker-compose.yml.tmpl",
 }
 
-// 静态文件（原样输出）
-var staticFiles = []string{}
+// 静态文件（原样输出，不随 RenderAll 上传）
+var staticFiles = []string{
+	"templates/cloudcode-agent.service", // 自动停机 agent 的 systemd unit
+}
+
+// proxyParams Caddyfile 中 devbox_proxy 子模板的参数
+type proxyParams struct {
+	Data *TemplateData
+	Port int
+}
+
+var funcMap = template.FuncMap{
+	"proxy": func(data *TemplateData, port int) proxyParams {
+		return proxyParams{Data: data, Port: port}
+	},
+}
 
 // RenderTemplate 渲染指定模板文件，返回渲染后的内容
 func RenderTemplate(name string, data *TemplateData) ([]byte, error) {
@@ -51,7 +107,7 @@ func RenderTemplate(name string, data *TemplateData) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read template %s: %w", name, err)
 	}
 
-	tmpl, err := template.New(name).Parse(string(content))
+	tmpl, err := template.New(name).Funcs(funcMap).Parse(string(content))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
 	}
@@ -0,0 +1,136 @@
+package wake
+
+// acme.go 通过 ACME（Let's Encrypt）为唤醒页域名签发 HTTPS 证书。
+// 使用 HTTP-01 验证：唤醒页按账号公钥指纹无状态应答验证请求，签发时无需更新函数。
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Certificate 签发的证书（PEM）
+type Certificate struct {
+	CertPEM  string
+	KeyPEM   string
+	NotAfter time.Time
+}
+
+// ACMEIssuer ACME 证书签发
+type ACMEIssuer struct {
+	DirectoryURL string        // 默认 Let's Encrypt 生产环境
+	Key          crypto.Signer // ACME 账号私钥，需长期保存（唤醒页使用其指纹应答验证）
+}
+
+// LoadOrCreateAccountKey 读取 ACME 账号私钥，不存在时生成并保存（0600）
+func LoadOrCreateAccountKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("ACME 账号私钥 %s 格式错误", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取 ACME 账号私钥失败: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, fmt.Errorf("保存 ACME 账号私钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// Thumbprint 返回账号公钥的 JWK 指纹
+func (i *ACMEIssuer) Thumbprint() (string, error) {
+	return acme.JWKThumbprint(i.Key.Public())
+}
+
+// Issue 为 domain 签发证书（域名须已指向唤醒页，且唤醒页已配置同一账号的指纹）
+func (i *ACMEIssuer) Issue(ctx context.Context, domain string) (*Certificate, error) {
+	dir := i.DirectoryURL
+	if dir == "" {
+		dir = acme.LetsEncryptURL
+	}
+	client := &acme.Client{Key: i.Key, DirectoryURL: dir}
+	if _, err := client.Register(ctx, &acme.Account{}, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("注册 ACME 账号失败: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return nil, fmt.Errorf("创建证书订单失败: %w", err)
+	}
+	for _, u := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("查询域名验证失败: %w", err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == "http-01" {
+				chal = c
+				break
+			}
+		}
+		if chal == nil {
+			return nil, fmt.Errorf("CA 未提供 http-01 验证方式")
+		}
+		if _, err := client.Accept(ctx, chal); err != nil {
+			return nil, fmt.Errorf("发起域名验证失败: %w", err)
+		}
+		if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+			return nil, fmt.Errorf("域名验证失败: %w", err)
+		}
+	}
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, fmt.Errorf("等待证书订单失败: %w", err)
+	}
+
+	certKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{domain}}, certKey)
+	if err != nil {
+		return nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("签发证书失败: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(certKey)})
+	return &Certificate{CertPEM: string(certPEM), KeyPEM: string(keyPEM), NotAfter: leaf.NotAfter}, nil
+}
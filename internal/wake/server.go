@@ -0,0 +1,314 @@
+// Package wake 实现停机实例的唤醒页（cloudcode wake serve，运行在函数计算的自定义运行时中）。
+// 实例停机后域名无法访问，用户打开唤醒页，输入唤醒密钥登录后点击启动，页面调用 StartInstance
+// 并轮询实例状态，Caddy 可访问后跳转回原域名。唤醒页同时应答 ACME HTTP-01 验证，用于签发自身的 HTTPS 证书。
+package wake
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/alicloud"
+)
+
+// 函数环境变量（cloudcode wake enable 写入）
+const (
+	EnvInstanceID     = "CLOUDCODE_WAKE_INSTANCE_ID"
+	EnvRegion         = "CLOUDCODE_WAKE_REGION"
+	EnvTarget         = "CLOUDCODE_WAKE_TARGET"
+	EnvKeyHash        = "CLOUDCODE_WAKE_KEY_HASH"
+	EnvCookieSecret   = "CLOUDCODE_WAKE_COOKIE_SECRET"
+	EnvACMEThumbprint = "CLOUDCODE_WAKE_ACME_THUMBPRINT"
+)
+
+const (
+	DefaultPort = 9000 // 函数计算自定义运行时监听端口
+
+	cookieName     = "cloudcode_wake"
+	cookieTTL      = 30 * 24 * time.Hour
+	acmePathPrefix = "/.well-known/acme-challenge/"
+)
+
+//go:embed page.html
+var pageHTML string
+
+var pageTemplate = template.Must(template.New("page").Parse(pageHTML))
+
+// Config 唤醒页配置
+type Config struct {
+	InstanceID     string
+	Region         string
+	Target         string // 实例上的服务地址，如 https://code.example.com
+	KeyHash        string // 唤醒密钥的 SHA-256（十六进制）
+	CookieSecret   string
+	ACMEThumbprint string // ACME 账号公钥指纹，用于无状态应答 HTTP-01 验证
+}
+
+// Env 返回写入函数的环境变量
+func (c *Config) Env() map[string]string {
+	return map[string]string{
+		EnvInstanceID:     c.InstanceID,
+		EnvRegion:         c.Region,
+		EnvTarget:         c.Target,
+		EnvKeyHash:        c.KeyHash,
+		EnvCookieSecret:   c.CookieSecret,
+		EnvACMEThumbprint: c.ACMEThumbprint,
+	}
+}
+
+// ConfigFromEnv 从环境变量读取配置
+func ConfigFromEnv() (*Config, error) {
+	cfg := &Config{
+		InstanceID:     os.Getenv(EnvInstanceID),
+		Region:         os.Getenv(EnvRegion),
+		Target:         os.Getenv(EnvTarget),
+		KeyHash:        os.Getenv(EnvKeyHash),
+		CookieSecret:   os.Getenv(EnvCookieSecret),
+		ACMEThumbprint: os.Getenv(EnvACMEThumbprint),
+	}
+	if cfg.InstanceID == "" || cfg.Region == "" || cfg.Target == "" || cfg.KeyHash == "" || cfg.CookieSecret == "" {
+		return nil, fmt.Errorf("缺少唤醒页配置（%s 等环境变量）", EnvInstanceID)
+	}
+	return cfg, nil
+}
+
+// NewKey 生成随机密钥（唤醒密钥 / cookie 签名密钥）
+func NewKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashKey 返回唤醒密钥的 SHA-256（密钥为 128 位随机数，无需慢哈希）
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ECSFactory 为请求创建 ECS 客户端
+type ECSFactory func(r *http.Request) (alicloud.ECSAPI, error)
+
+// Server 唤醒页 HTTP 服务
+type Server struct {
+	Config Config
+	NewECS ECSFactory                                 // 默认使用函数计算注入的函数角色临时凭证
+	Probe  func(ctx context.Context, url string) bool // 检测实例上的服务是否可访问，默认发起 HTTPS 请求
+	Now    func() time.Time                           // 测试用，默认 time.Now
+}
+
+// Handler 返回 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(acmePathPrefix, s.handleACME)
+	mux.HandleFunc("/login", s.handleLogin)
+	mux.HandleFunc("/start", s.requireAuth(s.handleStart))
+	mux.HandleFunc("/status", s.requireAuth(s.handleStatus))
+	mux.HandleFunc("/", s.handleIndex)
+	return mux
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// handleACME 应答 HTTP-01 验证：key authorization = token.指纹，与具体订单无关
+func (s *Server) handleACME(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, acmePathPrefix)
+	if s.Config.ACMEThumbprint == "" || token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "%s.%s", token, s.Config.ACMEThumbprint)
+}
+
+type pageData struct {
+	Authed bool
+	Error  string
+	Target string
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.renderPage(w, http.StatusOK, pageData{Authed: s.authed(r), Target: s.Config.Target})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	hash := HashKey(strings.TrimSpace(r.FormValue("key")))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(s.Config.KeyHash)) != 1 {
+		s.renderPage(w, http.StatusUnauthorized, pageData{Error: "唤醒密钥不正确", Target: s.Config.Target})
+		return
+	}
+	expires := s.now().Add(cookieTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    s.signCookie(expires.Unix()),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// instanceStatus 唤醒页接口的响应
+type instanceStatus struct {
+	Status string `json:"status"`
+	Ready  bool   `json:"ready"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ecsCli, info, err := s.describe(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, instanceStatus{Error: err.Error()})
+		return
+	}
+	if info.Status == "Stopped" {
+		if err := alicloud.StartECSInstance(ecsCli, s.Config.InstanceID); err != nil {
+			writeJSON(w, http.StatusBadGateway, instanceStatus{Status: info.Status, Error: fmt.Sprintf("启动实例失败: %v", err)})
+			return
+		}
+		info.Status = "Starting"
+	}
+	writeJSON(w, http.StatusOK, instanceStatus{Status: info.Status})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	_, info, err := s.describe(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, instanceStatus{Error: err.Error()})
+		return
+	}
+	resp := instanceStatus{Status: info.Status}
+	if info.Status == "Running" {
+		probe := s.Probe
+		if probe == nil {
+			probe = probeHTTPS
+		}
+		resp.Ready = probe(r.Context(), s.Config.Target)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) describe(r *http.Request) (alicloud.ECSAPI, *alicloud.ECSResource, error) {
+	newECS := s.NewECS
+	if newECS == nil {
+		newECS = s.fcRoleECS
+	}
+	ecsCli, err := newECS(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("初始化 ECS 客户端失败: %w", err)
+	}
+	info, err := alicloud.DescribeECSInstance(ecsCli, s.Config.InstanceID, s.Config.Region)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询实例失败: %w", err)
+	}
+	return ecsCli, info, nil
+}
+
+// fcRoleECS 使用函数角色的临时凭证：优先取请求头（每次调用下发），其次取运行时环境变量
+func (s *Server) fcRoleECS(r *http.Request) (alicloud.ECSAPI, error) {
+	id, secret, token := r.Header.Get("x-fc-access-key-id"), r.Header.Get("x-fc-access-key-secret"), r.Header.Get("x-fc-security-token")
+	if id == "" {
+		id, secret, token = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID"), os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET"), os.Getenv("ALIBABA_CLOUD_SECURITY_TOKEN")
+	}
+	if id == "" {
+		return nil, fmt.Errorf("未获取到函数角色凭证")
+	}
+	return alicloud.NewECSClientWithSTSToken(s.Config.Region, id, secret, token)
+}
+
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authed(r) {
+			writeJSON(w, http.StatusUnauthorized, instanceStatus{Error: "未登录"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authed 校验登录 cookie：<到期时间戳>.<HMAC>
+func (s *Server) authed(r *http.Request) bool {
+	c, err := r.Cookie(cookieName)
+	if err != nil {
+		return false
+	}
+	ts, _, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || s.now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(c.Value), []byte(s.signCookie(expires)))
+}
+
+func (s *Server) signCookie(expires int64) string {
+	ts := strconv.FormatInt(expires, 10)
+	mac := hmac.New(sha256.New, []byte(s.Config.CookieSecret))
+	mac.Write([]byte(ts))
+	return ts + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) renderPage(w http.ResponseWriter, status int, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	pageTemplate.Execute(w, data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// probeHTTPS 实例上的 Caddy 返回任意响应（包括跳转到 Authelia）即视为可访问
+func probeHTTPS(ctx context.Context, url string) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
@@ -5,6 +5,7 @@ import (
 	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
 	stsclient "github.com/alibabacloud-go/sts-20150401/v2/client"
 	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/hwuu/cloudcode/internal/alicloud"
 )
 
 type MockSTSAPI struct {
@@ -94,23 +95,31 @@ type MockECSAPI struct {
 	DescribeInstancesFunc       func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error)
 	StartInstanceFunc           func(req *ecsclient.StartInstanceRequest) (*ecsclient.StartInstanceResponse, error)
 	StopInstanceFunc            func(req *ecsclient.StopInstanceRequest) (*ecsclient.StopInstanceResponse, error)
+	ModifyInstanceSpecFunc      func(req *ecsclient.ModifyInstanceSpecRequest) (*ecsclient.ModifyInstanceSpecResponse, error)
 	CreateKeyPairFunc           func(req *ecsclient.CreateKeyPairRequest) (*ecsclient.CreateKeyPairResponse, error)
 	DeleteKeyPairsFunc          func(req *ecsclient.DeleteKeyPairsRequest) (*ecsclient.DeleteKeyPairsResponse, error)
 	DescribeKeyPairsFunc        func(req *ecsclient.DescribeKeyPairsRequest) (*ecsclient.DescribeKeyPairsResponse, error)
 	DescribeZonesFunc           func(req *ecsclient.DescribeZonesRequest) (*ecsclient.DescribeZonesResponse, error)
 	DescribeAccountAttributesFunc func(req *ecsclient.DescribeAccountAttributesRequest) (*ecsclient.DescribeAccountAttributesResponse, error)
 	ImportKeyPairFunc           func(req *ecsclient.ImportKeyPairRequest) (*ecsclient.ImportKeyPairResponse, error)
+	AttachKeyPairFunc           func(req *ecsclient.AttachKeyPairRequest) (*ecsclient.AttachKeyPairResponse, error)
 	CreateSecurityGroupFunc     func(req *ecsclient.CreateSecurityGroupRequest) (*ecsclient.CreateSecurityGroupResponse, error)
 	DeleteSecurityGroupFunc     func(req *ecsclient.DeleteSecurityGroupRequest) (*ecsclient.DeleteSecurityGroupResponse, error)
 	DescribeSecurityGroupsFunc  func(req *ecsclient.DescribeSecurityGroupsRequest) (*ecsclient.DescribeSecurityGroupsResponse, error)
 	AuthorizeSecurityGroupFunc  func(req *ecsclient.AuthorizeSecurityGroupRequest) (*ecsclient.AuthorizeSecurityGroupResponse, error)
 	DescribeDisksFunc           func(req *ecsclient.DescribeDisksRequest) (*ecsclient.DescribeDisksResponse, error)
+	ResizeDiskFunc              func(req *ecsclient.ResizeDiskRequest) (*ecsclient.ResizeDiskResponse, error)
 	CreateSnapshotFunc          func(req *ecsclient.CreateSnapshotRequest) (*ecsclient.CreateSnapshotResponse, error)
 	DescribeSnapshotsFunc       func(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error)
 	DeleteSnapshotFunc          func(req *ecsclient.DeleteSnapshotRequest) (*ecsclient.DeleteSnapshotResponse, error)
+	CopySnapshotFunc            func(req *ecsclient.CopySnapshotRequest) (*ecsclient.CopySnapshotResponse, error)
+	ReplaceSystemDiskFunc       func(req *ecsclient.ReplaceSystemDiskRequest) (*ecsclient.ReplaceSystemDiskResponse, error)
 	CreateImageFunc             func(req *ecsclient.CreateImageRequest) (*ecsclient.CreateImageResponse, error)
 	DescribeImagesFunc          func(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error)
 	DeleteImageFunc             func(req *ecsclient.DeleteImageRequest) (*ecsclient.DeleteImageResponse, error)
+	AttachInstanceRamRoleFunc   func(req *ecsclient.AttachInstanceRamRoleRequest) (*ecsclient.AttachInstanceRamRoleResponse, error)
+	DetachInstanceRamRoleFunc   func(req *ecsclient.DetachInstanceRamRoleRequest) (*ecsclient.DetachInstanceRamRoleResponse, error)
+	DescribeInstanceRamRoleFunc func(req *ecsclient.DescribeInstanceRamRoleRequest) (*ecsclient.DescribeInstanceRamRoleResponse, error)
 }
 
 func (m *MockECSAPI) CreateInstance(req *ecsclient.CreateInstanceRequest) (*ecsclient.CreateInstanceResponse, error) {
@@ -142,6 +151,13 @@ func (m *MockECSAPI) StopInstance(req *ecsclient.StopInstanceRequest) (*ecsclien
 	return m.StopInstanceFunc(req)
 }
 
+func (m *MockECSAPI) ModifyInstanceSpec(req *ecsclient.ModifyInstanceSpecRequest) (*ecsclient.ModifyInstanceSpecResponse, error) {
+	if m.ModifyInstanceSpecFunc == nil {
+		return &ecsclient.ModifyInstanceSpecResponse{}, nil
+	}
+	return m.ModifyInstanceSpecFunc(req)
+}
+
 func (m *MockECSAPI) CreateKeyPair(req *ecsclient.CreateKeyPairRequest) (*ecsclient.CreateKeyPairResponse, error) {
 	return m.CreateKeyPairFunc(req)
 }
@@ -169,6 +185,13 @@ func (m *MockECSAPI) ImportKeyPair(req *ecsclient.ImportKeyPairRequest) (*ecscli
 	return m.ImportKeyPairFunc(req)
 }
 
+func (m *MockECSAPI) AttachKeyPair(req *ecsclient.AttachKeyPairRequest) (*ecsclient.AttachKeyPairResponse, error) {
+	if m.AttachKeyPairFunc == nil {
+		return &ecsclient.AttachKeyPairResponse{}, nil
+	}
+	return m.AttachKeyPairFunc(req)
+}
+
 func (m *MockECSAPI) CreateSecurityGroup(req *ecsclient.CreateSecurityGroupRequest) (*ecsclient.CreateSecurityGroupResponse, error) {
 	return m.CreateSecurityGroupFunc(req)
 }
@@ -198,6 +221,13 @@ func (m *MockECSAPI) DescribeDisks(req *ecsclient.DescribeDisksRequest) (*ecscli
 	return m.DescribeDisksFunc(req)
 }
 
+func (m *MockECSAPI) ResizeDisk(req *ecsclient.ResizeDiskRequest) (*ecsclient.ResizeDiskResponse, error) {
+	if m.ResizeDiskFunc == nil {
+		return &ecsclient.ResizeDiskResponse{}, nil
+	}
+	return m.ResizeDiskFunc(req)
+}
+
 func (m *MockECSAPI) CreateSnapshot(req *ecsclient.CreateSnapshotRequest) (*ecsclient.CreateSnapshotResponse, error) {
 	if m.CreateSnapshotFunc == nil {
 		return &ecsclient.CreateSnapshotResponse{}, nil
@@ -219,6 +249,20 @@ func (m *MockECSAPI) DeleteSnapshot(req *ecsclient.DeleteSnapshotRequest) (*ecsc
 	return m.DeleteSnapshotFunc(req)
 }
 
+func (m *MockECSAPI) CopySnapshot(req *ecsclient.CopySnapshotRequest) (*ecsclient.CopySnapshotResponse, error) {
+	if m.CopySnapshotFunc == nil {
+		return &ecsclient.CopySnapshotResponse{}, nil
+	}
+	return m.CopySnapshotFunc(req)
+}
+
+func (m *MockECSAPI) ReplaceSystemDisk(req *ecsclient.ReplaceSystemDiskRequest) (*ecsclient.ReplaceSystemDiskResponse, error) {
+	if m.ReplaceSystemDiskFunc == nil {
+		return &ecsclient.ReplaceSystemDiskResponse{}, nil
+	}
+	return m.ReplaceSystemDiskFunc(req)
+}
+
 func (m *MockECSAPI) CreateImage(req *ecsclient.CreateImageRequest) (*ecsclient.CreateImageResponse, error) {
 	if m.CreateImageFunc == nil {
 		return &ecsclient.CreateImageResponse{}, nil
@@ -240,6 +284,27 @@ func (m *MockECSAPI) DeleteImage(req *ecsclient.DeleteImageRequest) (*ecsclient.
 	return m.DeleteImageFunc(req)
 }
 
+func (m *MockECSAPI) AttachInstanceRamRole(req *ecsclient.AttachInstanceRamRoleRequest) (*ecsclient.AttachInstanceRamRoleResponse, error) {
+	if m.AttachInstanceRamRoleFunc == nil {
+		return &ecsclient.AttachInstanceRamRoleResponse{}, nil
+	}
+	return m.AttachInstanceRamRoleFunc(req)
+}
+
+func (m *MockECSAPI) DetachInstanceRamRole(req *ecsclient.DetachInstanceRamRoleRequest) (*ecsclient.DetachInstanceRamRoleResponse, error) {
+	if m.DetachInstanceRamRoleFunc == nil {
+		return &ecsclient.DetachInstanceRamRoleResponse{}, nil
+	}
+	return m.DetachInstanceRamRoleFunc(req)
+}
+
+func (m *MockECSAPI) DescribeInstanceRamRole(req *ecsclient.DescribeInstanceRamRoleRequest) (*ecsclient.DescribeInstanceRamRoleResponse, error) {
+	if m.DescribeInstanceRamRoleFunc == nil {
+		return &ecsclient.DescribeInstanceRamRoleResponse{}, nil
+	}
+	return m.DescribeInstanceRamRoleFunc(req)
+}
+
 type MockDnsAPI struct {
 	DescribeDomainsFunc       func(req *dnsclient.DescribeDomainsRequest) (*dnsclient.DescribeDomainsResponse, error)
 	DescribeDomainRecordsFunc func(req *dnsclient.DescribeDomainRecordsRequest) (*dnsclient.DescribeDomainRecordsResponse, error)
@@ -262,3 +327,158 @@ func (m *MockDnsAPI) AddDomainRecord(req *dnsclient.AddDomainRecordRequest) (*dn
 func (m *MockDnsAPI) UpdateDomainRecord(req *dnsclient.UpdateDomainRecordRequest) (*dnsclient.UpdateDomainRecordResponse, error) {
 	return m.UpdateDomainRecordFunc(req)
 }
+
+// MockRAMAPI 记录调用顺序；未设置的方法返回成功
+type MockRAMAPI struct {
+	Calls                    []string
+	GetRoleFunc              func(roleName string) error
+	CreateRoleFunc           func(roleName, assumeRolePolicy, description string) error
+	DeleteRoleFunc           func(roleName string) error
+	CreatePolicyFunc         func(policyName, policyDocument, description string) error
+	DeletePolicyFunc         func(policyName string) error
+	AttachPolicyToRoleFunc   func(policyName, roleName string) error
+	DetachPolicyFromRoleFunc func(policyName, roleName string) error
+}
+
+func (m *MockRAMAPI) GetRole(roleName string) error {
+	m.Calls = append(m.Calls, "GetRole")
+	if m.GetRoleFunc == nil {
+		return nil
+	}
+	return m.GetRoleFunc(roleName)
+}
+
+func (m *MockRAMAPI) CreateRole(roleName, assumeRolePolicy, description string) error {
+	m.Calls = append(m.Calls, "CreateRole")
+	if m.CreateRoleFunc == nil {
+		return nil
+	}
+	return m.CreateRoleFunc(roleName, assumeRolePolicy, description)
+}
+
+func (m *MockRAMAPI) DeleteRole(roleName string) error {
+	m.Calls = append(m.Calls, "DeleteRole")
+	if m.DeleteRoleFunc == nil {
+		return nil
+	}
+	return m.DeleteRoleFunc(roleName)
+}
+
+func (m *MockRAMAPI) CreatePolicy(policyName, policyDocument, description string) error {
+	m.Calls = append(m.Calls, "CreatePolicy")
+	if m.CreatePolicyFunc == nil {
+		return nil
+	}
+	return m.CreatePolicyFunc(policyName, policyDocument, description)
+}
+
+func (m *MockRAMAPI) DeletePolicy(policyName string) error {
+	m.Calls = append(m.Calls, "DeletePolicy")
+	if m.DeletePolicyFunc == nil {
+		return nil
+	}
+	return m.DeletePolicyFunc(policyName)
+}
+
+func (m *MockRAMAPI) AttachPolicyToRole(policyName, roleName string) error {
+	m.Calls = append(m.Calls, "AttachPolicyToRole")
+	if m.AttachPolicyToRoleFunc == nil {
+		return nil
+	}
+	return m.AttachPolicyToRoleFunc(policyName, roleName)
+}
+
+func (m *MockRAMAPI) DetachPolicyFromRole(policyName, roleName string) error {
+	m.Calls = append(m.Calls, "DetachPolicyFromRole")
+	if m.DetachPolicyFromRoleFunc == nil {
+		return nil
+	}
+	return m.DetachPolicyFromRoleFunc(policyName, roleName)
+}
+
+// MockFCAPI 记录调用顺序和最近一次提交的函数 / 自定义域名；未设置的方法返回成功
+type MockFCAPI struct {
+	Calls                  []string
+	Function               *alicloud.FCFunction
+	CustomDomain           *alicloud.FCCustomDomain
+	GetFunctionFunc        func(functionName string) error
+	CreateFunctionFunc     func(fn *alicloud.FCFunction) error
+	GetCustomDomainFunc    func(domainName string) error
+	DeleteFunctionFunc     func(functionName string) error
+	DeleteTriggerFunc      func(functionName, triggerName string) error
+	DeleteCustomDomainFunc func(domainName string) error
+}
+
+func (m *MockFCAPI) GetFunction(functionName string) error {
+	m.Calls = append(m.Calls, "GetFunction")
+	if m.GetFunctionFunc == nil {
+		return nil
+	}
+	return m.GetFunctionFunc(functionName)
+}
+
+func (m *MockFCAPI) CreateFunction(fn *alicloud.FCFunction) error {
+	m.Calls = append(m.Calls, "CreateFunction")
+	m.Function = fn
+	if m.CreateFunctionFunc == nil {
+		return nil
+	}
+	return m.CreateFunctionFunc(fn)
+}
+
+func (m *MockFCAPI) UpdateFunction(fn *alicloud.FCFunction) error {
+	m.Calls = append(m.Calls, "UpdateFunction")
+	m.Function = fn
+	return nil
+}
+
+func (m *MockFCAPI) DeleteFunction(functionName string) error {
+	m.Calls = append(m.Calls, "DeleteFunction")
+	if m.DeleteFunctionFunc == nil {
+		return nil
+	}
+	return m.DeleteFunctionFunc(functionName)
+}
+
+func (m *MockFCAPI) CreateHTTPTrigger(functionName, triggerName string) error {
+	m.Calls = append(m.Calls, "CreateHTTPTrigger")
+	return nil
+}
+
+func (m *MockFCAPI) DeleteTrigger(functionName, triggerName string) error {
+	m.Calls = append(m.Calls, "DeleteTrigger")
+	if m.DeleteTriggerFunc == nil {
+		return nil
+	}
+	return m.DeleteTriggerFunc(functionName, triggerName)
+}
+
+func (m *MockFCAPI) GetCustomDomain(domainName string) error {
+	m.Calls = append(m.Calls, "GetCustomDomain")
+	if m.GetCustomDomainFunc == nil {
+		return nil
+	}
+	return m.GetCustomDomainFunc(domainName)
+}
+
+func (m *MockFCAPI) CreateCustomDomain(domain *alicloud.FCCustomDomain) error {
+	m.Calls = append(m.Calls, "CreateCustomDomain")
+	d := *domain
+	m.CustomDomain = &d
+	return nil
+}
+
+func (m *MockFCAPI) UpdateCustomDomain(domain *alicloud.FCCustomDomain) error {
+	m.Calls = append(m.Calls, "UpdateCustomDomain")
+	d := *domain
+	m.CustomDomain = &d
+	return nil
+}
+
+func (m *MockFCAPI) DeleteCustomDomain(domainName string) error {
+	m.Calls = append(m.Calls, "DeleteCustomDomain")
+	if m.DeleteCustomDomainFunc == nil {
+		return nil
+	}
+	return m.DeleteCustomDomainFunc(domainName)
+}
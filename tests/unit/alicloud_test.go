@@ -286,6 +286,88 @@ func TestSelectAvailableZone_Fallback(t *testing.T) {
 	}
 }
 
+func TestSelectAvailableZone_AnyZoneSupportingInstanceType(t *testing.T) {
+	mockECS := &MockECSAPI{
+		DescribeZonesFunc: func(req *ecsclient.DescribeZonesRequest) (*ecsclient.DescribeZonesResponse, error) {
+			zone := func(id string, types ...string) *ecsclient.DescribeZonesResponseBodyZonesZone {
+				var ptrs []*string
+				for _, t := range types {
+					ptrs = append(ptrs, teaString(t))
+				}
+				return &ecsclient.DescribeZonesResponseBodyZonesZone{
+					ZoneId: teaString(id),
+					AvailableResourceCreation: &ecsclient.DescribeZonesResponseBodyZonesZoneAvailableResourceCreation{
+						ResourceTypes: []*string{teaString("Instance")},
+					},
+					AvailableInstanceTypes: &ecsclient.DescribeZonesResponseBodyZonesZoneAvailableInstanceTypes{InstanceTypes: ptrs},
+				}
+			}
+			return &ecsclient.DescribeZonesResponse{
+				Body: &ecsclient.DescribeZonesResponseBody{
+					Zones: &ecsclient.DescribeZonesResponseBodyZones{
+						Zone: []*ecsclient.DescribeZonesResponseBodyZonesZone{
+							zone("cn-hangzhou-h", "ecs.e-c1m2.large"),
+							zone("cn-hangzhou-j", "ecs.e-c1m2.large", "ecs.g7.xlarge"),
+						},
+					},
+				},
+			}, nil
+		},
+	}
+
+	// 未指定优先级时选择第一个支持该规格的可用区
+	zone, err := alicloud.SelectAvailableZone(mockECS, "cn-hangzhou", "ecs.g7.xlarge", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if zone != "cn-hangzhou-j" {
+		t.Errorf("expected cn-hangzhou-j, got %s", zone)
+	}
+
+	// 指定的可用区不支持该规格
+	if _, err := alicloud.SelectAvailableZone(mockECS, "cn-hangzhou", "ecs.g7.xlarge", []string{"cn-hangzhou-h"}); !errors.Is(err, alicloud.ErrNoAvailableZone) {
+		t.Errorf("expected ErrNoAvailableZone, got %v", err)
+	}
+}
+
+func TestFindLatestUbuntuImage(t *testing.T) {
+	mockECS := &MockECSAPI{
+		DescribeImagesFunc: func(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error) {
+			if *req.RegionId != "cn-hangzhou" || *req.ImageOwnerAlias != "system" {
+				t.Errorf("unexpected request: %v", req)
+			}
+			return &ecsclient.DescribeImagesResponse{
+				Body: &ecsclient.DescribeImagesResponseBody{
+					Images: &ecsclient.DescribeImagesResponseBodyImages{
+						Image: []*ecsclient.DescribeImagesResponseBodyImagesImage{
+							{ImageId: teaString("ubuntu_24_04_x64_20G_alibase_20260301.vhd"), CreationTime: teaString("2026-03-01T08:00:00Z")},
+							{ImageId: teaString("ubuntu_24_04_uefi_x64_20G_alibase_20260401.vhd"), CreationTime: teaString("2026-04-01T08:00:00Z")},
+							{ImageId: teaString("ubuntu_24_04_x64_20G_alibase_20260119.vhd"), CreationTime: teaString("2026-01-19T08:00:00Z")},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+
+	imageID, err := alicloud.FindLatestUbuntuImage(mockECS, "cn-hangzhou")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if imageID != "ubuntu_24_04_x64_20G_alibase_20260301.vhd" {
+		t.Errorf("unexpected image: %s", imageID)
+	}
+
+	empty := &MockECSAPI{
+		DescribeImagesFunc: func(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error) {
+			return &ecsclient.DescribeImagesResponse{}, nil
+		},
+	}
+	if _, err := alicloud.FindLatestUbuntuImage(empty, "cn-hangzhou"); !errors.Is(err, alicloud.ErrResourceNotFound) {
+		t.Errorf("expected ErrResourceNotFound, got %v", err)
+	}
+}
+
 func TestSelectAvailableZone_NoAvailableZone(t *testing.T) {
 	regionID := "ap-southeast-1"
 	zone1a := "ap-southeast-1a"
@@ -348,7 +430,7 @@ func TestCreateECSInstance_Success(t *testing.T) {
 		},
 	}
 
-	ecs, err := alicloud.CreateECSInstance(mockECS, regionID, zoneID, "", "", sgID, vswitchID, sshKeyName, "test-instance", "")
+	ecs, err := alicloud.CreateECSInstance(mockECS, regionID, zoneID, alicloud.InstanceSpec{}, sgID, vswitchID, sshKeyName, "test-instance", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
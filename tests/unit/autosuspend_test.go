@@ -0,0 +1,481 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	stsclient "github.com/alibabacloud-go/sts-20150401/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hwuu/cloudcode/internal/agent"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// --- 策略解析 ---
+
+func TestParseActiveHours(t *testing.T) {
+	loc := time.UTC
+	tests := []struct {
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"09:00-19:00", time.Date(2026, 10, 18, 9, 0, 0, 0, loc), true},   // 周日
+		{"09:00-19:00", time.Date(2026, 10, 18, 19, 0, 0, 0, loc), false}, // 结束时间不含
+		{"Mon-Fri 09:00-19:00", time.Date(2026, 10, 19, 12, 0, 0, 0, loc), true},
+		{"Mon-Fri 09:00-19:00", time.Date(2026, 10, 18, 12, 0, 0, 0, loc), false},
+		{"Sat,Sun 10:00-12:00", time.Date(2026, 10, 17, 11, 0, 0, 0, loc), true},
+		{"Fri 22:00-02:00", time.Date(2026, 10, 23, 23, 0, 0, 0, loc), true},    // 周五晚
+		{"Fri 22:00-02:00", time.Date(2026, 10, 24, 1, 0, 0, 0, loc), true},     // 跨午夜到周六
+		{"Fri 22:00-02:00", time.Date(2026, 10, 25, 1, 0, 0, 0, loc), false},    // 周日凌晨
+		{"Fri-Mon 00:00-24:00", time.Date(2026, 10, 18, 3, 0, 0, 0, loc), true}, // 星期范围跨周末
+	}
+	for _, tt := range tests {
+		h, err := config.ParseActiveHours(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseActiveHours(%q) failed: %v", tt.spec, err)
+		}
+		if got := h.Contains(tt.at); got != tt.want {
+			t.Errorf("%q contains %s: got %v, want %v", tt.spec, tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "9-19", "Mon-Fry 09:00-19:00", "09:00-25:00", "09:00-09:00", "Mon 09:00 19:00"} {
+		if _, err := config.ParseActiveHours(bad); err == nil {
+			t.Errorf("ParseActiveHours(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAutoSuspendPolicy_Validate(t *testing.T) {
+	ok := &config.AutoSuspendPolicy{Idle: "30m", ActiveHours: "Mon-Fri 09:00-19:00", Timezone: "Asia/Tokyo"}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("expected valid policy, got %v", err)
+	}
+
+	bad := &config.AutoSuspendPolicy{Idle: "5m", ActiveHours: "always", Timezone: "Mars/Base"}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, field := range []string{"idle:", "active_hours:", "timezone:"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s in error, got:\n%v", field, err)
+		}
+	}
+
+	var nilPolicy *config.AutoSuspendPolicy
+	if nilPolicy.Enabled() || nilPolicy.String() != "未开启" {
+		t.Error("nil policy should be disabled")
+	}
+}
+
+func TestParseDeployFile_AutoSuspend(t *testing.T) {
+	file, err := config.ParseDeployFile([]byte(`
+auto_suspend:
+  idle: 45m
+  active_hours: Mon-Fri 09:00-19:00
+`), testEnv(nil))
+	if err != nil {
+		t.Fatalf("ParseDeployFile failed: %v", err)
+	}
+	if file.AutoSuspend == nil || file.AutoSuspend.Idle != "45m" || file.AutoSuspend.ActiveHours != "Mon-Fri 09:00-19:00" {
+		t.Errorf("unexpected auto_suspend: %+v", file.AutoSuspend)
+	}
+
+	_, err = config.ParseDeployFile([]byte(`
+auto_suspend:
+  idle: 1m
+  active_hours: sometimes
+`), testEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "auto_suspend.idle:") || !strings.Contains(err.Error(), "auto_suspend.active_hours:") {
+		t.Errorf("expected auto_suspend field errors, got %v", err)
+	}
+}
+
+// --- agent ---
+
+func TestShouldSuspend(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	// 周一 12:00 / 22:00（上海时间）
+	inHours := time.Date(2026, 10, 19, 12, 0, 0, 0, shanghai)
+	outHours := time.Date(2026, 10, 19, 22, 0, 0, 0, shanghai)
+
+	idleOnly := &config.AutoSuspendPolicy{Idle: "30m"}
+	if reason, _ := agent.ShouldSuspend(idleOnly, inHours, inHours.Add(-29*time.Minute)); reason != "" {
+		t.Errorf("should not suspend before idle timeout, got %q", reason)
+	}
+	if reason, _ := agent.ShouldSuspend(idleOnly, inHours, inHours.Add(-30*time.Minute)); reason == "" {
+		t.Error("should suspend after idle timeout")
+	}
+
+	hours := &config.AutoSuspendPolicy{ActiveHours: "Mon-Fri 09:00-19:00"}
+	if reason, _ := agent.ShouldSuspend(hours, inHours, inHours.Add(-5*time.Hour)); reason != "" {
+		t.Errorf("should not suspend within active hours, got %q", reason)
+	}
+	if reason, _ := agent.ShouldSuspend(hours, outHours, outHours.Add(-5*time.Minute)); reason != "" {
+		t.Errorf("should keep a grace period outside active hours, got %q", reason)
+	}
+	if reason, _ := agent.ShouldSuspend(hours, outHours, outHours.Add(-config.OutsideHoursGrace)); reason == "" {
+		t.Error("should suspend outside active hours after grace period")
+	}
+}
+
+func TestCountEstablished(t *testing.T) {
+	// 0016 = 22, 1000 = 4096, 1E01 = 7681；状态 01 = ESTABLISHED, 0A = LISTEN
+	data := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1
+   1: 0100007F:1000 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 2 1
+   2: 0100007F:1E01 0100007F:C351 01 00000000:00000000 00:00000000 00000000     0        0 3 1
+   3: 0100007F:1E01 0100007F:C352 06 00000000:00000000 00:00000000 00000000     0        0 4 1
+   4: 0A00000F:0016 0A000001:D431 01 00000000:00000000 00:00000000 00000000     0        0 5 1
+`)
+	if got := agent.CountEstablished(data, agent.OpenCodePort, agent.TTYDPort); got != 2 {
+		t.Errorf("expected 2 devbox connections, got %d", got)
+	}
+	if got := agent.CountEstablished(data, agent.SSHPort); got != 1 {
+		t.Errorf("expected 1 SSH session, got %d", got)
+	}
+}
+
+func TestAgentCheck(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	sessions := 1
+	var stopReqs []*ecsclient.StopInstanceRequest
+	a := &agent.Agent{
+		Config: agent.Config{InstanceID: "i-test", Region: "ap-southeast-1", RoleName: "cloudcode-agent-x", Policy: config.AutoSuspendPolicy{Idle: "30m"}},
+		ECS: &MockECSAPI{
+			StopInstanceFunc: func(req *ecsclient.StopInstanceRequest) (*ecsclient.StopInstanceResponse, error) {
+				stopReqs = append(stopReqs, req)
+				return &ecsclient.StopInstanceResponse{}, nil
+			},
+		},
+		Probe:  func(ctx context.Context) (int, error) { return sessions, nil },
+		Output: &bytes.Buffer{},
+		Now:    func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	// 有会话：不停机
+	if stopped, err := a.Check(ctx); err != nil || stopped {
+		t.Fatalf("expected no stop with active session, got %v, %v", stopped, err)
+	}
+	// 会话结束 20 分钟：不停机
+	sessions = 0
+	now = now.Add(20 * time.Minute)
+	if stopped, _ := a.Check(ctx); stopped {
+		t.Fatal("should not stop before idle timeout")
+	}
+	// 探测失败按有活动处理
+	a.Probe = func(ctx context.Context) (int, error) { return 0, errors.New("docker unavailable") }
+	now = now.Add(20 * time.Minute)
+	if stopped, err := a.Check(ctx); err == nil || stopped {
+		t.Fatalf("probe failure should count as activity, got %v, %v", stopped, err)
+	}
+	// 闲置 30 分钟：停机
+	a.Probe = func(ctx context.Context) (int, error) { return 0, nil }
+	now = now.Add(30 * time.Minute)
+	if stopped, err := a.Check(ctx); err != nil || !stopped {
+		t.Fatalf("expected stop after idle timeout, got %v, %v", stopped, err)
+	}
+	if len(stopReqs) != 1 || tea.StringValue(stopReqs[0].InstanceId) != "i-test" || tea.StringValue(stopReqs[0].StoppedMode) != "StopCharging" {
+		t.Fatalf("expected one StopCharging request for i-test, got %+v", stopReqs)
+	}
+	// 已停机后不再重复调用
+	a.Check(ctx)
+	if len(stopReqs) != 1 {
+		t.Errorf("StopInstance should be called once, got %d", len(stopReqs))
+	}
+}
+
+// --- AutoSuspendManager ---
+
+// autoSuspendInstance 模拟实例上的 SSH 命令和 SFTP 上传
+type autoSuspendInstance struct {
+	commands []string
+	files    map[string]string
+}
+
+func (inst *autoSuspendInstance) manager(t *testing.T, out *bytes.Buffer, state *config.State, ram *MockRAMAPI, ecs *MockECSAPI) *deploy.AutoSuspendManager {
+	t.Helper()
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, state)
+	writeDummySSHKey(t, stateDir)
+	inst.files = map[string]string{}
+
+	accountID := "1234567890"
+	return &deploy.AutoSuspendManager{
+		ECS: ecs,
+		STS: &MockSTSAPI{
+			GetCallerIdentityFunc: func() (*stsclient.GetCallerIdentityResponse, error) {
+				return &stsclient.GetCallerIdentityResponse{
+					Body: &stsclient.GetCallerIdentityResponseBody{
+						AccountId: &accountID,
+						UserId:    tea.String("user-1"),
+						Arn:       tea.String("acs:ram::1234567890:user/test"),
+					},
+				}, nil
+			},
+		},
+		RAM:      ram,
+		Output:   out,
+		Region:   "ap-southeast-1",
+		StateDir: stateDir,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return func() (remote.SSHClient, error) {
+				return &MockSSHClient{
+					RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+						inst.commands = append(inst.commands, cmd)
+						if cmd == "uname -m" {
+							return "x86_64\n", nil
+						}
+						return "", nil
+					},
+				}, nil
+			}
+		},
+		SFTPFactory: func(host string, port int, user string, privateKey []byte) (remote.SFTPClient, error) {
+			return &MockSFTPClient{
+				UploadFileFunc: func(content []byte, remotePath string) error {
+					inst.files[remotePath] = string(content)
+					return nil
+				},
+			}, nil
+		},
+		Version:     "0.3.0",
+		AgentBinary: func(arch string) ([]byte, error) { return []byte("bin-" + arch), nil },
+	}
+}
+
+func TestAutoSuspendSet(t *testing.T) {
+	inst := &autoSuspendInstance{}
+	state := fullState()
+	state.DeploymentID = "abc123"
+	ram := &MockRAMAPI{GetRoleFunc: func(string) error { return errors.New("EntityNotExist.Role") }}
+	var attached string
+	ecs := &MockECSAPI{
+		AttachInstanceRamRoleFunc: func(req *ecsclient.AttachInstanceRamRoleRequest) (*ecsclient.AttachInstanceRamRoleResponse, error) {
+			attached = tea.StringValue(req.RamRoleName)
+			return &ecsclient.AttachInstanceRamRoleResponse{}, nil
+		},
+	}
+	var policyDoc string
+	ram.CreatePolicyFunc = func(name, doc, desc string) error {
+		policyDoc = doc
+		return nil
+	}
+	out := &bytes.Buffer{}
+	m := inst.manager(t, out, state, ram, ecs)
+
+	policy := &config.AutoSuspendPolicy{Idle: "30m"}
+	if err := m.Set(context.Background(), policy); err != nil {
+		t.Fatalf("Set failed: %v\n%s", err, out.String())
+	}
+
+	if !strings.Contains(strings.Join(ram.Calls, ","), "CreateRole") || attached != "cloudcode-agent-abc123" {
+		t.Errorf("expected role created and attached, calls=%v attached=%q", ram.Calls, attached)
+	}
+	if !strings.Contains(policyDoc, "ecs:StopInstance") || !strings.Contains(policyDoc, "acs:ecs:ap-southeast-1:1234567890:instance/i-test") {
+		t.Errorf("policy should only allow stopping this instance: %s", policyDoc)
+	}
+
+	if inst.files["/usr/local/bin/cloudcode.new"] != "bin-amd64" {
+		t.Errorf("expected amd64 binary upload, got files %v", inst.files)
+	}
+	var agentCfg agent.Config
+	if err := json.Unmarshal([]byte(inst.files[agent.DefaultConfigPath]), &agentCfg); err != nil {
+		t.Fatalf("agent config not uploaded: %v", err)
+	}
+	if agentCfg.InstanceID != "i-test" || agentCfg.RoleName != "cloudcode-agent-abc123" || agentCfg.Policy.Idle != "30m" {
+		t.Errorf("unexpected agent config: %+v", agentCfg)
+	}
+	if !strings.Contains(inst.files["/etc/systemd/system/cloudcode-agent.service"], "cloudcode agent") {
+		t.Error("systemd unit should be uploaded")
+	}
+	last := inst.commands[len(inst.commands)-1]
+	if !strings.Contains(last, "systemctl enable cloudcode-agent") || !strings.Contains(last, "restart cloudcode-agent") {
+		t.Errorf("expected agent service restart, got %v", inst.commands)
+	}
+
+	saved, err := config.LoadStateFrom(m.StateDir)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if saved.Resources.RAMRole.Name != "cloudcode-agent-abc123" || saved.CloudCode.AutoSuspend == nil || saved.CloudCode.AutoSuspend.Idle != "30m" {
+		t.Errorf("state should record role and policy: %+v %+v", saved.Resources.RAMRole, saved.CloudCode.AutoSuspend)
+	}
+}
+
+func TestAutoSuspendSet_InstanceHasOtherRole(t *testing.T) {
+	inst := &autoSuspendInstance{}
+	ecs := &MockECSAPI{
+		DescribeInstanceRamRoleFunc: func(req *ecsclient.DescribeInstanceRamRoleRequest) (*ecsclient.DescribeInstanceRamRoleResponse, error) {
+			return &ecsclient.DescribeInstanceRamRoleResponse{
+				Body: &ecsclient.DescribeInstanceRamRoleResponseBody{
+					InstanceRamRoleSets: &ecsclient.DescribeInstanceRamRoleResponseBodyInstanceRamRoleSets{
+						InstanceRamRoleSet: []*ecsclient.DescribeInstanceRamRoleResponseBodyInstanceRamRoleSetsInstanceRamRoleSet{
+							{InstanceId: tea.String("i-test"), RamRoleName: tea.String("ops-role")},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+	m := inst.manager(t, &bytes.Buffer{}, fullState(), &MockRAMAPI{}, ecs)
+
+	err := m.Set(context.Background(), &config.AutoSuspendPolicy{Idle: "1h"})
+	if err == nil || !strings.Contains(err.Error(), "ops-role") {
+		t.Fatalf("expected conflict with existing role, got %v", err)
+	}
+	if len(inst.commands) != 0 {
+		t.Errorf("agent should not be installed, got %v", inst.commands)
+	}
+}
+
+func TestAutoSuspendSet_RequiresRunning(t *testing.T) {
+	inst := &autoSuspendInstance{}
+	state := fullState()
+	state.Status = "suspended"
+	m := inst.manager(t, &bytes.Buffer{}, state, &MockRAMAPI{}, &MockECSAPI{})
+
+	err := m.Set(context.Background(), &config.AutoSuspendPolicy{Idle: "1h"})
+	if err == nil || !strings.Contains(err.Error(), "cloudcode resume") {
+		t.Fatalf("expected resume hint, got %v", err)
+	}
+	if err := m.Set(context.Background(), &config.AutoSuspendPolicy{}); err == nil {
+		t.Fatal("empty policy should be rejected")
+	}
+}
+
+func TestAutoSuspendDisable(t *testing.T) {
+	inst := &autoSuspendInstance{}
+	state := fullState()
+	state.Resources.RAMRole.Name = "cloudcode-agent-abc123"
+	state.CloudCode.AutoSuspend = &config.AutoSuspendPolicy{Idle: "30m"}
+	ram := &MockRAMAPI{}
+	var detached string
+	ecs := &MockECSAPI{
+		DescribeInstanceRamRoleFunc: func(req *ecsclient.DescribeInstanceRamRoleRequest) (*ecsclient.DescribeInstanceRamRoleResponse, error) {
+			return &ecsclient.DescribeInstanceRamRoleResponse{
+				Body: &ecsclient.DescribeInstanceRamRoleResponseBody{
+					InstanceRamRoleSets: &ecsclient.DescribeInstanceRamRoleResponseBodyInstanceRamRoleSets{
+						InstanceRamRoleSet: []*ecsclient.DescribeInstanceRamRoleResponseBodyInstanceRamRoleSetsInstanceRamRoleSet{
+							{InstanceId: tea.String("i-test"), RamRoleName: tea.String("cloudcode-agent-abc123")},
+						},
+					},
+				},
+			}, nil
+		},
+		DetachInstanceRamRoleFunc: func(req *ecsclient.DetachInstanceRamRoleRequest) (*ecsclient.DetachInstanceRamRoleResponse, error) {
+			detached = tea.StringValue(req.RamRoleName)
+			return &ecsclient.DetachInstanceRamRoleResponse{}, nil
+		},
+	}
+	m := inst.manager(t, &bytes.Buffer{}, state, ram, ecs)
+
+	if err := m.Disable(context.Background()); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	if len(inst.commands) != 1 || !strings.Contains(inst.commands[0], "systemctl disable --now cloudcode-agent") {
+		t.Errorf("expected agent disabled, got %v", inst.commands)
+	}
+	if detached != "cloudcode-agent-abc123" {
+		t.Errorf("role should be detached from instance, got %q", detached)
+	}
+	if got := strings.Join(ram.Calls, ","); got != "DetachPolicyFromRole,DeletePolicy,DeleteRole" {
+		t.Errorf("unexpected RAM calls: %s", got)
+	}
+
+	saved, _ := config.LoadStateFrom(m.StateDir)
+	if saved.CloudCode.AutoSuspend != nil || saved.Resources.RAMRole.Name != "" {
+		t.Error("state should clear policy and role")
+	}
+}
+
+// --- 状态同步 ---
+
+func TestReconcileStatus(t *testing.T) {
+	status := "Stopped"
+	ecs := &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+							{InstanceId: tea.String("i-test"), Status: &status},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+	stateDir := t.TempDir()
+	state := fullState()
+	state.Status = "running"
+	writeTestState(t, stateDir, state)
+	out := &bytes.Buffer{}
+
+	changed, err := deploy.ReconcileStatus(ecs, "ap-southeast-1", stateDir, out)
+	if err != nil || !changed {
+		t.Fatalf("expected state change, got %v, %v", changed, err)
+	}
+	saved, _ := config.LoadStateFrom(stateDir)
+	if saved.Status != "suspended" || !strings.Contains(out.String(), "cloudcode resume") {
+		t.Errorf("expected suspended with resume hint, got %q: %s", saved.Status, out.String())
+	}
+
+	// 状态一致时不修改
+	if changed, _ := deploy.ReconcileStatus(ecs, "ap-southeast-1", stateDir, out); changed {
+		t.Error("should not change when already in sync")
+	}
+
+	status = "Running"
+	if changed, _ := deploy.ReconcileStatus(ecs, "ap-southeast-1", stateDir, out); !changed {
+		t.Error("expected running after resume from console")
+	}
+	saved, _ = config.LoadStateFrom(stateDir)
+	if saved.Status != "running" {
+		t.Errorf("expected running, got %q", saved.Status)
+	}
+}
+
+func TestAgentRoleName(t *testing.T) {
+	if got := alicloud.AgentRoleName("abc123"); got != "cloudcode-agent-abc123" {
+		t.Errorf("unexpected role name %q", got)
+	}
+}
+
+func TestDestroy_DeletesAgentRole(t *testing.T) {
+	stateDir := t.TempDir()
+	state := fullState()
+	state.Resources.RAMRole.Name = "cloudcode-agent-abc123"
+	writeTestState(t, stateDir, state)
+
+	ram := &MockRAMAPI{}
+	output := &bytes.Buffer{}
+	d := &deploy.Destroyer{
+		ECS:      &deployMockECS{},
+		VPC:      &deployMockVPC{},
+		RAM:      ram,
+		Output:   output,
+		StateDir: stateDir,
+		Region:   "ap-southeast-1",
+	}
+	if err := d.Run(context.Background(), true, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(ram.Calls, ","); got != "DetachPolicyFromRole,DeletePolicy,DeleteRole" {
+		t.Errorf("unexpected RAM calls: %s", got)
+	}
+	if !strings.Contains(output.String(), "删除 RAM 角色 (cloudcode-agent-abc123)... ✓") {
+		t.Errorf("expected role deletion in output:\n%s", output.String())
+	}
+}
@@ -0,0 +1,485 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// backupCloud 模拟快照备份涉及的 ECS 接口，记录调用顺序
+type backupCloud struct {
+	calls     []string
+	status    string // 实例状态
+	diskID    string // 当前系统盘
+	snapshots map[string]string
+	deleted   []string
+	envTags   []string // 创建快照时的 cloudcode-env 标签
+	next      int
+}
+
+func (c *backupCloud) mock() *MockECSAPI {
+	c.status = "Running"
+	c.diskID = "d-old"
+	if c.snapshots == nil {
+		c.snapshots = map[string]string{}
+	}
+	return &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{{
+							InstanceId:   tea.String("i-test"),
+							Status:       tea.String(c.status),
+							InstanceType: tea.String("ecs.e-c1m2.large"),
+							ZoneId:       tea.String("ap-southeast-1a"),
+						}},
+					},
+				},
+			}, nil
+		},
+		StopInstanceFunc: func(req *ecsclient.StopInstanceRequest) (*ecsclient.StopInstanceResponse, error) {
+			c.calls = append(c.calls, "stop")
+			c.status = "Stopped"
+			return &ecsclient.StopInstanceResponse{}, nil
+		},
+		StartInstanceFunc: func(req *ecsclient.StartInstanceRequest) (*ecsclient.StartInstanceResponse, error) {
+			c.calls = append(c.calls, "start")
+			c.status = "Running"
+			return &ecsclient.StartInstanceResponse{}, nil
+		},
+		DescribeDisksFunc: func(req *ecsclient.DescribeDisksRequest) (*ecsclient.DescribeDisksResponse, error) {
+			return &ecsclient.DescribeDisksResponse{
+				Body: &ecsclient.DescribeDisksResponseBody{
+					Disks: &ecsclient.DescribeDisksResponseBodyDisks{
+						Disk: []*ecsclient.DescribeDisksResponseBodyDisksDisk{{DiskId: tea.String(c.diskID), Status: tea.String("In_use")}},
+					},
+				},
+			}, nil
+		},
+		CreateSnapshotFunc: func(req *ecsclient.CreateSnapshotRequest) (*ecsclient.CreateSnapshotResponse, error) {
+			c.next++
+			id := fmt.Sprintf("s-new-%d", c.next)
+			c.calls = append(c.calls, "snapshot:"+*req.DiskId)
+			for _, tag := range req.Tag {
+				if tea.StringValue(tag.Key) == alicloud.TagKeyEnv {
+					c.envTags = append(c.envTags, tea.StringValue(tag.Value))
+				}
+			}
+			c.snapshots[id] = "accomplished"
+			return &ecsclient.CreateSnapshotResponse{Body: &ecsclient.CreateSnapshotResponseBody{SnapshotId: tea.String(id)}}, nil
+		},
+		DescribeSnapshotsFunc: func(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error) {
+			var snaps []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot
+			for id, status := range c.snapshots {
+				if strings.Contains(*req.SnapshotIds, `"`+id+`"`) {
+					snaps = append(snaps, &ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{SnapshotId: tea.String(id), Status: tea.String(status)})
+				}
+			}
+			return &ecsclient.DescribeSnapshotsResponse{
+				Body: &ecsclient.DescribeSnapshotsResponseBody{Snapshots: &ecsclient.DescribeSnapshotsResponseBodySnapshots{Snapshot: snaps}},
+			}, nil
+		},
+		DeleteSnapshotFunc: func(req *ecsclient.DeleteSnapshotRequest) (*ecsclient.DeleteSnapshotResponse, error) {
+			c.deleted = append(c.deleted, *req.SnapshotId)
+			delete(c.snapshots, *req.SnapshotId)
+			return &ecsclient.DeleteSnapshotResponse{}, nil
+		},
+		CreateImageFunc: func(req *ecsclient.CreateImageRequest) (*ecsclient.CreateImageResponse, error) {
+			c.calls = append(c.calls, "image:"+*req.SnapshotId)
+			return &ecsclient.CreateImageResponse{Body: &ecsclient.CreateImageResponseBody{ImageId: tea.String("m-restore")}}, nil
+		},
+		DescribeImagesFunc: func(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error) {
+			return &ecsclient.DescribeImagesResponse{
+				Body: &ecsclient.DescribeImagesResponseBody{
+					Images: &ecsclient.DescribeImagesResponseBodyImages{
+						Image: []*ecsclient.DescribeImagesResponseBodyImagesImage{{ImageId: req.ImageId, Status: tea.String("Available")}},
+					},
+				},
+			}, nil
+		},
+		ReplaceSystemDiskFunc: func(req *ecsclient.ReplaceSystemDiskRequest) (*ecsclient.ReplaceSystemDiskResponse, error) {
+			c.calls = append(c.calls, fmt.Sprintf("replace:%s:%s:%d", *req.ImageId, *req.KeyPairName, *req.SystemDisk.Size))
+			c.diskID = "d-new"
+			return &ecsclient.ReplaceSystemDiskResponse{Body: &ecsclient.ReplaceSystemDiskResponseBody{DiskId: tea.String("d-new")}}, nil
+		},
+		DeleteImageFunc: func(req *ecsclient.DeleteImageRequest) (*ecsclient.DeleteImageResponse, error) {
+			c.calls = append(c.calls, "delete-image:"+*req.ImageId)
+			return &ecsclient.DeleteImageResponse{}, nil
+		},
+	}
+}
+
+func newBackupManager(t *testing.T, cloud *backupCloud, state *config.State, input string, out *bytes.Buffer) *deploy.BackupManager {
+	t.Helper()
+	dir := t.TempDir()
+	if state != nil {
+		writeTestState(t, dir, state)
+	}
+	return &deploy.BackupManager{
+		ECS:          cloud.mock(),
+		Prompter:     config.NewPrompter(strings.NewReader(input), out),
+		Output:       out,
+		Region:       "ap-southeast-1",
+		StateDir:     dir,
+		Version:      "0.3.0",
+		WaitInterval: time.Millisecond,
+		WaitTimeout:  time.Second,
+	}
+}
+
+func backupAt(id, createdAt, label string) config.Backup {
+	return config.Backup{SnapshotID: id, CreatedAt: createdAt, Label: label, Region: "ap-southeast-1", DiskSize: 60}
+}
+
+func snapshotIDs(backups []config.Backup) string {
+	ids := make([]string, 0, len(backups))
+	for _, b := range backups {
+		ids = append(ids, b.SnapshotID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestRetentionPolicy_Apply(t *testing.T) {
+	backups := []config.Backup{
+		backupAt("s-1", "2026-03-01T03:00:00Z", ""), // 周日（第 9 周）
+		backupAt("s-2", "2026-03-04T03:00:00Z", ""), // 第 10 周
+		backupAt("s-3", "2026-03-05T03:00:00Z", ""),
+		backupAt("s-4", "2026-03-10T03:00:00Z", ""), // 第 11 周
+		backupAt("s-5", "2026-03-10T15:00:00Z", ""),
+		backupAt("s-6", "2026-03-11T03:00:00Z", ""),
+	}
+
+	tests := []struct {
+		name   string
+		policy config.RetentionPolicy
+		prune  string
+	}{
+		{"empty", config.RetentionPolicy{}, ""},
+		{"last", config.RetentionPolicy{KeepLast: 2}, "s-1,s-2,s-3,s-4"},
+		{"daily", config.RetentionPolicy{KeepDaily: 3}, "s-1,s-2,s-4"},
+		{"weekly", config.RetentionPolicy{KeepWeekly: 2}, "s-1,s-2,s-4,s-5"},
+		{"combined", config.RetentionPolicy{KeepLast: 1, KeepDaily: 2, KeepWeekly: 3}, "s-2,s-4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snapshotIDs(tt.policy.Apply(backups, time.UTC)); got != tt.prune {
+				t.Errorf("expected prune %q, got %q", tt.prune, got)
+			}
+		})
+	}
+}
+
+func TestBackupCatalog_AddAndFind(t *testing.T) {
+	dir := t.TempDir()
+	catalog, err := config.LoadBackupCatalogFrom(dir)
+	if err != nil || len(catalog.Backups) != 0 {
+		t.Fatalf("missing catalog should be empty, got %+v, %v", catalog, err)
+	}
+	catalog.Add(backupAt("s-2", "2026-03-02T00:00:00Z", "nightly"))
+	catalog.Add(backupAt("s-1", "2026-03-01T00:00:00Z", "nightly"))
+	catalog.Add(backupAt("s-3", "2026-03-03T00:00:00Z", "before-upgrade"))
+	catalog.Add(backupAt("s-2", "2026-03-02T00:00:00Z", "nightly"))
+	if err := config.SaveBackupCatalogTo(dir, catalog); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := config.LoadBackupCatalogFrom(dir)
+	if got := snapshotIDs(loaded.Backups); got != "s-1,s-2,s-3" {
+		t.Errorf("expected sorted unique backups, got %s", got)
+	}
+	if b, err := loaded.Find("nightly"); err != nil || b.SnapshotID != "s-2" {
+		t.Errorf("label should resolve to the newest backup, got %+v, %v", b, err)
+	}
+	if b, err := loaded.Find("s-1"); err != nil || b.SnapshotID != "s-1" {
+		t.Errorf("expected s-1, got %+v, %v", b, err)
+	}
+	if _, err := loaded.Find("missing"); err == nil {
+		t.Error("expected error for unknown backup")
+	}
+}
+
+func TestBackupCreate(t *testing.T) {
+	cloud := &backupCloud{}
+	out := &bytes.Buffer{}
+	state := fullState()
+	state.Resources.ECS.SystemDiskSize = 60
+	m := newBackupManager(t, cloud, state, "", out)
+
+	backup, err := m.Create(context.Background(), "nightly", false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if backup.SnapshotID != "s-new-1" || backup.Label != "nightly" || backup.DiskSize != 60 || backup.CloudCodeVersion != "0.3.0" {
+		t.Errorf("unexpected backup: %+v", backup)
+	}
+	catalog, _ := config.LoadBackupCatalogFrom(m.StateDir)
+	if len(catalog.Backups) != 1 || catalog.Backups[0].SnapshotID != "s-new-1" {
+		t.Errorf("backup not recorded: %+v", catalog.Backups)
+	}
+	if b, _ := config.LoadBackupFrom(m.StateDir); b != nil {
+		t.Error("create should not change the deploy restore point")
+	}
+	if !strings.Contains(out.String(), "备份已创建: s-new-1（nightly）") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestBackupCreate_Quiesce(t *testing.T) {
+	cloud := &backupCloud{}
+	out := &bytes.Buffer{}
+	m := newBackupManager(t, cloud, fullState(), "", out)
+	writeDummySSHKey(t, m.StateDir)
+
+	var commands []string
+	m.SSHDialFunc = func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+		return func() (remote.SSHClient, error) {
+			return &MockSSHClient{
+				RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+					commands = append(commands, cmd)
+					cloud.calls = append(cloud.calls, "ssh")
+					return "", nil
+				},
+			}, nil
+		}
+	}
+
+	backup, err := m.Create(context.Background(), "", true)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !backup.Quiesced {
+		t.Error("expected backup to be marked quiesced")
+	}
+	if len(commands) != 2 || !strings.Contains(commands[0], "docker compose stop") || !strings.Contains(commands[1], "docker compose start") {
+		t.Errorf("unexpected commands: %v", commands)
+	}
+	if got := strings.Join(cloud.calls, ","); got != "ssh,snapshot:d-old,ssh" {
+		t.Errorf("containers should be stopped only around the snapshot, got %s", got)
+	}
+}
+
+func TestBackupCreate_Destroyed(t *testing.T) {
+	state := fullState()
+	state.Status = "destroyed"
+	m := newBackupManager(t, &backupCloud{}, state, "", &bytes.Buffer{})
+	if _, err := m.Create(context.Background(), "", false); err == nil || !strings.Contains(err.Error(), "实例已销毁") {
+		t.Errorf("expected destroyed error, got %v", err)
+	}
+}
+
+func TestBackupList(t *testing.T) {
+	cloud := &backupCloud{snapshots: map[string]string{"s-1": "accomplished"}}
+	out := &bytes.Buffer{}
+	m := newBackupManager(t, cloud, fullState(), "", out)
+	catalog := &config.BackupCatalog{}
+	catalog.Add(backupAt("s-1", "2026-03-01T00:00:00Z", "nightly"))
+	catalog.Add(backupAt("s-2", "2026-03-02T00:00:00Z", ""))
+	config.SaveBackupCatalogTo(m.StateDir, catalog)
+	config.SaveBackupTo(m.StateDir, &config.Backup{SnapshotID: "s-1"})
+
+	if err := m.List(); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"nightly", "accomplished（deploy 恢复点）", "已删除"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestBackupPrune_ProtectsRestorePoint(t *testing.T) {
+	cloud := &backupCloud{}
+	out := &bytes.Buffer{}
+	m := newBackupManager(t, cloud, fullState(), "y\n", out)
+	catalog := &config.BackupCatalog{}
+	for i := 1; i <= 4; i++ {
+		catalog.Add(backupAt(fmt.Sprintf("s-%d", i), fmt.Sprintf("2026-03-0%dT00:00:00Z", i), ""))
+	}
+	config.SaveBackupCatalogTo(m.StateDir, catalog)
+	config.SaveBackupTo(m.StateDir, &config.Backup{SnapshotID: "s-1"})
+
+	if err := m.Prune(config.RetentionPolicy{KeepLast: 1}, true, false); err != nil {
+		t.Fatalf("dry-run failed: %v", err)
+	}
+	if len(cloud.deleted) != 0 {
+		t.Fatalf("dry-run should not delete, deleted %v", cloud.deleted)
+	}
+
+	if err := m.Prune(config.RetentionPolicy{KeepLast: 1}, false, false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if got := strings.Join(cloud.deleted, ","); got != "s-2,s-3" {
+		t.Errorf("expected s-2,s-3 deleted, got %s", got)
+	}
+	updated, _ := config.LoadBackupCatalogFrom(m.StateDir)
+	if got := snapshotIDs(updated.Backups); got != "s-1,s-4" {
+		t.Errorf("expected restore point and newest kept, got %s", got)
+	}
+
+	if err := m.Prune(config.RetentionPolicy{}, false, true); err == nil {
+		t.Error("expected error for empty policy")
+	}
+}
+
+func TestBackupRestore_ReplacesSystemDisk(t *testing.T) {
+	cloud := &backupCloud{}
+	out := &bytes.Buffer{}
+	state := fullState()
+	state.Status = "running"
+	state.Resources.ECS.SystemDiskSize = 60
+	m := newBackupManager(t, cloud, state, "y\n", out)
+	catalog := &config.BackupCatalog{}
+	b := backupAt("s-old", "2026-03-01T00:00:00Z", "before-upgrade")
+	b.DiskSize = 80
+	catalog.Add(b)
+	config.SaveBackupCatalogTo(m.StateDir, catalog)
+
+	needsDeploy, err := m.Restore(context.Background(), "before-upgrade", false, false)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if needsDeploy {
+		t.Error("restoring a live instance should not need deploy")
+	}
+	want := "stop,snapshot:d-old,image:s-old,replace:m-restore:cloudcode-ssh-key:80,start,delete-image:m-restore"
+	if got := strings.Join(cloud.calls, ","); got != want {
+		t.Errorf("unexpected call order:\n got %s\nwant %s", got, want)
+	}
+
+	updated, _ := config.LoadStateFrom(m.StateDir)
+	if updated.Status != "running" || updated.Resources.ECS.SystemDiskSize != 80 {
+		t.Errorf("unexpected state after restore: status=%s disk=%d", updated.Status, updated.Resources.ECS.SystemDiskSize)
+	}
+	updatedCatalog, _ := config.LoadBackupCatalogFrom(m.StateDir)
+	if pre, err := updatedCatalog.Find(deploy.BackupLabelPreRestore); err != nil || pre.SnapshotID != "s-new-1" {
+		t.Errorf("expected pre-restore backup recorded, got %+v, %v", pre, err)
+	}
+}
+
+func TestBackupRestore_Cancelled(t *testing.T) {
+	cloud := &backupCloud{}
+	m := newBackupManager(t, cloud, fullState(), "n\n", &bytes.Buffer{})
+	catalog := &config.BackupCatalog{}
+	catalog.Add(backupAt("s-old", "2026-03-01T00:00:00Z", ""))
+	config.SaveBackupCatalogTo(m.StateDir, catalog)
+
+	if _, err := m.Restore(context.Background(), "s-old", false, false); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(cloud.calls) != 0 {
+		t.Errorf("cancelled restore should not touch the instance: %v", cloud.calls)
+	}
+}
+
+func TestBackupRestore_DestroyedSetsRestorePoint(t *testing.T) {
+	for _, name := range []string{"destroyed", "no state"} {
+		t.Run(name, func(t *testing.T) {
+			var state *config.State
+			if name == "destroyed" {
+				state = fullState()
+				state.Status = "destroyed"
+			}
+			cloud := &backupCloud{}
+			m := newBackupManager(t, cloud, state, "", &bytes.Buffer{})
+			catalog := &config.BackupCatalog{}
+			catalog.Add(backupAt("s-old", "2026-03-01T00:00:00Z", "nightly"))
+			config.SaveBackupCatalogTo(m.StateDir, catalog)
+
+			needsDeploy, err := m.Restore(context.Background(), "nightly", false, false)
+			if err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			if !needsDeploy || len(cloud.calls) != 0 {
+				t.Errorf("expected deploy to finish the restore without cloud calls, got %v %v", needsDeploy, cloud.calls)
+			}
+			if b, _ := config.LoadBackupFrom(m.StateDir); b == nil || b.SnapshotID != "s-old" {
+				t.Errorf("expected backup.json to point at s-old, got %+v", b)
+			}
+			if s, _ := config.LoadStateFrom(m.StateDir); s == nil || s.Status != "destroyed" {
+				t.Errorf("expected destroyed state, got %+v", s)
+			}
+		})
+	}
+}
+
+func TestBackupRestore_RegionMismatch(t *testing.T) {
+	m := newBackupManager(t, &backupCloud{}, fullState(), "", &bytes.Buffer{})
+	catalog := &config.BackupCatalog{}
+	b := backupAt("s-old", "2026-03-01T00:00:00Z", "")
+	b.Region = "cn-hangzhou"
+	catalog.Add(b)
+	config.SaveBackupCatalogTo(m.StateDir, catalog)
+
+	if _, err := m.Restore(context.Background(), "s-old", true, false); err == nil || !strings.Contains(err.Error(), "cn-hangzhou") {
+		t.Errorf("expected region mismatch error, got %v", err)
+	}
+}
+
+func TestDestroy_KeepsCatalogBackups(t *testing.T) {
+	cloud := &backupCloud{}
+	out := &bytes.Buffer{}
+	state := fullState()
+	state.Status = "running"
+	m := newBackupManager(t, cloud, state, "", out)
+	catalog := &config.BackupCatalog{}
+	catalog.Add(backupAt("s-nightly", "2026-03-01T00:00:00Z", "nightly"))
+	catalog.Add(backupAt("s-destroy", "2026-03-02T00:00:00Z", deploy.BackupLabelDestroy))
+	config.SaveBackupCatalogTo(m.StateDir, catalog)
+	config.SaveBackupTo(m.StateDir, &config.Backup{SnapshotID: "s-destroy"})
+
+	// 输入: y(保留快照) + y(确认销毁)
+	d := &deploy.Destroyer{
+		ECS:          m.ECS,
+		VPC:          &deployMockVPC{},
+		Prompter:     config.NewPrompter(strings.NewReader("y\ny\n"), out),
+		Output:       out,
+		StateDir:     m.StateDir,
+		Env:          "staging",
+		Region:       "ap-southeast-1",
+		WaitInterval: time.Millisecond,
+		WaitTimeout:  time.Second,
+	}
+	cloud.status = "Stopped"
+	if err := d.Run(context.Background(), false, false); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+
+	if got := strings.Join(cloud.deleted, ","); got != "s-destroy" {
+		t.Errorf("only the previous destroy snapshot should be replaced, deleted %s", got)
+	}
+	updated, _ := config.LoadBackupCatalogFrom(m.StateDir)
+	if got := snapshotIDs(updated.Backups); !strings.HasPrefix(got, "s-nightly,") || strings.Contains(got, "s-destroy") {
+		t.Errorf("unexpected catalog after destroy: %s", got)
+	}
+	if !strings.Contains(out.String(), "备份快照仍保留") {
+		t.Errorf("expected notice about retained backups:\n%s", out.String())
+	}
+	if got := strings.Join(cloud.envTags, ","); got != "staging" {
+		t.Errorf("destroy snapshot should be tagged with the destroyer's env, got %q", got)
+	}
+}
+
+func TestBackupCreate_TagsManagerEnv(t *testing.T) {
+	cloud := &backupCloud{}
+	out := &bytes.Buffer{}
+	m := newBackupManager(t, cloud, fullState(), "", out)
+	m.Env = "staging"
+	if _, err := m.Create(context.Background(), "nightly", false); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if got := strings.Join(cloud.envTags, ","); got != "staging" {
+		t.Errorf("snapshot should be tagged with the manager's env, got %q", got)
+	}
+}
@@ -0,0 +1,249 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
+	"github.com/hwuu/cloudcode/internal/deploy"
+)
+
+func TestBuiltinPrices_DefaultSpec(t *testing.T) {
+	e := cost.BuiltinPrices().Estimate(cost.Spec{
+		Region:       alicloud.DefaultRegion,
+		InstanceType: alicloud.DefaultInstanceType,
+		DiskSize:     alicloud.DefaultSystemDiskSize,
+		DiskCategory: alicloud.DefaultSystemDiskCategory,
+		EIP:          true,
+	})
+	if len(e.Missing) != 0 {
+		t.Fatalf("builtin prices should cover the default spec, missing %v", e.Missing)
+	}
+	if e.Instance <= 0 || e.Disk <= 0 || e.EIP <= 0 || e.EIPTrafficGB <= 0 {
+		t.Errorf("unexpected estimate: %+v", e)
+	}
+	if e.Monthly("suspended") >= e.Monthly("running") || e.Hourly("destroyed") != 0 {
+		t.Errorf("suspended should exclude the instance, destroyed without snapshot should be free: %+v", e)
+	}
+}
+
+func TestLoadPrices_Override(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	os.WriteFile(path, []byte(`
+regions:
+  ap-southeast-1:
+    instance_types:
+      ecs.e-c1m2.large: 0.05
+      ecs.custom.large: 0.5
+  eu-central-1:
+    disks:
+      cloud_essd: 0.03
+`), 0600)
+
+	prices, err := cost.LoadPrices(path, true)
+	if err != nil {
+		t.Fatalf("LoadPrices failed: %v", err)
+	}
+	sg := prices.Regions["ap-southeast-1"]
+	if sg.InstanceTypes["ecs.e-c1m2.large"] != 0.05 || sg.InstanceTypes["ecs.custom.large"] != 0.5 {
+		t.Errorf("override not applied: %v", sg.InstanceTypes)
+	}
+	builtin := cost.BuiltinPrices().Regions["ap-southeast-1"]
+	if sg.InstanceTypes["ecs.g7.xlarge"] != builtin.InstanceTypes["ecs.g7.xlarge"] || sg.EIPHourly != builtin.EIPHourly {
+		t.Error("entries absent from the override should keep builtin prices")
+	}
+	if prices.Regions["eu-central-1"].Disks["cloud_essd"] != 0.03 || prices.Currency != "USD" {
+		t.Error("new region should be added and currency kept")
+	}
+}
+
+func TestLoadPrices_Errors(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.yaml")
+	if _, err := cost.LoadPrices(missing, false); err != nil {
+		t.Errorf("optional missing file should be ignored, got %v", err)
+	}
+	if _, err := cost.LoadPrices(missing, true); err == nil {
+		t.Error("required missing file should fail")
+	}
+
+	typo := filepath.Join(dir, "typo.yaml")
+	os.WriteFile(typo, []byte("regions:\n  ap-southeast-1:\n    eip_hourl: 0.01\n"), 0600)
+	if _, err := cost.LoadPrices(typo, false); err == nil || !strings.Contains(err.Error(), "eip_hourl") {
+		t.Errorf("unknown field should be reported, got %v", err)
+	}
+}
+
+func TestSetStatus_RecordsChanges(t *testing.T) {
+	state := config.NewState("ap-southeast-1", "")
+	state.SetStatus("running")
+	state.SetStatus("running")
+	state.SetStatus("suspended")
+	if len(state.StatusHistory) != 2 || state.StatusHistory[1].Status != "suspended" || state.Status != "suspended" {
+		t.Errorf("unexpected history: %+v", state.StatusHistory)
+	}
+	if _, err := time.Parse(time.RFC3339, state.StatusHistory[0].At); err != nil {
+		t.Errorf("invalid timestamp: %v", err)
+	}
+}
+
+// costState 运行 10 小时 → 停机 20 小时 → 运行 5 小时 → 停机至今
+func costState(t0 time.Time) *config.State {
+	state := fullState()
+	state.CreatedAt = t0.Format(time.RFC3339)
+	state.Status = "suspended"
+	state.StatusHistory = []config.StatusChange{
+		{Status: "running", At: t0.Format(time.RFC3339)},
+		{Status: "suspended", At: t0.Add(10 * time.Hour).Format(time.RFC3339)},
+		{Status: "running", At: t0.Add(30 * time.Hour).Format(time.RFC3339)},
+		{Status: "suspended", At: t0.Add(35 * time.Hour).Format(time.RFC3339)},
+	}
+	return state
+}
+
+func TestUsageOf(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	usage, err := cost.UsageOf(costState(t0), t0.Add(40*time.Hour))
+	if err != nil {
+		t.Fatalf("UsageOf failed: %v", err)
+	}
+	if usage.Durations["running"] != 15*time.Hour || usage.Durations["suspended"] != 25*time.Hour {
+		t.Errorf("unexpected durations: %v", usage.Durations)
+	}
+
+	e := &cost.Estimate{Instance: 1, Disk: 0.1}
+	if got := usage.Cost(e); got < 18.99 || got > 19.01 { // 15 × 1.1 + 25 × 0.1
+		t.Errorf("expected cost 19, got %v", got)
+	}
+
+	// 旧版 state 没有状态记录：全部按 running 计
+	legacy := fullState()
+	legacy.CreatedAt = t0.Format(time.RFC3339)
+	usage, _ = cost.UsageOf(legacy, t0.Add(2*time.Hour))
+	if usage.Recorded || usage.Durations["running"] != 2*time.Hour {
+		t.Errorf("legacy state should count as running: %+v", usage)
+	}
+}
+
+func TestCostRunner(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeTestState(t, dir, costState(t0))
+	config.SaveBackupTo(dir, &config.Backup{SnapshotID: "s-old", DiskSize: 60})
+
+	out := &bytes.Buffer{}
+	c := &deploy.CostRunner{
+		Output:   out,
+		StateDir: dir,
+		Now:      func() time.Time { return t0.Add(40 * time.Hour) },
+	}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"ECS 实例 ecs.e-c1m2.large",
+		"系统盘 cloud_essd 60GB",
+		"快照 60GB",
+		"停机:",
+		"运行 15.0 小时，停机 25.0 小时",
+		"估算费用: $",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if strings.Contains(line, "← 当前") && !strings.Contains(line, "停机:") {
+			t.Errorf("current marker on wrong line: %q", line)
+		}
+	}
+}
+
+func TestCostRunner_OverrideSpecAndMissingPrice(t *testing.T) {
+	out := &bytes.Buffer{}
+	c := &deploy.CostRunner{
+		Output:   out,
+		StateDir: t.TempDir(),
+		Region:   "ap-southeast-1",
+		Spec:     config.CloudSpec{InstanceType: "ecs.unknown.large"},
+	}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "未找到部署记录") || !strings.Contains(got, "实例规格 ecs.unknown.large") {
+		t.Errorf("expected default spec with missing price warning:\n%s", got)
+	}
+	if strings.Contains(got, "累计") {
+		t.Error("no usage without a deployment")
+	}
+}
+
+func TestSuspend_PromptShowsCost(t *testing.T) {
+	dir := t.TempDir()
+	state := fullState()
+	state.Status = "running"
+	writeTestState(t, dir, state)
+
+	var buf bytes.Buffer
+	s := &deploy.Suspender{
+		ECS:      &MockECSAPI{},
+		Prompter: config.NewPrompter(strings.NewReader("n\n"), &buf),
+		Output:   &buf,
+		Region:   "ap-southeast-1",
+		StateDir: dir,
+		Prices: &cost.PriceTable{Currency: "USD", Regions: map[string]*cost.RegionPrices{
+			"ap-southeast-1": {
+				InstanceTypes: map[string]float64{"ecs.e-c1m2.large": 1},
+				Disks:         map[string]float64{"cloud_essd": 0.1},
+				EIPHourly:     0.01,
+			},
+		}},
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// 60GB × 0.1 + 0.01 × 730 = 13.30
+	if !strings.Contains(buf.String(), "(~$13.30/月)") {
+		t.Errorf("expected suspended cost in prompt:\n%s", buf.String())
+	}
+}
+
+func TestReconcile_RecordsStatusHistory(t *testing.T) {
+	dir := t.TempDir()
+	state := fullState()
+	state.Status = "suspended"
+	writeTestState(t, dir, state)
+
+	status := "Running"
+	mockECS := &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+							{InstanceId: tea.String("i-test"), Status: &status},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+	if _, err := deploy.ReconcileStatus(mockECS, "ap-southeast-1", dir, &bytes.Buffer{}); err != nil {
+		t.Fatalf("ReconcileStatus failed: %v", err)
+	}
+	updated, _ := config.LoadStateFrom(dir)
+	if len(updated.StatusHistory) != 1 || updated.StatusHistory[0].Status != "running" {
+		t.Errorf("expected status change to be recorded: %+v", updated.StatusHistory)
+	}
+}
@@ -0,0 +1,256 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+)
+
+func testEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeDeployFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cloudcode.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDeployFile_CloudSection(t *testing.T) {
+	path := writeDeployFile(t, `
+cloud:
+  instance_type: ecs.g7.xlarge
+  disk_size: 100
+  disk_category: cloud_auto
+  zones: [cn-hangzhou-j, cn-hangzhou-k]
+`)
+	file, err := config.LoadDeployFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := file.Cloud
+	if c.InstanceType != "ecs.g7.xlarge" || c.DiskSize != 100 || c.DiskCategory != "cloud_auto" || len(c.Zones) != 2 {
+		t.Errorf("unexpected cloud spec: %+v", c)
+	}
+}
+
+func TestLoadDeployFile_Empty(t *testing.T) {
+	file, err := config.LoadDeployFile(writeDeployFile(t, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Cloud.InstanceType != "" {
+		t.Errorf("expected zero spec, got %+v", file.Cloud)
+	}
+}
+
+func TestLoadDeployFile_UnknownField(t *testing.T) {
+	_, err := config.LoadDeployFile(writeDeployFile(t, "cloud:\n  instance_typ: ecs.g7.xlarge\n"))
+	if !errors.Is(err, config.ErrInvalidDeployFile) {
+		t.Fatalf("expected ErrInvalidDeployFile, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "第 2 行: cloud.instance_typ: 未知字段") {
+		t.Errorf("expected field path and line in error, got: %v", err)
+	}
+}
+
+func TestLoadDeployFile_ValidationErrors(t *testing.T) {
+	_, err := config.LoadDeployFile(writeDeployFile(t, `
+cloud:
+  instance_type: g7
+  disk_size: 10
+  disk_category: hdd
+  zones: ["Hangzhou J"]
+`))
+	if !errors.Is(err, config.ErrInvalidDeployFile) {
+		t.Fatalf("expected ErrInvalidDeployFile, got %v", err)
+	}
+	for _, field := range []string{"cloud.instance_type", "cloud.disk_size", "cloud.disk_category", "cloud.zones[0]"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s in error, got:\n%v", field, err)
+		}
+	}
+}
+
+func TestCloudSpec_MergeFlagsOverrideFile(t *testing.T) {
+	file := config.CloudSpec{InstanceType: "ecs.g7.xlarge", DiskSize: 100, Zones: []string{"cn-hangzhou-j"}}
+	flags := config.CloudSpec{DiskSize: 200}
+
+	got := file.Merge(flags)
+	if got.InstanceType != "ecs.g7.xlarge" || got.DiskSize != 200 || len(got.Zones) != 1 {
+		t.Errorf("unexpected merge result: %+v", got)
+	}
+}
+
+func TestParseDeployFile_FullWithInterpolation(t *testing.T) {
+	data := []byte(`
+domain: code.example.com
+ssh_ip: 203.0.113.7
+admin:
+  username: alice
+  password: ${ADMIN_PASSWORD}
+api_keys:
+  openai: ${OPENAI_API_KEY}
+  anthropic: ${ANTHROPIC_API_KEY:-}
+  openai_base_url: ${OPENAI_BASE_URL:-https://api.openai.com/v1}
+authelia:
+  session_expiration: 7d
+  policy: one_factor
+cloud:
+  disk_size: ${DISK_SIZE}
+`)
+	file, err := config.ParseDeployFile(data, testEnv(map[string]string{
+		"ADMIN_PASSWORD": "pa$$w0rd-123",
+		"OPENAI_API_KEY": "sk-test",
+		"DISK_SIZE":      "80",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Admin.Password != "pa$$w0rd-123" || file.APIKeys.OpenAI != "sk-test" || file.APIKeys.Anthropic != "" {
+		t.Errorf("unexpected interpolation: %+v", file)
+	}
+	if file.APIKeys.OpenAIBaseURL != "https://api.openai.com/v1" || file.Cloud.DiskSize != 80 {
+		t.Errorf("unexpected defaults: %+v", file)
+	}
+
+	cfg := deploy.NewDeployConfigFromFile(file)
+	if cfg.SSHIP != "203.0.113.7/32" || cfg.Email != "alice@localhost" || cfg.AccessPolicy != "one_factor" {
+		t.Errorf("unexpected deploy config: %+v", cfg)
+	}
+}
+
+func TestParseDeployFile_MissingEnvVar(t *testing.T) {
+	data := []byte("admin:\n  password: ${ADMIN_PASSWORD}\n")
+	_, err := config.ParseDeployFile(data, testEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "第 2 行: admin.password: 环境变量 ADMIN_PASSWORD 未设置") {
+		t.Errorf("expected missing env var error, got: %v", err)
+	}
+}
+
+func TestParseDeployFile_EscapedDollar(t *testing.T) {
+	file, err := config.ParseDeployFile([]byte("admin:\n  password: cost$$100abc\n"), testEnv(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Admin.Password != "cost$100abc" {
+		t.Errorf("unexpected password: %q", file.Admin.Password)
+	}
+}
+
+func TestParseDeployFile_FieldErrors(t *testing.T) {
+	_, err := config.ParseDeployFile([]byte(`
+domain: "not a domain"
+ssh_ip: 300.1.1.1
+admin:
+  username: Alice
+  password: short
+api_keys:
+  openai_base_url: api.openai.com
+authelia:
+  session_inactivity: 30 minutes
+  policy: none
+devbox_mode: private
+`), testEnv(nil))
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, field := range []string{"domain:", "ssh_ip:", "admin.username:", "admin.password:", "api_keys.openai_base_url:", "authelia.session_inactivity:", "authelia.policy:", "devbox_mode:"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s in error, got:\n%v", field, err)
+		}
+	}
+}
+
+func TestDeployRun_NonInteractiveRequiresPassword(t *testing.T) {
+	d := newTestDeployer(t.TempDir(), "")
+	d.NonInteractive = true
+	d.Config = deploy.NewDeployConfigFromFile(&config.DeployFile{})
+
+	err := d.Run(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), "admin.password") {
+		t.Fatalf("expected admin.password error, got: %v", err)
+	}
+	if out := d.Output.(*bytes.Buffer).String(); strings.Contains(out, "请输入") {
+		t.Errorf("non-interactive deploy should not prompt:\n%s", out)
+	}
+}
+
+func TestDeployRun_ConfigWithoutPasswordPromptsOnlyPassword(t *testing.T) {
+	stateDir := t.TempDir()
+	// 环境已有 API Key，配置文件只缺管理员密码：只询问密码
+	if err := config.SaveSecretsTo(stateDir, map[string]string{"OPENAI_API_KEY": "sk-old"}); err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDeployer(stateDir, "test-password\ntest-password\n")
+	d.Config = deploy.NewDeployConfigFromFile(&config.DeployFile{
+		SSHIP: "203.0.113.7",
+		Admin: config.AdminSpec{Username: "alice"},
+	})
+
+	if err := d.Run(context.Background(), false); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, d.Output)
+	}
+	out := d.Output.(*bytes.Buffer).String()
+	if strings.Contains(out, "请输入域名") || strings.Contains(out, "请输入管理员用户名") || strings.Contains(out, "API Key:") {
+		t.Errorf("only the missing password should be prompted:\n%s", out)
+	}
+	if !strings.Contains(out, "SSH 限制 203.0.113.7/32") {
+		t.Errorf("ssh_ip from the deploy file should be used:\n%s", out)
+	}
+	state, err := config.LoadStateFrom(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.CloudCode.Username != "alice" {
+		t.Errorf("admin.username from the deploy file should be used, got %s", state.CloudCode.Username)
+	}
+}
+
+func TestDeployRun_ConfigWithoutAPIKeysKeepsPromptedKeys(t *testing.T) {
+	stateDir := t.TempDir()
+	// 配置文件没有 admin.password 和 api_keys：交互输入的 API Key 不应被配置文件清空
+	d := newTestDeployer(stateDir, "test-password\ntest-password\nsk-typed\n\n\n")
+	d.Config = deploy.NewDeployConfigFromFile(&config.DeployFile{})
+
+	if err := d.Run(context.Background(), false); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, d.Output)
+	}
+	secrets, err := config.LoadSecretsFrom(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets["OPENAI_API_KEY"] != "sk-typed" {
+		t.Errorf("prompted key should be saved to secrets.env, got %v", secrets)
+	}
+}
+
+func TestParseDeployFile_ExtraAPIKeys(t *testing.T) {
+	data := []byte("api_keys:\n  openai: sk-1\n  extra:\n    GEMINI_API_KEY: ${GEMINI_API_KEY}\n    OPENROUTER_API_KEY: \"\"\n")
+	file, err := config.ParseDeployFile(data, testEnv(map[string]string{"GEMINI_API_KEY": "gm-1"}))
+	if err != nil {
+		t.Fatalf("ParseDeployFile failed: %v", err)
+	}
+	secrets := file.APIKeys.Secrets()
+	if len(secrets) != 2 || secrets["OPENAI_API_KEY"] != "sk-1" || secrets["GEMINI_API_KEY"] != "gm-1" {
+		t.Errorf("unexpected secrets: %v", secrets)
+	}
+
+	_, err = config.ParseDeployFile([]byte("api_keys:\n  extra:\n    gemini-key: x\n"), testEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "api_keys.extra.gemini-key") {
+		t.Errorf("expected invalid name error, got %v", err)
+	}
+}
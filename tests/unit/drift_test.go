@@ -0,0 +1,337 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/hwuu/cloudcode/internal/deploy"
+)
+
+// driftCloud 模拟云上资源，默认与 fullState() 完全一致
+type driftCloud struct {
+	vpcExists      bool
+	instanceType   string
+	instanceStatus string
+	eipInstanceID  string
+	extraInstance  string // 按 cloudcode-ecs 名称查询时额外返回的实例 ID
+}
+
+func newDriftCloud() *driftCloud {
+	return &driftCloud{
+		vpcExists:      true,
+		instanceType:   "ecs.e-c1m2.large",
+		instanceStatus: "Running",
+		eipInstanceID:  "i-test",
+	}
+}
+
+func (c *driftCloud) ecs() *MockECSAPI {
+	return &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			var instances []*ecsclient.DescribeInstancesResponseBodyInstancesInstance
+			if req.InstanceName != nil {
+				instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+					InstanceId: teaString("i-test"), Status: teaString(c.instanceStatus),
+				})
+				if c.extraInstance != "" {
+					instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+						InstanceId: teaString(c.extraInstance), Status: teaString("Stopped"),
+					})
+				}
+			} else {
+				instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+					InstanceId:   teaString("i-test"),
+					InstanceType: teaString(c.instanceType),
+					ZoneId:       teaString("ap-southeast-1a"),
+					Status:       teaString(c.instanceStatus),
+					KeyPairName:  teaString("cloudcode-ssh-key"),
+					ImageId:      teaString("ubuntu_24_04_x64"),
+					VpcAttributes: &ecsclient.DescribeInstancesResponseBodyInstancesInstanceVpcAttributes{
+						VpcId:     teaString("vpc-test"),
+						VSwitchId: teaString("vsw-test"),
+					},
+					SecurityGroupIds: &ecsclient.DescribeInstancesResponseBodyInstancesInstanceSecurityGroupIds{
+						SecurityGroupId: []*string{teaString("sg-test")},
+					},
+				})
+			}
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{Instance: instances},
+				},
+			}, nil
+		},
+		DescribeSecurityGroupsFunc: func(req *ecsclient.DescribeSecurityGroupsRequest) (*ecsclient.DescribeSecurityGroupsResponse, error) {
+			return &ecsclient.DescribeSecurityGroupsResponse{
+				Body: &ecsclient.DescribeSecurityGroupsResponseBody{
+					SecurityGroups: &ecsclient.DescribeSecurityGroupsResponseBodySecurityGroups{
+						SecurityGroup: []*ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup{
+							{SecurityGroupId: teaString("sg-test"), VpcId: teaString("vpc-test")},
+						},
+					},
+				},
+			}, nil
+		},
+		DescribeKeyPairsFunc: func(req *ecsclient.DescribeKeyPairsRequest) (*ecsclient.DescribeKeyPairsResponse, error) {
+			return &ecsclient.DescribeKeyPairsResponse{
+				Body: &ecsclient.DescribeKeyPairsResponseBody{
+					KeyPairs: &ecsclient.DescribeKeyPairsResponseBodyKeyPairs{
+						KeyPair: []*ecsclient.DescribeKeyPairsResponseBodyKeyPairsKeyPair{
+							{KeyPairName: teaString("cloudcode-ssh-key")},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func (c *driftCloud) vpc() *MockVPCAPI {
+	return &MockVPCAPI{
+		DescribeVpcsFunc: func(req *vpcclient.DescribeVpcsRequest) (*vpcclient.DescribeVpcsResponse, error) {
+			var vpcs []*vpcclient.DescribeVpcsResponseBodyVpcsVpc
+			if c.vpcExists {
+				vpcs = append(vpcs, &vpcclient.DescribeVpcsResponseBodyVpcsVpc{
+					VpcId: teaString("vpc-test"), CidrBlock: teaString("192.168.0.0/16"),
+				})
+			}
+			return &vpcclient.DescribeVpcsResponse{
+				Body: &vpcclient.DescribeVpcsResponseBody{
+					Vpcs: &vpcclient.DescribeVpcsResponseBodyVpcs{Vpc: vpcs},
+				},
+			}, nil
+		},
+		DescribeVSwitchesFunc: func(req *vpcclient.DescribeVSwitchesRequest) (*vpcclient.DescribeVSwitchesResponse, error) {
+			return &vpcclient.DescribeVSwitchesResponse{
+				Body: &vpcclient.DescribeVSwitchesResponseBody{
+					VSwitches: &vpcclient.DescribeVSwitchesResponseBodyVSwitches{
+						VSwitch: []*vpcclient.DescribeVSwitchesResponseBodyVSwitchesVSwitch{
+							{VSwitchId: teaString("vsw-test"), VpcId: teaString("vpc-test"), ZoneId: teaString("ap-southeast-1a")},
+						},
+					},
+				},
+			}, nil
+		},
+		DescribeEipAddressesFunc: func(req *vpcclient.DescribeEipAddressesRequest) (*vpcclient.DescribeEipAddressesResponse, error) {
+			eip := &vpcclient.DescribeEipAddressesResponseBodyEipAddressesEipAddress{
+				AllocationId: teaString("eip-test"),
+				IpAddress:    teaString("47.100.1.1"),
+			}
+			if c.eipInstanceID != "" {
+				eip.InstanceId = teaString(c.eipInstanceID)
+			}
+			return &vpcclient.DescribeEipAddressesResponse{
+				Body: &vpcclient.DescribeEipAddressesResponseBody{
+					EipAddresses: &vpcclient.DescribeEipAddressesResponseBodyEipAddresses{
+						EipAddress: []*vpcclient.DescribeEipAddressesResponseBodyEipAddressesEipAddress{eip},
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func (c *driftCloud) checker() *deploy.DriftChecker {
+	return &deploy.DriftChecker{ECS: c.ecs(), VPC: c.vpc(), Region: "ap-southeast-1"}
+}
+
+func TestDrift_NoDrift(t *testing.T) {
+	items, err := newDriftCloud().checker().Check(fullState())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected no drift, got: %v", items)
+	}
+}
+
+func TestDrift_MissingVPC(t *testing.T) {
+	cloud := newDriftCloud()
+	cloud.vpcExists = false
+
+	items, err := cloud.checker().Check(fullState())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Resource != "vpc" || items[0].Kind != deploy.DriftMissing {
+		t.Errorf("expected missing vpc, got: %v", items)
+	}
+}
+
+func TestDrift_Mismatches(t *testing.T) {
+	cloud := newDriftCloud()
+	cloud.instanceType = "ecs.g7.xlarge"
+	cloud.instanceStatus = "Stopped"
+	cloud.eipInstanceID = ""
+
+	items, err := cloud.checker().Check(fullState())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var details []string
+	for _, item := range items {
+		if item.Kind != deploy.DriftMismatch {
+			t.Errorf("unexpected kind: %v", item)
+		}
+		details = append(details, item.String())
+	}
+	joined := strings.Join(details, "\n")
+	for _, want := range []string{"ecs.g7.xlarge", "实例状态 Stopped", "未绑定到实例 i-test"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in drift, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestDrift_SuspendedExpectsStopped(t *testing.T) {
+	cloud := newDriftCloud()
+	cloud.instanceStatus = "Stopped"
+	state := fullState()
+	state.Status = "suspended"
+
+	items, err := cloud.checker().Check(state)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected no drift for suspended+Stopped, got: %v", items)
+	}
+}
+
+func TestDrift_ExtraInstance(t *testing.T) {
+	cloud := newDriftCloud()
+	cloud.extraInstance = "i-orphan"
+
+	items, err := cloud.checker().Check(fullState())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "i-orphan" || items[0].Kind != deploy.DriftExtra {
+		t.Fatalf("expected extra i-orphan, got: %v", items)
+	}
+
+	// 其他环境记录的实例不算 extra
+	checker := cloud.checker()
+	checker.KnownIDs = map[string]bool{"i-orphan": true}
+	items, err = checker.Check(fullState())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected no drift for known instance, got: %v", items)
+	}
+}
+
+func TestDrift_ExtraInstanceOnLaterPage(t *testing.T) {
+	cloud := newDriftCloud()
+	ecs := cloud.ecs()
+	describe := ecs.DescribeInstancesFunc
+	known := map[string]bool{}
+	ecs.DescribeInstancesFunc = func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+		if req.InstanceName == nil {
+			return describe(req)
+		}
+		// 同名实例共 51 台：第 1 页 50 台（本环境和其他环境的），第 2 页是孤儿实例
+		var instances []*ecsclient.DescribeInstancesResponseBodyInstancesInstance
+		if *req.PageNumber == 1 {
+			instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{InstanceId: teaString("i-test")})
+			for i := 1; i < 50; i++ {
+				id := fmt.Sprintf("i-env-%d", i)
+				known[id] = true
+				instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{InstanceId: teaString(id)})
+			}
+		} else if *req.PageNumber == 2 {
+			instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{InstanceId: teaString("i-orphan"), Status: teaString("Stopped")})
+		}
+		return &ecsclient.DescribeInstancesResponse{
+			Body: &ecsclient.DescribeInstancesResponseBody{
+				TotalCount: tea.Int32(51),
+				Instances:  &ecsclient.DescribeInstancesResponseBodyInstances{Instance: instances},
+			},
+		}, nil
+	}
+	checker := &deploy.DriftChecker{ECS: ecs, VPC: cloud.vpc(), Region: "ap-southeast-1", KnownIDs: known}
+
+	items, err := checker.Check(fullState())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "i-orphan" || items[0].Kind != deploy.DriftExtra {
+		t.Fatalf("expected extra i-orphan from the second page, got: %v", items)
+	}
+}
+
+func TestDrift_APIErrorIsNotMissing(t *testing.T) {
+	cloud := newDriftCloud()
+	vpc := cloud.vpc()
+	vpc.DescribeVpcsFunc = func(req *vpcclient.DescribeVpcsRequest) (*vpcclient.DescribeVpcsResponse, error) {
+		return nil, errors.New("Forbidden.RAM")
+	}
+	checker := &deploy.DriftChecker{ECS: cloud.ecs(), VPC: vpc}
+
+	if _, err := checker.Check(fullState()); err == nil {
+		t.Fatal("expected error when DescribeVpcs fails")
+	}
+}
+
+func TestStatus_CheckCloudJSON(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+
+	cloud := newDriftCloud()
+	cloud.eipInstanceID = ""
+
+	output := &bytes.Buffer{}
+	s := &deploy.StatusRunner{
+		Output:     output,
+		StateDir:   stateDir,
+		Format:     deploy.OutputJSON,
+		ECS:        cloud.ecs(),
+		VPC:        cloud.vpc(),
+		CheckCloud: true,
+	}
+
+	err := s.Run(context.Background())
+	if !errors.Is(err, deploy.ErrUnhealthy) {
+		t.Fatalf("expected ErrUnhealthy, got: %v", err)
+	}
+
+	var report deploy.StatusReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Drift == nil || len(report.Drift.Items) != 1 || report.Drift.Items[0].Resource != "eip" {
+		t.Errorf("unexpected drift: %+v", report.Drift)
+	}
+}
+
+func TestStatus_CheckCloudText(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+
+	cloud := newDriftCloud()
+	output := &bytes.Buffer{}
+	s := &deploy.StatusRunner{
+		Output:     output,
+		StateDir:   stateDir,
+		ECS:        cloud.ecs(),
+		VPC:        cloud.vpc(),
+		CheckCloud: true,
+	}
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output.String(), "云上资源与 state 一致") {
+		t.Errorf("expected consistent message, got:\n%s", output.String())
+	}
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hwuu/cloudcode/internal/config"
+)
+
+// setupEnvHome 将 HOME 指向临时目录并清空环境选择，返回 ~/.cloudcode 路径
+func setupEnvHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.EnvVarName, "")
+	config.SetActiveEnv("")
+	t.Cleanup(func() { config.SetActiveEnv("") })
+	return filepath.Join(home, config.StateDirName)
+}
+
+func TestActiveEnv_Precedence(t *testing.T) {
+	setupEnvHome(t)
+
+	if got := config.ActiveEnv(); got != config.DefaultEnvName {
+		t.Errorf("expected default env, got %s", got)
+	}
+
+	if err := config.UseEnv("staging"); err != nil {
+		t.Fatalf("UseEnv failed: %v", err)
+	}
+	if got := config.ActiveEnv(); got != "staging" {
+		t.Errorf("expected current_env staging, got %s", got)
+	}
+
+	t.Setenv(config.EnvVarName, "ci")
+	if got := config.ActiveEnv(); got != "ci" {
+		t.Errorf("expected CLOUDCODE_ENV to override current_env, got %s", got)
+	}
+
+	config.SetActiveEnv("prod")
+	if got := config.ActiveEnv(); got != "prod" {
+		t.Errorf("expected --env to override everything, got %s", got)
+	}
+}
+
+func TestValidateEnvName(t *testing.T) {
+	valid := []string{"default", "prod", "team-a", "x1"}
+	for _, name := range valid {
+		if err := config.ValidateEnvName(name); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+	invalid := []string{"", "Prod", "-a", "a/b", "../x", "a_b"}
+	for _, name := range invalid {
+		if err := config.ValidateEnvName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestSaveState_PerEnv(t *testing.T) {
+	root := setupEnvHome(t)
+
+	config.SetActiveEnv("a")
+	stateA := config.NewState("ap-southeast-1", "img")
+	stateA.CloudCode.Domain = "a.example.com"
+	if err := config.SaveState(stateA); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	config.SetActiveEnv("b")
+	if _, err := config.LoadState(); err == nil {
+		t.Error("env b should not see env a's state")
+	}
+
+	if _, err := os.Stat(filepath.Join(root, config.EnvsDirName, "a", config.StateFileName)); err != nil {
+		t.Errorf("expected state under envs/a: %v", err)
+	}
+
+	envs, err := config.ListEnvs()
+	if err != nil {
+		t.Fatalf("ListEnvs failed: %v", err)
+	}
+	if len(envs) != 1 || envs[0].Name != "a" || envs[0].Domain != "a.example.com" {
+		t.Errorf("unexpected env list: %+v", envs)
+	}
+}
+
+func TestRemoveEnv_ResetsCurrent(t *testing.T) {
+	setupEnvHome(t)
+
+	if err := config.UseEnv("tmp"); err != nil {
+		t.Fatalf("UseEnv failed: %v", err)
+	}
+	if err := config.RemoveEnv("tmp"); err != nil {
+		t.Fatalf("RemoveEnv failed: %v", err)
+	}
+	if got := config.ActiveEnv(); got != config.DefaultEnvName {
+		t.Errorf("expected fallback to default, got %s", got)
+	}
+	if err := config.RemoveEnv("tmp"); err == nil {
+		t.Error("expected error removing missing env")
+	}
+}
+
+func TestMigrateLegacyState(t *testing.T) {
+	root := setupEnvHome(t)
+	if err := os.MkdirAll(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := config.NewState("ap-southeast-1", "img")
+	legacy.Status = "running"
+	legacy.Resources.SSHKeyPair = config.SSHKeyPairResource{Name: "cloudcode-ssh-key", PrivateKeyPath: ".cloudcode/ssh_key"}
+	if err := config.SaveStateTo(root, legacy); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(root, config.SSHKeyFileName), []byte("key"), 0600)
+	os.WriteFile(filepath.Join(root, config.BackupFileName), []byte("{}"), 0600)
+
+	migrated, err := config.MigrateLegacyState()
+	if err != nil {
+		t.Fatalf("MigrateLegacyState failed: %v", err)
+	}
+	if !migrated {
+		t.Fatal("expected migration to happen")
+	}
+
+	envDir := filepath.Join(root, config.EnvsDirName, config.DefaultEnvName)
+	for _, name := range []string{config.StateFileName, config.BackupFileName, config.SSHKeyFileName} {
+		if _, err := os.Stat(filepath.Join(envDir, name)); err != nil {
+			t.Errorf("expected %s migrated: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("expected legacy %s removed", name)
+		}
+	}
+
+	state, err := config.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState after migration failed: %v", err)
+	}
+	if state.Resources.SSHKeyPair.PrivateKeyPath != config.EnvKeyRelPath(config.DefaultEnvName) {
+		t.Errorf("private key path not updated: %s", state.Resources.SSHKeyPair.PrivateKeyPath)
+	}
+
+	// 再次执行应为空操作
+	migrated, err = config.MigrateLegacyState()
+	if err != nil || migrated {
+		t.Errorf("second migration should be a no-op, got migrated=%v err=%v", migrated, err)
+	}
+}
+
+func TestKnownResourceIDs_IncludesMigrateState(t *testing.T) {
+	stateDir := setupEnvHome(t)
+	envDir := filepath.Join(stateDir, config.EnvsDirName, config.DefaultEnvName)
+	migrateDir := filepath.Join(envDir, config.MigrateDirName)
+	os.MkdirAll(migrateDir, 0700)
+
+	source := config.NewState("ap-southeast-1", "ubuntu_24_04_x64")
+	source.DeploymentID = "aaaa"
+	source.Resources.ECS.ID = "i-source"
+	writeTestState(t, envDir, source)
+
+	// 迁移中断：目标区域资源只记录在 <env>/migrate/state.json 中
+	target := config.NewState("cn-hangzhou", "ubuntu_24_04_x64")
+	target.DeploymentID = "bbbb"
+	target.Resources.VPC.ID = "vpc-target"
+	target.Resources.ECS.ID = "i-target"
+	writeTestState(t, migrateDir, target)
+
+	known, err := config.KnownResourceIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"i-source", "vpc-target", "i-target"} {
+		if !known[id] {
+			t.Errorf("expected %s to be known, got %v", id, known)
+		}
+	}
+	deployments, err := config.KnownDeploymentIDs()
+	if err != nil || !deployments["aaaa"] || !deployments["bbbb"] {
+		t.Errorf("KnownDeploymentIDs = %v, %v", deployments, err)
+	}
+}
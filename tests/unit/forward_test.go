@@ -0,0 +1,265 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+func TestParseForwardSpec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    remote.ForwardSpec
+		wantErr bool
+	}{
+		{in: "3000:devbox:3000", want: remote.ForwardSpec{LocalPort: 3000, Container: "devbox", Port: 3000}},
+		{in: "15432:devbox-alice:5432", want: remote.ForwardSpec{LocalPort: 15432, Container: "devbox-alice", Port: 5432}},
+		{in: "devbox:8080", want: remote.ForwardSpec{LocalPort: 8080, Container: "devbox", Port: 8080}},
+		{in: "0:devbox:8080", want: remote.ForwardSpec{LocalPort: 0, Container: "devbox", Port: 8080}},
+		{in: "3000", wantErr: true},
+		{in: "3000:devbox:0", wantErr: true},
+		{in: "x:devbox:3000", wantErr: true},
+		{in: "3000:dev;rm -rf /:3000", wantErr: true},
+		{in: "1:2:3:4", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := remote.ParseForwardSpec(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseForwardSpec(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseForwardSpec(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContainerIP(t *testing.T) {
+	var gotCmd string
+	client := &MockSSHClient{
+		RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+			gotCmd = cmd
+			return "bridge 172.17.0.2\ncloudcode_cloudcode-net 172.18.0.3\n", nil
+		},
+	}
+	ip, err := remote.ContainerIP(context.Background(), client, "devbox")
+	if err != nil || ip != "172.18.0.3" {
+		t.Fatalf("ContainerIP = %q, %v", ip, err)
+	}
+	if !strings.HasPrefix(gotCmd, "docker inspect ") || !strings.HasSuffix(gotCmd, " devbox") {
+		t.Errorf("command = %q", gotCmd)
+	}
+
+	client.RunCommandFunc = func(ctx context.Context, cmd string) (string, error) {
+		return "bridge 172.17.0.2\n", nil
+	}
+	if _, err := remote.ContainerIP(context.Background(), client, "devbox"); err == nil {
+		t.Error("expected error when container is not on cloudcode-net")
+	}
+}
+
+// inspectSSHClient 包装真实 SSH 连接，docker inspect 命令返回 inspect() 的输出
+type inspectSSHClient struct {
+	remote.SSHClient
+	inspect func() string
+}
+
+func (c *inspectSSHClient) RunCommand(ctx context.Context, cmd string) (string, error) {
+	if strings.HasPrefix(cmd, "docker inspect ") {
+		return c.inspect(), nil
+	}
+	return c.SSHClient.RunCommand(ctx, cmd)
+}
+
+func (c *inspectSSHClient) Dial(network, addr string) (net.Conn, error) {
+	return remote.Dial(c.SSHClient, network, addr)
+}
+
+// startEchoServer 启动逐行回显的 TCP 服务（模拟容器内的服务），返回端口
+func startEchoServer(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// startForward 在随机本地端口上启动转发，返回本地地址和停止转发的函数（返回 ServeForward 的结果）
+func startForward(t *testing.T, dial func(ctx context.Context) (net.Conn, error), onError func(error)) (string, func() error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- remote.ServeForward(ctx, ln, dial, onError) }()
+	stop := func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("ServeForward did not return after cancel")
+			return nil
+		}
+	}
+	t.Cleanup(func() { cancel() })
+	return ln.Addr().String(), stop
+}
+
+// echoRoundTrip 经 conn 发送一行并读取回显
+func echoRoundTrip(t *testing.T, conn net.Conn, r *bufio.Reader, msg string) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintln(conn, msg)
+	line, err := r.ReadString('\n')
+	if err != nil || line != msg+"\n" {
+		t.Fatalf("echo = %q, %v", line, err)
+	}
+}
+
+func TestServeForward_ThroughSSHToContainer(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+	sshClient, err := pool.DialFunc(srv.Host, srv.Port, "root", key)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoPort := startEchoServer(t)
+	client := &inspectSSHClient{SSHClient: sshClient, inspect: func() string {
+		return "cloudcode_cloudcode-net 127.0.0.1\n"
+	}}
+	dialer := &remote.ContainerDialer{Client: client, Container: "devbox", Port: echoPort}
+	addr, stop := startForward(t, dialer.Dial, func(err error) { t.Errorf("forward error: %v", err) })
+
+	// 多个连接同时转发
+	var conns []net.Conn
+	var readers []*bufio.Reader
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+		readers = append(readers, bufio.NewReader(conn))
+	}
+	for i, conn := range conns {
+		echoRoundTrip(t, conn, readers[i], fmt.Sprintf("hello %d", i))
+	}
+	waitForEvent(t, srv, "direct-tcpip "+net.JoinHostPort("127.0.0.1", strconv.Itoa(echoPort)))
+	if n := srv.Conns(); n != 1 {
+		t.Errorf("TCP connections = %d, want 1 (shared SSH connection)", n)
+	}
+
+	// 停止转发时关闭监听端口和转发中的连接
+	if err := stop(); err != nil {
+		t.Errorf("ServeForward = %v", err)
+	}
+	conns[0].SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := readers[0].ReadByte(); err == nil {
+		t.Error("forwarded connection should be closed after stop")
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("listener should be closed after stop")
+	}
+}
+
+func TestContainerDialer_ReresolvesAfterFailure(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+	sshClient, err := pool.DialFunc(srv.Host, srv.Port, "root", key)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoPort := startEchoServer(t)
+
+	// 第一次解析到旧 IP（容器重建前），连接失败后重新解析到新 IP
+	var inspects int32
+	client := &inspectSSHClient{SSHClient: sshClient, inspect: func() string {
+		if atomic.AddInt32(&inspects, 1) == 1 {
+			return "cloudcode_cloudcode-net 127.0.0.2\n"
+		}
+		return "cloudcode_cloudcode-net 127.0.0.1\n"
+	}}
+	dialer := &remote.ContainerDialer{Client: client, Container: "devbox", Port: echoPort}
+	if ip, err := dialer.Resolve(context.Background()); err != nil || ip != "127.0.0.2" {
+		t.Fatalf("Resolve = %q, %v", ip, err)
+	}
+	// 127.0.0.2 上没有监听，远程连接被拒绝
+	conn, err := dialer.Dial(context.Background())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	echoRoundTrip(t, conn, bufio.NewReader(conn), "after re-resolve")
+	if n := atomic.LoadInt32(&inspects); n != 2 {
+		t.Errorf("inspects = %d, want 2", n)
+	}
+}
+
+func TestServeForward_DialFailureKeepsServing(t *testing.T) {
+	echoPort := startEchoServer(t)
+	var dials int32
+	dial := func(ctx context.Context) (net.Conn, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return nil, errors.New("container down")
+		}
+		return net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(echoPort)))
+	}
+	errs := make(chan error, 1)
+	addr, stop := startForward(t, dial, func(err error) { errs <- err })
+
+	// 第一个连接失败：本地连接被关闭，错误交给 onError
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err == nil {
+		t.Error("failed connection should be closed")
+	}
+	select {
+	case err := <-errs:
+		if err.Error() != "container down" {
+			t.Errorf("onError = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("onError not called")
+	}
+
+	// 之后的连接正常转发
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	echoRoundTrip(t, second, bufio.NewReader(second), "still serving")
+	if err := stop(); err != nil {
+		t.Errorf("ServeForward = %v", err)
+	}
+}
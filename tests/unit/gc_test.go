@@ -0,0 +1,313 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+)
+
+// gcCloud 模拟带 CloudCode 标签的云上资源：一套完整部署（-known）加一组中断部署残留（-orphan）
+type gcCloud struct {
+	deleted   []string                                                    // 按调用顺序记录被删除的资源 ID
+	snapshots []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot // 额外的快照
+}
+
+func gcECSTags(deploymentID string) *ecsclient.DescribeInstancesResponseBodyInstancesInstanceTags {
+	return &ecsclient.DescribeInstancesResponseBodyInstancesInstanceTags{
+		Tag: []*ecsclient.DescribeInstancesResponseBodyInstancesInstanceTagsTag{
+			{TagKey: teaString(alicloud.TagKeyDeploymentID), TagValue: teaString(deploymentID)},
+			{TagKey: teaString(alicloud.TagKeyEnv), TagValue: teaString("default")},
+		},
+	}
+}
+
+func (c *gcCloud) ecs() *MockECSAPI {
+	return &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+							{InstanceId: teaString("i-known"), Status: teaString("Running"), Tags: gcECSTags("aaaa")},
+							{InstanceId: teaString("i-orphan"), Status: teaString("Stopped"), Tags: gcECSTags("bbbb")},
+						},
+					},
+				},
+			}, nil
+		},
+		DeleteInstanceFunc: func(req *ecsclient.DeleteInstanceRequest) (*ecsclient.DeleteInstanceResponse, error) {
+			c.deleted = append(c.deleted, *req.InstanceId)
+			return &ecsclient.DeleteInstanceResponse{}, nil
+		},
+		DescribeKeyPairsFunc: func(req *ecsclient.DescribeKeyPairsRequest) (*ecsclient.DescribeKeyPairsResponse, error) {
+			return &ecsclient.DescribeKeyPairsResponse{
+				Body: &ecsclient.DescribeKeyPairsResponseBody{
+					KeyPairs: &ecsclient.DescribeKeyPairsResponseBodyKeyPairs{
+						KeyPair: []*ecsclient.DescribeKeyPairsResponseBodyKeyPairsKeyPair{
+							{KeyPairName: teaString("cloudcode-ssh-key")},
+						},
+					},
+				},
+			}, nil
+		},
+		DescribeSecurityGroupsFunc: func(req *ecsclient.DescribeSecurityGroupsRequest) (*ecsclient.DescribeSecurityGroupsResponse, error) {
+			return &ecsclient.DescribeSecurityGroupsResponse{
+				Body: &ecsclient.DescribeSecurityGroupsResponseBody{
+					SecurityGroups: &ecsclient.DescribeSecurityGroupsResponseBodySecurityGroups{
+						SecurityGroup: []*ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup{
+							{SecurityGroupId: teaString("sg-orphan")},
+						},
+					},
+				},
+			}, nil
+		},
+		DeleteSecurityGroupFunc: func(req *ecsclient.DeleteSecurityGroupRequest) (*ecsclient.DeleteSecurityGroupResponse, error) {
+			c.deleted = append(c.deleted, *req.SecurityGroupId)
+			return &ecsclient.DeleteSecurityGroupResponse{}, nil
+		},
+		DescribeSnapshotsFunc: func(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error) {
+			return &ecsclient.DescribeSnapshotsResponse{
+				Body: &ecsclient.DescribeSnapshotsResponseBody{
+					Snapshots: &ecsclient.DescribeSnapshotsResponseBodySnapshots{
+						Snapshot: append([]*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{
+							{SnapshotId: teaString("s-backup")},
+						}, c.snapshots...),
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func gcSnapshot(id, deploymentID string) *ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot {
+	return &ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{
+		SnapshotId: teaString(id),
+		Tags: &ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshotTags{
+			Tag: []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshotTagsTag{
+				{TagKey: teaString(alicloud.TagKeyDeploymentID), TagValue: teaString(deploymentID)},
+			},
+		},
+	}
+}
+
+func (c *gcCloud) vpc() *MockVPCAPI {
+	return &MockVPCAPI{
+		DescribeVpcsFunc: func(req *vpcclient.DescribeVpcsRequest) (*vpcclient.DescribeVpcsResponse, error) {
+			return &vpcclient.DescribeVpcsResponse{
+				Body: &vpcclient.DescribeVpcsResponseBody{
+					Vpcs: &vpcclient.DescribeVpcsResponseBodyVpcs{
+						Vpc: []*vpcclient.DescribeVpcsResponseBodyVpcsVpc{
+							{VpcId: teaString("vpc-known")},
+							{VpcId: teaString("vpc-orphan")},
+						},
+					},
+				},
+			}, nil
+		},
+		DeleteVpcFunc: func(req *vpcclient.DeleteVpcRequest) (*vpcclient.DeleteVpcResponse, error) {
+			c.deleted = append(c.deleted, *req.VpcId)
+			return &vpcclient.DeleteVpcResponse{}, nil
+		},
+		DescribeVSwitchesFunc: func(req *vpcclient.DescribeVSwitchesRequest) (*vpcclient.DescribeVSwitchesResponse, error) {
+			return &vpcclient.DescribeVSwitchesResponse{Body: &vpcclient.DescribeVSwitchesResponseBody{}}, nil
+		},
+		DescribeEipAddressesFunc: func(req *vpcclient.DescribeEipAddressesRequest) (*vpcclient.DescribeEipAddressesResponse, error) {
+			return &vpcclient.DescribeEipAddressesResponse{
+				Body: &vpcclient.DescribeEipAddressesResponseBody{
+					EipAddresses: &vpcclient.DescribeEipAddressesResponseBodyEipAddresses{
+						EipAddress: []*vpcclient.DescribeEipAddressesResponseBodyEipAddressesEipAddress{
+							{AllocationId: teaString("eip-orphan"), IpAddress: teaString("47.1.1.1"), InstanceId: teaString("i-orphan")},
+						},
+					},
+				},
+			}, nil
+		},
+		UnassociateEipAddressFunc: func(req *vpcclient.UnassociateEipAddressRequest) (*vpcclient.UnassociateEipAddressResponse, error) {
+			c.deleted = append(c.deleted, "unassociate:"+*req.AllocationId)
+			return &vpcclient.UnassociateEipAddressResponse{}, nil
+		},
+		ReleaseEipAddressFunc: func(req *vpcclient.ReleaseEipAddressRequest) (*vpcclient.ReleaseEipAddressResponse, error) {
+			c.deleted = append(c.deleted, *req.AllocationId)
+			return &vpcclient.ReleaseEipAddressResponse{}, nil
+		},
+	}
+}
+
+func (c *gcCloud) collector(output *bytes.Buffer, input string) *deploy.GarbageCollector {
+	return &deploy.GarbageCollector{
+		ECS:          c.ecs(),
+		VPC:          c.vpc(),
+		Prompter:     config.NewPrompter(strings.NewReader(input), output),
+		Output:       output,
+		Region:       "ap-southeast-1",
+		KnownIDs:     map[string]bool{"i-known": true, "vpc-known": true, "cloudcode-ssh-key": true, "s-backup": true},
+		WaitInterval: time.Millisecond,
+	}
+}
+
+func TestResourceTags_SkipsEmpty(t *testing.T) {
+	tags := alicloud.ResourceTags("abcd", "", "1.0.0")
+	if len(tags) != 2 || tags[0].Key != alicloud.TagKeyDeploymentID || tags[1].Key != alicloud.TagKeyVersion {
+		t.Errorf("unexpected tags: %+v", tags)
+	}
+}
+
+func TestCreateVPC_WithTags(t *testing.T) {
+	var got []*vpcclient.CreateVpcRequestTag
+	mock := &MockVPCAPI{
+		CreateVpcFunc: func(req *vpcclient.CreateVpcRequest) (*vpcclient.CreateVpcResponse, error) {
+			got = req.Tag
+			return &vpcclient.CreateVpcResponse{Body: &vpcclient.CreateVpcResponseBody{VpcId: teaString("vpc-1")}}, nil
+		},
+	}
+	if _, err := alicloud.CreateVPC(mock, "ap-southeast-1", "cloudcode-vpc", alicloud.ResourceTags("abcd", "default", "1.0.0")...); err != nil {
+		t.Fatalf("CreateVPC failed: %v", err)
+	}
+	if len(got) != 3 || *got[0].Key != alicloud.TagKeyDeploymentID || *got[0].Value != "abcd" {
+		t.Errorf("unexpected request tags: %+v", got)
+	}
+}
+
+func TestListTaggedResources_Paginates(t *testing.T) {
+	cloud := &gcCloud{}
+	ecsMock := cloud.ecs()
+	var instancePages []int32
+	ecsMock.DescribeInstancesFunc = func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+		page := *req.PageNumber
+		instancePages = append(instancePages, page)
+		// 共 150 台：第 1 页 100 台，第 2 页 50 台
+		n := 100
+		if page == 2 {
+			n = 50
+		} else if page > 2 {
+			n = 0
+		}
+		var instances []*ecsclient.DescribeInstancesResponseBodyInstancesInstance
+		for i := 0; i < n; i++ {
+			id := fmt.Sprintf("i-%d-%d", page, i)
+			instances = append(instances, &ecsclient.DescribeInstancesResponseBodyInstancesInstance{InstanceId: teaString(id), Tags: gcECSTags("bbbb")})
+		}
+		return &ecsclient.DescribeInstancesResponse{
+			Body: &ecsclient.DescribeInstancesResponseBody{
+				TotalCount: tea.Int32(150),
+				Instances:  &ecsclient.DescribeInstancesResponseBodyInstances{Instance: instances},
+			},
+		}, nil
+	}
+
+	resources, err := alicloud.ListTaggedResources(ecsMock, cloud.vpc(), "ap-southeast-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, r := range resources {
+		if r.Type == alicloud.ResourceTypeECS {
+			count++
+		}
+	}
+	if count != 150 {
+		t.Errorf("ECS resources = %d, want 150", count)
+	}
+	if len(instancePages) != 2 {
+		t.Errorf("DescribeInstances pages = %v, want [1 2]", instancePages)
+	}
+}
+
+func TestGC_ListOnly(t *testing.T) {
+	cloud := &gcCloud{}
+	output := &bytes.Buffer{}
+
+	if err := cloud.collector(output, "").Run(context.Background(), false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := output.String()
+	for _, want := range []string{"eip-orphan", "i-orphan", "deployment=bbbb", "sg-orphan", "vpc-orphan", "cloudcode gc --delete"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	for _, known := range []string{"i-known", "vpc-known", "cloudcode-ssh-key", "s-backup"} {
+		if strings.Contains(out, known) {
+			t.Errorf("referenced resource %q should not be listed:\n%s", known, out)
+		}
+	}
+	if len(cloud.deleted) != 0 {
+		t.Errorf("list mode should not delete, got: %v", cloud.deleted)
+	}
+}
+
+func TestGC_DeleteInDependencyOrder(t *testing.T) {
+	cloud := &gcCloud{}
+	output := &bytes.Buffer{}
+
+	if err := cloud.collector(output, "y\n").Run(context.Background(), true, false); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output.String())
+	}
+	want := []string{"unassociate:eip-orphan", "eip-orphan", "i-orphan", "sg-orphan", "vpc-orphan"}
+	if strings.Join(cloud.deleted, ",") != strings.Join(want, ",") {
+		t.Errorf("delete order = %v, want %v", cloud.deleted, want)
+	}
+}
+
+func TestGC_DeleteCancelled(t *testing.T) {
+	cloud := &gcCloud{}
+	output := &bytes.Buffer{}
+
+	if err := cloud.collector(output, "n\n").Run(context.Background(), true, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cloud.deleted) != 0 {
+		t.Errorf("cancelled gc should not delete, got: %v", cloud.deleted)
+	}
+}
+
+func TestGC_KeepsReclaimSnapshotOfKnownDeployment(t *testing.T) {
+	// 抢占式实例被回收：state 中只剩部署 ID，agent 的回收快照尚未记入备份目录
+	stateDir := setupEnvHome(t)
+	envDir := filepath.Join(stateDir, config.EnvsDirName, config.DefaultEnvName)
+	os.MkdirAll(envDir, 0700)
+	state := config.NewState("ap-southeast-1", "ubuntu_24_04_x64")
+	state.DeploymentID = "aaaa"
+	state.Status = "reclaimed"
+	writeTestState(t, envDir, state)
+
+	deployments, err := config.KnownDeploymentIDs()
+	if err != nil || !deployments["aaaa"] {
+		t.Fatalf("KnownDeploymentIDs = %v, %v", deployments, err)
+	}
+
+	cloud := &gcCloud{snapshots: []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{
+		gcSnapshot("s-reclaim", "aaaa"),
+		gcSnapshot("s-stale", "bbbb"),
+	}}
+	output := &bytes.Buffer{}
+	g := cloud.collector(output, "")
+	g.KnownDeployments = deployments
+	orphans, err := g.FindOrphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range orphans {
+		ids = append(ids, r.ID)
+	}
+	got := strings.Join(ids, ",")
+	if strings.Contains(got, "s-reclaim") {
+		t.Errorf("reclaim snapshot of a known deployment should be kept, orphans: %s", got)
+	}
+	if !strings.Contains(got, "s-stale") {
+		t.Errorf("snapshot of an unknown deployment should be listed, orphans: %s", got)
+	}
+}
@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+func TestHostKeyCallback_TrustOnFirstUse(t *testing.T) {
+	signer, _ := newTestSigner(t)
+	other, _ := newTestSigner(t)
+	store := &remote.MemoryHostKeyStore{}
+	cb := remote.HostKeyCallback(store)
+
+	if err := cb("47.100.1.1:22", nil, signer.PublicKey()); err != nil {
+		t.Fatalf("first connection should be accepted: %v", err)
+	}
+	saved, _ := store.LoadHostKey("47.100.1.1")
+	if saved != remote.MarshalHostKey(signer.PublicKey()) {
+		t.Errorf("saved key = %q", saved)
+	}
+	if err := cb("47.100.1.1:22", nil, signer.PublicKey()); err != nil {
+		t.Errorf("same key should be accepted: %v", err)
+	}
+
+	err := cb("47.100.1.1:22", nil, other.PublicKey())
+	if !errors.Is(err, remote.ErrHostKeyMismatch) {
+		t.Fatalf("expected ErrHostKeyMismatch, got %v", err)
+	}
+	var mismatch *remote.HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected *HostKeyMismatchError, got %T", err)
+	}
+	if mismatch.Host != "47.100.1.1" {
+		t.Errorf("Host = %q", mismatch.Host)
+	}
+	if !strings.Contains(err.Error(), "--reset-hostkey") {
+		t.Errorf("error should mention --reset-hostkey: %v", err)
+	}
+	saved, _ = store.LoadHostKey("47.100.1.1")
+	if saved != remote.MarshalHostKey(signer.PublicKey()) {
+		t.Error("mismatch should not overwrite the pinned key")
+	}
+}
+
+func TestConfigHostKeyStore_OnlyStateEIP(t *testing.T) {
+	dir := t.TempDir()
+	writeTestState(t, dir, fullState())
+	store := config.HostKeyStore{Dir: dir}
+
+	if err := store.SaveHostKey("10.0.0.9", "ssh-ed25519 AAAAother"); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := store.LoadHostKey("10.0.0.9"); key != "" {
+		t.Errorf("other host should not be recorded, got %q", key)
+	}
+
+	if err := store.SaveHostKey("47.100.1.1", "ssh-ed25519 AAAAkey"); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := store.LoadHostKey("47.100.1.1"); key != "ssh-ed25519 AAAAkey" {
+		t.Errorf("LoadHostKey = %q", key)
+	}
+
+	empty := config.HostKeyStore{Dir: t.TempDir()}
+	if err := empty.SaveHostKey("47.100.1.1", "ssh-ed25519 AAAAkey"); err != nil {
+		t.Errorf("missing state should be a no-op: %v", err)
+	}
+}
+
+func TestSaveStateTo_PreservesHostKey(t *testing.T) {
+	dir := t.TempDir()
+	writeTestState(t, dir, fullState())
+	stale, err := config.LoadStateFrom(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 连接时记录主机公钥，随后调用方保存连接前加载的 state
+	if err := (config.HostKeyStore{Dir: dir}).SaveHostKey("47.100.1.1", "ssh-ed25519 AAAAkey"); err != nil {
+		t.Fatal(err)
+	}
+	stale.Resources.ECS.InstanceType = "ecs.g7.large"
+	if err := config.SaveStateTo(dir, stale); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := config.LoadStateFrom(dir)
+	if got.Resources.ECS.HostKey != "ssh-ed25519 AAAAkey" {
+		t.Errorf("host key lost: %q", got.Resources.ECS.HostKey)
+	}
+	if got.Resources.ECS.InstanceType != "ecs.g7.large" {
+		t.Errorf("InstanceType = %q", got.Resources.ECS.InstanceType)
+	}
+
+	// 实例已替换：不沿用旧实例的主机公钥
+	stale.Resources.ECS.ID = "i-new"
+	if err := config.SaveStateTo(dir, stale); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = config.LoadStateFrom(dir)
+	if got.Resources.ECS.HostKey != "" {
+		t.Errorf("new instance should not inherit host key, got %q", got.Resources.ECS.HostKey)
+	}
+}
+
+func TestClearHostKey(t *testing.T) {
+	dir := t.TempDir()
+	state := fullState()
+	state.Resources.ECS.HostKey = "ssh-ed25519 AAAAkey"
+	writeTestState(t, dir, state)
+
+	if err := config.ClearHostKey(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := config.LoadStateFrom(dir)
+	if got.Resources.ECS.HostKey != "" {
+		t.Errorf("host key not cleared: %q", got.Resources.ECS.HostKey)
+	}
+	if got.Resources.ECS.ID != "i-test" {
+		t.Errorf("ECS ID = %q", got.Resources.ECS.ID)
+	}
+}
+
+func TestWaitForSSH_HostKeyMismatchNoRetry(t *testing.T) {
+	calls := 0
+	dial := func() (remote.SSHClient, error) {
+		calls++
+		return nil, &remote.HostKeyMismatchError{Host: "47.100.1.1", Want: "SHA256:a", Got: "SHA256:b"}
+	}
+	_, err := remote.WaitForSSH(context.Background(), dial, remote.WaitSSHOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond,
+		Timeout:         time.Second,
+	})
+	if !errors.Is(err, remote.ErrHostKeyMismatch) {
+		t.Fatalf("expected ErrHostKeyMismatch, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("dial calls = %d, want 1", calls)
+	}
+}
+
+func TestSSHDial_PinsAndVerifiesHostKey(t *testing.T) {
+	hostKey, _ := newTestSigner(t)
+	_, clientKey := newTestSigner(t)
+	srv := startTestSSHServer(t, hostKey)
+
+	store := &remote.MemoryHostKeyStore{}
+	remote.SetHostKeyStore(store)
+	t.Cleanup(func() { remote.SetHostKeyStore(&remote.MemoryHostKeyStore{}) })
+
+	dial := remote.NewSSHDialFunc(srv.Host, srv.Port, "root", clientKey)
+	client, err := dial()
+	if err != nil {
+		t.Fatalf("first dial: %v", err)
+	}
+	out, err := client.RunCommand(context.Background(), "echo hi")
+	client.Close()
+	if err != nil || out != "ran: echo hi" {
+		t.Fatalf("RunCommand = %q, %v", out, err)
+	}
+	if key, _ := store.LoadHostKey(srv.Host); key != remote.MarshalHostKey(hostKey.PublicKey()) {
+		t.Fatalf("host key not pinned: %q", key)
+	}
+
+	// 服务器换了主机密钥：拒绝连接
+	other, _ := newTestSigner(t)
+	srv2 := startTestSSHServer(t, other)
+	store.SaveHostKey(srv2.Host, remote.MarshalHostKey(hostKey.PublicKey()))
+	_, err = remote.NewSSHDialFunc(srv2.Host, srv2.Port, "root", clientKey)()
+	if !errors.Is(err, remote.ErrHostKeyMismatch) {
+		t.Fatalf("expected ErrHostKeyMismatch, got %v", err)
+	}
+}
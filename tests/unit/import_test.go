@@ -0,0 +1,189 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"golang.org/x/crypto/ssh"
+)
+
+func writeRSAKey(t *testing.T, path string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+}
+
+func TestImport_WithExistingKey(t *testing.T) {
+	stateDir := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "id_rsa")
+	writeRSAKey(t, keyFile)
+
+	cloud := newDriftCloud()
+	output := &bytes.Buffer{}
+	im := &deploy.Importer{
+		ECS:      cloud.ecs(),
+		VPC:      cloud.vpc(),
+		Output:   output,
+		Region:   "ap-southeast-1",
+		StateDir: stateDir,
+		Env:      "default",
+		KeyFile:  keyFile,
+	}
+
+	if err := im.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output.String())
+	}
+
+	state, err := config.LoadStateFrom(stateDir)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	r := state.Resources
+	if r.VPC.ID != "vpc-test" || r.VSwitch.ID != "vsw-test" || r.SecurityGroup.ID != "sg-test" ||
+		r.ECS.ID != "i-test" || r.EIP.ID != "eip-test" || r.SSHKeyPair.Name != "cloudcode-ssh-key" {
+		t.Errorf("unexpected resources: %+v", r)
+	}
+	if !state.IsComplete() {
+		t.Error("expected complete state")
+	}
+	if state.Status != "running" {
+		t.Errorf("status = %q, want running", state.Status)
+	}
+	if state.CloudCode.Domain != "47.100.1.1.nip.io" || state.CloudCode.Username != "admin" {
+		t.Errorf("unexpected app config: %+v", state.CloudCode)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "ssh_key")); err != nil {
+		t.Errorf("expected ssh_key copied: %v", err)
+	}
+}
+
+func TestImport_InvalidKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "bad")
+	os.WriteFile(keyFile, []byte("not a key"), 0600)
+
+	cloud := newDriftCloud()
+	im := &deploy.Importer{
+		ECS:      cloud.ecs(),
+		VPC:      cloud.vpc(),
+		Output:   &bytes.Buffer{},
+		Region:   "ap-southeast-1",
+		StateDir: t.TempDir(),
+		KeyFile:  keyFile,
+	}
+	if err := im.Run(context.Background()); err == nil {
+		t.Fatal("expected error for invalid key file")
+	}
+}
+
+func TestImport_GeneratesKeyForStoppedInstance(t *testing.T) {
+	stateDir := t.TempDir()
+	cloud := newDriftCloud()
+	cloud.instanceStatus = "Stopped"
+
+	var imported, attached string
+	ecs := cloud.ecs()
+	ecs.DeleteKeyPairsFunc = func(req *ecsclient.DeleteKeyPairsRequest) (*ecsclient.DeleteKeyPairsResponse, error) {
+		return &ecsclient.DeleteKeyPairsResponse{}, nil
+	}
+	ecs.ImportKeyPairFunc = func(req *ecsclient.ImportKeyPairRequest) (*ecsclient.ImportKeyPairResponse, error) {
+		imported = *req.PublicKeyBody
+		return &ecsclient.ImportKeyPairResponse{
+			Body: &ecsclient.ImportKeyPairResponseBody{KeyPairName: req.KeyPairName},
+		}, nil
+	}
+	ecs.AttachKeyPairFunc = func(req *ecsclient.AttachKeyPairRequest) (*ecsclient.AttachKeyPairResponse, error) {
+		attached = *req.KeyPairName
+		return &ecsclient.AttachKeyPairResponse{}, nil
+	}
+
+	im := &deploy.Importer{
+		ECS:      ecs,
+		VPC:      cloud.vpc(),
+		Output:   &bytes.Buffer{},
+		Region:   "ap-southeast-1",
+		StateDir: stateDir,
+		Env:      "staging",
+		Domain:   "code.example.com",
+		Username: "alice",
+	}
+	if err := im.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(imported, "ssh-rsa ") {
+		t.Errorf("expected ssh-rsa public key, got %q", imported)
+	}
+	if attached != "cloudcode-ssh-key-staging" {
+		t.Errorf("attached key = %q", attached)
+	}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, "ssh_key"))
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		t.Fatalf("invalid generated key: %v", err)
+	}
+	if got := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))); got != imported {
+		t.Error("local private key does not match imported public key")
+	}
+
+	state, _ := config.LoadStateFrom(stateDir)
+	if state.Status != "suspended" || state.Resources.SSHKeyPair.Name != "cloudcode-ssh-key-staging" {
+		t.Errorf("unexpected state: status=%q key=%q", state.Status, state.Resources.SSHKeyPair.Name)
+	}
+	if state.CloudCode.Domain != "code.example.com" || state.CloudCode.Username != "alice" {
+		t.Errorf("unexpected app config: %+v", state.CloudCode)
+	}
+}
+
+func TestImport_RefusesExistingState(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+
+	cloud := newDriftCloud()
+	im := &deploy.Importer{
+		ECS:      cloud.ecs(),
+		VPC:      cloud.vpc(),
+		Output:   &bytes.Buffer{},
+		Region:   "ap-southeast-1",
+		StateDir: stateDir,
+	}
+	if err := im.Run(context.Background()); err == nil {
+		t.Fatal("expected error when state already has an instance")
+	}
+}
+
+func TestImport_SkipsKnownInstances(t *testing.T) {
+	cloud := newDriftCloud()
+	im := &deploy.Importer{
+		ECS:      cloud.ecs(),
+		VPC:      cloud.vpc(),
+		Output:   &bytes.Buffer{},
+		Region:   "ap-southeast-1",
+		StateDir: t.TempDir(),
+		KnownIDs: map[string]bool{"i-test": true},
+	}
+	err := im.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "未找到") {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// dialTestSSHServer 启动进程内 SSH 服务器并建立连接
+func dialTestSSHServer(t *testing.T) (*testSSHServer, remote.SSHClient) {
+	t.Helper()
+	hostKey, _ := newTestSigner(t)
+	_, clientKey := newTestSigner(t)
+	srv := startTestSSHServer(t, hostKey)
+
+	remote.SetHostKeyStore(&remote.MemoryHostKeyStore{})
+	client, err := remote.NewSSHDialFunc(srv.Host, srv.Port, "root", clientKey)()
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return srv, client
+}
+
+// waitForEvent 等待服务器记录到 want 事件
+func waitForEvent(t *testing.T, srv *testSSHServer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, e := range srv.Events() {
+			if e == want {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("event %q not received, got %v", want, srv.Events())
+}
+
+func TestRunInteractive_PTYShell(t *testing.T) {
+	srv, client := dialTestSSHServer(t)
+
+	var stdout bytes.Buffer
+	err := remote.RunInteractive(context.Background(), client, "", remote.SessionOptions{
+		TTY:    true,
+		Term:   "xterm",
+		Size:   remote.WindowSize{Width: 100, Height: 40},
+		Stdout: &stdout,
+	})
+	if err != nil {
+		t.Fatalf("RunInteractive: %v", err)
+	}
+	if stdout.String() != "shell" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	events := srv.Events()
+	if len(events) < 2 || events[0] != "pty-req xterm 100x40" || events[1] != "shell" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestRunInteractive_DefaultPTYSize(t *testing.T) {
+	srv, client := dialTestSSHServer(t)
+
+	err := remote.RunInteractive(context.Background(), client, "echo", remote.SessionOptions{
+		TTY:    true,
+		Stdout: &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("RunInteractive: %v", err)
+	}
+	waitForEvent(t, srv, "pty-req xterm-256color 80x24")
+}
+
+func TestRunInteractive_NoPTYStreamsStdin(t *testing.T) {
+	srv, client := dialTestSSHServer(t)
+
+	var stdout bytes.Buffer
+	err := remote.RunInteractive(context.Background(), client, "cat", remote.SessionOptions{
+		Stdin:  strings.NewReader("hello\n"),
+		Stdout: &stdout,
+	})
+	if err != nil {
+		t.Fatalf("RunInteractive: %v", err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	for _, e := range srv.Events() {
+		if strings.HasPrefix(e, "pty-req") {
+			t.Errorf("unexpected PTY request: %v", srv.Events())
+		}
+	}
+}
+
+func TestRunInteractive_ExitStatus(t *testing.T) {
+	_, client := dialTestSSHServer(t)
+
+	err := remote.RunInteractive(context.Background(), client, "exit 3", remote.SessionOptions{Stdout: &bytes.Buffer{}})
+	var exitErr *remote.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %v", err)
+	}
+	if exitErr.Status != 3 || exitErr.Signal != "" {
+		t.Errorf("ExitError = %+v", exitErr)
+	}
+}
+
+func TestRunInteractive_ResizeAndSignal(t *testing.T) {
+	srv, client := dialTestSSHServer(t)
+
+	resize := make(chan remote.WindowSize, 1)
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- remote.RunInteractive(context.Background(), client, "wait", remote.SessionOptions{
+			TTY:     true,
+			Resize:  resize,
+			Signals: signals,
+			Stdout:  &bytes.Buffer{},
+		})
+	}()
+
+	waitForEvent(t, srv, "exec wait")
+	resize <- remote.WindowSize{Width: 120, Height: 50}
+	waitForEvent(t, srv, "window-change 120x50")
+	signals <- os.Interrupt
+
+	select {
+	case err := <-done:
+		var exitErr *remote.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected *ExitError, got %v", err)
+		}
+		if exitErr.Signal != "INT" || exitErr.Status != 130 {
+			t.Errorf("ExitError = %+v", exitErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not end after signal")
+	}
+}
+
+func TestRunInteractive_ContextCancel(t *testing.T) {
+	srv, client := dialTestSSHServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- remote.RunInteractive(ctx, client, "wait", remote.SessionOptions{Stdout: &bytes.Buffer{}})
+	}()
+	waitForEvent(t, srv, "exec wait")
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not end after cancel")
+	}
+	waitForEvent(t, srv, "signal TERM")
+}
+
+func TestRunInteractive_UnsupportedClient(t *testing.T) {
+	err := remote.RunInteractive(context.Background(), &MockSSHClient{}, "", remote.SessionOptions{})
+	if err == nil {
+		t.Fatal("expected error for client without interactive support")
+	}
+}
@@ -31,6 +31,7 @@ func TestLoadConfig_EnvPriority(t *testing.T) {
 }
 
 func TestLoadConfig_EnvDefaultRegion(t *testing.T) {
+	setupEnvHome(t)
 	t.Setenv("ALICLOUD_ACCESS_KEY_ID", "env-key-id")
 	t.Setenv("ALICLOUD_ACCESS_KEY_SECRET", "env-key-secret")
 	t.Setenv("ALICLOUD_REGION", "")
@@ -44,6 +45,29 @@ func TestLoadConfig_EnvDefaultRegion(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_DeployedRegion(t *testing.T) {
+	setupEnvHome(t)
+	t.Setenv("ALICLOUD_ACCESS_KEY_ID", "env-key-id")
+	t.Setenv("ALICLOUD_ACCESS_KEY_SECRET", "env-key-secret")
+	t.Setenv("ALICLOUD_REGION", "")
+	if err := config.SaveState(config.NewState("cn-hongkong", "")); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	cfg, err := alicloud.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.RegionID != "cn-hongkong" {
+		t.Errorf("deployed environment should use its state region, got %s", cfg.RegionID)
+	}
+
+	t.Setenv("ALICLOUD_REGION", "cn-hangzhou")
+	if cfg, _ = alicloud.LoadConfig(); cfg.RegionID != "cn-hangzhou" {
+		t.Errorf("ALICLOUD_REGION should override state region, got %s", cfg.RegionID)
+	}
+}
+
 func TestLoadConfig_CredentialsFile(t *testing.T) {
 	// 清除环境变量
 	t.Setenv("ALICLOUD_ACCESS_KEY_ID", "")
@@ -0,0 +1,261 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+)
+
+// migrateSource 模拟源区域：记录调用顺序
+type migrateSource struct {
+	calls   []string
+	status  string
+	copyErr error
+}
+
+func (s *migrateSource) mock() *MockECSAPI {
+	s.status = "Running"
+	return &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{{
+							InstanceId:   tea.String("i-test"),
+							Status:       tea.String(s.status),
+							InstanceType: tea.String("ecs.e-c1m2.large"),
+							ZoneId:       tea.String("ap-southeast-1a"),
+						}},
+					},
+				},
+			}, nil
+		},
+		StopInstanceFunc: func(req *ecsclient.StopInstanceRequest) (*ecsclient.StopInstanceResponse, error) {
+			s.calls = append(s.calls, "stop")
+			s.status = "Stopped"
+			return &ecsclient.StopInstanceResponse{}, nil
+		},
+		StartInstanceFunc: func(req *ecsclient.StartInstanceRequest) (*ecsclient.StartInstanceResponse, error) {
+			s.calls = append(s.calls, "start")
+			s.status = "Running"
+			return &ecsclient.StartInstanceResponse{}, nil
+		},
+		DescribeDisksFunc: func(req *ecsclient.DescribeDisksRequest) (*ecsclient.DescribeDisksResponse, error) {
+			return &ecsclient.DescribeDisksResponse{
+				Body: &ecsclient.DescribeDisksResponseBody{
+					Disks: &ecsclient.DescribeDisksResponseBodyDisks{
+						Disk: []*ecsclient.DescribeDisksResponseBodyDisksDisk{{DiskId: tea.String("d-src")}},
+					},
+				},
+			}, nil
+		},
+		CreateSnapshotFunc: func(req *ecsclient.CreateSnapshotRequest) (*ecsclient.CreateSnapshotResponse, error) {
+			s.calls = append(s.calls, "snapshot:"+*req.DiskId)
+			return &ecsclient.CreateSnapshotResponse{Body: &ecsclient.CreateSnapshotResponseBody{SnapshotId: tea.String("s-src")}}, nil
+		},
+		DescribeSnapshotsFunc: func(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error) {
+			return accomplishedSnapshot(), nil
+		},
+		CopySnapshotFunc: func(req *ecsclient.CopySnapshotRequest) (*ecsclient.CopySnapshotResponse, error) {
+			s.calls = append(s.calls, "copy:"+*req.SnapshotId+":"+*req.RegionId+"->"+*req.DestinationRegionId)
+			if s.copyErr != nil {
+				return nil, s.copyErr
+			}
+			return &ecsclient.CopySnapshotResponse{Body: &ecsclient.CopySnapshotResponseBody{SnapshotId: tea.String("s-dst")}}, nil
+		},
+		DeleteSnapshotFunc: func(req *ecsclient.DeleteSnapshotRequest) (*ecsclient.DeleteSnapshotResponse, error) {
+			s.calls = append(s.calls, "delete-snapshot:"+*req.SnapshotId)
+			return &ecsclient.DeleteSnapshotResponse{}, nil
+		},
+		DeleteInstanceFunc: func(req *ecsclient.DeleteInstanceRequest) (*ecsclient.DeleteInstanceResponse, error) {
+			s.calls = append(s.calls, "delete-instance:"+*req.InstanceId)
+			return &ecsclient.DeleteInstanceResponse{}, nil
+		},
+		DeleteKeyPairsFunc: func(req *ecsclient.DeleteKeyPairsRequest) (*ecsclient.DeleteKeyPairsResponse, error) {
+			s.calls = append(s.calls, "delete-keypair")
+			return &ecsclient.DeleteKeyPairsResponse{}, nil
+		},
+		DeleteSecurityGroupFunc: func(req *ecsclient.DeleteSecurityGroupRequest) (*ecsclient.DeleteSecurityGroupResponse, error) {
+			s.calls = append(s.calls, "delete-sg:"+*req.SecurityGroupId)
+			return &ecsclient.DeleteSecurityGroupResponse{}, nil
+		},
+	}
+}
+
+func accomplishedSnapshot() *ecsclient.DescribeSnapshotsResponse {
+	return &ecsclient.DescribeSnapshotsResponse{
+		Body: &ecsclient.DescribeSnapshotsResponseBody{
+			Snapshots: &ecsclient.DescribeSnapshotsResponseBodySnapshots{
+				Snapshot: []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{{Status: tea.String("accomplished")}},
+			},
+		},
+	}
+}
+
+// migrateTargetECS 目标区域：在 deployMockECS 基础上支持快照镜像和导入密钥对
+type migrateTargetECS struct {
+	deployMockECS
+	imported []string
+}
+
+func (m *migrateTargetECS) DescribeSnapshots(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error) {
+	return accomplishedSnapshot(), nil
+}
+
+func (m *migrateTargetECS) CreateImage(req *ecsclient.CreateImageRequest) (*ecsclient.CreateImageResponse, error) {
+	return &ecsclient.CreateImageResponse{Body: &ecsclient.CreateImageResponseBody{ImageId: tea.String("m-migrate")}}, nil
+}
+
+func (m *migrateTargetECS) DescribeImages(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error) {
+	return &ecsclient.DescribeImagesResponse{
+		Body: &ecsclient.DescribeImagesResponseBody{
+			Images: &ecsclient.DescribeImagesResponseBodyImages{
+				Image: []*ecsclient.DescribeImagesResponseBodyImagesImage{{ImageId: tea.String("m-migrate"), Status: tea.String("Available")}},
+			},
+		},
+	}, nil
+}
+
+func (m *migrateTargetECS) ImportKeyPair(req *ecsclient.ImportKeyPairRequest) (*ecsclient.ImportKeyPairResponse, error) {
+	m.imported = append(m.imported, *req.KeyPairName+"@"+*req.RegionId+":"+strings.Fields(*req.PublicKeyBody)[0])
+	return m.deployMockECS.ImportKeyPair(req)
+}
+
+// migrateTargetVPC 目标区域分配新的 EIP
+type migrateTargetVPC struct {
+	deployMockVPC
+}
+
+func (m *migrateTargetVPC) AllocateEipAddress(req *vpcclient.AllocateEipAddressRequest) (*vpcclient.AllocateEipAddressResponse, error) {
+	return &vpcclient.AllocateEipAddressResponse{
+		Body: &vpcclient.AllocateEipAddressResponseBody{AllocationId: tea.String("eip-hk"), EipAddress: tea.String("8.8.4.4")},
+	}, nil
+}
+
+func newTestMigrator(t *testing.T, source *migrateSource, input string) (*deploy.Migrator, *migrateTargetECS, string) {
+	t.Helper()
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+	writeRSAKey(t, filepath.Join(stateDir, config.SSHKeyFileName))
+
+	target := &migrateTargetECS{}
+	d := newTestDeployer("", "")
+	d.ECS = target
+	d.VPC = &migrateTargetVPC{}
+	d.Region = "cn-hongkong"
+	d.Env = "default"
+
+	out := &bytes.Buffer{}
+	return &deploy.Migrator{
+		ECS:          source.mock(),
+		VPC:          &deployMockVPC{},
+		Target:       d,
+		Prompter:     config.NewPrompter(strings.NewReader(input), out),
+		Output:       out,
+		Region:       "ap-southeast-1",
+		StateDir:     stateDir,
+		WaitInterval: 10 * time.Millisecond,
+		WaitTimeout:  time.Second,
+	}, target, stateDir
+}
+
+func TestMigrate_MovesDeploymentToTargetRegion(t *testing.T) {
+	source := &migrateSource{}
+	m, target, stateDir := newTestMigrator(t, source, "")
+
+	if err := m.Run(context.Background(), true); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, m.Output)
+	}
+
+	state, err := config.LoadStateFrom(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Region != "cn-hongkong" || state.Resources.ECS.ID != "i-test-001" || state.Resources.EIP.IP != "8.8.4.4" {
+		t.Errorf("state should describe the target deployment: %+v", state)
+	}
+	if state.CloudCode.Domain != "8.8.4.4.nip.io" || state.Status != "running" {
+		t.Errorf("nip.io domain should follow the new EIP, got %s (%s)", state.CloudCode.Domain, state.Status)
+	}
+	if state.Resources.SSHKeyPair.Name != "cloudcode-ssh-key" || len(target.imported) != 1 ||
+		target.imported[0] != "cloudcode-ssh-key@cn-hongkong:ssh-rsa" {
+		t.Errorf("existing public key should be imported into the target region: %v", target.imported)
+	}
+	if target.createReq == nil || *target.createReq.ImageId != "m-migrate" || *target.createReq.InstanceType != "ecs.e-c1m2.large" {
+		t.Errorf("target instance should be created from the copied snapshot with the same spec: %+v", target.createReq)
+	}
+
+	want := "stop,snapshot:d-src,copy:s-src:ap-southeast-1->cn-hongkong,delete-snapshot:s-src," +
+		"delete-instance:i-test,delete-keypair,delete-sg:sg-test"
+	if got := strings.Join(source.calls, ","); got != want {
+		t.Errorf("unexpected source calls:\n got %s\nwant %s", got, want)
+	}
+
+	catalog, _ := config.LoadBackupCatalogFrom(stateDir)
+	if b, err := catalog.Find("s-dst"); err != nil || b.Label != deploy.BackupLabelMigrate || b.Region != "cn-hongkong" {
+		t.Errorf("copied snapshot should be recorded as a migrate backup: %+v, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, deploy.MigrateDirName)); !os.IsNotExist(err) {
+		t.Error("migrate directory should be removed after cutover")
+	}
+}
+
+func TestMigrate_FailureBeforeInstanceRestartsSource(t *testing.T) {
+	source := &migrateSource{copyErr: errors.New("quota exceeded")}
+	m, target, stateDir := newTestMigrator(t, source, "")
+
+	err := m.Run(context.Background(), true)
+	if err == nil || !strings.Contains(err.Error(), "原部署未受影响") {
+		t.Fatalf("expected failure, got %v", err)
+	}
+	if got := strings.Join(source.calls, ","); !strings.HasSuffix(got, "delete-snapshot:s-src,start") {
+		t.Errorf("source instance should be restarted, got %s", got)
+	}
+	if len(target.createdInstances) != 0 {
+		t.Error("no target instance should be created")
+	}
+	state, _ := config.LoadStateFrom(stateDir)
+	if state.Region != "ap-southeast-1" || state.Resources.ECS.ID != "i-test" {
+		t.Errorf("original state should be untouched: %+v", state)
+	}
+
+	// 重新运行时复用已导入的密钥对
+	pending, err := config.LoadStateFrom(filepath.Join(stateDir, deploy.MigrateDirName))
+	if err != nil || pending.Region != "cn-hongkong" || !pending.HasSSHKeyPair() {
+		t.Errorf("pending target state should be kept for a retry: %+v, %v", pending, err)
+	}
+}
+
+func TestMigrate_Preconditions(t *testing.T) {
+	m, _, stateDir := newTestMigrator(t, &migrateSource{}, "n\n")
+	if err := m.Run(context.Background(), false); err != nil {
+		t.Errorf("cancel should not fail: %v", err)
+	}
+	if !strings.Contains(m.Output.(*bytes.Buffer).String(), "已取消") {
+		t.Error("expected cancel message")
+	}
+
+	m.Target.Region = "ap-southeast-1"
+	if err := m.Run(context.Background(), true); err == nil || !strings.Contains(err.Error(), "已在区域") {
+		t.Errorf("expected already-in-region error, got %v", err)
+	}
+
+	other := config.NewState("cn-beijing", "")
+	os.MkdirAll(filepath.Join(stateDir, deploy.MigrateDirName), 0700)
+	writeTestState(t, filepath.Join(stateDir, deploy.MigrateDirName), other)
+	m.Target.Region = "cn-hongkong"
+	if err := m.Run(context.Background(), true); err == nil || !strings.Contains(err.Error(), "--to-region cn-beijing") {
+		t.Errorf("expected unfinished migration error, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// newPoolTestServer 启动进程内 SSH 服务器，返回服务器、连接池和客户端私钥
+func newPoolTestServer(t *testing.T) (*testSSHServer, *remote.Pool, []byte) {
+	t.Helper()
+	hostKey, _ := newTestSigner(t)
+	_, clientKey := newTestSigner(t)
+	srv := startTestSSHServer(t, hostKey)
+	remote.SetHostKeyStore(&remote.MemoryHostKeyStore{})
+	pool := remote.NewPool()
+	t.Cleanup(func() { pool.Close() })
+	return srv, pool, clientKey
+}
+
+func TestPool_SharesConnectionAcrossPhases(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+	ctx := context.Background()
+
+	// 两个阶段各自 dial + Close，复用同一个 TCP 连接
+	for i := 0; i < 2; i++ {
+		client, err := remote.WaitForSSH(ctx, pool.DialFunc(srv.Host, srv.Port, "root", key), remote.WaitSSHOptions{Timeout: 5 * time.Second})
+		if err != nil {
+			t.Fatalf("dial %d: %v", i, err)
+		}
+		if out, err := client.RunCommand(ctx, "echo hi"); err != nil || out != "ran: echo hi" {
+			t.Fatalf("RunCommand = %q, %v", out, err)
+		}
+		client.Close()
+	}
+
+	// SFTP 在同一个连接上打开子系统
+	remotePath := filepath.Join(t.TempDir(), "sub", "file.txt")
+	sftpClient, err := pool.NewSFTPClient(srv.Host, srv.Port, "root", key)
+	if err != nil {
+		t.Fatalf("NewSFTPClient: %v", err)
+	}
+	if err := sftpClient.UploadFile([]byte("hello"), remotePath); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	sftpClient.Close()
+	if data, _ := os.ReadFile(remotePath); string(data) != "hello" {
+		t.Errorf("uploaded content = %q", data)
+	}
+
+	if n := srv.Conns(); n != 1 {
+		t.Errorf("TCP connections = %d, want 1", n)
+	}
+}
+
+func TestPool_SeparateConnectionPerKey(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+	_, otherKey := newTestSigner(t)
+
+	for _, k := range [][]byte{key, otherKey} {
+		client, err := pool.DialFunc(srv.Host, srv.Port, "root", k)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+	}
+	if n := srv.Conns(); n != 2 {
+		t.Errorf("TCP connections = %d, want 2", n)
+	}
+}
+
+func TestPool_ReconnectsAfterDrop(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+	ctx := context.Background()
+
+	client, err := pool.DialFunc(srv.Host, srv.Port, "root", key)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	srv.DropAll()
+	// 同一个客户端在连接断开后继续可用
+	var out string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		out, err = client.RunCommand(ctx, "echo again")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil || out != "ran: echo again" {
+		t.Fatalf("RunCommand after drop = %q, %v", out, err)
+	}
+	if n := srv.Conns(); n != 2 {
+		t.Errorf("TCP connections = %d, want 2 (reconnected)", n)
+	}
+}
+
+func TestPool_SendsKeepalive(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+	pool.KeepaliveInterval = 20 * time.Millisecond
+
+	client, err := pool.DialFunc(srv.Host, srv.Port, "root", key)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	waitForEvent(t, srv, "global keepalive@openssh.com")
+	// keepalive 被拒绝（服务器不认识该请求）不影响连接
+	if _, err := client.RunCommand(context.Background(), "echo hi"); err != nil {
+		t.Fatalf("RunCommand: %v", err)
+	}
+	if n := srv.Conns(); n != 1 {
+		t.Errorf("TCP connections = %d, want 1", n)
+	}
+}
+
+func TestPool_CloseDisconnects(t *testing.T) {
+	srv, pool, key := newPoolTestServer(t)
+
+	client, err := pool.DialFunc(srv.Host, srv.Port, "root", key)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+
+	if _, err := client.RunCommand(context.Background(), "echo hi"); err == nil {
+		t.Error("expected error after pool is closed")
+	}
+	if _, err := pool.DialFunc(srv.Host, srv.Port, "root", key)(); err == nil {
+		t.Error("expected dial error after pool is closed")
+	}
+}
@@ -3,6 +3,8 @@ package unit
 import (
 	"context"
 	"errors"
+	"io"
+	"os"
 	"testing"
 	"time"
 
@@ -12,14 +14,19 @@ import (
 // --- Mock implementations ---
 
 type MockSSHClient struct {
-	RunCommandFunc func(ctx context.Context, cmd string) (string, error)
-	CloseFunc      func() error
+	RunCommandFunc    func(ctx context.Context, cmd string) (string, error)
+	StreamCommandFunc func(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error
+	CloseFunc         func() error
 }
 
 func (m *MockSSHClient) RunCommand(ctx context.Context, cmd string) (string, error) {
 	return m.RunCommandFunc(ctx, cmd)
 }
 
+func (m *MockSSHClient) StreamCommand(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	return m.StreamCommandFunc(ctx, cmd, stdin, stdout)
+}
+
 func (m *MockSSHClient) Close() error {
 	if m.CloseFunc != nil {
 		return m.CloseFunc()
@@ -29,6 +36,7 @@ func (m *MockSSHClient) Close() error {
 
 type MockSFTPClient struct {
 	UploadFileFunc func(localContent []byte, remotePath string) error
+	ReadFileFunc   func(remotePath string) ([]byte, error)
 	CloseFunc      func() error
 }
 
@@ -36,6 +44,13 @@ func (m *MockSFTPClient) UploadFile(localContent []byte, remotePath string) erro
 	return m.UploadFileFunc(localContent, remotePath)
 }
 
+func (m *MockSFTPClient) ReadFile(remotePath string) ([]byte, error) {
+	if m.ReadFileFunc != nil {
+		return m.ReadFileFunc(remotePath)
+	}
+	return nil, os.ErrNotExist
+}
+
 func (m *MockSFTPClient) Close() error {
 	if m.CloseFunc != nil {
 		return m.CloseFunc()
@@ -0,0 +1,215 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// resizeCloud 记录变更流程中的 ECS 调用，实例状态随 Stop / Start 变化
+type resizeCloud struct {
+	calls     []string
+	status    string
+	modifyErr error
+}
+
+func (c *resizeCloud) mock() *MockECSAPI {
+	c.status = "Running"
+	return &MockECSAPI{
+		StopInstanceFunc: func(req *ecsclient.StopInstanceRequest) (*ecsclient.StopInstanceResponse, error) {
+			c.calls = append(c.calls, fmt.Sprintf("stop:charging=%v", req.StoppedMode == nil))
+			c.status = "Stopped"
+			return &ecsclient.StopInstanceResponse{}, nil
+		},
+		StartInstanceFunc: func(req *ecsclient.StartInstanceRequest) (*ecsclient.StartInstanceResponse, error) {
+			c.calls = append(c.calls, "start")
+			c.status = "Running"
+			return &ecsclient.StartInstanceResponse{}, nil
+		},
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{Body: &ecsclient.DescribeInstancesResponseBody{
+				Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+					Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{{
+						InstanceId:   tea.String("i-test"),
+						Status:       tea.String(c.status),
+						InstanceType: tea.String("ecs.g7.xlarge"),
+						ZoneId:       tea.String("ap-southeast-1a"),
+					}},
+				},
+			}}, nil
+		},
+		ModifyInstanceSpecFunc: func(req *ecsclient.ModifyInstanceSpecRequest) (*ecsclient.ModifyInstanceSpecResponse, error) {
+			c.calls = append(c.calls, "modify:"+c.status+":"+tea.StringValue(req.InstanceType))
+			if c.modifyErr != nil {
+				return nil, c.modifyErr
+			}
+			return &ecsclient.ModifyInstanceSpecResponse{}, nil
+		},
+		DescribeDisksFunc: func(req *ecsclient.DescribeDisksRequest) (*ecsclient.DescribeDisksResponse, error) {
+			return &ecsclient.DescribeDisksResponse{Body: &ecsclient.DescribeDisksResponseBody{
+				Disks: &ecsclient.DescribeDisksResponseBodyDisks{
+					Disk: []*ecsclient.DescribeDisksResponseBodyDisksDisk{{DiskId: tea.String("d-sys")}},
+				},
+			}}, nil
+		},
+		ResizeDiskFunc: func(req *ecsclient.ResizeDiskRequest) (*ecsclient.ResizeDiskResponse, error) {
+			c.calls = append(c.calls, fmt.Sprintf("resize:%s:%s:%d:%s", c.status, tea.StringValue(req.DiskId), tea.Int32Value(req.NewSize), tea.StringValue(req.Type)))
+			return &ecsclient.ResizeDiskResponse{}, nil
+		},
+	}
+}
+
+func newTestResizer(t *testing.T, cloud *resizeCloud, state *config.State, spec config.CloudSpec, input string, out *bytes.Buffer) (*deploy.Resizer, *[]string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestState(t, dir, state)
+	writeDummySSHKey(t, dir)
+	var commands []string
+	return &deploy.Resizer{
+		ECS:      cloud.mock(),
+		Prompter: config.NewPrompter(strings.NewReader(input), out),
+		Output:   out,
+		Region:   "ap-southeast-1",
+		StateDir: dir,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return func() (remote.SSHClient, error) {
+				return &MockSSHClient{
+					RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+						commands = append(commands, cmd)
+						return "99G\n", nil
+					},
+				}, nil
+			}
+		},
+		Prices: &cost.PriceTable{Currency: "USD", Regions: map[string]*cost.RegionPrices{
+			"ap-southeast-1": {
+				InstanceTypes: map[string]float64{"ecs.e-c1m2.large": 1, "ecs.g7.xlarge": 2},
+				Disks:         map[string]float64{"cloud_essd": 0.1},
+			},
+		}},
+		Spec:         spec,
+		WaitInterval: time.Millisecond,
+		WaitTimeout:  time.Second,
+	}, &commands
+}
+
+func TestResize_DryRunShowsCostDiff(t *testing.T) {
+	cloud := &resizeCloud{}
+	var out bytes.Buffer
+	r, _ := newTestResizer(t, cloud, fullState(), config.CloudSpec{InstanceType: "ecs.g7.xlarge", DiskSize: 100}, "", &out)
+
+	if err := r.Run(context.Background(), true, false); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// 运行中: 1×730 + 60×0.1 = 736 → 2×730 + 100×0.1 = 1470
+	for _, want := range []string{"ecs.e-c1m2.large → ecs.g7.xlarge", "60GB → 100GB", "$736.00/月 → $1470.00/月（+$734.00/月）", "dry-run"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if len(cloud.calls) != 0 {
+		t.Errorf("dry-run should not call ECS: %v", cloud.calls)
+	}
+}
+
+func TestResize_Success(t *testing.T) {
+	cloud := &resizeCloud{}
+	var out bytes.Buffer
+	running := fullState()
+	running.Status = "running"
+	r, commands := newTestResizer(t, cloud, running, config.CloudSpec{InstanceType: "ecs.g7.xlarge", DiskSize: 100}, "y\n", &out)
+
+	if err := r.Run(context.Background(), false, false); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+	want := "stop:charging=true,modify:Stopped:ecs.g7.xlarge,resize:Stopped:d-sys:100:offline,start"
+	if got := strings.Join(cloud.calls, ","); got != want {
+		t.Errorf("unexpected calls:\n got %s\nwant %s", got, want)
+	}
+	if len(*commands) != 1 || !strings.Contains((*commands)[0], "growpart") || !strings.Contains((*commands)[0], "resize2fs") {
+		t.Errorf("expected filesystem grow over SSH, got %v", *commands)
+	}
+	if !strings.Contains(out.String(), "/ 共 99G") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	state, err := loadStateFrom(t, r.StateDir)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if state.Resources.ECS.InstanceType != "ecs.g7.xlarge" || state.Resources.ECS.SystemDiskSize != 100 {
+		t.Errorf("state not updated: %+v", state.Resources.ECS)
+	}
+	if state.Status != "running" {
+		t.Errorf("status should stay running, got %s", state.Status)
+	}
+}
+
+func TestResize_ModifyFailureRestartsInstance(t *testing.T) {
+	cloud := &resizeCloud{modifyErr: fmt.Errorf("OperationDenied.NoStock")}
+	var out bytes.Buffer
+	r, commands := newTestResizer(t, cloud, fullState(), config.CloudSpec{InstanceType: "ecs.g7.xlarge", DiskSize: 100}, "", &out)
+
+	err := r.Run(context.Background(), false, true)
+	if err == nil || !strings.Contains(err.Error(), "NoStock") {
+		t.Fatalf("expected modify error, got %v", err)
+	}
+	if got := strings.Join(cloud.calls, ","); got != "stop:charging=true,modify:Stopped:ecs.g7.xlarge,start" {
+		t.Errorf("instance should be restarted without resizing the disk, got %s", got)
+	}
+	if len(*commands) != 0 {
+		t.Errorf("filesystem should not be grown: %v", *commands)
+	}
+	state, _ := loadStateFrom(t, r.StateDir)
+	if state.Resources.ECS.InstanceType != "ecs.e-c1m2.large" {
+		t.Errorf("state should keep the old instance type, got %s", state.Resources.ECS.InstanceType)
+	}
+}
+
+func TestResize_Preconditions(t *testing.T) {
+	suspended := fullState()
+	suspended.Status = "suspended"
+	sized := fullState()
+	sized.Resources.ECS.SystemDiskSize = 80
+
+	tests := []struct {
+		name    string
+		state   *config.State
+		spec    config.CloudSpec
+		wantErr string
+		wantOut string
+	}{
+		{"no flags", fullState(), config.CloudSpec{}, "--instance-type", ""},
+		{"suspended", suspended, config.CloudSpec{DiskSize: 100}, "cloudcode resume", ""},
+		{"shrink", sized, config.CloudSpec{DiskSize: 60}, "只能扩容", ""},
+		{"unchanged", sized, config.CloudSpec{InstanceType: "ecs.e-c1m2.large", DiskSize: 80}, "", "规格未变化"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud := &resizeCloud{}
+			var out bytes.Buffer
+			r, _ := newTestResizer(t, cloud, tt.state, tt.spec, "", &out)
+			err := r.Run(context.Background(), false, true)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil || !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("expected %q, got %v:\n%s", tt.wantOut, err, out.String())
+			}
+			if len(cloud.calls) != 0 {
+				t.Errorf("no ECS calls expected: %v", cloud.calls)
+			}
+		})
+	}
+}
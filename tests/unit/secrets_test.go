@@ -0,0 +1,273 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// secretsRemote 记录上传的文件和执行的命令
+type secretsRemote struct {
+	uploads  map[string]string
+	commands []string
+}
+
+func (r *secretsRemote) manager(stateDir string, output *bytes.Buffer) *deploy.SecretsManager {
+	r.uploads = make(map[string]string)
+	return &deploy.SecretsManager{
+		Output:   output,
+		StateDir: stateDir,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return func() (remote.SSHClient, error) {
+				return &MockSSHClient{
+					RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+						r.commands = append(r.commands, cmd)
+						return "", nil
+					},
+				}, nil
+			}
+		},
+		SFTPFactory: func(host string, port int, user string, privateKey []byte) (remote.SFTPClient, error) {
+			return &MockSFTPClient{
+				UploadFileFunc: func(content []byte, remotePath string) error {
+					r.uploads[remotePath] = string(content)
+					return nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestSecretsFile_RoundTripAndMode(t *testing.T) {
+	dir := t.TempDir()
+	secrets := map[string]string{"OPENAI_API_KEY": "sk-a=b", "GEMINI_API_KEY": "gm-1"}
+	if err := config.SaveSecretsTo(dir, secrets); err != nil {
+		t.Fatalf("SaveSecretsTo failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, config.SecretsFileName))
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("secrets file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := config.LoadSecretsFrom(dir)
+	if err != nil {
+		t.Fatalf("LoadSecretsFrom failed: %v", err)
+	}
+	if len(loaded) != 2 || loaded["OPENAI_API_KEY"] != "sk-a=b" || loaded["GEMINI_API_KEY"] != "gm-1" {
+		t.Errorf("unexpected secrets: %v", loaded)
+	}
+}
+
+func TestSecretsFile_Missing(t *testing.T) {
+	secrets, err := config.LoadSecretsFrom(t.TempDir())
+	if err != nil || len(secrets) != 0 {
+		t.Errorf("missing file should yield empty secrets, got %v, %v", secrets, err)
+	}
+}
+
+func TestValidateSecretName(t *testing.T) {
+	for _, name := range []string{"OPENAI_API_KEY", "DEEPSEEK_API_KEY", "X1"} {
+		if err := config.ValidateSecretName(name); err != nil {
+			t.Errorf("%s should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "openai_api_key", "1KEY", "MY-KEY", "A B"} {
+		if err := config.ValidateSecretName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	if got := config.MaskSecret("sk-proj-abcdefghijkl1234"); got != "sk-p...1234" {
+		t.Errorf("MaskSecret = %q", got)
+	}
+	if got := config.MaskSecret("short"); strings.Contains(got, "short") {
+		t.Errorf("short secret should be fully masked, got %q", got)
+	}
+}
+
+func TestSecretsSet_PushesEnvAndRecreatesDevbox(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+	writeDummySSHKey(t, stateDir)
+	if err := config.SaveSecretsTo(stateDir, map[string]string{"OPENAI_API_KEY": "sk-old"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &secretsRemote{}
+	output := &bytes.Buffer{}
+	if err := r.manager(stateDir, output).Set(context.Background(), "DEEPSEEK_API_KEY", "ds-123"); err != nil {
+		t.Fatalf("Set failed: %v\n%s", err, output.String())
+	}
+
+	env := r.uploads["/root/cloudcode/.env"]
+	if !strings.Contains(env, "DEEPSEEK_API_KEY=ds-123") || !strings.Contains(env, "OPENAI_API_KEY=sk-old") {
+		t.Errorf("unexpected .env:\n%s", env)
+	}
+	if len(r.uploads) != 1 {
+		t.Errorf("only .env should be uploaded, got %v", r.uploads)
+	}
+	if len(r.commands) != 1 || !strings.Contains(r.commands[0], "--no-deps") || !strings.Contains(r.commands[0], "grep '^devbox'") {
+		t.Errorf("should recreate only devbox, got %v", r.commands)
+	}
+
+	saved, _ := config.LoadSecretsFrom(stateDir)
+	if saved["DEEPSEEK_API_KEY"] != "ds-123" {
+		t.Errorf("secret not saved locally: %v", saved)
+	}
+}
+
+func TestSecretsSet_InvalidName(t *testing.T) {
+	r := &secretsRemote{}
+	if err := r.manager(t.TempDir(), &bytes.Buffer{}).Set(context.Background(), "gemini", "x"); err == nil {
+		t.Error("expected error for lowercase name")
+	}
+}
+
+func TestSecretsSet_SuspendedSavesLocally(t *testing.T) {
+	stateDir := t.TempDir()
+	state := fullState()
+	state.Status = "suspended"
+	writeTestState(t, stateDir, state)
+
+	r := &secretsRemote{}
+	output := &bytes.Buffer{}
+	if err := r.manager(stateDir, output).Set(context.Background(), "GEMINI_API_KEY", "gm-1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if len(r.uploads) != 0 || len(r.commands) != 0 {
+		t.Errorf("suspended instance should not be contacted: %v %v", r.uploads, r.commands)
+	}
+	if !strings.Contains(output.String(), "已停机") {
+		t.Errorf("expected suspended hint:\n%s", output.String())
+	}
+	saved, _ := config.LoadSecretsFrom(stateDir)
+	if saved["GEMINI_API_KEY"] != "gm-1" {
+		t.Errorf("secret not saved locally: %v", saved)
+	}
+}
+
+func TestSecretsUnset(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+	writeDummySSHKey(t, stateDir)
+	if err := config.SaveSecretsTo(stateDir, map[string]string{"OPENAI_API_KEY": "sk-1", "ANTHROPIC_API_KEY": "sk-ant"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &secretsRemote{}
+	m := r.manager(stateDir, &bytes.Buffer{})
+	if err := m.Unset(context.Background(), "OPENAI_API_KEY"); err != nil {
+		t.Fatalf("Unset failed: %v", err)
+	}
+	env := r.uploads["/root/cloudcode/.env"]
+	if strings.Contains(env, "OPENAI_API_KEY") || !strings.Contains(env, "ANTHROPIC_API_KEY=sk-ant") {
+		t.Errorf("unexpected .env:\n%s", env)
+	}
+	if err := m.Unset(context.Background(), "OPENAI_API_KEY"); err == nil {
+		t.Error("unsetting a missing secret should fail")
+	}
+}
+
+func TestSecretsList_MasksValues(t *testing.T) {
+	stateDir := t.TempDir()
+	if err := config.SaveSecretsTo(stateDir, map[string]string{
+		"OPENAI_API_KEY":  "sk-proj-abcdefghijkl1234",
+		"OPENAI_BASE_URL": "https://llm.example.com/v1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &secretsRemote{}
+	output := &bytes.Buffer{}
+	if err := r.manager(stateDir, output).List(); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	out := output.String()
+	if strings.Contains(out, "abcdefghijkl") || !strings.Contains(out, "sk-p...1234") {
+		t.Errorf("API key should be masked:\n%s", out)
+	}
+	if !strings.Contains(out, "https://llm.example.com/v1") {
+		t.Errorf("base URL should be shown as is:\n%s", out)
+	}
+	if !strings.Contains(out, "GEMINI_API_KEY") {
+		t.Errorf("unset providers should be suggested:\n%s", out)
+	}
+}
+
+func TestDeployRunApp_KeepsLocalSecrets(t *testing.T) {
+	stateDir := t.TempDir()
+	state := fullState()
+	state.Status = "running"
+	writeTestState(t, stateDir, state)
+	writeDummySSHKey(t, stateDir)
+	if err := config.SaveSecretsTo(stateDir, map[string]string{"ANTHROPIC_API_KEY": "sk-ant"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var env string
+	d := newTestDeployer(stateDir, "")
+	d.SFTPFactory = func(host string, port int, user string, privateKey []byte) (remote.SFTPClient, error) {
+		return &MockSFTPClient{
+			UploadFileFunc: func(content []byte, remotePath string) error {
+				if strings.HasSuffix(remotePath, "/.env") {
+					env = string(content)
+				}
+				return nil
+			},
+		}, nil
+	}
+
+	if err := d.Run(context.Background(), true); err != nil {
+		t.Fatalf("Run --app failed: %v", err)
+	}
+	if !strings.Contains(env, "ANTHROPIC_API_KEY=sk-ant") {
+		t.Errorf("deploy --app should keep configured keys, .env:\n%s", env)
+	}
+}
+
+func TestPromptConfig_AsksProviderKeys(t *testing.T) {
+	d := newTestDeployer(t.TempDir(), "\nadmin\npassword1\npassword1\nsk-openai\nhttps://llm.example.com/v1\n\n")
+
+	cfg, err := d.PromptConfig(context.Background())
+	if err != nil {
+		t.Fatalf("PromptConfig failed: %v", err)
+	}
+	if cfg.Secrets["OPENAI_API_KEY"] != "sk-openai" || cfg.Secrets["OPENAI_BASE_URL"] != "https://llm.example.com/v1" {
+		t.Errorf("unexpected secrets: %v", cfg.Secrets)
+	}
+	if _, ok := cfg.Secrets["ANTHROPIC_API_KEY"]; ok {
+		t.Errorf("empty Anthropic key should be skipped: %v", cfg.Secrets)
+	}
+}
+
+func TestPromptConfig_SkipsKeysWhenConfigured(t *testing.T) {
+	stateDir := t.TempDir()
+	if err := config.SaveSecretsTo(stateDir, map[string]string{"OPENAI_API_KEY": "sk-1"}); err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDeployer(stateDir, "\nadmin\npassword1\npassword1\n")
+
+	cfg, err := d.PromptConfig(context.Background())
+	if err != nil {
+		t.Fatalf("PromptConfig failed: %v", err)
+	}
+	if len(cfg.Secrets) != 0 {
+		t.Errorf("configured env should not prompt for keys: %v", cfg.Secrets)
+	}
+	if !strings.Contains(d.Output.(*bytes.Buffer).String(), "已配置 1 个 API Key") {
+		t.Errorf("expected configured hint:\n%s", d.Output.(*bytes.Buffer).String())
+	}
+}
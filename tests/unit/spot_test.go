@@ -0,0 +1,355 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/hwuu/cloudcode/internal/agent"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/cost"
+	"github.com/hwuu/cloudcode/internal/deploy"
+)
+
+func TestInstanceSpec_SpotStrategy(t *testing.T) {
+	tests := []struct {
+		spec alicloud.InstanceSpec
+		want string
+	}{
+		{alicloud.InstanceSpec{}, "NoSpot"},
+		{alicloud.InstanceSpec{Spot: true}, "SpotAsPriceGo"},
+		{alicloud.InstanceSpec{Spot: true, SpotPriceLimit: 0.05}, "SpotWithPriceLimit"},
+	}
+	for _, tt := range tests {
+		if got := tt.spec.SpotStrategy(); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestCreateResources_Spot(t *testing.T) {
+	stateDir := t.TempDir()
+	mockECS := &deployMockECS{}
+	d := newTestDeployer(stateDir, "")
+	d.ECS = mockECS
+	d.Spec = config.CloudSpec{Spot: true, SpotPriceLimit: 0.05}
+
+	state := config.NewState("ap-southeast-1", "")
+	if err := d.CreateResources(context.Background(), state, ""); err != nil {
+		t.Fatalf("CreateResources failed: %v", err)
+	}
+	req := mockECS.createReq
+	if tea.StringValue(req.SpotStrategy) != "SpotWithPriceLimit" || tea.Float32Value(req.SpotPriceLimit) != float32(0.05) {
+		t.Errorf("expected spot request with price limit, got %v / %v", tea.StringValue(req.SpotStrategy), tea.Float32Value(req.SpotPriceLimit))
+	}
+	if !state.Resources.ECS.Spot || state.Resources.ECS.SpotPriceLimit != 0.05 {
+		t.Errorf("state should record spot mode: %+v", state.Resources.ECS)
+	}
+}
+
+func TestCloudSpec_ValidateSpot(t *testing.T) {
+	if err := (config.CloudSpec{Spot: true, SpotPriceLimit: 0.1}).Validate(); err != nil {
+		t.Errorf("valid spot spec rejected: %v", err)
+	}
+	if err := (config.CloudSpec{SpotPriceLimit: 0.1}).Validate(); err == nil || !strings.Contains(err.Error(), "spot: true") {
+		t.Errorf("price limit without spot should be rejected, got %v", err)
+	}
+	if err := (config.CloudSpec{Spot: true, SpotPriceLimit: -1}).Validate(); err == nil {
+		t.Error("negative price limit should be rejected")
+	}
+}
+
+func TestAgentPolicyDocument_Spot(t *testing.T) {
+	plain := alicloud.AgentPolicyDocument("ap-southeast-1", "123", "i-test", false)
+	if strings.Contains(plain, "ecs:CreateSnapshot") {
+		t.Errorf("non-spot policy should not allow snapshots: %s", plain)
+	}
+	spot := alicloud.AgentPolicyDocument("ap-southeast-1", "123", "i-test", true)
+	for _, want := range []string{"ecs:CreateSnapshot", "ecs:DescribeDisks", "acs:ecs:ap-southeast-1:123:snapshot/*"} {
+		if !strings.Contains(spot, want) {
+			t.Errorf("spot policy missing %q: %s", want, spot)
+		}
+	}
+}
+
+// --- agent 回收监测 ---
+
+func TestSpotWatcher_SnapshotsOnNotice(t *testing.T) {
+	var snapshots []*ecsclient.CreateSnapshotRequest
+	ecs := &MockECSAPI{
+		DescribeDisksFunc: func(req *ecsclient.DescribeDisksRequest) (*ecsclient.DescribeDisksResponse, error) {
+			return &ecsclient.DescribeDisksResponse{Body: &ecsclient.DescribeDisksResponseBody{
+				Disks: &ecsclient.DescribeDisksResponseBodyDisks{
+					Disk: []*ecsclient.DescribeDisksResponseBodyDisksDisk{{DiskId: tea.String("d-sys")}},
+				},
+			}}, nil
+		},
+		CreateSnapshotFunc: func(req *ecsclient.CreateSnapshotRequest) (*ecsclient.CreateSnapshotResponse, error) {
+			snapshots = append(snapshots, req)
+			return &ecsclient.CreateSnapshotResponse{Body: &ecsclient.CreateSnapshotResponseBody{SnapshotId: tea.String("s-spot")}}, nil
+		},
+	}
+	notice := false
+	flushed := 0
+	w := &agent.SpotWatcher{
+		Config: agent.Config{InstanceID: "i-test", Region: "ap-southeast-1", RoleName: "r", Spot: true, DeploymentID: "abc123", Env: "default"},
+		ECS:    ecs,
+		Notice: func(ctx context.Context) (time.Time, bool, error) {
+			return time.Now().Add(5 * time.Minute), notice, nil
+		},
+		Flush:   func(ctx context.Context) error { flushed++; return errors.New("docker not running") },
+		Output:  &bytes.Buffer{},
+		Version: "0.3.0",
+	}
+	ctx := context.Background()
+
+	if done, err := w.Check(ctx); err != nil || done || len(snapshots) != 0 {
+		t.Fatalf("no snapshot expected before notice, got %v, %v", done, err)
+	}
+	notice = true
+	// 停止容器失败仍创建快照
+	if done, err := w.Check(ctx); err != nil || !done {
+		t.Fatalf("expected snapshot on notice, got %v, %v", done, err)
+	}
+	if flushed != 1 || len(snapshots) != 1 || tea.StringValue(snapshots[0].DiskId) != "d-sys" {
+		t.Fatalf("expected one flush and one snapshot of d-sys, got %d / %+v", flushed, snapshots)
+	}
+	tags := map[string]string{}
+	for _, tag := range snapshots[0].Tag {
+		tags[tea.StringValue(tag.Key)] = tea.StringValue(tag.Value)
+	}
+	if tags[alicloud.TagKeyDeploymentID] != "abc123" || tags[alicloud.TagKeyEnv] != "default" {
+		t.Errorf("snapshot should carry deployment tags: %v", tags)
+	}
+	// 只创建一次
+	w.Check(ctx)
+	if len(snapshots) != 1 || flushed != 1 {
+		t.Errorf("snapshot should be created once, got %d", len(snapshots))
+	}
+}
+
+func TestAutoSuspendSet_SpotAgentConfig(t *testing.T) {
+	inst := &autoSuspendInstance{}
+	state := fullState()
+	state.DeploymentID = "abc123"
+	state.Resources.ECS.Spot = true
+	var policyDoc string
+	ram := &MockRAMAPI{CreatePolicyFunc: func(name, doc, desc string) error {
+		policyDoc = doc
+		return nil
+	}}
+	m := inst.manager(t, &bytes.Buffer{}, state, ram, &MockECSAPI{})
+	m.Env = "staging"
+
+	if err := m.Set(context.Background(), &config.AutoSuspendPolicy{Idle: "30m"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if !strings.Contains(policyDoc, "ecs:CreateSnapshot") {
+		t.Errorf("spot role should allow snapshots: %s", policyDoc)
+	}
+	var agentCfg agent.Config
+	if err := json.Unmarshal([]byte(inst.files[agent.DefaultConfigPath]), &agentCfg); err != nil {
+		t.Fatalf("agent config not uploaded: %v", err)
+	}
+	if !agentCfg.Spot || agentCfg.DeploymentID != "abc123" || agentCfg.Env != "staging" {
+		t.Errorf("unexpected agent config: %+v", agentCfg)
+	}
+
+	// 关闭自动停机：抢占式实例保留 agent 和角色，只清空策略
+	inst.commands = nil
+	if err := m.Disable(context.Background()); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	var spotOnly agent.Config
+	if err := json.Unmarshal([]byte(inst.files[agent.DefaultConfigPath]), &spotOnly); err != nil {
+		t.Fatal(err)
+	}
+	if !spotOnly.Spot || spotOnly.Policy.Enabled() {
+		t.Errorf("agent should keep watching without a policy: %+v", spotOnly)
+	}
+	if strings.Contains(strings.Join(ram.Calls, ","), "DeleteRole") {
+		t.Errorf("role should be kept for spot instances: %v", ram.Calls)
+	}
+	saved, _ := config.LoadStateFrom(m.StateDir)
+	if saved.CloudCode.AutoSuspend != nil || saved.Resources.RAMRole.Name == "" {
+		t.Errorf("state should clear policy but keep role: %+v %+v", saved.CloudCode.AutoSuspend, saved.Resources.RAMRole)
+	}
+}
+
+// --- 回收检测与重建 ---
+
+func TestReconcileStatus_SpotReclaimed(t *testing.T) {
+	// DescribeInstances 返回空列表：实例已不存在
+	ecs := &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{Body: &ecsclient.DescribeInstancesResponseBody{}}, nil
+		},
+	}
+	stateDir := t.TempDir()
+	state := fullState()
+	state.Status = "running"
+	state.Resources.ECS.Spot = true
+	state.Resources.ECS.SystemDiskSize = 60
+	writeTestState(t, stateDir, state)
+	out := &bytes.Buffer{}
+
+	changed, err := deploy.ReconcileStatus(ecs, "ap-southeast-1", stateDir, out)
+	if err != nil || !changed {
+		t.Fatalf("expected state change, got %v, %v", changed, err)
+	}
+	saved, _ := config.LoadStateFrom(stateDir)
+	if saved.Status != "reclaimed" || saved.Resources.ECS.ID != "" || !strings.Contains(out.String(), "cloudcode deploy") {
+		t.Errorf("expected reclaimed with deploy hint, got %q %+v: %s", saved.Status, saved.Resources.ECS, out.String())
+	}
+	if !saved.Resources.ECS.Spot || saved.Resources.ECS.SystemDiskSize != 60 || saved.Resources.EIP.ID != "eip-test" {
+		t.Errorf("spec and EIP should be kept for rebuild: %+v", saved.Resources)
+	}
+
+	// 非抢占式实例不存在时报错，不改 state
+	state.Resources.ECS.Spot = false
+	writeTestState(t, stateDir, state)
+	if _, err := deploy.ReconcileStatus(ecs, "ap-southeast-1", stateDir, out); err == nil {
+		t.Error("missing on-demand instance should be reported as an error")
+	}
+}
+
+// spotRebuildECS 在 deployMockECS 基础上返回带部署标签的快照和快照镜像
+type spotRebuildECS struct {
+	deployMockECS
+	snapshots []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot
+	imageFrom string
+}
+
+func (m *spotRebuildECS) DescribeSnapshots(req *ecsclient.DescribeSnapshotsRequest) (*ecsclient.DescribeSnapshotsResponse, error) {
+	return &ecsclient.DescribeSnapshotsResponse{Body: &ecsclient.DescribeSnapshotsResponseBody{
+		Snapshots: &ecsclient.DescribeSnapshotsResponseBodySnapshots{Snapshot: m.snapshots},
+	}}, nil
+}
+
+func (m *spotRebuildECS) CreateImage(req *ecsclient.CreateImageRequest) (*ecsclient.CreateImageResponse, error) {
+	m.imageFrom = tea.StringValue(req.SnapshotId)
+	return &ecsclient.CreateImageResponse{Body: &ecsclient.CreateImageResponseBody{ImageId: tea.String("m-spot")}}, nil
+}
+
+func (m *spotRebuildECS) DescribeImages(req *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error) {
+	return &ecsclient.DescribeImagesResponse{Body: &ecsclient.DescribeImagesResponseBody{
+		Images: &ecsclient.DescribeImagesResponseBodyImages{
+			Image: []*ecsclient.DescribeImagesResponseBodyImagesImage{{ImageId: tea.String("m-spot"), Status: tea.String("Available")}},
+		},
+	}}, nil
+}
+
+// spotRebuildVPC 记录 EIP 绑定，分配新 EIP 时报错（重建必须沿用原 EIP）
+type spotRebuildVPC struct {
+	deployMockVPC
+	associated []string
+}
+
+func (m *spotRebuildVPC) AllocateEipAddress(req *vpcclient.AllocateEipAddressRequest) (*vpcclient.AllocateEipAddressResponse, error) {
+	return nil, errors.New("unexpected EIP allocation")
+}
+
+func (m *spotRebuildVPC) AssociateEipAddress(req *vpcclient.AssociateEipAddressRequest) (*vpcclient.AssociateEipAddressResponse, error) {
+	m.associated = append(m.associated, tea.StringValue(req.AllocationId)+"->"+tea.StringValue(req.InstanceId))
+	return &vpcclient.AssociateEipAddressResponse{}, nil
+}
+
+func reclaimedState() *config.State {
+	state := fullState()
+	state.DeploymentID = "abc123"
+	state.Resources.ECS = config.ECSResource{InstanceType: "ecs.e-c1m2.large", SystemDiskSize: 60, SystemDiskCategory: "cloud_essd", Spot: true}
+	state.SetStatus("reclaimed")
+	return state
+}
+
+func TestDeploy_RebuildsReclaimedSpotInstance(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, reclaimedState())
+	writeDummySSHKey(t, stateDir)
+
+	ecs := &spotRebuildECS{snapshots: []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{
+		{SnapshotId: tea.String("s-backup"), Status: tea.String("accomplished"), CreationTime: tea.String("2026-10-17T01:00:00Z"), SourceDiskSize: tea.String("60")},
+		{SnapshotId: tea.String("s-spot"), Status: tea.String("accomplished"), CreationTime: tea.String("2026-10-18T03:00:00Z"), SourceDiskSize: tea.String("80")},
+		{SnapshotId: tea.String("s-pending"), Status: tea.String("progressing"), CreationTime: tea.String("2026-10-18T04:00:00Z"), SourceDiskSize: tea.String("80")},
+	}}
+	vpc := &spotRebuildVPC{}
+	d := newTestDeployer(stateDir, "")
+	d.ECS = ecs
+	d.VPC = vpc
+
+	if err := d.Run(context.Background(), false); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, d.Output)
+	}
+
+	if ecs.imageFrom != "s-spot" {
+		t.Errorf("instance should be rebuilt from the latest accomplished snapshot, got %q", ecs.imageFrom)
+	}
+	req := ecs.createReq
+	if tea.StringValue(req.SpotStrategy) != "SpotAsPriceGo" || tea.Int32Value(req.SystemDisk.Size) != 80 {
+		t.Errorf("rebuilt instance should stay spot with the snapshot's disk size: %v %d",
+			tea.StringValue(req.SpotStrategy), tea.Int32Value(req.SystemDisk.Size))
+	}
+	if len(vpc.associated) != 1 || vpc.associated[0] != "eip-test->i-test-001" {
+		t.Errorf("existing EIP should be bound to the new instance: %v", vpc.associated)
+	}
+
+	state, _ := config.LoadStateFrom(stateDir)
+	if state.Status != "running" || state.Resources.ECS.ID != "i-test-001" || !state.Resources.ECS.Spot {
+		t.Errorf("unexpected state after rebuild: %s %+v", state.Status, state.Resources.ECS)
+	}
+	if state.CloudCode.Domain != "47.100.1.1.nip.io" || state.Resources.EIP.IP != "47.100.1.1" {
+		t.Errorf("domain and EIP should be unchanged: %s %s", state.CloudCode.Domain, state.Resources.EIP.IP)
+	}
+	catalog, _ := config.LoadBackupCatalogFrom(stateDir)
+	if b, err := catalog.Find("s-spot"); err != nil || b.Label != deploy.BackupLabelSpot {
+		t.Errorf("spot snapshot should be recorded in the catalog: %+v, %v", b, err)
+	}
+}
+
+func TestDeploy_RebuildReclaimedHintsWakeEnable(t *testing.T) {
+	stateDir := t.TempDir()
+	state := reclaimedState()
+	state.Resources.Wake = config.WakeResource{FunctionName: "cloudcode-wake-abc123", Domain: "wake.code.example.com"}
+	writeTestState(t, stateDir, state)
+	writeDummySSHKey(t, stateDir)
+
+	d := newTestDeployer(stateDir, "")
+	d.ECS = &spotRebuildECS{snapshots: []*ecsclient.DescribeSnapshotsResponseBodySnapshotsSnapshot{
+		{SnapshotId: tea.String("s-spot"), Status: tea.String("accomplished"), CreationTime: tea.String("2026-10-18T03:00:00Z"), SourceDiskSize: tea.String("60")},
+	}}
+	d.VPC = &spotRebuildVPC{}
+
+	if err := d.Run(context.Background(), false); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, d.Output)
+	}
+	if out := d.Output.(*bytes.Buffer).String(); !strings.Contains(out, "cloudcode wake enable") {
+		t.Errorf("rebuild should ask to refresh the wake page:\n%s", out)
+	}
+}
+
+func TestDeploy_ReclaimedWithoutSnapshot(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, reclaimedState())
+	d := newTestDeployer(stateDir, "")
+	d.ECS = &spotRebuildECS{}
+
+	err := d.Run(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), "未找到本部署的快照") {
+		t.Fatalf("expected missing snapshot error, got %v", err)
+	}
+}
+
+func TestEstimate_ReclaimedHourly(t *testing.T) {
+	e := &cost.Estimate{Instance: 1, Disk: 0.1, EIP: 0.02, Snapshot: 0.01}
+	if got := e.Hourly("reclaimed"); got != 0.03 {
+		t.Errorf("reclaimed should only charge EIP and snapshots, got %v", got)
+	}
+}
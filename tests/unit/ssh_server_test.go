@@ -0,0 +1,272 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestSigner 生成 ed25519 密钥，返回 signer 和 PEM 格式私钥
+func newTestSigner(t *testing.T) (ssh.Signer, []byte) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, pem.EncodeToMemory(block)
+}
+
+// testSSHServer 进程内 SSH 服务器，接受任意公钥认证。exec 命令：
+//   - "exit N"：以状态 N 退出
+//   - "cat"：将 stdin 原样输出，stdin 结束后退出
+//   - "wait"：等待 signal 请求，收到后以该信号终止（exit-signal）
+//   - 其他：输出 "ran: <cmd>" 后以 0 退出
+//
+// shell 请求输出 "shell" 后以 0 退出；sftp 子系统直接读写本机文件系统；
+// direct-tcpip 通道连接本机上的目标地址并双向转发（可作为跳板机），记录为 "direct-tcpip host:port"。
+// pty-req / window-change / signal 等会话请求和 keepalive 等全局请求记录在 Events 中。
+type testSSHServer struct {
+	Host string
+	Port int
+
+	mu     sync.Mutex
+	events []string
+	conns  []net.Conn
+}
+
+// Conns 返回已接受的 TCP 连接数
+func (s *testSSHServer) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// DropAll 断开所有已建立的连接（模拟网络中断）
+func (s *testSSHServer) DropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+}
+
+// Events 返回收到的会话请求记录（如 "pty-req xterm 100x40"、"window-change 120x50"、"signal INT"）
+func (s *testSSHServer) Events() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+func (s *testSSHServer) record(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprintf(format, args...))
+}
+
+func startTestSSHServer(t *testing.T, hostKey ssh.Signer) *testSSHServer {
+	t.Helper()
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	addr := ln.Addr().(*net.TCPAddr)
+	srv := &testSSHServer{Host: addr.IP.String(), Port: addr.Port}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.mu.Lock()
+			srv.conns = append(srv.conns, conn)
+			srv.mu.Unlock()
+			go srv.serveConn(conn, cfg)
+		}
+	}()
+	return srv
+}
+
+func (s *testSSHServer) serveConn(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go func() {
+		for req := range reqs {
+			s.record("global %s", req.Type)
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}()
+	for newCh := range chans {
+		if newCh.ChannelType() == "direct-tcpip" {
+			go s.serveDirectTCPIP(newCh)
+			continue
+		}
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go s.serveSession(ch, chReqs)
+	}
+}
+
+func (s *testSSHServer) serveDirectTCPIP(newCh ssh.NewChannel) {
+	var p struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	ssh.Unmarshal(newCh.ExtraData(), &p)
+	addr := net.JoinHostPort(p.Host, strconv.Itoa(int(p.Port)))
+	s.record("direct-tcpip %s", addr)
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		newCh.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		io.Copy(ch, target)
+		ch.CloseWrite()
+	}()
+	io.Copy(target, ch)
+	target.Close()
+	ch.Close()
+}
+
+func (s *testSSHServer) serveSession(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close()
+	signals := make(chan string, 1)
+	exited := make(chan struct{})
+	exit := func(status uint32) {
+		ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		close(exited)
+	}
+
+	for {
+		var req *ssh.Request
+		select {
+		case req = <-reqs:
+		case <-exited:
+			return
+		}
+		if req == nil {
+			return
+		}
+		switch req.Type {
+		case "pty-req":
+			var p struct {
+				Term                string
+				Columns, Rows, W, H uint32
+				Modes               string
+			}
+			ssh.Unmarshal(req.Payload, &p)
+			s.record("pty-req %s %dx%d", p.Term, p.Columns, p.Rows)
+			req.Reply(true, nil)
+		case "window-change":
+			var p struct{ Columns, Rows, W, H uint32 }
+			ssh.Unmarshal(req.Payload, &p)
+			s.record("window-change %dx%d", p.Columns, p.Rows)
+		case "signal":
+			var p struct{ Signal string }
+			ssh.Unmarshal(req.Payload, &p)
+			s.record("signal %s", p.Signal)
+			select {
+			case signals <- p.Signal:
+			default:
+			}
+		case "subsystem":
+			var p struct{ Name string }
+			ssh.Unmarshal(req.Payload, &p)
+			s.record("subsystem %s", p.Name)
+			if p.Name != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			go func() {
+				server, err := sftp.NewServer(ch)
+				if err == nil {
+					server.Serve()
+					server.Close()
+				}
+				exit(0)
+			}()
+		case "shell":
+			s.record("shell")
+			req.Reply(true, nil)
+			fmt.Fprint(ch, "shell")
+			exit(0)
+		case "exec":
+			var p struct{ Command string }
+			ssh.Unmarshal(req.Payload, &p)
+			s.record("exec %s", p.Command)
+			req.Reply(true, nil)
+			go s.runExec(ch, p.Command, signals, exit)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func (s *testSSHServer) runExec(ch ssh.Channel, cmd string, signals <-chan string, exit func(uint32)) {
+	var status int
+	switch {
+	case strings.HasPrefix(cmd, "exit "):
+		status, _ = strconv.Atoi(strings.TrimPrefix(cmd, "exit "))
+	case cmd == "cat":
+		io.Copy(ch, ch)
+	case cmd == "wait":
+		sig := <-signals
+		ch.SendRequest("exit-signal", false, ssh.Marshal(struct {
+			Signal     string
+			CoreDumped bool
+			Error      string
+			Lang       string
+		}{Signal: sig}))
+		ch.Close()
+		return
+	default:
+		fmt.Fprintf(ch, "ran: %s", cmd)
+	}
+	exit(uint32(status))
+}
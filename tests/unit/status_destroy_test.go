@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 	"github.com/hwuu/cloudcode/internal/config"
 	"github.com/hwuu/cloudcode/internal/deploy"
 	"github.com/hwuu/cloudcode/internal/remote"
+	"gopkg.in/yaml.v3"
 )
 
 func writeTestState(t *testing.T, stateDir string, state *config.State) {
@@ -118,6 +120,172 @@ func TestStatus_WithContainers(t *testing.T) {
 	}
 }
 
+func statusMockSSH(psOutput string) deploy.SSHDialFactory {
+	return func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+		return func() (remote.SSHClient, error) {
+			return &MockSSHClient{
+				RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+					return psOutput, nil
+				},
+			}, nil
+		}
+	}
+}
+
+func statusMockECS(status string) *MockECSAPI {
+	return &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+							{
+								InstanceId:   teaString("i-test"),
+								InstanceType: teaString("ecs.e-c1m2.large"),
+								ZoneId:       teaString("ap-southeast-1a"),
+								Status:       teaString(status),
+							},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestStatus_JSONHealthy(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+	writeDummySSHKey(t, stateDir)
+
+	output := &bytes.Buffer{}
+	s := &deploy.StatusRunner{
+		Output:      output,
+		StateDir:    stateDir,
+		Format:      deploy.OutputJSON,
+		ECS:         statusMockECS("Running"),
+		SSHDialFunc: statusMockSSH("caddy running\nauthelia running\ndevbox running\n"),
+	}
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report deploy.StatusReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output.String())
+	}
+	if report.SchemaVersion != deploy.StatusSchemaVersion {
+		t.Errorf("schema_version = %q", report.SchemaVersion)
+	}
+	if !report.Healthy || len(report.Problems) != 0 {
+		t.Errorf("expected healthy, problems: %v", report.Problems)
+	}
+	if report.Status != "running" {
+		t.Errorf("status = %q, want running", report.Status)
+	}
+	if report.Resources.ECSID != "i-test" || report.Resources.EIP != "47.100.1.1" {
+		t.Errorf("unexpected resources: %+v", report.Resources)
+	}
+	if report.Instance == nil || report.Instance.State != "Running" {
+		t.Errorf("unexpected instance: %+v", report.Instance)
+	}
+	if report.App == nil || report.App.URL != "https://47.100.1.1.nip.io" {
+		t.Errorf("unexpected app: %+v", report.App)
+	}
+	if len(report.Containers) != 3 || report.Containers[2].Name != "devbox" {
+		t.Errorf("unexpected containers: %+v", report.Containers)
+	}
+}
+
+func TestStatus_JSONUnhealthyContainer(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+	writeDummySSHKey(t, stateDir)
+
+	output := &bytes.Buffer{}
+	s := &deploy.StatusRunner{
+		Output:      output,
+		StateDir:    stateDir,
+		Format:      deploy.OutputJSON,
+		SSHDialFunc: statusMockSSH("caddy running\nauthelia exited\n"),
+	}
+
+	err := s.Run(context.Background())
+	if !errors.Is(err, deploy.ErrUnhealthy) {
+		t.Fatalf("expected ErrUnhealthy, got: %v", err)
+	}
+
+	var report deploy.StatusReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Healthy || len(report.Problems) != 1 || !strings.Contains(report.Problems[0], "authelia") {
+		t.Errorf("unexpected problems: %v", report.Problems)
+	}
+}
+
+func TestStatus_YAMLInstanceStopped(t *testing.T) {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, fullState())
+
+	output := &bytes.Buffer{}
+	s := &deploy.StatusRunner{
+		Output:   output,
+		StateDir: stateDir,
+		Format:   deploy.OutputYAML,
+		ECS:      statusMockECS("Stopped"),
+	}
+
+	err := s.Run(context.Background())
+	if !errors.Is(err, deploy.ErrUnhealthy) {
+		t.Fatalf("expected ErrUnhealthy, got: %v", err)
+	}
+
+	var report deploy.StatusReport
+	if err := yaml.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("invalid YAML: %v\n%s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "schema_version: \"1\"") {
+		t.Errorf("expected schema_version in YAML, got:\n%s", output.String())
+	}
+	if report.Instance == nil || report.Instance.State != "Stopped" {
+		t.Errorf("unexpected instance: %+v", report.Instance)
+	}
+}
+
+func TestStatus_JSONNoState(t *testing.T) {
+	output := &bytes.Buffer{}
+	s := &deploy.StatusRunner{
+		Output:   output,
+		StateDir: t.TempDir(),
+		Format:   deploy.OutputJSON,
+	}
+
+	err := s.Run(context.Background())
+	if !errors.Is(err, deploy.ErrUnhealthy) {
+		t.Fatalf("expected ErrUnhealthy, got: %v", err)
+	}
+	var report deploy.StatusReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Deployed || report.Status != "not_deployed" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestStatus_InvalidFormat(t *testing.T) {
+	s := &deploy.StatusRunner{
+		Output:   &bytes.Buffer{},
+		StateDir: t.TempDir(),
+		Format:   "xml",
+	}
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
 // --- Destroy Tests ---
 
 func TestDestroy_NoState(t *testing.T) {
@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// streamingSSHClient 支持实时输出的 mock SSH 连接
+type streamingSSHClient struct {
+	MockSSHClient
+	StreamingFunc func(ctx context.Context, cmd string, stdout, stderr io.Writer) error
+}
+
+func (m *streamingSSHClient) RunCommandStreaming(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	return m.StreamingFunc(ctx, cmd, stdout, stderr)
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := remote.NewLineWriter(func(line string) { lines = append(lines, line) })
+
+	fmt.Fprint(w, "first\r\nsec")
+	fmt.Fprint(w, "ond\n\nthi")
+	if got := strings.Join(lines, "|"); got != "first|second|" {
+		t.Errorf("lines before flush = %q", got)
+	}
+	w.Flush()
+	w.Flush()
+	if got := strings.Join(lines, "|"); got != "first|second||thi" {
+		t.Errorf("lines after flush = %q", got)
+	}
+}
+
+func TestRunCommandStreaming_RealClient(t *testing.T) {
+	_, client := dialTestSSHServer(t)
+
+	var out bytes.Buffer
+	if err := remote.RunCommandStreaming(context.Background(), client, "echo hi", &out, &out); err != nil {
+		t.Fatalf("RunCommandStreaming: %v", err)
+	}
+	if out.String() != "ran: echo hi" {
+		t.Errorf("output = %q", out.String())
+	}
+
+	err := remote.RunCommandStreaming(context.Background(), client, "exit 2", &out, nil)
+	var exitErr *remote.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Status != 2 {
+		t.Errorf("expected ExitError status 2, got %v", err)
+	}
+}
+
+func TestRunCommand_ReturnsTypedExitError(t *testing.T) {
+	_, client := dialTestSSHServer(t)
+
+	_, err := client.RunCommand(context.Background(), "exit 4")
+	var exitErr *remote.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Status != 4 {
+		t.Fatalf("expected wrapped ExitError status 4, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "远程命令退出码 4") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRunCommandStreaming_FallbackToRunCommand(t *testing.T) {
+	client := &MockSSHClient{
+		RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+			return "buffered\n", nil
+		},
+	}
+	var out bytes.Buffer
+	if err := remote.RunCommandStreaming(context.Background(), client, "ls", &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "buffered\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestDeployApp_StreamsProgress(t *testing.T) {
+	stateDir := t.TempDir()
+	writeDummySSHKey(t, stateDir)
+	d := newTestDeployer(stateDir, "")
+	autheliaRemote(d, "")
+
+	var streamed []string
+	d.SSHDialFunc = func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+		return func() (remote.SSHClient, error) {
+			return &streamingSSHClient{
+				MockSSHClient: MockSSHClient{
+					RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+						return "", nil
+					},
+				},
+				StreamingFunc: func(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+					streamed = append(streamed, cmd)
+					if strings.Contains(cmd, "docker compose pull caddy") {
+						fmt.Fprint(stdout, " caddy Pulling\n")
+						fmt.Fprint(stderr, " caddy Pulled")
+					}
+					return nil
+				},
+			}, nil
+		}
+	}
+
+	cfg := &deploy.DeployConfig{Domain: "47.100.1.1.nip.io", Username: "admin", Password: "test-password", Email: "admin@localhost"}
+	if err := d.DeployApp(context.Background(), autheliaTestState(), cfg); err != nil {
+		t.Fatalf("DeployApp failed: %v", err)
+	}
+
+	if len(streamed) != 5 {
+		t.Errorf("streamed commands = %v, want docker install + 3 pulls + compose up", streamed)
+	}
+	output := d.Output.(*bytes.Buffer).String()
+	if !strings.Contains(output, "\n     caddy Pulling\n     caddy Pulled\n") {
+		t.Errorf("progress lines not shown:\n%s", output)
+	}
+}
+
+func TestDeployApp_StreamingFailureKeepsExitStatus(t *testing.T) {
+	stateDir := t.TempDir()
+	writeDummySSHKey(t, stateDir)
+	d := newTestDeployer(stateDir, "")
+	autheliaRemote(d, "")
+	d.SSHDialFunc = func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+		return func() (remote.SSHClient, error) {
+			return &streamingSSHClient{
+				MockSSHClient: MockSSHClient{
+					RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+						return "", nil
+					},
+				},
+				StreamingFunc: func(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+					if strings.Contains(cmd, "get.docker.com") {
+						fmt.Fprint(stderr, "curl: (6) Could not resolve host\n")
+						return &remote.ExitError{Status: 6}
+					}
+					return nil
+				},
+			}, nil
+		}
+	}
+
+	cfg := &deploy.DeployConfig{Domain: "47.100.1.1.nip.io", Username: "admin", Password: "test-password"}
+	err := d.DeployApp(context.Background(), autheliaTestState(), cfg)
+	var exitErr *remote.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Status != 6 {
+		t.Fatalf("expected ExitError status 6, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "安装 Docker 失败") {
+		t.Errorf("error = %v", err)
+	}
+	if !strings.Contains(d.Output.(*bytes.Buffer).String(), "    curl: (6) Could not resolve host\n") {
+		t.Error("stderr should be shown as progress")
+	}
+}
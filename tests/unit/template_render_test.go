@@ -15,10 +15,13 @@ func testData() *tmpl.TemplateData {
 		Email:                "admin@example.com",
 		SessionSecret:        "test-session-secret",
 		StorageEncryptionKey: "test-storage-key",
-		OpenAIAPIKey:         "sk-test-key",
-		OpenAIBaseURL:        "https://api.openai.com/v1",
-		AnthropicAPIKey:      "sk-ant-test",
-		Version:              "0.2.0-dev",
+		Env: map[string]string{
+			"OPENAI_API_KEY":    "sk-test-key",
+			"OPENAI_BASE_URL":   "https://api.openai.com/v1",
+			"ANTHROPIC_API_KEY": "sk-ant-test",
+			"GEMINI_API_KEY":    "gm-test",
+		},
+		Version: "0.2.0-dev",
 	}
 }
 
@@ -94,11 +97,18 @@ func TestRenderEnv(t *testing.T) {
 	if !strings.Contains(s, "ANTHROPIC_API_KEY=sk-ant-test") {
 		t.Error("env should contain Anthropic API key")
 	}
+	if !strings.Contains(s, "GEMINI_API_KEY=gm-test") {
+		t.Error("env should contain extra provider key")
+	}
+	// 按变量名排序输出
+	if strings.Index(s, "ANTHROPIC_API_KEY") > strings.Index(s, "OPENAI_API_KEY") {
+		t.Errorf("env should be sorted by name:\n%s", s)
+	}
 }
 
 func TestRenderEnv_OptionalFieldsEmpty(t *testing.T) {
 	data := &tmpl.TemplateData{
-		OpenAIAPIKey: "sk-test-key",
+		Env: map[string]string{"OPENAI_API_KEY": "sk-test-key"},
 	}
 	content, err := tmpl.RenderTemplate("templates/env.tmpl", data)
 	if err != nil {
@@ -145,6 +155,24 @@ func TestRenderAutheliaConfig(t *testing.T) {
 	}
 }
 
+func TestRenderAutheliaConfig_CustomSettings(t *testing.T) {
+	data := testData()
+	data.SessionExpiration = "7d"
+	data.SessionInactivity = "2h"
+	data.AccessPolicy = "one_factor"
+	content, err := tmpl.RenderTemplate("templates/authelia/configuration.yml.tmpl", data)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	s := string(content)
+	for _, want := range []string{"expiration: 7d", "inactivity: 2h", "policy: one_factor"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("authelia config should contain %q", want)
+		}
+	}
+}
+
 func TestRenderAutheliaUsersDB(t *testing.T) {
 	data := testData()
 	content, err := tmpl.RenderTemplate("templates/authelia/users_database.yml.tmpl", data)
@@ -189,6 +217,65 @@ func TestRenderDockerCompose(t *testing.T) {
 	}
 }
 
+func TestRenderCaddyfile_PerUserDevbox(t *testing.T) {
+	data := testData()
+	data.DevboxUsers, _ = tmpl.NewDevboxUsers([]string{"admin", "bob.lee"})
+	content, err := tmpl.RenderTemplate("templates/Caddyfile.tmpl", data)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	s := string(content)
+	for _, want := range []string{
+		"header Remote-User admin",
+		"reverse_proxy @user0_4096 devbox-admin:4096",
+		"header Remote-User bob.lee",
+		"reverse_proxy @user1_7681 devbox-bob_lee:7681",
+		`respond "当前账号没有分配 devbox" 403`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Caddyfile should contain %q:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "reverse_proxy devbox:") {
+		t.Error("per-user Caddyfile should not proxy to the shared devbox")
+	}
+	if strings.Index(s, "forward_auth") > strings.Index(s, "header Remote-User admin") {
+		t.Error("forward_auth should run before Remote-User matching")
+	}
+}
+
+func TestNewDevboxUsers_RejectsServiceCollision(t *testing.T) {
+	if _, err := tmpl.NewDevboxUsers([]string{"a.b", "a_b"}); err == nil || !strings.Contains(err.Error(), "devbox-a_b") {
+		t.Errorf("expected collision error for a.b and a_b, got %v", err)
+	}
+	if got := tmpl.DevboxServiceConflict("a.b", []string{"admin", "a_b"}); got != "a_b" {
+		t.Errorf("DevboxServiceConflict = %q, want a_b", got)
+	}
+	if got := tmpl.DevboxServiceConflict("a.b", []string{"a.b", "a-b"}); got != "" {
+		t.Errorf("DevboxServiceConflict = %q, want none", got)
+	}
+}
+
+func TestRenderDockerCompose_PerUserDevbox(t *testing.T) {
+	data := testData()
+	data.DevboxUsers, _ = tmpl.NewDevboxUsers([]string{"admin", "bob"})
+	content, err := tmpl.RenderTemplate("templates/docker-compose.yml.tmpl", data)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	s := string(content)
+	for _, want := range []string{"devbox-admin:", "devbox-bob:", "devbox-bob_workspace:", "devbox-admin_config:"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("docker-compose should contain %q:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "\n  devbox:") {
+		t.Error("per-user compose should not define the shared devbox service")
+	}
+}
+
 func TestRenderAll(t *testing.T) {
 	data := testData()
 	files, err := tmpl.RenderAll(data)
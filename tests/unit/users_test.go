@@ -0,0 +1,291 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+const testUsersDB = `users:
+  admin:
+    displayname: "admin"
+    password: "$argon2id$v=19$m=65536,t=1,p=8$c2FsdA$aGFzaA"
+    email: "admin@localhost"
+    groups:
+      - admins
+  bob:
+    displayname: "Bob"
+    password: "$argon2id$v=19$m=65536,t=1,p=8$b2xk$b2xk"
+    email: "bob@example.com"
+    given_name: Bob
+`
+
+// usersInstance 模拟实例上的文件系统和命令执行
+type usersInstance struct {
+	files    map[string]string
+	commands []string
+}
+
+func newUsersInstance(autheliaConfig string) *usersInstance {
+	return &usersInstance{files: map[string]string{
+		"/root/cloudcode/authelia/users_database.yml": testUsersDB,
+		"/root/cloudcode/authelia/configuration.yml":  autheliaConfig,
+	}}
+}
+
+func (inst *usersInstance) manager(t *testing.T, output *bytes.Buffer) *deploy.UserManager {
+	return inst.managerWithState(t, output, fullState())
+}
+
+func (inst *usersInstance) managerWithState(t *testing.T, output *bytes.Buffer, state *config.State) *deploy.UserManager {
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, state)
+	writeDummySSHKey(t, stateDir)
+	return &deploy.UserManager{
+		Output:   output,
+		StateDir: stateDir,
+		SSHDialFunc: func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+			return func() (remote.SSHClient, error) {
+				return &MockSSHClient{
+					RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+						inst.commands = append(inst.commands, cmd)
+						return "", nil
+					},
+				}, nil
+			}
+		},
+		SFTPFactory: func(host string, port int, user string, privateKey []byte) (remote.SFTPClient, error) {
+			return &MockSFTPClient{
+				UploadFileFunc: func(content []byte, remotePath string) error {
+					inst.files[remotePath] = string(content)
+					return nil
+				},
+				ReadFileFunc: func(remotePath string) ([]byte, error) {
+					content, ok := inst.files[remotePath]
+					if !ok {
+						return nil, os.ErrNotExist
+					}
+					return []byte(content), nil
+				},
+			}, nil
+		},
+	}
+}
+
+func (inst *usersInstance) users(t *testing.T) *config.UsersDatabase {
+	t.Helper()
+	db, err := config.ParseUsersDatabase([]byte(inst.files["/root/cloudcode/authelia/users_database.yml"]))
+	if err != nil {
+		t.Fatalf("parse users db: %v", err)
+	}
+	return db
+}
+
+func TestUserAdd_KeepsExistingUsers(t *testing.T) {
+	inst := newUsersInstance("authentication_backend:\n  file:\n    watch: true\n")
+	output := &bytes.Buffer{}
+	spec := deploy.UserSpec{Username: "carol", Password: "carol-password", Groups: []string{"dev"}}
+	if err := inst.manager(t, output).Add(context.Background(), spec); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	db := inst.users(t)
+	carol := db.Users["carol"]
+	if carol == nil || carol.Email != "carol@localhost" || carol.DisplayName != "carol" || strings.Join(carol.Groups, ",") != "dev" {
+		t.Fatalf("unexpected carol: %+v", carol)
+	}
+	if !strings.HasPrefix(carol.Password, "$argon2id$") {
+		t.Errorf("password should be hashed, got %q", carol.Password)
+	}
+	if db.Users["admin"] == nil || db.Users["bob"] == nil || db.Users["bob"].Extra["given_name"] != "Bob" {
+		t.Errorf("existing users and fields should be preserved:\n%s", inst.files["/root/cloudcode/authelia/users_database.yml"])
+	}
+	if len(inst.commands) != 0 {
+		t.Errorf("watching Authelia should not be restarted, got %v", inst.commands)
+	}
+}
+
+func TestUserAdd_Existing(t *testing.T) {
+	inst := newUsersInstance("")
+	err := inst.manager(t, &bytes.Buffer{}).Add(context.Background(), deploy.UserSpec{Username: "bob", Password: "bob-password"})
+	if err == nil || !strings.Contains(err.Error(), "已存在") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestUserAdd_InvalidInput(t *testing.T) {
+	inst := newUsersInstance("")
+	m := inst.manager(t, &bytes.Buffer{})
+	for _, spec := range []deploy.UserSpec{
+		{Username: "Bad Name", Password: "long-enough"},
+		{Username: "dave", Password: "short"},
+		{Username: "dave", Password: "long-enough", Groups: []string{"Admins!"}},
+	} {
+		if err := m.Add(context.Background(), spec); err == nil {
+			t.Errorf("expected error for %+v", spec)
+		}
+	}
+}
+
+func TestUserPasswd_RestartsAutheliaWithoutWatch(t *testing.T) {
+	inst := newUsersInstance("authentication_backend:\n  file:\n    path: /config/users_database.yml\n")
+	oldHash := inst.users(t).Users["bob"].Password
+
+	if err := inst.manager(t, &bytes.Buffer{}).Passwd(context.Background(), "bob", "new-password"); err != nil {
+		t.Fatalf("Passwd failed: %v", err)
+	}
+	if got := inst.users(t).Users["bob"].Password; got == oldHash || !strings.HasPrefix(got, "$argon2id$") {
+		t.Errorf("password not updated: %q", got)
+	}
+	if len(inst.commands) != 1 || !strings.Contains(inst.commands[0], "restart authelia") {
+		t.Errorf("expected Authelia restart, got %v", inst.commands)
+	}
+}
+
+func TestUserRm(t *testing.T) {
+	inst := newUsersInstance("")
+	m := inst.manager(t, &bytes.Buffer{})
+
+	if err := m.Remove(context.Background(), "admin"); err == nil || !strings.Contains(err.Error(), "唯一的管理员") {
+		t.Errorf("removing the last admin should fail, got %v", err)
+	}
+	if err := m.Remove(context.Background(), "bob"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if db := inst.users(t); db.Users["bob"] != nil || db.Users["admin"] == nil {
+		t.Errorf("unexpected users after rm: %v", db.SortedUsernames())
+	}
+	if err := m.Remove(context.Background(), "bob"); err == nil {
+		t.Error("removing a missing user should fail")
+	}
+}
+
+func TestUserList(t *testing.T) {
+	inst := newUsersInstance("")
+	output := &bytes.Buffer{}
+	if err := inst.manager(t, output).List(context.Background()); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	out := output.String()
+	if !strings.Contains(out, "admin") || !strings.Contains(out, "admins") || !strings.Contains(out, "bob@example.com") {
+		t.Errorf("unexpected list output:\n%s", out)
+	}
+	if strings.Contains(out, "argon2id") {
+		t.Errorf("password hashes should not be listed:\n%s", out)
+	}
+}
+
+func TestUserManager_SuspendedInstance(t *testing.T) {
+	inst := newUsersInstance("")
+	m := inst.manager(t, &bytes.Buffer{})
+	state := fullState()
+	state.Status = "suspended"
+	writeTestState(t, m.StateDir, state)
+
+	if err := m.List(context.Background()); err == nil || !strings.Contains(err.Error(), "resume") {
+		t.Errorf("expected suspended error, got %v", err)
+	}
+}
+
+func TestUserAdd_PerUserDevbox(t *testing.T) {
+	inst := newUsersInstance("authentication_backend:\n  file:\n    watch: true\n")
+	state := fullState()
+	state.CloudCode.DevboxMode = config.DevboxModePerUser
+	state.CloudCode.ImageVersion = "0.3.0"
+	m := inst.managerWithState(t, &bytes.Buffer{}, state)
+
+	if err := m.Add(context.Background(), deploy.UserSpec{Username: "carol", Password: "carol-password"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	compose := inst.files["/root/cloudcode/docker-compose.yml"]
+	for _, svc := range []string{"devbox-admin:", "devbox-bob:", "devbox-carol:"} {
+		if !strings.Contains(compose, svc) {
+			t.Errorf("compose should contain %s:\n%s", svc, compose)
+		}
+	}
+	if !strings.Contains(compose, "cloudcode-devbox:0.3.0") {
+		t.Error("compose should keep the deployed image version")
+	}
+	if !strings.Contains(inst.files["/root/cloudcode/Caddyfile"], "header Remote-User carol") {
+		t.Error("Caddyfile should route carol")
+	}
+	if len(inst.commands) != 2 || !strings.HasSuffix(inst.commands[0], "up -d --no-deps devbox-carol") || !strings.Contains(inst.commands[1], "caddy reload") {
+		t.Errorf("expected devbox-carol start and caddy reload, got %v", inst.commands)
+	}
+
+	inst.commands = nil
+	if err := m.Remove(context.Background(), "carol"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if strings.Contains(inst.files["/root/cloudcode/docker-compose.yml"], "devbox-carol:") {
+		t.Error("compose should drop carol's devbox")
+	}
+	if len(inst.commands) != 2 || !strings.Contains(inst.commands[0], "caddy reload") || inst.commands[1] != "docker rm -f devbox-carol" {
+		t.Errorf("expected caddy reload and devbox-carol removal, got %v", inst.commands)
+	}
+}
+
+func TestUserAdd_RejectsDevboxNameCollision(t *testing.T) {
+	inst := newUsersInstance("authentication_backend:\n  file:\n    watch: true\n")
+	inst.files["/root/cloudcode/authelia/users_database.yml"] = testUsersDB + `  a.b:
+    displayname: "a.b"
+    password: "$argon2id$v=19$m=65536,t=1,p=8$b2xk$b2xk"
+`
+	state := fullState()
+	state.CloudCode.DevboxMode = config.DevboxModePerUser
+	m := inst.managerWithState(t, &bytes.Buffer{}, state)
+
+	err := m.Add(context.Background(), deploy.UserSpec{Username: "a_b", Password: "a_b-password"})
+	if err == nil || !strings.Contains(err.Error(), "devbox-a_b") {
+		t.Fatalf("expected devbox name collision error, got %v", err)
+	}
+	if _, ok := inst.users(t).Users["a_b"]; ok {
+		t.Error("colliding user should not be written")
+	}
+	if len(inst.commands) != 0 {
+		t.Errorf("no commands should run, got %v", inst.commands)
+	}
+}
+
+func TestUserRm_KeepsCollidingDevboxOfOtherUser(t *testing.T) {
+	// 旧版本允许添加的 a.b 与 a_b 共用 devbox-a_b：删除其中一个不能删掉另一个人的容器
+	inst := newUsersInstance("authentication_backend:\n  file:\n    watch: true\n")
+	inst.files["/root/cloudcode/authelia/users_database.yml"] = testUsersDB + `  a.b:
+    displayname: "a.b"
+    password: "$argon2id$v=19$m=65536,t=1,p=8$b2xk$b2xk"
+  a_b:
+    displayname: "a_b"
+    password: "$argon2id$v=19$m=65536,t=1,p=8$b2xk$b2xk"
+`
+	state := fullState()
+	state.CloudCode.DevboxMode = config.DevboxModePerUser
+	if err := inst.managerWithState(t, &bytes.Buffer{}, state).Remove(context.Background(), "a.b"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	for _, cmd := range inst.commands {
+		if strings.HasPrefix(cmd, "docker rm") {
+			t.Errorf("devbox-a_b still belongs to a_b, got %q", cmd)
+		}
+	}
+	if !strings.Contains(inst.files["/root/cloudcode/docker-compose.yml"], "devbox-a_b:") {
+		t.Error("compose should keep a_b's devbox")
+	}
+}
+
+func TestUserPasswd_PerUserDevboxUntouched(t *testing.T) {
+	inst := newUsersInstance("authentication_backend:\n  file:\n    watch: true\n")
+	state := fullState()
+	state.CloudCode.DevboxMode = config.DevboxModePerUser
+	if err := inst.managerWithState(t, &bytes.Buffer{}, state).Passwd(context.Background(), "bob", "new-password"); err != nil {
+		t.Fatalf("Passwd failed: %v", err)
+	}
+	if _, ok := inst.files["/root/cloudcode/docker-compose.yml"]; ok || len(inst.commands) != 0 {
+		t.Errorf("passwd should not touch devboxes, commands %v", inst.commands)
+	}
+}
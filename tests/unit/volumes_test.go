@@ -0,0 +1,151 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/objstore"
+	"github.com/hwuu/cloudcode/internal/remote"
+)
+
+// volumeInstance 模拟实例上的 SSH 命令和流式传输
+type volumeInstance struct {
+	commands []string
+	streams  []string
+	received string // 恢复时传到实例的数据
+}
+
+func (inst *volumeInstance) manager(t *testing.T, state *config.State, input string, out *bytes.Buffer) *deploy.BackupManager {
+	t.Helper()
+	m := newBackupManager(t, &backupCloud{}, state, input, out)
+	writeDummySSHKey(t, m.StateDir)
+	m.SSHDialFunc = func(host string, port int, user string, privateKey []byte) remote.DialFunc {
+		return func() (remote.SSHClient, error) {
+			return &MockSSHClient{
+				RunCommandFunc: func(ctx context.Context, cmd string) (string, error) {
+					inst.commands = append(inst.commands, cmd)
+					return "", nil
+				},
+				StreamCommandFunc: func(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+					inst.streams = append(inst.streams, cmd)
+					inst.commands = append(inst.commands, "stream")
+					if stdin != nil {
+						data, _ := io.ReadAll(stdin)
+						inst.received = string(data)
+						return nil
+					}
+					_, err := stdout.Write([]byte("tarball"))
+					return err
+				},
+			}, nil
+		}
+	}
+	return m
+}
+
+func TestVolumeBackupName(t *testing.T) {
+	got := deploy.VolumeBackupName("prod", time.Date(2026, 3, 1, 3, 4, 5, 0, time.UTC))
+	if got != "cloudcode-volumes-prod-20260301-030405.tar.gz" {
+		t.Errorf("unexpected name %s", got)
+	}
+}
+
+func TestBackupVolumes_ToLocalFile(t *testing.T) {
+	inst := &volumeInstance{}
+	out := &bytes.Buffer{}
+	m := inst.manager(t, fullState(), "", out)
+	path := filepath.Join(t.TempDir(), "data.tar.gz")
+
+	if err := m.BackupVolumes(context.Background(), &objstore.FileStore{Path: path}, false); err != nil {
+		t.Fatalf("BackupVolumes failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "tarball" {
+		t.Errorf("unexpected backup content %q", data)
+	}
+	script := inst.streams[0]
+	for _, want := range []string{"label=com.docker.compose.project=cloudcode", "cloudcode_caddy_*) continue", "/root/cloudcode/authelia:/backup/authelia:ro", "czf -"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("backup command missing %q:\n%s", want, script)
+		}
+	}
+	if !strings.Contains(out.String(), "数据已备份: "+path+" (7 B)") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestBackupVolumes_QuiesceAndS3(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	inst := &volumeInstance{}
+	m := inst.manager(t, fullState(), "", &bytes.Buffer{})
+	store, _ := objstore.New("s3://backups/", "v.tar.gz", newTestS3Client(t, srv.URL))
+
+	if err := m.BackupVolumes(context.Background(), store, true); err != nil {
+		t.Fatalf("BackupVolumes failed: %v", err)
+	}
+	if string(fake.objects["/backups/v.tar.gz"]) != "tarball" {
+		t.Errorf("object not uploaded: %v", fake.requests)
+	}
+	if len(inst.commands) != 3 || !strings.Contains(inst.commands[0], "docker compose stop") ||
+		inst.commands[1] != "stream" || !strings.Contains(inst.commands[2], "docker compose start") {
+		t.Errorf("containers should be stopped only while archiving, got %v", inst.commands)
+	}
+}
+
+func TestBackupVolumes_Suspended(t *testing.T) {
+	state := fullState()
+	state.Status = "suspended"
+	m := (&volumeInstance{}).manager(t, state, "", &bytes.Buffer{})
+	err := m.BackupVolumes(context.Background(), &objstore.FileStore{Path: filepath.Join(t.TempDir(), "x")}, false)
+	if err == nil || !strings.Contains(err.Error(), "cloudcode resume") {
+		t.Errorf("expected suspended error, got %v", err)
+	}
+}
+
+func TestRestoreVolumes(t *testing.T) {
+	inst := &volumeInstance{}
+	out := &bytes.Buffer{}
+	m := inst.manager(t, fullState(), "y\n", out)
+	path := filepath.Join(t.TempDir(), "data.tar.gz")
+	os.WriteFile(path, []byte("archive"), 0600)
+
+	restored, err := m.RestoreVolumes(context.Background(), &objstore.FileStore{Path: path}, false)
+	if err != nil || !restored {
+		t.Fatalf("RestoreVolumes failed: %v, %v", restored, err)
+	}
+	if inst.received != "archive" || !strings.HasPrefix(inst.streams[0], "cat > ") {
+		t.Errorf("archive not uploaded: %q via %v", inst.received, inst.streams)
+	}
+	script := inst.commands[len(inst.commands)-1]
+	for _, want := range []string{"docker compose stop", "tar tzf", "cloudcode_$n:/backup/volumes/$n", "tar xzf /archive.tar.gz -C /backup"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("restore command missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestRestoreVolumes_Cancelled(t *testing.T) {
+	inst := &volumeInstance{}
+	m := inst.manager(t, fullState(), "n\n", &bytes.Buffer{})
+	restored, err := m.RestoreVolumes(context.Background(), &objstore.FileStore{Path: "/nonexistent"}, false)
+	if err != nil || restored {
+		t.Errorf("expected cancelled restore, got %v, %v", restored, err)
+	}
+	if len(inst.commands) != 0 {
+		t.Errorf("cancelled restore should not run commands: %v", inst.commands)
+	}
+}
+
+func TestRestoreVolumes_MissingFile(t *testing.T) {
+	m := (&volumeInstance{}).manager(t, fullState(), "", &bytes.Buffer{})
+	if _, err := m.RestoreVolumes(context.Background(), &objstore.FileStore{Path: "/nonexistent"}, true); err == nil {
+		t.Error("expected error for missing backup file")
+	}
+}
@@ -0,0 +1,319 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	dnsclient "github.com/alibabacloud-go/alidns-20150109/v4/client"
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	stsclient "github.com/alibabacloud-go/sts-20150401/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hwuu/cloudcode/internal/alicloud"
+	"github.com/hwuu/cloudcode/internal/config"
+	"github.com/hwuu/cloudcode/internal/deploy"
+	"github.com/hwuu/cloudcode/internal/wake"
+)
+
+// --- 唤醒页服务 ---
+
+// newWakeServer 创建唤醒页服务，实例状态由 status 控制
+func newWakeServer(status *string, started *int, ready bool) *wake.Server {
+	ecs := &MockECSAPI{
+		DescribeInstancesFunc: func(req *ecsclient.DescribeInstancesRequest) (*ecsclient.DescribeInstancesResponse, error) {
+			return &ecsclient.DescribeInstancesResponse{
+				Body: &ecsclient.DescribeInstancesResponseBody{
+					Instances: &ecsclient.DescribeInstancesResponseBodyInstances{
+						Instance: []*ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+							{InstanceId: tea.String("i-test"), Status: status},
+						},
+					},
+				},
+			}, nil
+		},
+		StartInstanceFunc: func(req *ecsclient.StartInstanceRequest) (*ecsclient.StartInstanceResponse, error) {
+			*started++
+			return &ecsclient.StartInstanceResponse{}, nil
+		},
+	}
+	return &wake.Server{
+		Config: wake.Config{
+			InstanceID:     "i-test",
+			Region:         "ap-southeast-1",
+			Target:         "https://code.example.com",
+			KeyHash:        wake.HashKey("secret-key"),
+			CookieSecret:   "cookie-secret",
+			ACMEThumbprint: "thumb",
+		},
+		NewECS: func(r *http.Request) (alicloud.ECSAPI, error) { return ecs, nil },
+		Probe:  func(ctx context.Context, url string) bool { return ready },
+	}
+}
+
+// wakeLogin 登录并返回 cookie
+func wakeLogin(t *testing.T, h http.Handler, key string) (*httptest.ResponseRecorder, []*http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(url.Values{"key": {key}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec, rec.Result().Cookies()
+}
+
+func wakeRequest(h http.Handler, method, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWakeServer_Login(t *testing.T) {
+	status, started := "Stopped", 0
+	h := newWakeServer(&status, &started, false).Handler()
+
+	rec := wakeRequest(h, http.MethodGet, "/", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `name="key"`) {
+		t.Fatalf("expected login form, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	if rec := wakeRequest(h, http.MethodPost, "/start", nil); rec.Code != http.StatusUnauthorized || started != 0 {
+		t.Fatalf("start without login should be rejected, got %d", rec.Code)
+	}
+
+	rec, cookies := wakeLogin(t, h, "wrong-key")
+	if rec.Code != http.StatusUnauthorized || len(cookies) != 0 || !strings.Contains(rec.Body.String(), "唤醒密钥不正确") {
+		t.Fatalf("wrong key should be rejected, got %d", rec.Code)
+	}
+
+	rec, cookies = wakeLogin(t, h, "secret-key")
+	if rec.Code != http.StatusSeeOther || len(cookies) != 1 || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("expected secure session cookie, got %d %+v", rec.Code, cookies)
+	}
+	rec = wakeRequest(h, http.MethodGet, "/", cookies)
+	if !strings.Contains(rec.Body.String(), "启动 devbox") || !strings.Contains(rec.Body.String(), `"https://code.example.com"`) {
+		t.Errorf("expected wake page after login:\n%s", rec.Body.String())
+	}
+
+	// 篡改的 cookie 无效
+	forged := []*http.Cookie{{Name: cookies[0].Name, Value: "99999999999.deadbeef"}}
+	if rec := wakeRequest(h, http.MethodGet, "/status", forged); rec.Code != http.StatusUnauthorized {
+		t.Errorf("forged cookie should be rejected, got %d", rec.Code)
+	}
+}
+
+func TestWakeServer_StartAndStatus(t *testing.T) {
+	status, started := "Stopped", 0
+	s := newWakeServer(&status, &started, false)
+	h := s.Handler()
+	_, cookies := wakeLogin(t, h, "secret-key")
+
+	rec := wakeRequest(h, http.MethodPost, "/start", cookies)
+	if rec.Code != http.StatusOK || started != 1 || !strings.Contains(rec.Body.String(), `"status":"Starting"`) {
+		t.Fatalf("expected StartInstance, got %d started=%d: %s", rec.Code, started, rec.Body.String())
+	}
+
+	status = "Running"
+	if rec := wakeRequest(h, http.MethodGet, "/status", cookies); !strings.Contains(rec.Body.String(), `"ready":false`) {
+		t.Errorf("should wait until Caddy is reachable: %s", rec.Body.String())
+	}
+	s.Probe = func(ctx context.Context, url string) bool { return url == "https://code.example.com" }
+	if rec := wakeRequest(h, http.MethodGet, "/status", cookies); !strings.Contains(rec.Body.String(), `"ready":true`) {
+		t.Errorf("expected ready: %s", rec.Body.String())
+	}
+
+	// 已在运行时不重复启动
+	wakeRequest(h, http.MethodPost, "/start", cookies)
+	if started != 1 {
+		t.Errorf("running instance should not be started again, got %d", started)
+	}
+}
+
+func TestWakeServer_ACMEChallenge(t *testing.T) {
+	status, started := "Stopped", 0
+	h := newWakeServer(&status, &started, false).Handler()
+
+	rec := wakeRequest(h, http.MethodGet, "/.well-known/acme-challenge/tok123", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "tok123.thumb" {
+		t.Errorf("unexpected challenge response %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// --- WakeManager ---
+
+type stubIssuer struct {
+	issued []string
+}
+
+func (s *stubIssuer) Thumbprint() (string, error) { return "thumb", nil }
+
+func (s *stubIssuer) Issue(ctx context.Context, domain string) (*wake.Certificate, error) {
+	s.issued = append(s.issued, domain)
+	return &wake.Certificate{CertPEM: "CERT", KeyPEM: "KEY", NotAfter: time.Now().Add(90 * 24 * time.Hour)}, nil
+}
+
+func newWakeManager(t *testing.T, state *config.State, fc *MockFCAPI, ram *MockRAMAPI, issuer *stubIssuer, dns *MockDnsAPI, out *bytes.Buffer) *deploy.WakeManager {
+	t.Helper()
+	stateDir := t.TempDir()
+	writeTestState(t, stateDir, state)
+	accountID := "1234567890"
+	m := &deploy.WakeManager{
+		ECS: &MockECSAPI{},
+		STS: &MockSTSAPI{
+			GetCallerIdentityFunc: func() (*stsclient.GetCallerIdentityResponse, error) {
+				return &stsclient.GetCallerIdentityResponse{
+					Body: &stsclient.GetCallerIdentityResponseBody{
+						AccountId: &accountID,
+						UserId:    tea.String("user-1"),
+						Arn:       tea.String("acs:ram::1234567890:user/test"),
+					},
+				}, nil
+			},
+		},
+		RAM:         ram,
+		FC:          fc,
+		Output:      out,
+		Region:      "ap-southeast-1",
+		StateDir:    stateDir,
+		Binary:      func() ([]byte, error) { return []byte("binary"), nil },
+		Issuer:      issuer,
+		LookupCNAME: func(host string) (string, error) { return "1234567890.ap-southeast-1.fc.aliyuncs.com.", nil },
+	}
+	if dns != nil {
+		m.DNS = dns
+	}
+	return m
+}
+
+func wakeDNS(added *[]string) *MockDnsAPI {
+	return &MockDnsAPI{
+		DescribeDomainsFunc: func(req *dnsclient.DescribeDomainsRequest) (*dnsclient.DescribeDomainsResponse, error) {
+			return &dnsclient.DescribeDomainsResponse{
+				Body: &dnsclient.DescribeDomainsResponseBody{
+					TotalCount: tea.Int64(1),
+					Domains: &dnsclient.DescribeDomainsResponseBodyDomains{
+						Domain: []*dnsclient.DescribeDomainsResponseBodyDomainsDomain{{DomainName: tea.String("example.com")}},
+					},
+				},
+			}, nil
+		},
+		DescribeDomainRecordsFunc: func(req *dnsclient.DescribeDomainRecordsRequest) (*dnsclient.DescribeDomainRecordsResponse, error) {
+			return &dnsclient.DescribeDomainRecordsResponse{Body: &dnsclient.DescribeDomainRecordsResponseBody{}}, nil
+		},
+		AddDomainRecordFunc: func(req *dnsclient.AddDomainRecordRequest) (*dnsclient.AddDomainRecordResponse, error) {
+			*added = append(*added, *req.RR+" "+*req.Type+" "+*req.Value)
+			return &dnsclient.AddDomainRecordResponse{}, nil
+		},
+	}
+}
+
+func TestWakeEnable(t *testing.T) {
+	state := fullState()
+	state.DeploymentID = "abc123"
+	state.CloudCode.Domain = "code.example.com"
+	fc := &MockFCAPI{
+		GetFunctionFunc:     func(string) error { return errors.New("FunctionNotFound") },
+		GetCustomDomainFunc: func(string) error { return errors.New("DomainNameNotFound") },
+	}
+	ram := &MockRAMAPI{}
+	issuer := &stubIssuer{}
+	var added []string
+	out := &bytes.Buffer{}
+	m := newWakeManager(t, state, fc, ram, issuer, wakeDNS(&added), out)
+
+	if err := m.Enable(context.Background(), "", false); err != nil {
+		t.Fatalf("Enable failed: %v\n%s", err, out.String())
+	}
+
+	if got := strings.Join(fc.Calls, ","); got != "GetFunction,CreateFunction,CreateHTTPTrigger,GetCustomDomain,CreateCustomDomain,UpdateCustomDomain" {
+		t.Errorf("unexpected FC calls: %s", got)
+	}
+	fn := fc.Function
+	if fn.Name != "cloudcode-wake-abc123" || fn.RoleARN != "acs:ram::1234567890:role/cloudcode-wake-abc123" {
+		t.Errorf("unexpected function: %+v", fn)
+	}
+	if fn.Env[wake.EnvInstanceID] != "i-test" || fn.Env[wake.EnvTarget] != "https://code.example.com" || fn.Env[wake.EnvACMEThumbprint] != "thumb" {
+		t.Errorf("unexpected function env: %v", fn.Env)
+	}
+	if len(added) != 1 || added[0] != "wake.code CNAME 1234567890.ap-southeast-1.fc.aliyuncs.com" {
+		t.Errorf("expected CNAME record, got %v", added)
+	}
+	if len(issuer.issued) != 1 || fc.CustomDomain.DomainName != "wake.code.example.com" || fc.CustomDomain.CertPEM != "CERT" {
+		t.Errorf("expected certificate for wake domain, issued=%v domain=%+v", issuer.issued, fc.CustomDomain)
+	}
+	if !strings.Contains(strings.Join(ram.Calls, ","), "CreatePolicy") {
+		t.Errorf("expected wake role policy, got %v", ram.Calls)
+	}
+
+	// 唤醒密钥只显示一次，state 中保存哈希
+	match := regexp.MustCompile(`唤醒密钥（只显示一次，请妥善保存）: ([0-9a-f]+)`).FindStringSubmatch(out.String())
+	if match == nil {
+		t.Fatalf("wake key should be printed:\n%s", out.String())
+	}
+	saved, _ := config.LoadStateFrom(m.StateDir)
+	if saved.Resources.Wake.KeyHash != wake.HashKey(match[1]) || fn.Env[wake.EnvKeyHash] != saved.Resources.Wake.KeyHash {
+		t.Error("state and function should store the key hash")
+	}
+	if saved.Resources.Wake.Domain != "wake.code.example.com" || saved.Resources.Wake.CertExpiresAt == "" {
+		t.Errorf("unexpected wake state: %+v", saved.Resources.Wake)
+	}
+
+	// 再次运行：更新函数，沿用密钥，证书未到期不重新签发
+	fc.Calls = nil
+	fc.GetFunctionFunc = nil
+	fc.GetCustomDomainFunc = nil
+	out.Reset()
+	if err := m.Enable(context.Background(), "", false); err != nil {
+		t.Fatalf("second Enable failed: %v", err)
+	}
+	if got := strings.Join(fc.Calls, ","); got != "GetFunction,UpdateFunction,GetCustomDomain" {
+		t.Errorf("unexpected FC calls on update: %s", got)
+	}
+	if len(issuer.issued) != 1 || strings.Contains(out.String(), "唤醒密钥") {
+		t.Error("should keep the certificate and key")
+	}
+}
+
+func TestWakeEnable_RequiresOwnDomain(t *testing.T) {
+	state := fullState() // 47.100.1.1.nip.io
+	fc := &MockFCAPI{}
+	m := newWakeManager(t, state, fc, &MockRAMAPI{}, &stubIssuer{}, nil, &bytes.Buffer{})
+
+	err := m.Enable(context.Background(), "", false)
+	if err == nil || !strings.Contains(err.Error(), "自有域名") {
+		t.Fatalf("expected own domain error, got %v", err)
+	}
+	if len(fc.Calls) != 0 {
+		t.Errorf("no FC calls expected, got %v", fc.Calls)
+	}
+}
+
+func TestWakeDisable(t *testing.T) {
+	state := fullState()
+	state.Resources.Wake = config.WakeResource{FunctionName: "cloudcode-wake-abc123", Domain: "wake.code.example.com", KeyHash: "h"}
+	fc := &MockFCAPI{DeleteTriggerFunc: func(string, string) error { return errors.New("TriggerNotFound") }}
+	ram := &MockRAMAPI{}
+	m := newWakeManager(t, state, fc, ram, &stubIssuer{}, nil, &bytes.Buffer{})
+
+	if err := m.Disable(context.Background()); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	if got := strings.Join(fc.Calls, ","); got != "DeleteCustomDomain,DeleteTrigger,DeleteFunction" {
+		t.Errorf("unexpected FC calls: %s", got)
+	}
+	if got := strings.Join(ram.Calls, ","); got != "DetachPolicyFromRole,DeletePolicy,DeleteRole" {
+		t.Errorf("unexpected RAM calls: %s", got)
+	}
+	saved, _ := config.LoadStateFrom(m.StateDir)
+	if saved.Resources.Wake.FunctionName != "" {
+		t.Error("state should clear wake resources")
+	}
+}